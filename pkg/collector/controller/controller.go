@@ -23,6 +23,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/cleaner"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/hook"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/labelstore"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/tap"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/tracestore"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/wait"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pingserver"
@@ -501,11 +502,14 @@ loop:
 
 			start := time.Now()
 			rtype := task.Record().RecordType
+			tapper := tap.NewRecorder(task.Record())
 			for i := 0; i < task.StageCount(); i++ {
 				// 任务执行应该事务的 一旦中间某一环执行失败那就整体失败
 				stage := task.StageAt(i)
 				logger.Debugf("process original stage: %s, recordType: %+v", stage, task.Record().RecordType)
+				tapper.Capture(stage, tap.PhaseBefore, nil)
 				derivedRecord, err := c.pipelineMgr.GetProcessor(stage).Process(task.Record())
+				tapper.Capture(stage, tap.PhaseAfter, err)
 				if err == define.ErrSkipEmptyRecord {
 					token := task.Record().Token
 					DefaultMetricMonitor.IncSkippedCounter(task.PipelineName(), rtype, token.GetDataID(rtype), stage, token.Original)
@@ -564,12 +568,15 @@ loop:
 
 			start := time.Now()
 			rtype := task.Record().RecordType
+			tapper := tap.NewRecorder(task.Record())
 			for i := 0; i < task.StageCount(); i++ {
 				// 任务执行应该事务的 一旦中间某一环执行失败那就整体失败
 				// 无需再关注是否为 derived 类型
 				stage := task.StageAt(i)
 				logger.Debugf("process derived stage: %s, recordType: %+v", stage, task.Record().RecordType)
+				tapper.Capture(stage, tap.PhaseBefore, nil)
 				_, err := c.pipelineMgr.GetProcessor(stage).Process(task.Record())
+				tapper.Capture(stage, tap.PhaseAfter, err)
 				if err == define.ErrSkipEmptyRecord {
 					token := task.Record().Token
 					logger.Warnf("skip empty record '%s' at stage: %v, token: %+v, err: %v", task.Record().RecordType, stage, token, err)
@@ -22,11 +22,13 @@ import (
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/ratelimiter"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/resourcefilter"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/sampler"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/seriesguard"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/servicediscover"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/tokenchecker"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/tracesderiver"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver/jaeger"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver/otlp"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver/promscrape"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver/pushgateway"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver/remotewrite"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver/skywalking"
@@ -34,6 +34,7 @@ const (
 	SourceZipkin      = "zipkin"
 	SourceProxy       = "proxy"
 	SourceSkywalking  = "skywalking"
+	SourcePromScrape  = "promscrape"
 )
 
 type RecordType string
@@ -93,6 +94,8 @@ const (
 	RequestHttp    RequestType = "http"
 	RequestGrpc    RequestType = "grpc"
 	RequestICMP    RequestType = "icmp"
+	RequestUdp     RequestType = "udp"
+	RequestKafka   RequestType = "kafka"
 	RequestDerived RequestType = "derived"
 )
 
@@ -150,6 +153,9 @@ type PushMode string
 const (
 	PushModeGuarantee  PushMode = "guarantee"
 	PushModeDropIfFull PushMode = "dropIfFull"
+
+	// PushModeSpillToDisk 队列已满时溢写至磁盘 目前仅 exporter 发送队列支持
+	PushModeSpillToDisk PushMode = "spillToDisk"
 )
 
 type RecordQueue struct {
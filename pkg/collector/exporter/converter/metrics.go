@@ -73,15 +73,45 @@ type otMetricMapper struct {
 	Value      float64
 	Dimensions map[string]string
 	Time       time.Time
+	Exemplar   common.MapStr
 }
 
 func (p otMetricMapper) AsMapStr() common.MapStr {
-	return common.MapStr{
+	ms := common.MapStr{
 		"metrics":   map[string]float64{p.Metric: p.Value},
 		"target":    define.Identity(),
 		"timestamp": p.Time.UnixMilli(),
 		"dimension": p.Dimensions,
 	}
+
+	// 按需处理 exemplar 数据
+	if p.Exemplar != nil {
+		ms["exemplar"] = p.Exemplar
+	}
+	return ms
+}
+
+// wrapExemplar 取首个有效的 exemplar 格式与 pushgateway 保持一致
+// 当且仅当 traceID/spanID 不为空时才生效
+func wrapExemplar(exemplars pmetric.ExemplarSlice) common.MapStr {
+	for i := 0; i < exemplars.Len(); i++ {
+		exemplar := exemplars.At(i)
+		if exemplar.TraceID().IsEmpty() || exemplar.SpanID().IsEmpty() {
+			continue
+		}
+
+		val := exemplar.DoubleVal()
+		if exemplar.ValueType() == pmetric.ExemplarValueTypeInt {
+			val = float64(exemplar.IntVal())
+		}
+		return common.MapStr{
+			"bk_trace_timestamp": exemplar.Timestamp().AsTime().UnixMilli(),
+			"bk_trace_value":     val,
+			"bk_trace_id":        exemplar.TraceID().HexString(),
+			"bk_span_id":         exemplar.SpanID().HexString(),
+		}
+	}
+	return nil
 }
 
 func (c metricsConverter) Extract(dataId int32, pdMetric pmetric.Metric, rsAttrs pcommon.Map) []common.MapStr {
@@ -101,6 +131,7 @@ func (c metricsConverter) Extract(dataId int32, pdMetric pmetric.Metric, rsAttrs
 				Value:      dp.DoubleVal(),
 				Time:       dp.Timestamp().AsTime(),
 				Dimensions: utils.MergeReplaceAttributeMaps(dp.Attributes(), rsAttrs),
+				Exemplar:   wrapExemplar(dp.Exemplars()),
 			}
 			items = append(items, m.AsMapStr())
 		}
@@ -170,6 +201,7 @@ func (c metricsConverter) Extract(dataId int32, pdMetric pmetric.Metric, rsAttrs
 				Value:      dp.DoubleVal(),
 				Dimensions: utils.MergeReplaceAttributeMaps(dp.Attributes(), rsAttrs),
 				Time:       dp.Timestamp().AsTime(),
+				Exemplar:   wrapExemplar(dp.Exemplars()),
 			}
 			items = append(items, m.AsMapStr())
 		}
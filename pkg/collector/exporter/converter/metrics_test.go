@@ -14,6 +14,7 @@ import (
 
 	"github.com/elastic/beats/libbeat/common"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/generator"
@@ -79,6 +80,34 @@ func TestConvertGaugeMetrics(t *testing.T) {
 	assert.Equal(t, event.RecordType(), define.RecordMetrics)
 }
 
+func TestConvertGaugeMetricsWithExemplar(t *testing.T) {
+	opts := define.MetricsOptions{
+		GaugeCount: 1,
+		MetricName: "bk_apm_duration_bucket",
+	}
+	metrics := generator.NewMetricsGenerator(opts).Generate()
+
+	dp := testkits.FirstGaugeDataPoint(metrics)
+	dp.SetTimestamp(0)
+	exemplar := dp.Exemplars().AppendEmpty()
+	exemplar.SetTraceID(pcommon.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}))
+	exemplar.SetSpanID(pcommon.NewSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8}))
+	exemplar.SetTimestamp(pcommon.Timestamp(1000 * 1e6))
+	exemplar.SetDoubleVal(10)
+
+	events := make([]define.Event, 0)
+	NewCommonConverter().Convert(&define.Record{RecordType: define.RecordMetrics, Data: metrics}, func(evts ...define.Event) {
+		events = append(events, evts...)
+	})
+	assert.Len(t, events, 1)
+	assert.Equal(t, common.MapStr{
+		"bk_trace_timestamp": int64(1000),
+		"bk_trace_value":     float64(10),
+		"bk_trace_id":        "0102030405060708090a0b0c0d0e0f10",
+		"bk_span_id":         "0102030405060708",
+	}, events[0].Data()["exemplar"])
+}
+
 func TestConvertHistogramMetrics(t *testing.T) {
 	opts := define.MetricsOptions{
 		HistogramCount: 1,
@@ -50,6 +50,54 @@ var (
 		},
 		[]string{"record_type", "id"},
 	)
+
+	spoolWrittenTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "exporter_spool_written_total",
+			Help:      "Exporter spool written total",
+		},
+	)
+
+	spoolReplayedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "exporter_spool_replayed_total",
+			Help:      "Exporter spool replayed total",
+		},
+	)
+
+	spoolDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "exporter_spool_dropped_total",
+			Help:      "Exporter spool dropped total",
+		},
+	)
+
+	spoolEvictedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "exporter_spool_evicted_segments_total",
+			Help:      "Exporter spool evicted segments total",
+		},
+	)
+
+	spoolCorruptedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "exporter_spool_corrupted_segments_total",
+			Help:      "Exporter spool corrupted segments total",
+		},
+	)
+
+	spoolSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "exporter_spool_size_bytes",
+			Help:      "Exporter spool size bytes",
+		},
+	)
 )
 
 func init() {
@@ -57,6 +105,12 @@ func init() {
 		queueFullTotal,
 		queueTickTotal,
 		queuePopBatchSize,
+		spoolWrittenTotal,
+		spoolReplayedTotal,
+		spoolDroppedTotal,
+		spoolEvictedTotal,
+		spoolCorruptedTotal,
+		spoolSize,
 	)
 }
 
@@ -76,6 +130,30 @@ func (m *metricMonitor) ObserveQueuePopBatchSizeDistribution(n int, dataId int32
 	queuePopBatchSize.WithLabelValues(rtype.S(), strconv.Itoa(int(dataId))).Observe(float64(n))
 }
 
+func (m *metricMonitor) IncSpoolWrittenCounter() {
+	spoolWrittenTotal.Inc()
+}
+
+func (m *metricMonitor) IncSpoolReplayedCounter() {
+	spoolReplayedTotal.Inc()
+}
+
+func (m *metricMonitor) IncSpoolDroppedCounter() {
+	spoolDroppedTotal.Inc()
+}
+
+func (m *metricMonitor) IncSpoolEvictedCounter() {
+	spoolEvictedTotal.Inc()
+}
+
+func (m *metricMonitor) IncSpoolCorruptedCounter() {
+	spoolCorruptedTotal.Inc()
+}
+
+func (m *metricMonitor) SetSpoolSize(n int64) {
+	spoolSize.Set(float64(n))
+}
+
 type BatchQueue struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -85,6 +163,7 @@ type BatchQueue struct {
 	out     chan common.MapStr
 	conf    Config
 	getSize func(string) Config
+	spool   *Spool // 仅 spillToDisk 模式下启用
 }
 
 // Config 不同类型的数据大小不同 因此要允许为每种类型单独设置队列批次
@@ -93,6 +172,10 @@ type Config struct {
 	LogsBatchSize    int           `config:"logs_batch_size" mapstructure:"logs_batch_size"`
 	TracesBatchSize  int           `config:"traces_batch_size" mapstructure:"traces_batch_size"`
 	FlushInterval    time.Duration `config:"flush_interval" mapstructure:"flush_interval"`
+
+	// PushMode 为 spillToDisk 时 下游阻塞期间的数据会溢写至磁盘 恢复后重放
+	PushMode string      `config:"push_mode" mapstructure:"push_mode"`
+	Spill    SpillConfig `config:"spill" mapstructure:"spill"`
 }
 
 func NewBatchQueue(conf Config, fn func(string) Config) Queue {
@@ -106,6 +189,17 @@ func NewBatchQueue(conf Config, fn func(string) Config) Queue {
 		getSize: fn,
 	}
 
+	if define.PushMode(conf.PushMode) == define.PushModeSpillToDisk {
+		spool, err := OpenSpool(conf.Spill)
+		if err != nil {
+			logger.Errorf("failed to open spool, fallback to memory queue, err: %v", err)
+		} else {
+			cq.spool = spool
+			cq.wg.Add(1)
+			go cq.replay()
+		}
+	}
+
 	return cq
 }
 
@@ -157,14 +251,14 @@ func (bq *BatchQueue) compact(dc DataIDChan) {
 		DefaultMetricMonitor.ObserveQueuePopBatchSizeDistribution(len(data), dc.dataID, dc.rtype)
 		switch dc.rtype {
 		case define.RecordTraces, define.RecordLogs:
-			bq.out <- NewEventsMapStr(dc.dataID, data)
+			bq.send(NewEventsMapStr(dc.dataID, data))
 		case define.RecordMetrics, define.RecordPushGateway, define.RecordRemoteWrite:
-			bq.out <- NewMetricsMapStr(dc.dataID, data)
+			bq.send(NewMetricsMapStr(dc.dataID, data))
 
 		// proxy/pingserver 数据不做聚合（没办法做聚合
 		case define.RecordProxy, define.RecordPingserver:
 			for _, item := range data {
-				bq.out <- item
+				bq.send(item)
 			}
 		}
 
@@ -201,6 +295,59 @@ func (bq *BatchQueue) compact(dc DataIDChan) {
 	}
 }
 
+// send 发送数据至 out 队列 spillToDisk 模式下队列已满时写入磁盘
+func (bq *BatchQueue) send(item common.MapStr) {
+	if bq.spool == nil {
+		bq.out <- item
+		return
+	}
+
+	select {
+	case bq.out <- item:
+	default:
+		if err := bq.spool.Write(item); err != nil {
+			DefaultMetricMonitor.IncSpoolDroppedCounter()
+			logger.Warnf("failed to write spool, err: %v", err)
+			return
+		}
+		DefaultMetricMonitor.IncSpoolWrittenCounter()
+	}
+}
+
+// replay 周期性地将磁盘中的数据重放至 out 队列
+func (bq *BatchQueue) replay() {
+	defer bq.wg.Done()
+
+	ticker := time.NewTicker(defaultSpillReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			bq.spool.Expire(time.Now())
+			DefaultMetricMonitor.SetSpoolSize(bq.spool.Size())
+
+			// 投递成功后才确认 避免退出时丢失已读取但未投递的数据
+			for {
+				item, ok := bq.spool.Peek()
+				if !ok {
+					break
+				}
+				select {
+				case bq.out <- item:
+					bq.spool.Ack()
+					DefaultMetricMonitor.IncSpoolReplayedCounter()
+				case <-bq.ctx.Done():
+					return
+				}
+			}
+
+		case <-bq.ctx.Done():
+			return
+		}
+	}
+}
+
 func (bq *BatchQueue) Pop() <-chan common.MapStr {
 	return bq.out
 }
@@ -208,6 +355,9 @@ func (bq *BatchQueue) Pop() <-chan common.MapStr {
 func (bq *BatchQueue) Close() {
 	bq.cancel()
 	bq.wg.Wait()
+	if bq.spool != nil {
+		bq.spool.Close()
+	}
 }
 
 func (bq *BatchQueue) Put(events ...define.Event) {
@@ -0,0 +1,403 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package queue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	segmentSuffix = ".seg"
+	entryHeadSize = 8 // length(4) + crc32(4)
+
+	defaultSpillDir            = "spool"
+	defaultSpillSegmentSize    = 16 * 1024 * 1024   // 16MB
+	defaultSpillMaxSize        = 1024 * 1024 * 1024 // 1GB
+	defaultSpillMaxAge         = time.Hour
+	defaultSpillReplayInterval = time.Second
+)
+
+var errSpoolFull = errors.New("spool exceeded max size")
+
+// SpillConfig 磁盘溢写配置 仅在 push_mode 为 spillToDisk 时生效
+type SpillConfig struct {
+	Dir            string        `config:"dir" mapstructure:"dir"`
+	MaxSegmentSize int64         `config:"max_segment_size" mapstructure:"max_segment_size"`
+	MaxSize        int64         `config:"max_size" mapstructure:"max_size"`
+	MaxAge         time.Duration `config:"max_age" mapstructure:"max_age"`
+}
+
+func (c *SpillConfig) Validate() {
+	if c.Dir == "" {
+		c.Dir = defaultSpillDir
+	}
+	if c.MaxSegmentSize <= 0 {
+		c.MaxSegmentSize = defaultSpillSegmentSize
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = defaultSpillMaxSize
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = defaultSpillMaxAge
+	}
+}
+
+// Spool 基于分段文件的磁盘队列
+//
+// 每个 segment 文件由若干 entry 组成 entry 格式为 [length(4)][crc32(4)][payload]
+// 写入总是追加到最新的 segment 读取从最旧的 segment 开始 消费完毕的 segment 会被删除
+// 读取偏移不做持久化 进程重启后未消费完的 segment 会从头重放 即至少一次语义
+type Spool struct {
+	mut  sync.Mutex
+	conf SpillConfig
+
+	segments []uint64 // 按序号升序排列
+	size     int64
+
+	writer     *os.File
+	writerSeq  uint64
+	writerSize int64
+
+	reader       *os.File
+	readerSeq    uint64
+	readerOffset int64
+	pendingSize  int64 // 最近一次 peek 的 entry 大小 ack 时推进 readerOffset
+
+	closed bool
+}
+
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%s", seq, segmentSuffix)
+}
+
+// OpenSpool 打开 dir 下的 spool 已存在的 segment 会被保留等待重放
+func OpenSpool(conf SpillConfig) (*Spool, error) {
+	conf.Validate()
+	if err := os.MkdirAll(conf.Dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(conf.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{conf: conf}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, seq)
+		s.size += info.Size()
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+
+	// 重启后总是写入新的 segment 避免追加到可能被截断的文件尾部
+	if n := len(s.segments); n > 0 {
+		s.writerSeq = s.segments[n-1] + 1
+	}
+	logger.Infof("spool opened, dir=%s, segments=%d, size=%d", conf.Dir, len(s.segments), s.size)
+	return s, nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.conf.Dir, segmentName(seq))
+}
+
+// Size 返回 spool 占用的磁盘大小
+func (s *Spool) Size() int64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.size
+}
+
+// Write 写入单条数据 超出 max_size 时优先淘汰最旧的 segment
+func (s *Spool) Write(item common.MapStr) error {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	entry := make([]byte, entryHeadSize+len(payload))
+	binary.BigEndian.PutUint32(entry[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(entry[4:8], crc32.ChecksumIEEE(payload))
+	copy(entry[entryHeadSize:], payload)
+	n := int64(len(entry))
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return errors.New("spool closed")
+	}
+
+	for s.size+n > s.conf.MaxSize && len(s.segments) > 0 && s.segments[0] != s.writerSeq {
+		DefaultMetricMonitor.IncSpoolEvictedCounter()
+		s.removeSegment(s.segments[0])
+	}
+	if s.size+n > s.conf.MaxSize {
+		return errSpoolFull
+	}
+
+	if s.writer != nil && s.writerSize > 0 && s.writerSize+n > s.conf.MaxSegmentSize {
+		s.closeWriter()
+		s.writerSeq++
+	}
+	if s.writer == nil {
+		f, err := os.OpenFile(s.path(s.writerSeq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		s.writer = f
+		s.writerSize = 0
+		s.segments = append(s.segments, s.writerSeq)
+	}
+
+	// 单次 Write 写入完整 entry 保证读取侧看到的都是完整数据
+	if _, err := s.writer.Write(entry); err != nil {
+		return err
+	}
+	s.writerSize += n
+	s.size += n
+	return nil
+}
+
+// Read 读取并确认最旧的一条数据 spool 为空时返回 false
+func (s *Spool) Read() (common.MapStr, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	item, ok := s.peek()
+	if ok {
+		s.ack()
+	}
+	return item, ok
+}
+
+// Peek 读取最旧的一条数据但不移动读取偏移 需调用 Ack 确认后才会消费该条数据
+// 重复 Peek 会返回同一条数据
+func (s *Spool) Peek() (common.MapStr, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.peek()
+}
+
+// Ack 确认最近一次 Peek 返回的数据已被投递
+func (s *Spool) Ack() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.ack()
+}
+
+func (s *Spool) ack() {
+	s.readerOffset += s.pendingSize
+	s.pendingSize = 0
+}
+
+func (s *Spool) peek() (common.MapStr, bool) {
+
+	for len(s.segments) > 0 && !s.closed {
+		seq := s.segments[0]
+		if s.reader == nil || s.readerSeq != seq {
+			s.closeReader()
+			f, err := os.Open(s.path(seq))
+			if err != nil {
+				logger.Warnf("spool failed to open segment %d, err: %v", seq, err)
+				s.removeSegment(seq)
+				continue
+			}
+			s.reader, s.readerSeq, s.readerOffset = f, seq, 0
+		}
+
+		item, err := s.readEntry()
+		if err == nil {
+			return item, true
+		}
+
+		// 正在写入的 segment 读到末尾说明暂无更多数据
+		if err == errIncompleteEntry && seq == s.writerSeq && s.writer != nil {
+			return nil, false
+		}
+		if err != errIncompleteEntry {
+			DefaultMetricMonitor.IncSpoolCorruptedCounter()
+			logger.Warnf("spool drop corrupted segment %d at offset %d, err: %v", seq, s.readerOffset, err)
+		}
+		if seq == s.writerSeq {
+			s.closeWriter()
+			s.writerSeq++
+		}
+		s.removeSegment(seq)
+	}
+	return nil, false
+}
+
+var errIncompleteEntry = errors.New("incomplete entry")
+
+func (s *Spool) readEntry() (common.MapStr, error) {
+	head := make([]byte, entryHeadSize)
+	if n, _ := s.reader.ReadAt(head, s.readerOffset); n < entryHeadSize {
+		return nil, errIncompleteEntry
+	}
+
+	length := binary.BigEndian.Uint32(head[0:4])
+	checksum := binary.BigEndian.Uint32(head[4:8])
+	if int64(length) > s.conf.MaxSegmentSize+s.conf.MaxSize {
+		return nil, errors.Errorf("invalid entry length %d", length)
+	}
+
+	payload := make([]byte, length)
+	if n, _ := s.reader.ReadAt(payload, s.readerOffset+entryHeadSize); n < int(length) {
+		return nil, errIncompleteEntry
+	}
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, errors.New("checksum mismatched")
+	}
+
+	// 使用 json.Number 保证整型字段（如 timestamp）不丢失精度
+	var item common.MapStr
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&item); err != nil {
+		return nil, err
+	}
+	if err := restoreDataID(item); err != nil {
+		return nil, err
+	}
+
+	s.pendingSize = entryHeadSize + int64(length)
+	return item, nil
+}
+
+// restoreDataID 将 dataid 还原为写入时的 int32 类型
+// 下游 gse 输出仅识别整型或字符串类型的 dataid json.Number 会被当成 0 导致数据丢失
+func restoreDataID(item common.MapStr) error {
+	v, ok := item["dataid"]
+	if !ok {
+		return nil
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil
+	}
+	dataID, err := strconv.ParseInt(n.String(), 10, 32)
+	if err != nil {
+		return errors.Wrapf(err, "invalid dataid %s", n)
+	}
+	item["dataid"] = int32(dataID)
+	return nil
+}
+
+// Expire 删除最后写入时间超过 max_age 的 segment
+func (s *Spool) Expire(now time.Time) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for len(s.segments) > 0 {
+		seq := s.segments[0]
+		info, err := os.Stat(s.path(seq))
+		if err == nil && now.Sub(info.ModTime()) <= s.conf.MaxAge {
+			return
+		}
+
+		DefaultMetricMonitor.IncSpoolEvictedCounter()
+		logger.Infof("spool drop expired segment %d", seq)
+		if seq == s.writerSeq {
+			s.closeWriter()
+			s.writerSeq++
+		}
+		s.removeSegment(seq)
+	}
+}
+
+func (s *Spool) removeSegment(seq uint64) {
+	if s.readerSeq == seq {
+		s.closeReader()
+	}
+
+	p := s.path(seq)
+	if info, err := os.Stat(p); err == nil {
+		s.size -= info.Size()
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("spool failed to remove segment %d, err: %v", seq, err)
+	}
+
+	for i := 0; i < len(s.segments); i++ {
+		if s.segments[i] == seq {
+			s.segments = append(s.segments[:i], s.segments[i+1:]...)
+			break
+		}
+	}
+	if s.size < 0 {
+		s.size = 0
+	}
+}
+
+func (s *Spool) closeWriter() {
+	if s.writer == nil {
+		return
+	}
+	if err := s.writer.Close(); err != nil {
+		logger.Warnf("spool failed to close segment %d, err: %v", s.writerSeq, err)
+	}
+	s.writer = nil
+	s.writerSize = 0
+}
+
+func (s *Spool) closeReader() {
+	if s.reader == nil {
+		return
+	}
+	_ = s.reader.Close()
+	s.reader = nil
+	s.readerOffset = 0
+	s.pendingSize = 0
+}
+
+// Close 关闭文件句柄 未消费的数据保留在磁盘等待下次重放
+func (s *Spool) Close() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.closeWriter()
+	s.closeReader()
+}
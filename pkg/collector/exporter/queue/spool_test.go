@@ -0,0 +1,211 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package queue
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastic/beats/libbeat/common"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+)
+
+func readAll(s *Spool) []common.MapStr {
+	var items []common.MapStr
+	for {
+		item, ok := s.Read()
+		if !ok {
+			return items
+		}
+		items = append(items, item)
+	}
+}
+
+func TestSpoolReadWrite(t *testing.T) {
+	s, err := OpenSpool(SpillConfig{Dir: t.TempDir(), MaxSegmentSize: 64})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Write(common.MapStr{"dataid": 1001, "index": i}))
+	}
+	assert.Greater(t, len(s.segments), 1)
+
+	items := readAll(s)
+	assert.Len(t, items, 10)
+	for i, item := range items {
+		assert.Equal(t, int32(1001), item["dataid"])
+		assert.Equal(t, json.Number(string(rune('0'+i))), item["index"])
+	}
+
+	// 除正在写入的 segment 外均已被清理
+	assert.Len(t, s.segments, 1)
+	assert.Equal(t, s.writerSeq, s.segments[0])
+}
+
+func TestSpoolPeekAck(t *testing.T) {
+	s, err := OpenSpool(SpillConfig{Dir: t.TempDir()})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	assert.NoError(t, s.Write(common.MapStr{"index": 0}))
+	assert.NoError(t, s.Write(common.MapStr{"index": 1}))
+
+	// 未确认前重复 Peek 返回同一条数据
+	item, ok := s.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, json.Number("0"), item["index"])
+	item, ok = s.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, json.Number("0"), item["index"])
+
+	s.Ack()
+	item, ok = s.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, json.Number("1"), item["index"])
+}
+
+func TestSpoolReplayOnRestart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(SpillConfig{Dir: dir})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Write(common.MapStr{"index": i}))
+	}
+	s.Close()
+
+	s, err = OpenSpool(SpillConfig{Dir: dir})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	assert.Greater(t, s.Size(), int64(0))
+	assert.Len(t, readAll(s), 3)
+	assert.Equal(t, int64(0), s.Size())
+}
+
+func TestSpoolCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(SpillConfig{Dir: dir})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Write(common.MapStr{"index": 0}))
+	assert.NoError(t, s.Write(common.MapStr{"index": 1}))
+	s.Close()
+
+	// 篡改第二条数据的 payload
+	p := filepath.Join(dir, segmentName(0))
+	b, err := os.ReadFile(p)
+	assert.NoError(t, err)
+	b[len(b)-2] = 'x'
+	assert.NoError(t, os.WriteFile(p, b, 0o644))
+
+	s, err = OpenSpool(SpillConfig{Dir: dir})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	items := readAll(s)
+	assert.Len(t, items, 1)
+	assert.Len(t, s.segments, 0)
+}
+
+func TestSpoolTruncated(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSpool(SpillConfig{Dir: dir})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Write(common.MapStr{"index": 0}))
+	assert.NoError(t, s.Write(common.MapStr{"index": 1}))
+	s.Close()
+
+	p := filepath.Join(dir, segmentName(0))
+	info, err := os.Stat(p)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(p, info.Size()-3))
+
+	s, err = OpenSpool(SpillConfig{Dir: dir})
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.Len(t, readAll(s), 1)
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	s, err := OpenSpool(SpillConfig{Dir: t.TempDir(), MaxSegmentSize: 32, MaxSize: 100})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Write(common.MapStr{"index": i}))
+	}
+	assert.LessOrEqual(t, s.Size(), int64(100))
+
+	// 最旧的数据被淘汰
+	items := readAll(s)
+	assert.Less(t, len(items), 10)
+	assert.Equal(t, json.Number("9"), items[len(items)-1]["index"])
+
+	// 单条数据超过总大小限制
+	assert.Equal(t, errSpoolFull, s.Write(common.MapStr{"data": string(make([]byte, 200))}))
+}
+
+func TestSpoolExpire(t *testing.T) {
+	s, err := OpenSpool(SpillConfig{Dir: t.TempDir(), MaxAge: time.Minute})
+	assert.NoError(t, err)
+	defer s.Close()
+
+	assert.NoError(t, s.Write(common.MapStr{"index": 0}))
+	s.Expire(time.Now())
+	assert.Len(t, s.segments, 1)
+
+	s.Expire(time.Now().Add(time.Hour))
+	assert.Len(t, s.segments, 0)
+	assert.Equal(t, int64(0), s.Size())
+
+	// 过期后仍可继续写入
+	assert.NoError(t, s.Write(common.MapStr{"index": 1}))
+	assert.Len(t, readAll(s), 1)
+}
+
+func TestQueueSpillToDisk(t *testing.T) {
+	conf := Config{
+		MetricsBatchSize: 1,
+		FlushInterval:    time.Second,
+		PushMode:         string(define.PushModeSpillToDisk),
+		Spill:            SpillConfig{Dir: t.TempDir()},
+	}
+	queue := NewBatchQueue(conf, func(s string) Config {
+		return Config{}
+	})
+	defer queue.Close()
+
+	// 下游未消费 超出 out 容量的数据溢写至磁盘 Put 不会被阻塞
+	total := define.Concurrency() + 10
+	for i := 0; i < total; i++ {
+		queue.Put(&testEvent{
+			CommonEvent: define.NewCommonEvent(define.Token{}, 1001, common.MapStr{"count": i}),
+		})
+	}
+
+	// 消费后磁盘数据被重放
+	var n int
+	timeout := time.After(10 * time.Second)
+	for n < total {
+		select {
+		case e := <-queue.Pop():
+			assert.Equal(t, int32(1001), e["dataid"])
+			n++
+		case <-timeout:
+			t.Fatalf("expected %d items, got %d", total, n)
+		}
+	}
+	assert.Equal(t, total, n)
+}
@@ -3,6 +3,7 @@ go 1.19
 module github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector
 
 require (
+	github.com/Shopify/sarama v1.32.0
 	github.com/TencentBlueKing/bkmonitor-datalink/pkg/libgse v0.0.0-00010101000000-000000000000
 	github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils v0.0.0-00010101000000-000000000000
 	github.com/apache/thrift v0.16.0
@@ -44,7 +45,6 @@ require (
 )
 
 require (
-	github.com/Shopify/sarama v1.32.0 // indirect
 	github.com/aead/chacha20 v0.0.0-20180709150244-8b13a72661da // indirect
 	github.com/armon/go-metrics v0.3.3 // indirect
 	github.com/beorn7/perks v1.0.1 // indirect
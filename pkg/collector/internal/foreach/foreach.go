@@ -11,6 +11,7 @@ package foreach
 
 import (
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
@@ -79,3 +80,41 @@ func MetricsWithResourceAttrs(resourceMetricsSlice pmetric.ResourceMetricsSlice,
 		}
 	}
 }
+
+func Logs(resourceLogsSlice plog.ResourceLogsSlice, f func(logRecord plog.LogRecord)) {
+	for i := 0; i < resourceLogsSlice.Len(); i++ {
+		scopeLogsSlice := resourceLogsSlice.At(i).ScopeLogs()
+		for j := 0; j < scopeLogsSlice.Len(); j++ {
+			logRecords := scopeLogsSlice.At(j).LogRecords()
+			for k := 0; k < logRecords.Len(); k++ {
+				f(logRecords.At(k))
+			}
+		}
+	}
+}
+
+func LogsWithResourceAttrs(resourceLogsSlice plog.ResourceLogsSlice, f func(rsAttrs pcommon.Map, logRecord plog.LogRecord)) {
+	for i := 0; i < resourceLogsSlice.Len(); i++ {
+		resourceLogs := resourceLogsSlice.At(i)
+		rsAttrs := resourceLogs.Resource().Attributes()
+		scopeLogsSlice := resourceLogs.ScopeLogs()
+		for j := 0; j < scopeLogsSlice.Len(); j++ {
+			logRecords := scopeLogsSlice.At(j).LogRecords()
+			for k := 0; k < logRecords.Len(); k++ {
+				f(rsAttrs, logRecords.At(k))
+			}
+		}
+	}
+}
+
+func LogsRemoveIf(resourceLogsSlice plog.ResourceLogsSlice, f func(logRecord plog.LogRecord) bool) {
+	resourceLogsSlice.RemoveIf(func(resourceLogs plog.ResourceLogs) bool {
+		resourceLogs.ScopeLogs().RemoveIf(func(scopeLogs plog.ScopeLogs) bool {
+			scopeLogs.LogRecords().RemoveIf(func(logRecord plog.LogRecord) bool {
+				return f(logRecord)
+			})
+			return scopeLogs.LogRecords().Len() == 0
+		})
+		return resourceLogs.ScopeLogs().Len() == 0
+	})
+}
@@ -18,6 +18,15 @@ type Metric struct {
 	Val        float64
 	Ts         pcommon.Timestamp
 	Dimensions map[string]string
+	Exemplar   *Exemplar // 可选 关联的 trace 上下文
+}
+
+// Exemplar 指标样本关联的 trace 上下文
+type Exemplar struct {
+	TraceID pcommon.TraceID
+	SpanID  pcommon.SpanID
+	Ts      pcommon.Timestamp
+	Val     float64
 }
 
 type ResourceKv struct {
@@ -57,5 +66,12 @@ func (b Builder) Build(name string, ms ...Metric) {
 		for k, v := range m.Dimensions {
 			metric.Attributes().UpsertString(k, v)
 		}
+		if m.Exemplar != nil {
+			exemplar := metric.Exemplars().AppendEmpty()
+			exemplar.SetTraceID(m.Exemplar.TraceID)
+			exemplar.SetSpanID(m.Exemplar.SpanID)
+			exemplar.SetTimestamp(m.Exemplar.Ts)
+			exemplar.SetDoubleVal(m.Exemplar.Val)
+		}
 	}
 }
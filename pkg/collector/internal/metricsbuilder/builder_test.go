@@ -86,3 +86,38 @@ func TestMetricsBuilder(t *testing.T) {
 		}
 	}
 }
+
+func TestMetricsBuilderWithExemplar(t *testing.T) {
+	builder := New()
+
+	now := pcommon.NewTimestampFromTime(time.Now())
+	traceID := pcommon.NewTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	spanID := pcommon.NewSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+
+	builder.Build("my_metrics", []Metric{
+		{
+			Val: 1.0,
+			Ts:  now,
+			Exemplar: &Exemplar{
+				TraceID: traceID,
+				SpanID:  spanID,
+				Ts:      now,
+				Val:     0.5,
+			},
+		},
+		{
+			Val: 2.0,
+			Ts:  now,
+		},
+	}...)
+
+	dps := builder.Get().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints()
+	assert.Equal(t, 1, dps.At(0).Exemplars().Len())
+	assert.Equal(t, 0, dps.At(1).Exemplars().Len())
+
+	exemplar := dps.At(0).Exemplars().At(0)
+	assert.Equal(t, traceID, exemplar.TraceID())
+	assert.Equal(t, spanID, exemplar.SpanID())
+	assert.Equal(t, now, exemplar.Timestamp())
+	assert.Equal(t, 0.5, exemplar.DoubleVal())
+}
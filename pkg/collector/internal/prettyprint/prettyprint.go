@@ -10,9 +10,11 @@
 package prettyprint
 
 import (
+	"fmt"
 	"runtime"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 
@@ -47,32 +49,100 @@ func Traces(traces ptrace.Traces) {
 	}
 
 	foreach.SpansWithResourceAttrs(traces.ResourceSpans(), func(rsAttrs pcommon.Map, span ptrace.Span) {
-		logger.Debugf("Pretty/Tracing: resource=%#v, traceID=%s, spanID=%s, spanName=%s, spanKind=%s, spanStatus=%s, spanAttributes=%#v",
-			rsAttrs,
-			span.TraceID().HexString(),
-			span.SpanID().HexString(),
-			span.Name(),
-			span.Kind().String(),
-			span.Status().Code().String(),
-			span.Attributes().AsRaw(),
-		)
+		logger.Debugf("Pretty/Tracing: %s", spanString(rsAttrs, span))
 	})
 }
 
+func spanString(rsAttrs pcommon.Map, span ptrace.Span) string {
+	return fmt.Sprintf("resource=%#v, traceID=%s, spanID=%s, spanName=%s, spanKind=%s, spanStatus=%s, spanAttributes=%#v",
+		rsAttrs.AsRaw(),
+		span.TraceID().HexString(),
+		span.SpanID().HexString(),
+		span.Name(),
+		span.Kind().String(),
+		span.Status().Code().String(),
+		span.Attributes().AsRaw(),
+	)
+}
+
 func Metrics(metrics pmetric.Metrics) {
 	if !onPretty() {
 		return
 	}
 
 	foreach.MetricsWithResourceAttrs(metrics.ResourceMetrics(), func(rsAttrs pcommon.Map, metric pmetric.Metric) {
-		logger.Debugf("Pretty/Metrics: resource=%#v, metric=%s, dataType=%s",
-			rsAttrs,
-			metric.Name(),
-			metric.DataType().String(),
-		)
+		logger.Debugf("Pretty/Metrics: %s", metricString(rsAttrs, metric))
 	})
 }
 
+func metricString(rsAttrs pcommon.Map, metric pmetric.Metric) string {
+	return fmt.Sprintf("resource=%#v, metric=%s, dataType=%s",
+		rsAttrs.AsRaw(),
+		metric.Name(),
+		metric.DataType().String(),
+	)
+}
+
+func logString(rsAttrs pcommon.Map, logRecord plog.LogRecord) string {
+	return fmt.Sprintf("resource=%#v, severity=%s, body=%s, attributes=%#v",
+		rsAttrs.AsRaw(),
+		logRecord.SeverityText(),
+		logRecord.Body().AsString(),
+		logRecord.Attributes().AsRaw(),
+	)
+}
+
+// Lines 将 record 数据格式化为多行文本 不受日志级别影响
+// 每个 span/metric/log/timeseries 对应一行
+func Lines(rtype define.RecordType, data interface{}) []string {
+	var lines []string
+	switch rtype {
+	case define.RecordTraces, define.RecordTracesDerived:
+		if pdTraces, ok := data.(ptrace.Traces); ok {
+			foreach.SpansWithResourceAttrs(pdTraces.ResourceSpans(), func(rsAttrs pcommon.Map, span ptrace.Span) {
+				lines = append(lines, spanString(rsAttrs, span))
+			})
+		}
+
+	case define.RecordMetrics, define.RecordMetricsDerived:
+		if pdMetrics, ok := data.(pmetric.Metrics); ok {
+			foreach.MetricsWithResourceAttrs(pdMetrics.ResourceMetrics(), func(rsAttrs pcommon.Map, metric pmetric.Metric) {
+				lines = append(lines, metricString(rsAttrs, metric))
+			})
+		}
+
+	case define.RecordLogs, define.RecordLogsDerived:
+		if pdLogs, ok := data.(plog.Logs); ok {
+			foreach.LogsWithResourceAttrs(pdLogs.ResourceLogs(), func(rsAttrs pcommon.Map, logRecord plog.LogRecord) {
+				lines = append(lines, logString(rsAttrs, logRecord))
+			})
+		}
+
+	case define.RecordRemoteWrite:
+		if rwData, ok := data.(*define.RemoteWriteData); ok {
+			for _, ts := range rwData.Timeseries {
+				lines = append(lines, fmt.Sprintf("labels=%v, samples=%d", ts.Labels, len(ts.Samples)))
+			}
+		}
+
+	case define.RecordPushGateway:
+		if pgData, ok := data.(*define.PushGatewayData); ok && pgData.MetricFamilies != nil {
+			for _, m := range pgData.MetricFamilies.Metric {
+				lines = append(lines, fmt.Sprintf("metric=%s, type=%s, groupLabels=%v, labels=%v",
+					pgData.MetricFamilies.GetName(),
+					pgData.MetricFamilies.GetType().String(),
+					pgData.Labels,
+					m.Label,
+				))
+			}
+		}
+
+	default:
+		lines = append(lines, fmt.Sprintf("%+v", data))
+	}
+	return lines
+}
+
 func bToMb(b uint64) uint64 {
 	return b / 1024 / 1024
 }
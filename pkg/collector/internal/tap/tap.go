@@ -0,0 +1,249 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/prettyprint"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/ratelimiter"
+)
+
+// tap 用于线上排查 在 pipeline 每个 processor 执行前后抓取指定 token 的 record
+// 会话有最长存活时间且按 qps 采样 避免对生产环境造成影响
+
+const (
+	PhaseBefore = "before"
+	PhaseAfter  = "after"
+
+	DefaultTTL = 30 * time.Second
+	MaxTTL     = 2 * time.Minute // 需小于 http server 的写超时
+	DefaultQps = 1
+	MaxQps     = 10
+
+	maxSessions = 8
+	bufferSize  = 128
+)
+
+var (
+	errTooManySessions = errors.New("too many tap sessions")
+	errEmptyToken      = errors.New("empty token")
+)
+
+// Event 单次抓取结果
+type Event struct {
+	Time       time.Time
+	Stage      string
+	Phase      string
+	RecordType define.RecordType
+	Token      string
+	Err        string
+	Lines      []string
+}
+
+type Session struct {
+	token   string
+	rtype   define.RecordType
+	limiter ratelimiter.RateLimiter
+
+	ch        chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+	dropped   atomic.Int64
+
+	mut   sync.Mutex // 保护 timer 超时回调可能早于 Start 返回
+	timer *time.Timer
+}
+
+// Events 返回抓取结果 会话结束后不再写入
+func (s *Session) Events() <-chan Event {
+	return s.ch
+}
+
+// Done 会话结束信号
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Dropped 返回因缓冲区满而丢弃的事件数
+func (s *Session) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Session) Close() {
+	s.closeOnce.Do(func() {
+		s.mut.Lock()
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		s.mut.Unlock()
+		s.limiter.Stop()
+		close(s.done)
+		defaultManager.remove(s)
+	})
+}
+
+func (s *Session) match(token string, rtype define.RecordType) bool {
+	if s.token != token {
+		return false
+	}
+	return s.rtype == "" || s.rtype == rtype
+}
+
+func (s *Session) push(evt Event) {
+	select {
+	case <-s.done:
+	case s.ch <- evt:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+type manager struct {
+	mut      sync.RWMutex
+	sessions map[*Session]struct{}
+	active   atomic.Int32
+}
+
+var defaultManager = &manager{sessions: map[*Session]struct{}{}}
+
+func (m *manager) add(s *Session) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if len(m.sessions) >= maxSessions {
+		return errTooManySessions
+	}
+	m.sessions[s] = struct{}{}
+	m.active.Store(int32(len(m.sessions)))
+	return nil
+}
+
+func (m *manager) remove(s *Session) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	delete(m.sessions, s)
+	m.active.Store(int32(len(m.sessions)))
+}
+
+// match 返回匹配且通过采样的会话
+func (m *manager) match(token string, rtype define.RecordType) []*Session {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	var sessions []*Session
+	for s := range m.sessions {
+		if s.match(token, rtype) && s.limiter.TryAccept() {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions
+}
+
+// Start 创建抓取会话 rtype 为空表示不限制数据类型
+// ttl/qps 超出范围时使用默认值或上限
+func Start(token string, rtype define.RecordType, ttl time.Duration, qps float32) (*Session, error) {
+	if token == "" {
+		return nil, errEmptyToken
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if ttl > MaxTTL {
+		ttl = MaxTTL
+	}
+	if qps <= 0 {
+		qps = DefaultQps
+	}
+	if qps > MaxQps {
+		qps = MaxQps
+	}
+
+	s := &Session{
+		token: token,
+		rtype: rtype,
+		limiter: ratelimiter.New(ratelimiter.Config{
+			Type:  ratelimiter.TypeTokenBucket,
+			Qps:   qps,
+			Burst: 1,
+		}),
+		ch:   make(chan Event, bufferSize),
+		done: make(chan struct{}),
+	}
+	if err := defaultManager.add(s); err != nil {
+		s.limiter.Stop()
+		return nil, err
+	}
+
+	s.mut.Lock()
+	s.timer = time.AfterFunc(ttl, s.Close)
+	s.mut.Unlock()
+	return s, nil
+}
+
+// Active 是否存在活跃的会话 无会话时 Recorder 开销仅为一次原子读
+func Active() bool {
+	return defaultManager.active.Load() > 0
+}
+
+// Recorder 跟踪单条 record 在 pipeline 中的处理过程
+// token 在 token_checker 执行之前可能尚未解析 因此采样决策延迟到 token 可用时
+type Recorder struct {
+	record   *define.Record
+	decided  bool
+	sessions []*Session
+}
+
+// NewRecorder 无活跃会话时返回 nil Recorder 的所有方法均可安全地在 nil 上调用
+func NewRecorder(record *define.Record) *Recorder {
+	if !Active() {
+		return nil
+	}
+	return &Recorder{record: record}
+}
+
+func (r *Recorder) Capture(stage, phase string, err error) {
+	if r == nil {
+		return
+	}
+
+	if !r.decided {
+		token := r.record.Token.Original
+		if token == "" {
+			return
+		}
+		r.decided = true
+		r.sessions = defaultManager.match(token, r.record.RecordType)
+	}
+	if len(r.sessions) == 0 {
+		return
+	}
+
+	evt := Event{
+		Time:       time.Now(),
+		Stage:      stage,
+		Phase:      phase,
+		RecordType: r.record.RecordType,
+		Token:      r.record.Token.Original,
+		Lines:      prettyprint.Lines(r.record.RecordType, r.record.Data),
+	}
+	if err != nil {
+		evt.Err = err.Error()
+	}
+	for _, s := range r.sessions {
+		s.push(evt)
+	}
+}
@@ -0,0 +1,114 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+)
+
+func makeTracesRecord(token string) *define.Record {
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("GET /index")
+	return &define.Record{
+		RecordType: define.RecordTraces,
+		Token:      define.Token{Original: token},
+		Data:       traces,
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	assert.False(t, Active())
+	r := NewRecorder(makeTracesRecord("token1"))
+	assert.Nil(t, r)
+	assert.NotPanics(t, func() {
+		r.Capture("stage1", PhaseBefore, nil)
+	})
+}
+
+func TestStartFailed(t *testing.T) {
+	_, err := Start("", define.RecordTraces, time.Second, 1)
+	assert.Equal(t, errEmptyToken, err)
+
+	var sessions []*Session
+	for i := 0; i < maxSessions; i++ {
+		s, err := Start("token1", define.RecordTraces, time.Minute, 1)
+		assert.NoError(t, err)
+		sessions = append(sessions, s)
+	}
+	_, err = Start("token1", define.RecordTraces, time.Minute, 1)
+	assert.Equal(t, errTooManySessions, err)
+
+	for _, s := range sessions {
+		s.Close()
+	}
+	assert.False(t, Active())
+}
+
+func TestCapture(t *testing.T) {
+	session, err := Start("token1", define.RecordTraces, time.Minute, 1)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	// token 尚未解析时不做决策
+	record := makeTracesRecord("")
+	r := NewRecorder(record)
+	r.Capture("token_checker/fixed", PhaseBefore, nil)
+	record.Token.Original = "token1"
+	r.Capture("token_checker/fixed", PhaseAfter, nil)
+	r.Capture("attribute_filter/common", PhaseBefore, nil)
+	r.Capture("attribute_filter/common", PhaseAfter, errors.New("MUST ERROR"))
+
+	var events []Event
+	for i := 0; i < 3; i++ {
+		events = append(events, <-session.Events())
+	}
+	assert.Equal(t, "token_checker/fixed", events[0].Stage)
+	assert.Equal(t, PhaseAfter, events[0].Phase)
+	assert.Equal(t, PhaseBefore, events[1].Phase)
+	assert.Equal(t, "MUST ERROR", events[2].Err)
+	assert.Len(t, events[2].Lines, 1)
+	assert.Contains(t, events[2].Lines[0], "GET /index")
+
+	// 被限流的 record 不会被抓取
+	r = NewRecorder(makeTracesRecord("token1"))
+	r.Capture("stage", PhaseBefore, nil)
+	assert.Len(t, session.Events(), 0)
+}
+
+func TestCaptureMismatch(t *testing.T) {
+	session, err := Start("token1", define.RecordLogs, time.Minute, 10)
+	assert.NoError(t, err)
+	defer session.Close()
+
+	NewRecorder(makeTracesRecord("token1")).Capture("stage", PhaseBefore, nil)
+	NewRecorder(makeTracesRecord("token2")).Capture("stage", PhaseBefore, nil)
+	assert.Len(t, session.Events(), 0)
+}
+
+func TestSessionExpired(t *testing.T) {
+	session, err := Start("token1", "", 100*time.Millisecond, 1)
+	assert.NoError(t, err)
+
+	select {
+	case <-session.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session not expired")
+	}
+	assert.False(t, Active())
+	session.Close() // 重复关闭无副作用
+}
@@ -18,15 +18,49 @@ import (
 
 type Validator struct {
 	Func define.PreCheckValidateFunc
+
+	// Getter 为空时使用默认的 pipeline getter
+	Getter Getter
 }
 
 func (v Validator) Validate(r *define.Record) (define.StatusCode, string, error) {
 	if v.Func != nil {
 		return v.Func(r)
 	}
+	if v.Getter != nil {
+		return validatePreCheckProcessors(r, v.Getter)
+	}
 	return validatePreCheckProcessors(r, GetDefaultGetter())
 }
 
+// ResourceKey 返回 rtype 对应 pipeline 中 token_checker 主配置的 resource_key 未配置时返回空字符串
+// 供需要自行将 token 写入 resource attributes 的 receiver 使用 保证与 token_checker 的提取规则一致
+func (v Validator) ResourceKey(rtype define.RecordType) string {
+	getter := v.Getter
+	if getter == nil {
+		getter = GetDefaultGetter()
+	}
+	if getter == nil {
+		return ""
+	}
+
+	pl := getter.GetPipeline(rtype)
+	if pl == nil {
+		return ""
+	}
+
+	for _, name := range pl.PreCheckProcessors() {
+		inst := getter.GetProcessor(name)
+		if inst == nil || inst.Name() != define.ProcessorTokenChecker {
+			continue
+		}
+		if key, ok := inst.MainConfig()["resource_key"].(string); ok {
+			return key
+		}
+	}
+	return ""
+}
+
 func validatePreCheckProcessors(r *define.Record, getter Getter) (define.StatusCode, string, error) {
 	if getter == nil {
 		logger.Debug("no pipeline getter found")
@@ -60,6 +94,11 @@ func validatePreCheckProcessors(r *define.Record, getter Getter) (define.StatusC
 			if _, err := inst.Process(r); err != nil {
 				return define.StatusBadRequest, define.ProcessorLicenseChecker, err
 			}
+
+		case define.ProcessorSeriesGuard:
+			if _, err := inst.Process(r); err != nil {
+				return define.StatusCodeTooManyRequests, define.ProcessorSeriesGuard, err
+			}
 		}
 	}
 
@@ -12,19 +12,25 @@ package attributefilter
 import (
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
 )
 
 type Config struct {
-	AsString  AsStringAction   `config:"as_string" mapstructure:"as_string"`
-	AsInt     AsIntAction      `config:"as_int" mapstructure:"as_int"`
-	FromToken FromTokenAction  `config:"from_token" mapstructure:"from_token"`
-	Assemble  []AssembleAction `config:"assemble" mapstructure:"assemble"`
-	Drop      []DropAction     `config:"drop" mapstructure:"drop"`
-	Cut       []CutAction      `config:"cut" mapstructure:"cut"`
-}
-
-func (c *Config) Clean() {
+	AsString  AsStringAction    `config:"as_string" mapstructure:"as_string"`
+	AsInt     AsIntAction       `config:"as_int" mapstructure:"as_int"`
+	FromToken FromTokenAction   `config:"from_token" mapstructure:"from_token"`
+	Assemble  []AssembleAction  `config:"assemble" mapstructure:"assemble"`
+	Drop      []DropAction      `config:"drop" mapstructure:"drop"`
+	Cut       []CutAction       `config:"cut" mapstructure:"cut"`
+	Severity  SeverityAction    `config:"severity" mapstructure:"severity"`
+	Body      BodyAction        `config:"body" mapstructure:"body"`
+	Transform []TransformAction `config:"transform" mapstructure:"transform"`
+}
+
+// Clean 预处理配置 transform 规则编译失败时返回错误
+func (c *Config) Clean() error {
 	c.AsString.Clean()
 	c.AsInt.Clean()
 	for i := 0; i < len(c.Assemble); i++ {
@@ -38,6 +44,14 @@ func (c *Config) Clean() {
 	for i := 0; i < len(c.Cut); i++ {
 		c.Cut[i].Clean()
 	}
+	c.Severity.Clean()
+
+	for i := 0; i < len(c.Transform); i++ {
+		if err := c.Transform[i].Clean(); err != nil {
+			return errors.Wrapf(err, "invalid transform action [%d]", i)
+		}
+	}
+	return nil
 }
 
 type AsStringAction struct {
@@ -135,6 +149,76 @@ func (c *CutAction) Clean() {
 	}
 }
 
+// SeverityAction 根据日志级别过滤 logs 记录
+// 日志级别统一转换为大写后匹配 如 TRACE/DEBUG/INFO/WARN/ERROR/FATAL
+type SeverityAction struct {
+	Keep []string `config:"keep" mapstructure:"keep"` // 仅保留的日志级别 为空则不限制
+	Drop []string `config:"drop" mapstructure:"drop"` // 需要丢弃的日志级别
+
+	keep map[string]struct{}
+	drop map[string]struct{}
+}
+
+func (c *SeverityAction) Clean() {
+	if len(c.Keep) > 0 {
+		c.keep = make(map[string]struct{})
+		for _, s := range c.Keep {
+			c.keep[strings.ToUpper(s)] = struct{}{}
+		}
+	}
+
+	if len(c.Drop) > 0 {
+		c.drop = make(map[string]struct{})
+		for _, s := range c.Drop {
+			c.drop[strings.ToUpper(s)] = struct{}{}
+		}
+	}
+}
+
+func (c *SeverityAction) Enabled() bool {
+	return len(c.keep) > 0 || len(c.drop) > 0
+}
+
+// ShouldDrop 判断该日志级别是否需要被丢弃
+func (c *SeverityAction) ShouldDrop(level string) bool {
+	if _, ok := c.drop[level]; ok {
+		return true
+	}
+	if len(c.keep) == 0 {
+		return false
+	}
+	_, ok := c.keep[level]
+	return !ok
+}
+
+// BodyAction 处理 logs 的 body 内容
+type BodyAction struct {
+	MaxLength int `config:"max_length" mapstructure:"max_length"` // body 最大允许长度 超出则裁剪
+}
+
+// TransformAction 基于表达式的转换规则 配置加载时编译 编译失败则配置加载失败
+// where 为空时表示所有记录均执行 statements
+type TransformAction struct {
+	Where      string   `config:"where" mapstructure:"where"`
+	Statements []string `config:"statements" mapstructure:"statements"`
+
+	program *transformProgram
+}
+
+func (c *TransformAction) Clean() error {
+	prog, err := compileTransform(c.Where, c.Statements)
+	if err != nil {
+		c.program = nil
+		return err
+	}
+	c.program = prog
+	return nil
+}
+
+func (c *TransformAction) Enabled() bool {
+	return c.program != nil
+}
+
 func cleanAttributesPrefixes(keys []string) []string {
 	var ret []string
 	for _, key := range keys {
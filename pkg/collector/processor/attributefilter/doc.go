@@ -8,7 +8,9 @@
 // specific language governing permissions and limitations under the License.
 
 /*
-# AttributeFilter: 属性处理器 支持 as_string/as_int/from_token/assemble/cut/drop
+# AttributeFilter: 属性处理器 支持 as_string/as_int/from_token/assemble/cut/drop/severity/body/transform
+
+除 assemble 外 其余 action 均同时支持 traces 与 logs 数据
 
 processor:
    - name: "attribute_filter/common"
@@ -69,6 +71,38 @@ processor:
               - "postgresql"
             keys:                                   # 需要移除的key
               - "attributes.db.parameters"
+
+        # 根据日志级别过滤 logs（仅 logs 生效）
+        # 优先使用 SeverityText 缺省时根据 SeverityNumber 推断 TRACE/DEBUG/INFO/WARN/ERROR/FATAL
+        severity:
+          keep:                                     # 仅保留的日志级别 为空则不限制
+            - "WARN"
+            - "ERROR"
+          drop:                                     # 需要丢弃的日志级别 优先级高于 keep
+            - "DEBUG"
+
+        # 裁剪超出长度的日志正文（仅 logs 生效）
+        body:
+          max_length: 4096
+
+        # 基于表达式的转换规则 配置加载时编译 编译失败则配置加载失败 按顺序作用于每条 span/datapoint/log
+        # 字段: resource.xxx / attributes.xxx 可读写
+        #       写入 resource.xxx 的规则只能引用 resource.xxx 字段 每个 resource 仅执行一次 且先于其余规则执行
+        #       span_name/kind/status.code/trace_id/span_id（traces）metric_name（metrics）body/severity_text（logs）只读
+        # where: 支持 == != =~ > >= < <= 以及 && || ! () exists(field) 两侧均为数值时按数值比较 为空则全部匹配
+        # statements: set(field, value) / rename(from, to) / delete(field) / replace_regex(field, "pattern", "replacement") / hash(field)
+        transform:
+          - where: 'attributes.db.system == "mysql" && exists(attributes.db.statement)'
+            statements:
+              - 'replace_regex(attributes.db.statement, "\\d+", "?")'
+              - 'hash(attributes.db.user)'
+          - where: 'kind == 2 || span_name =~ "^GET"'
+            statements:
+              - 'set(attributes.api_name, span_name)'
+              - 'rename(attributes.http.url, attributes.http.target)'
+          - where: 'resource.service.name == "checkout"'
+            statements:
+              - 'set(resource.env, "prod")'
 */
 
 package attributefilter
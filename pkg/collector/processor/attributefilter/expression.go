@@ -0,0 +1,701 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package attributefilter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor"
+)
+
+// 表达式语法
+//
+// 条件（where）:
+//   expr    := or
+//   or      := and ('||' and)*
+//   and     := unary ('&&' unary)*
+//   unary   := '!' unary | '(' expr ')' | 'exists' '(' field ')' | operand op operand
+//   op      := '==' | '!=' | '=~' | '>' | '>=' | '<' | '<='
+//   operand := field | "string" | number
+//
+// 语句（statements）:
+//   set(field, operand) | rename(field, field) | delete(field)
+//   replace_regex(field, "pattern", "replacement") | hash(field)
+//
+// field 取值规则与 dimensions 一致：resource.* / attributes.* 以及内置字段（如 span_name/kind/status.code）
+//
+// 写入 resource.* 的规则每个 resource 仅执行一次 且 where 与 statements 只能引用 resource.* 字段
+// 此类规则先于 span/datapoint/log 级别的规则执行
+
+// evalContext 表达式求值上下文 对应单条 span/datapoint/log
+type evalContext struct {
+	resource pcommon.Map
+	attrs    pcommon.Map
+	method   func(key string) (string, bool)
+}
+
+type field struct {
+	from processor.DimensionFromType
+	key  string
+}
+
+func (f field) get(ctx evalContext) (pcommon.Value, bool) {
+	switch f.from {
+	case processor.DimensionFromResource:
+		return ctx.resource.Get(f.key)
+	case processor.DimensionFromAttribute:
+		return ctx.attrs.Get(f.key)
+	default:
+		if ctx.method == nil {
+			return pcommon.Value{}, false
+		}
+		s, ok := ctx.method(f.key)
+		if !ok {
+			return pcommon.Value{}, false
+		}
+		return pcommon.NewValueString(s), true
+	}
+}
+
+func (f field) target(ctx evalContext) pcommon.Map {
+	if f.from == processor.DimensionFromResource {
+		return ctx.resource
+	}
+	return ctx.attrs
+}
+
+func (f field) writable() bool {
+	return f.from == processor.DimensionFromResource || f.from == processor.DimensionFromAttribute
+}
+
+// scope 表达式引用及写入的字段范围
+type scope struct {
+	writeResource bool // 写入 resource.* 字段
+	readRecord    bool // 引用 span/datapoint/log 级别的字段
+}
+
+func (s *scope) merge(other scope) {
+	s.writeResource = s.writeResource || other.writeResource
+	s.readRecord = s.readRecord || other.readRecord
+}
+
+// ---------------------------------------------------------------------------
+// lexer
+
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == '/'
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ","})
+			i++
+
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes); j++ {
+				if runes[j] == '\\' {
+					j++
+					continue
+				}
+				if runes[j] == '"' {
+					break
+				}
+			}
+			if j >= len(runes) {
+				return nil, errors.Errorf("unterminated string at %d", i)
+			}
+			text, err := strconv.Unquote(string(runes[i : j+1]))
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid string at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text})
+			i = j + 1
+
+		case strings.ContainsRune("=!<>&|", r):
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "=~", ">=", "<=", "&&", "||":
+				tokens = append(tokens, token{kind: tokenOp, text: two})
+				i += 2
+				continue
+			}
+			switch r {
+			case '!', '<', '>':
+				tokens = append(tokens, token{kind: tokenOp, text: string(r)})
+				i++
+			default:
+				return nil, errors.Errorf("unexpected character '%c' at %d", r, i)
+			}
+
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:j])})
+			i = j
+
+		case isIdentRune(r):
+			j := i
+			for j < len(runes) && isIdentRune(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:j])})
+			i = j
+
+		default:
+			return nil, errors.Errorf("unexpected character '%c' at %d", r, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// ---------------------------------------------------------------------------
+// ast
+
+type condition interface {
+	eval(ctx evalContext) bool
+}
+
+type operand interface {
+	value(ctx evalContext) (pcommon.Value, bool)
+}
+
+type literal struct {
+	v pcommon.Value
+}
+
+func (l literal) value(evalContext) (pcommon.Value, bool) { return l.v, true }
+
+func (f field) value(ctx evalContext) (pcommon.Value, bool) { return f.get(ctx) }
+
+type andCond struct{ left, right condition }
+
+func (c andCond) eval(ctx evalContext) bool { return c.left.eval(ctx) && c.right.eval(ctx) }
+
+type orCond struct{ left, right condition }
+
+func (c orCond) eval(ctx evalContext) bool { return c.left.eval(ctx) || c.right.eval(ctx) }
+
+type notCond struct{ cond condition }
+
+func (c notCond) eval(ctx evalContext) bool { return !c.cond.eval(ctx) }
+
+type existsCond struct{ field field }
+
+func (c existsCond) eval(ctx evalContext) bool {
+	_, ok := c.field.get(ctx)
+	return ok
+}
+
+type regexCond struct {
+	left operand
+	re   *regexp.Regexp
+}
+
+func (c regexCond) eval(ctx evalContext) bool {
+	v, ok := c.left.value(ctx)
+	if !ok {
+		return false
+	}
+	return c.re.MatchString(v.AsString())
+}
+
+type compareCond struct {
+	op          string
+	left, right operand
+}
+
+func (c compareCond) eval(ctx evalContext) bool {
+	lv, ok := c.left.value(ctx)
+	if !ok {
+		return false
+	}
+	rv, ok := c.right.value(ctx)
+	if !ok {
+		return false
+	}
+
+	ls, rs := lv.AsString(), rv.AsString()
+	cmp := strings.Compare(ls, rs)
+
+	// 两侧均为数值时按数值比较
+	lf, lerr := strconv.ParseFloat(ls, 64)
+	rf, rerr := strconv.ParseFloat(rs, 64)
+	if lerr == nil && rerr == nil {
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch c.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// ---------------------------------------------------------------------------
+// parser
+
+type parser struct {
+	tokens []token
+	pos    int
+	scope  scope
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	t := p.next()
+	if t.kind != kind {
+		return errors.Errorf("expected '%s', got '%s'", text, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orCond{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andCond{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (condition, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenOp && t.text == "!":
+		p.next()
+		cond, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notCond{cond: cond}, nil
+
+	case t.kind == tokenLParen:
+		p.next()
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return cond, nil
+
+	case t.kind == tokenIdent && t.text == "exists" && p.tokens[p.pos+1].kind == tokenLParen:
+		p.next()
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		if len(args) != 1 {
+			return nil, errors.New("exists() requires exactly 1 argument")
+		}
+		f, ok := args[0].(field)
+		if !ok {
+			return nil, errors.New("exists() argument must be a field")
+		}
+		return existsCond{field: f}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.next()
+	if t.kind != tokenOp {
+		return nil, errors.Errorf("expected comparison operator, got '%s'", t.text)
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.text {
+	case "=~":
+		l, ok := right.(literal)
+		if !ok {
+			return nil, errors.New("right side of '=~' must be a string literal")
+		}
+		re, err := regexp.Compile(l.v.AsString())
+		if err != nil {
+			return nil, err
+		}
+		return regexCond{left: left, re: re}, nil
+	case "==", "!=", ">", ">=", "<", "<=":
+		return compareCond{op: t.text, left: left, right: right}, nil
+	}
+	return nil, errors.Errorf("unsupported operator '%s'", t.text)
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literal{v: pcommon.NewValueString(t.text)}, nil
+	case tokenNumber:
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return literal{v: pcommon.NewValueInt(i)}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return literal{v: pcommon.NewValueDouble(f)}, nil
+	case tokenIdent:
+		from, key := processor.DecodeDimensionFrom(t.text)
+		if from != processor.DimensionFromResource {
+			p.scope.readRecord = true
+		}
+		return field{from: from, key: key}, nil
+	}
+	return nil, errors.Errorf("unexpected token '%s'", t.text)
+}
+
+func (p *parser) parseArgs() ([]operand, error) {
+	if err := p.expect(tokenLParen, "("); err != nil {
+		return nil, err
+	}
+
+	var args []operand
+	if p.peek().kind == tokenRParen {
+		p.next()
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		t := p.next()
+		if t.kind == tokenRParen {
+			return args, nil
+		}
+		if t.kind != tokenComma {
+			return nil, errors.Errorf("expected ',' or ')', got '%s'", t.text)
+		}
+	}
+}
+
+func compileCondition(s string) (condition, scope, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, scope{}, err
+	}
+	p := &parser{tokens: tokens}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, scope{}, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, scope{}, errors.Errorf("unexpected token '%s'", t.text)
+	}
+	return cond, p.scope, nil
+}
+
+// ---------------------------------------------------------------------------
+// statements
+
+type statement func(ctx evalContext)
+
+func fieldArgs(name string, args []operand, n int) ([]field, error) {
+	fields := make([]field, 0, n)
+	for i := 0; i < n; i++ {
+		f, ok := args[i].(field)
+		if !ok || !f.writable() {
+			return nil, errors.Errorf("%s() argument %d must be a resource/attributes field", name, i+1)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func stringArg(name string, args []operand, i int) (string, error) {
+	l, ok := args[i].(literal)
+	if !ok || l.v.Type() != pcommon.ValueTypeString {
+		return "", errors.Errorf("%s() argument %d must be a string literal", name, i+1)
+	}
+	return l.v.StringVal(), nil
+}
+
+func compileStatement(s string) (statement, scope, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, scope{}, err
+	}
+	p := &parser{tokens: tokens}
+	stmt, writes, err := p.parseStatement()
+	if err != nil {
+		return nil, scope{}, err
+	}
+
+	sc := p.scope
+	for _, f := range writes {
+		if f.from == processor.DimensionFromResource {
+			sc.writeResource = true
+		}
+	}
+	return stmt, sc, nil
+}
+
+// parseStatement 解析单条语句 同时返回被写入的字段
+func (p *parser) parseStatement() (statement, []field, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, nil, errors.Errorf("expected function name, got '%s'", t.text)
+	}
+	name := t.text
+	args, err := p.parseArgs()
+	if err != nil {
+		return nil, nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, nil, errors.Errorf("unexpected token '%s'", t.text)
+	}
+
+	arity := map[string]int{"set": 2, "rename": 2, "delete": 1, "replace_regex": 3, "hash": 1}
+	n, ok := arity[name]
+	if !ok {
+		return nil, nil, errors.Errorf("unsupported function '%s'", name)
+	}
+	if len(args) != n {
+		return nil, nil, errors.Errorf("%s() requires exactly %d arguments", name, n)
+	}
+
+	switch name {
+	case "set":
+		fields, err := fieldArgs(name, args, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		dst, src := fields[0], args[1]
+		return func(ctx evalContext) {
+			v, ok := src.value(ctx)
+			if !ok {
+				return
+			}
+			dst.target(ctx).Upsert(dst.key, v)
+		}, fields, nil
+
+	case "rename":
+		fields, err := fieldArgs(name, args, 2)
+		if err != nil {
+			return nil, nil, err
+		}
+		src, dst := fields[0], fields[1]
+		return func(ctx evalContext) {
+			v, ok := src.get(ctx)
+			if !ok {
+				return
+			}
+			dst.target(ctx).Upsert(dst.key, v)
+			src.target(ctx).Remove(src.key)
+		}, fields, nil
+
+	case "delete":
+		fields, err := fieldArgs(name, args, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		f := fields[0]
+		return func(ctx evalContext) {
+			f.target(ctx).Remove(f.key)
+		}, fields, nil
+
+	case "replace_regex":
+		fields, err := fieldArgs(name, args, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		pattern, err := stringArg(name, args, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		replacement, err := stringArg(name, args, 2)
+		if err != nil {
+			return nil, nil, err
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		f := fields[0]
+		return func(ctx evalContext) {
+			v, ok := f.get(ctx)
+			if !ok {
+				return
+			}
+			f.target(ctx).UpsertString(f.key, re.ReplaceAllString(v.AsString(), replacement))
+		}, fields, nil
+
+	default: // hash
+		fields, err := fieldArgs(name, args, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		f := fields[0]
+		return func(ctx evalContext) {
+			v, ok := f.get(ctx)
+			if !ok {
+				return
+			}
+			sum := sha256.Sum256([]byte(v.AsString()))
+			f.target(ctx).UpsertString(f.key, hex.EncodeToString(sum[:]))
+		}, fields, nil
+	}
+}
+
+// transformProgram 编译后的转换规则
+// resource 为 true 时规则仅作用于 resource 每个 resource 执行一次
+type transformProgram struct {
+	where      condition
+	statements []statement
+	resource   bool
+}
+
+func compileTransform(where string, statements []string) (*transformProgram, error) {
+	var sc scope
+	prog := &transformProgram{}
+	if strings.TrimSpace(where) != "" {
+		cond, condScope, err := compileCondition(where)
+		if err != nil {
+			return nil, errors.Wrapf(err, "compile where '%s'", where)
+		}
+		prog.where = cond
+		sc.merge(condScope)
+	}
+
+	for _, s := range statements {
+		stmt, stmtScope, err := compileStatement(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "compile statement '%s'", s)
+		}
+		prog.statements = append(prog.statements, stmt)
+		sc.merge(stmtScope)
+	}
+
+	// 写入 resource 的规则若引用 span/datapoint/log 字段 结果会随记录顺序变化
+	if sc.writeResource && sc.readRecord {
+		return nil, errors.New("rules writing resource.* can only reference resource.* fields")
+	}
+	prog.resource = sc.writeResource
+	return prog, nil
+}
+
+func (prog *transformProgram) run(ctx evalContext) {
+	if prog.where != nil && !prog.where.eval(ctx) {
+		return
+	}
+	for _, stmt := range prog.statements {
+		stmt(ctx)
+	}
+}
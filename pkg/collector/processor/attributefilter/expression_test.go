@@ -0,0 +1,152 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package attributefilter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/testkits"
+)
+
+func makeEvalContext() evalContext {
+	resource := pcommon.NewMap()
+	resource.UpsertString("service.name", "checkout")
+	attrs := pcommon.NewMap()
+	attrs.UpsertString("http.method", "GET")
+	attrs.UpsertInt("http.status_code", 503)
+	attrs.UpsertString("db.statement", "select * from t where id = 10")
+
+	return evalContext{
+		resource: resource,
+		attrs:    attrs,
+		method: func(key string) (string, bool) {
+			if key == "span_name" {
+				return "GET /api", true
+			}
+			return "", false
+		},
+	}
+}
+
+func TestCompileCondition(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{expr: `attributes.http.method == "GET"`, want: true},
+		{expr: `attributes.http.method != "GET"`, want: false},
+		{expr: `attributes.http.status_code >= 500`, want: true},
+		{expr: `attributes.http.status_code < 500`, want: false},
+		{expr: `attributes.http.status_code > 60`, want: true},
+		{expr: `span_name =~ "^GET "`, want: true},
+		{expr: `resource.service.name == "checkout" && attributes.http.method == "POST"`, want: false},
+		{expr: `resource.service.name == "checkout" || attributes.http.method == "POST"`, want: true},
+		{expr: `!(attributes.http.method == "POST")`, want: true},
+		{expr: `exists(attributes.http.method) && !exists(attributes.not_exist)`, want: true},
+		{expr: `attributes.not_exist == ""`, want: false},
+	}
+
+	ctx := makeEvalContext()
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cond, _, err := compileCondition(tt.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, cond.eval(ctx))
+		})
+	}
+}
+
+func TestCompileConditionFailed(t *testing.T) {
+	exprs := []string{
+		`attributes.http.method ==`,
+		`attributes.http.method = "GET"`,
+		`attributes.http.method == "GET`,
+		`(attributes.http.method == "GET"`,
+		`span_name =~ "(["`,
+		`span_name =~ attributes.a`,
+		`exists("a")`,
+		`attributes.a == "1" attributes.b`,
+	}
+	for _, expr := range exprs {
+		_, _, err := compileCondition(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestCompileStatement(t *testing.T) {
+	ctx := makeEvalContext()
+	prog, err := compileTransform(`attributes.http.status_code >= 500`, []string{
+		`set(attributes.api_name, span_name)`,
+		`rename(attributes.http.method, attributes.method)`,
+		`replace_regex(attributes.db.statement, "\\d+", "?")`,
+		`delete(attributes.http.status_code)`,
+	})
+	assert.NoError(t, err)
+	assert.False(t, prog.resource)
+	prog.run(ctx)
+
+	testkits.AssertAttrsFoundStringVal(t, ctx.attrs, "api_name", "GET /api")
+	testkits.AssertAttrsFoundStringVal(t, ctx.attrs, "method", "GET")
+	testkits.AssertAttrsNotFound(t, ctx.attrs, "http.method")
+	testkits.AssertAttrsNotFound(t, ctx.attrs, "http.status_code")
+	testkits.AssertAttrsFoundStringVal(t, ctx.attrs, "db.statement", "select * from t where id = ?")
+
+	// 条件不满足时不执行
+	ctx = makeEvalContext()
+	ctx.attrs.UpsertInt("http.status_code", 200)
+	prog.run(ctx)
+	testkits.AssertAttrsNotFound(t, ctx.attrs, "api_name")
+}
+
+func TestCompileResourceStatement(t *testing.T) {
+	ctx := makeEvalContext()
+	prog, err := compileTransform(`resource.service.name == "checkout"`, []string{
+		`set(resource.env, "prod")`,
+		`hash(resource.service.name)`,
+	})
+	assert.NoError(t, err)
+	assert.True(t, prog.resource)
+	prog.run(ctx)
+
+	testkits.AssertAttrsFoundStringVal(t, ctx.resource, "env", "prod")
+	sum := sha256.Sum256([]byte("checkout"))
+	testkits.AssertAttrsFoundStringVal(t, ctx.resource, "service.name", hex.EncodeToString(sum[:]))
+
+	// 写入 resource 的规则不能引用 span/datapoint/log 级别的字段
+	_, err = compileTransform(`attributes.http.status_code >= 500`, []string{`set(resource.env, "prod")`})
+	assert.Error(t, err)
+	_, err = compileTransform("", []string{`set(resource.api_name, span_name)`})
+	assert.Error(t, err)
+	_, err = compileTransform("", []string{`set(attributes.env, "prod")`, `delete(resource.env)`})
+	assert.Error(t, err)
+}
+
+func TestCompileStatementFailed(t *testing.T) {
+	statements := []string{
+		`unknown(attributes.a)`,
+		`set(attributes.a)`,
+		`set(span_name, "a")`,
+		`delete("a")`,
+		`rename(attributes.a, kind)`,
+		`replace_regex(attributes.a, "([", "")`,
+		`replace_regex(attributes.a, attributes.b, "")`,
+		`hash(attributes.a) extra`,
+		`hash attributes.a`,
+	}
+	for _, s := range statements {
+		_, _, err := compileStatement(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 
@@ -41,7 +42,9 @@ func newFactory(conf map[string]interface{}, customized []processor.SubConfigPro
 	if err := mapstructure.Decode(conf, c); err != nil {
 		return nil, err
 	}
-	c.Clean()
+	if err := c.Clean(); err != nil {
+		return nil, err
+	}
 	configs.SetGlobal(*c)
 
 	for _, custom := range customized {
@@ -50,7 +53,10 @@ func newFactory(conf map[string]interface{}, customized []processor.SubConfigPro
 			logger.Errorf("failed to decode config: %v", err)
 			continue
 		}
-		cfg.Clean()
+		if err := cfg.Clean(); err != nil {
+			logger.Errorf("failed to clean config: %v", err)
+			continue
+		}
 		configs.Set(custom.Token, custom.Type, custom.ID, *cfg)
 	}
 
@@ -90,6 +96,11 @@ func (p *attributeFilter) Reload(config map[string]interface{}, customized []pro
 
 func (p *attributeFilter) Process(record *define.Record) (*define.Record, error) {
 	config := p.configs.GetByToken(record.Token.Original).(Config)
+	if config.Severity.Enabled() {
+		if p.severityAction(record, config) {
+			return nil, define.ErrSkipEmptyRecord
+		}
+	}
 	if len(config.AsString.Keys) > 0 {
 		p.asStringAction(record, config)
 	}
@@ -108,6 +119,12 @@ func (p *attributeFilter) Process(record *define.Record) (*define.Record, error)
 	if len(config.Cut) > 0 {
 		p.cutAction(record, config)
 	}
+	if config.Body.MaxLength > 0 {
+		p.bodyAction(record, config)
+	}
+	if len(config.Transform) > 0 {
+		p.transformAction(record, config)
+	}
 
 	return nil, nil
 }
@@ -126,6 +143,18 @@ func (p *attributeFilter) fromTokenAction(record *define.Record, config Config)
 			}
 		})
 
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.Logs(pdLogs.ResourceLogs(), func(logRecord plog.LogRecord) {
+			attrs := logRecord.Attributes()
+			if config.FromToken.BizId != "" {
+				attrs.UpsertInt(config.FromToken.BizId, int64(record.Token.BizId))
+			}
+			if config.FromToken.AppName != "" {
+				attrs.UpsertString(config.FromToken.AppName, record.Token.AppName)
+			}
+		})
+
 	case define.RecordMetrics:
 		pdMetrics := record.Data.(pmetric.Metrics)
 		foreach.Metrics(pdMetrics.ResourceMetrics(), func(metric pmetric.Metric) {
@@ -146,20 +175,29 @@ func (p *attributeFilter) fromTokenAction(record *define.Record, config Config)
 	}
 }
 
+func processAsStringAction(attrs pcommon.Map, key string) {
+	if v, ok := attrs.Get(key); ok {
+		attrs.UpsertString(key, v.AsString())
+	}
+}
+
 func (p *attributeFilter) asStringAction(record *define.Record, config Config) {
 	switch record.RecordType {
 	case define.RecordTraces:
 		pdTraces := record.Data.(ptrace.Traces)
 		foreach.SpansWithResourceAttrs(pdTraces.ResourceSpans(), func(rsAttrs pcommon.Map, span ptrace.Span) {
 			for _, key := range config.AsString.Keys {
-				if v, ok := rsAttrs.Get(key); ok {
-					rsAttrs.UpsertString(key, v.AsString())
-				}
+				processAsStringAction(rsAttrs, key)
+				processAsStringAction(span.Attributes(), key)
+			}
+		})
 
-				attrs := span.Attributes()
-				if v, ok := attrs.Get(key); ok {
-					attrs.UpsertString(key, v.AsString())
-				}
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.LogsWithResourceAttrs(pdLogs.ResourceLogs(), func(rsAttrs pcommon.Map, logRecord plog.LogRecord) {
+			for _, key := range config.AsString.Keys {
+				processAsStringAction(rsAttrs, key)
+				processAsStringAction(logRecord.Attributes(), key)
 			}
 		})
 	}
@@ -189,6 +227,15 @@ func (p *attributeFilter) asIntAction(record *define.Record, config Config) {
 				processAsIntAction(span.Attributes(), key)
 			}
 		})
+
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.LogsWithResourceAttrs(pdLogs.ResourceLogs(), func(rsAttrs pcommon.Map, logRecord plog.LogRecord) {
+			for _, key := range config.AsInt.Keys {
+				processAsIntAction(rsAttrs, key)
+				processAsIntAction(logRecord.Attributes(), key)
+			}
+		})
 	}
 }
 
@@ -263,62 +310,269 @@ func processAssembleAction(span ptrace.Span, action AssembleAction) bool {
 	return false
 }
 
+func processDropAction(attrs pcommon.Map, action DropAction) {
+	v, ok := attrs.Get(action.PredicateKey)
+	if !ok {
+		return
+	}
+
+	// 取到 key，但是判定条件不符合的时候
+	_, ok = action.match[v.AsString()]
+	if len(action.Match) > 0 && !ok {
+		return
+	}
+	for _, k := range action.Keys {
+		attrs.Remove(k)
+	}
+}
+
 func (p *attributeFilter) dropAction(record *define.Record, config Config) {
 	switch record.RecordType {
 	case define.RecordTraces:
 		pdTraces := record.Data.(ptrace.Traces)
-		resourceSpansSlice := pdTraces.ResourceSpans()
-		foreach.Spans(resourceSpansSlice, func(span ptrace.Span) {
+		foreach.Spans(pdTraces.ResourceSpans(), func(span ptrace.Span) {
 			for _, action := range config.Drop {
-				v, ok := span.Attributes().Get(action.PredicateKey)
-				if !ok {
-					continue
-				}
+				processDropAction(span.Attributes(), action)
+			}
+		})
 
-				// 取到 key，但是判定条件不符合的时候
-				_, ok = action.match[v.AsString()]
-				if len(action.Match) > 0 && !ok {
-					continue
-				}
-				for _, k := range action.Keys {
-					span.Attributes().Remove(k)
-				}
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.Logs(pdLogs.ResourceLogs(), func(logRecord plog.LogRecord) {
+			for _, action := range config.Drop {
+				processDropAction(logRecord.Attributes(), action)
 			}
 		})
 	}
 }
 
+func processCutAction(attrs pcommon.Map, action CutAction) {
+	v, ok := attrs.Get(action.PredicateKey)
+	if !ok {
+		return
+	}
+
+	// 不符合匹配条件的时候跳过
+	_, ok = action.match[v.AsString()]
+	if len(action.Match) > 0 && !ok {
+		return
+	}
+
+	// preKey 取值 ok 并且 无匹配条件 或 匹配条件符合的情况下
+	for _, k := range action.Keys {
+		// 无法获取到 key 的值 则跳过
+		if v, ok = attrs.Get(k); !ok {
+			continue
+		}
+		// 对于长度超出的情况，进行裁剪
+		value := v.AsString()
+		if len(value) > action.MaxLength {
+			attrs.UpsertString(k, value[:action.MaxLength])
+		}
+	}
+}
+
 func (p *attributeFilter) cutAction(record *define.Record, config Config) {
 	switch record.RecordType {
 	case define.RecordTraces:
 		pdTraces := record.Data.(ptrace.Traces)
-		resourceSpansSlice := pdTraces.ResourceSpans()
-		foreach.Spans(resourceSpansSlice, func(span ptrace.Span) {
+		foreach.Spans(pdTraces.ResourceSpans(), func(span ptrace.Span) {
 			for _, action := range config.Cut {
-				v, ok := span.Attributes().Get(action.PredicateKey)
-				if !ok {
-					continue
-				}
+				processCutAction(span.Attributes(), action)
+			}
+		})
 
-				// 不符合匹配条件的时候跳过
-				_, ok = action.match[v.AsString()]
-				if len(action.Match) > 0 && !ok {
-					continue
-				}
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.Logs(pdLogs.ResourceLogs(), func(logRecord plog.LogRecord) {
+			for _, action := range config.Cut {
+				processCutAction(logRecord.Attributes(), action)
+			}
+		})
+	}
+}
 
-				// preKey 取值 ok 并且 无匹配条件 或 匹配条件符合的情况下
-				for _, k := range action.Keys {
-					// 无法获取到 key 的值 则跳过
-					if v, ok = span.Attributes().Get(k); !ok {
-						continue
-					}
-					// 对于长度超出的情况，进行裁剪
-					value := v.AsString()
-					if len(value) > action.MaxLength {
-						span.Attributes().UpsertString(k, value[:action.MaxLength])
-					}
+// severityLevel 返回日志级别 优先使用 SeverityText 缺省时根据 SeverityNumber 推断
+func severityLevel(logRecord plog.LogRecord) string {
+	if text := logRecord.SeverityText(); text != "" {
+		return strings.ToUpper(text)
+	}
+
+	n := logRecord.SeverityNumber()
+	switch {
+	case n >= plog.SeverityNumberFATAL:
+		return "FATAL"
+	case n >= plog.SeverityNumberERROR:
+		return "ERROR"
+	case n >= plog.SeverityNumberWARN:
+		return "WARN"
+	case n >= plog.SeverityNumberINFO:
+		return "INFO"
+	case n >= plog.SeverityNumberDEBUG:
+		return "DEBUG"
+	case n >= plog.SeverityNumberTRACE:
+		return "TRACE"
+	}
+	return ""
+}
+
+// severityAction 按日志级别丢弃 logs 记录 返回值表示记录是否已被全部丢弃
+func (p *attributeFilter) severityAction(record *define.Record, config Config) bool {
+	switch record.RecordType {
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.LogsRemoveIf(pdLogs.ResourceLogs(), func(logRecord plog.LogRecord) bool {
+			return config.Severity.ShouldDrop(severityLevel(logRecord))
+		})
+		return pdLogs.LogRecordCount() == 0
+	}
+	return false
+}
+
+// bodyAction 裁剪超出长度的 logs body
+func (p *attributeFilter) bodyAction(record *define.Record, config Config) {
+	switch record.RecordType {
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		foreach.Logs(pdLogs.ResourceLogs(), func(logRecord plog.LogRecord) {
+			body := logRecord.Body()
+			if body.Type() != pcommon.ValueTypeString {
+				return
+			}
+			if s := body.StringVal(); len(s) > config.Body.MaxLength {
+				body.SetStringVal(s[:config.Body.MaxLength])
+			}
+		})
+	}
+}
+
+// transformAction 按顺序执行表达式转换规则
+// 写入 resource 的规则每个 resource 执行一次 其余规则对每条 span/datapoint/log 执行
+func (p *attributeFilter) transformAction(record *define.Record, config Config) {
+	var resourceActions, recordActions []TransformAction
+	for _, action := range config.Transform {
+		if !action.Enabled() {
+			continue
+		}
+		if action.program.resource {
+			resourceActions = append(resourceActions, action)
+		} else {
+			recordActions = append(recordActions, action)
+		}
+	}
+
+	runResource := func(rsAttrs pcommon.Map) {
+		for _, action := range resourceActions {
+			action.program.run(evalContext{resource: rsAttrs})
+		}
+	}
+	run := func(ctx evalContext) {
+		for _, action := range recordActions {
+			action.program.run(ctx)
+		}
+	}
+
+	switch record.RecordType {
+	case define.RecordTraces:
+		pdTraces := record.Data.(ptrace.Traces)
+		resourceSpans := pdTraces.ResourceSpans()
+		for i := 0; i < resourceSpans.Len(); i++ {
+			runResource(resourceSpans.At(i).Resource().Attributes())
+		}
+		if len(recordActions) == 0 {
+			return
+		}
+
+		fetcher := processor.NewSpanDimensionFetcher()
+		foreach.SpansWithResourceAttrs(resourceSpans, func(rsAttrs pcommon.Map, span ptrace.Span) {
+			run(evalContext{
+				resource: rsAttrs,
+				attrs:    span.Attributes(),
+				method: func(key string) (string, bool) {
+					s := fetcher.FetchMethod(span, key)
+					return s, s != ""
+				},
+			})
+		})
+
+	case define.RecordMetrics:
+		pdMetrics := record.Data.(pmetric.Metrics)
+		resourceMetrics := pdMetrics.ResourceMetrics()
+		for i := 0; i < resourceMetrics.Len(); i++ {
+			runResource(resourceMetrics.At(i).Resource().Attributes())
+		}
+		if len(recordActions) == 0 {
+			return
+		}
+
+		foreach.MetricsWithResourceAttrs(resourceMetrics, func(rsAttrs pcommon.Map, metric pmetric.Metric) {
+			method := func(key string) (string, bool) {
+				if key == "metric_name" {
+					return metric.Name(), true
 				}
+				return "", false
+			}
+			for _, attrs := range metricDataPointsAttrs(metric) {
+				run(evalContext{resource: rsAttrs, attrs: attrs, method: method})
 			}
 		})
+
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		resourceLogs := pdLogs.ResourceLogs()
+		for i := 0; i < resourceLogs.Len(); i++ {
+			runResource(resourceLogs.At(i).Resource().Attributes())
+		}
+		if len(recordActions) == 0 {
+			return
+		}
+
+		foreach.LogsWithResourceAttrs(resourceLogs, func(rsAttrs pcommon.Map, logRecord plog.LogRecord) {
+			run(evalContext{
+				resource: rsAttrs,
+				attrs:    logRecord.Attributes(),
+				method: func(key string) (string, bool) {
+					switch key {
+					case "body":
+						return logRecord.Body().AsString(), true
+					case "severity_text":
+						return severityLevel(logRecord), true
+					}
+					return "", false
+				},
+			})
+		})
+	}
+}
+
+func metricDataPointsAttrs(metric pmetric.Metric) []pcommon.Map {
+	var attrs []pcommon.Map
+	switch metric.DataType() {
+	case pmetric.MetricDataTypeGauge:
+		dps := metric.Gauge().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			attrs = append(attrs, dps.At(i).Attributes())
+		}
+	case pmetric.MetricDataTypeSum:
+		dps := metric.Sum().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			attrs = append(attrs, dps.At(i).Attributes())
+		}
+	case pmetric.MetricDataTypeHistogram:
+		dps := metric.Histogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			attrs = append(attrs, dps.At(i).Attributes())
+		}
+	case pmetric.MetricDataTypeExponentialHistogram:
+		dps := metric.ExponentialHistogram().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			attrs = append(attrs, dps.At(i).Attributes())
+		}
+	case pmetric.MetricDataTypeSummary:
+		dps := metric.Summary().DataPoints()
+		for i := 0; i < dps.Len(); i++ {
+			attrs = append(attrs, dps.At(i).Attributes())
+		}
 	}
+	return attrs
 }
@@ -10,10 +10,13 @@
 package attributefilter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	semconv "go.opentelemetry.io/collector/semconv/v1.8.0"
@@ -712,3 +715,342 @@ processor:
 	testkits.AssertAttrsFoundStringVal(t, attrs, semconv.AttributeDBStatement, "testDbStatement")
 	testkits.AssertAttrsFoundStringVal(t, attrs, "db.parameters", "testDbParameters")
 }
+
+func makeLogsAttributesGenerator(n int, attrs map[string]string) *generator.LogsGenerator {
+	opts := define.LogsOptions{
+		LogCount:  n,
+		LogLength: 20,
+	}
+	opts.Attributes = attrs
+	return generator.NewLogsGenerator(opts)
+}
+
+func firstLogRecord(logs plog.Logs) plog.LogRecord {
+	return logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+}
+
+func TestLogsFromTokenAction(t *testing.T) {
+	content := `
+processor:
+   - name: "attribute_filter/from_token"
+     config:
+       from_token:
+         biz_id: "bk_biz_id"
+         app_name: "bk_app_name"
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	g := makeLogsAttributesGenerator(1, nil)
+	record := &define.Record{
+		RecordType: define.RecordLogs,
+		Data:       g.Generate(),
+		Token: define.Token{
+			BizId:   10086,
+			AppName: "my_app_name",
+		},
+	}
+
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+
+	logRecord := firstLogRecord(record.Data.(plog.Logs))
+	testkits.AssertAttrsFoundStringVal(t, logRecord.Attributes(), "bk_biz_id", "10086")
+	testkits.AssertAttrsFoundStringVal(t, logRecord.Attributes(), "bk_app_name", "my_app_name")
+}
+
+func TestLogsAsIntAction(t *testing.T) {
+	content := `
+processor:
+   - name: "attribute_filter/as_int"
+     config:
+       as_int:
+         keys:
+           - "attributes.http.status_code"
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	g := makeLogsAttributesGenerator(1, map[string]string{"http.status_code": "200"})
+	record := &define.Record{
+		RecordType: define.RecordLogs,
+		Data:       g.Generate(),
+	}
+
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+
+	logRecord := firstLogRecord(record.Data.(plog.Logs))
+	testkits.AssertAttrsFoundIntVal(t, logRecord.Attributes(), "http.status_code", 200)
+}
+
+func TestLogsDropAndCutAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/common"
+    config:
+      drop:
+        - predicate_key: "attributes.db.system"
+          keys:
+            - "attributes.db.parameters"
+      cut:
+        - predicate_key: "attributes.db.system"
+          max_length: 10
+          keys:
+            - "attributes.db.statement"
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	m := map[string]string{
+		"db.system":     "mysql",
+		"db.parameters": "testDbParameters",
+		"db.statement":  "testDbStatement",
+	}
+	g := makeLogsAttributesGenerator(1, m)
+	record := &define.Record{
+		RecordType: define.RecordLogs,
+		Data:       g.Generate(),
+	}
+
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+
+	attrs := firstLogRecord(record.Data.(plog.Logs)).Attributes()
+	testkits.AssertAttrsNotFound(t, attrs, "db.parameters")
+	testkits.AssertAttrsFoundStringVal(t, attrs, semconv.AttributeDBStatement, "testDbStatement"[:10])
+}
+
+func TestLogsSeverityAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/common"
+    config:
+      severity:
+        keep: ["warn", "error", "fatal"]
+        drop: ["fatal"]
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	t.Run("Partial", func(t *testing.T) {
+		logs := makeLogsAttributesGenerator(4, nil).Generate()
+		logRecords := logs.ResourceLogs().At(0).ScopeLogs()
+		logRecords.At(0).LogRecords().At(0).SetSeverityText("info")
+		logRecords.At(1).LogRecords().At(0).SetSeverityText("Error")
+		logRecords.At(2).LogRecords().At(0).SetSeverityNumber(plog.SeverityNumberWARN2)
+		logRecords.At(3).LogRecords().At(0).SetSeverityNumber(plog.SeverityNumberFATAL)
+
+		record := &define.Record{
+			RecordType: define.RecordLogs,
+			Data:       logs,
+		}
+		_, err := factory.Process(record)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, record.Data.(plog.Logs).LogRecordCount())
+	})
+
+	t.Run("All", func(t *testing.T) {
+		logs := makeLogsAttributesGenerator(2, nil).Generate()
+		record := &define.Record{
+			RecordType: define.RecordLogs,
+			Data:       logs,
+		}
+		_, err := factory.Process(record)
+		assert.Equal(t, define.ErrSkipEmptyRecord, err)
+	})
+}
+
+func TestLogsBodyAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/common"
+    config:
+      body:
+        max_length: 5
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	record := &define.Record{
+		RecordType: define.RecordLogs,
+		Data:       makeLogsAttributesGenerator(1, nil).Generate(),
+	}
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+
+	body := firstLogRecord(record.Data.(plog.Logs)).Body()
+	assert.Len(t, body.StringVal(), 5)
+}
+
+func TestTracesTransformAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - where: 'attributes.http.method == "GET" && kind == 2'
+          statements:
+            - 'set(attributes.api_name, span_name)'
+            - 'rename(attributes.http.url, attributes.http.target)'
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+	config := factory.(*attributeFilter).configs.GetGlobal().(Config)
+	assert.Len(t, config.Transform, 1)
+	assert.True(t, config.Transform[0].Enabled())
+
+	g := makeTracesAttributesGenerator(int(ptrace.SpanKindServer), map[string]string{
+		"http.method": "GET",
+		"http.url":    "/api/v1",
+	})
+	data := g.Generate()
+	span := data.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	span.SetName("GET /api")
+
+	record := define.Record{
+		RecordType: define.RecordTraces,
+		Data:       data,
+	}
+	_, err := factory.Process(&record)
+	assert.NoError(t, err)
+
+	attrs := span.Attributes()
+	testkits.AssertAttrsFoundStringVal(t, attrs, "api_name", "GET /api")
+	testkits.AssertAttrsFoundStringVal(t, attrs, "http.target", "/api/v1")
+	testkits.AssertAttrsFoundStringVal(t, attrs, "http.method", "GET")
+	testkits.AssertAttrsNotFound(t, attrs, "http.url")
+}
+
+func TestTransformActionInvalid(t *testing.T) {
+	contents := []string{
+		`
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - where: 'invalid =='
+          statements:
+            - 'delete(attributes.http.method)'
+`,
+		`
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - statements:
+            - 'unknown(attributes.http.method)'
+`,
+		`
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - where: 'attributes.http.method == "GET"'
+          statements:
+            - 'set(resource.env, "prod")'
+`,
+		`
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - statements:
+            - 'set(resource.api_name, span_name)'
+`,
+	}
+	for _, content := range contents {
+		psc := processor.MustLoadConfigs(content)
+		_, err := NewFactory(psc[0].Config, nil)
+		assert.Error(t, err)
+	}
+}
+
+func TestTracesTransformResourceAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - where: 'resource.service.name == "checkout"'
+          statements:
+            - 'hash(resource.service.name)'
+        - statements:
+            - 'set(attributes.service, resource.service.name)'
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().UpsertString("service.name", "checkout")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	spans.AppendEmpty()
+	spans.AppendEmpty()
+
+	record := define.Record{
+		RecordType: define.RecordTraces,
+		Data:       traces,
+	}
+	_, err := factory.Process(&record)
+	assert.NoError(t, err)
+
+	// resource 规则每个 resource 仅执行一次 且先于 span 规则
+	sum := sha256.Sum256([]byte("checkout"))
+	hashed := hex.EncodeToString(sum[:])
+	testkits.AssertAttrsFoundStringVal(t, rs.Resource().Attributes(), "service.name", hashed)
+	for i := 0; i < spans.Len(); i++ {
+		testkits.AssertAttrsFoundStringVal(t, spans.At(i).Attributes(), "service", hashed)
+	}
+}
+
+func TestMetricsTransformAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - where: 'metric_name =~ "^bk_"'
+          statements:
+            - 'hash(attributes.user)'
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	metrics := pmetric.NewMetrics()
+	metric := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("bk_requests_total")
+	metric.SetDataType(pmetric.MetricDataTypeSum)
+	metric.Sum().DataPoints().AppendEmpty().Attributes().UpsertString("user", "admin")
+
+	record := define.Record{
+		RecordType: define.RecordMetrics,
+		Data:       metrics,
+	}
+	_, err := factory.Process(&record)
+	assert.NoError(t, err)
+
+	v, ok := metric.Sum().DataPoints().At(0).Attributes().Get("user")
+	assert.True(t, ok)
+	assert.Len(t, v.StringVal(), 64)
+}
+
+func TestLogsTransformAction(t *testing.T) {
+	content := `
+processor:
+  - name: "attribute_filter/transform"
+    config:
+      transform:
+        - where: 'severity_text == "ERROR"'
+          statements:
+            - 'set(attributes.alert, "true")'
+            - 'replace_regex(attributes.password, ".+", "******")'
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	logs := makeLogsAttributesGenerator(1, map[string]string{"password": "secret"}).Generate()
+	firstLogRecord(logs).SetSeverityText("error")
+
+	record := define.Record{
+		RecordType: define.RecordLogs,
+		Data:       logs,
+	}
+	_, err := factory.Process(&record)
+	assert.NoError(t, err)
+
+	attrs := firstLogRecord(logs).Attributes()
+	testkits.AssertAttrsFoundStringVal(t, attrs, "alert", "true")
+	testkits.AssertAttrsFoundStringVal(t, attrs, "password", "******")
+}
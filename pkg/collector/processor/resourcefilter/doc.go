@@ -8,7 +8,7 @@
 // specific language governing permissions and limitations under the License.
 
 /*
-# ResourceFilter: resource 过滤器
+# ResourceFilter: resource 过滤器 支持 traces/metrics/logs 数据
 
 processor:
     # Drop Action
@@ -126,6 +126,26 @@ func (p *resourceFilter) assembleAction(record *define.Record, config Config) {
 				attrs.UpsertString(action.Destination, strings.Join(values, action.Separator))
 			}
 		}
+
+	case define.RecordLogs:
+		pdLogs := record.Data.(plog.Logs)
+		resourceLogsSlice := pdLogs.ResourceLogs()
+		for _, action := range config.Assemble {
+			for i := 0; i < resourceLogsSlice.Len(); i++ {
+				attrs := resourceLogsSlice.At(i).Resource().Attributes()
+				var values []string
+				for _, key := range action.Keys {
+					v, ok := attrs.Get(key)
+					if !ok {
+						// 空值保留
+						values = append(values, "")
+						continue
+					}
+					values = append(values, v.AsString())
+				}
+				attrs.UpsertString(action.Destination, strings.Join(values, action.Separator))
+			}
+		}
 	}
 }
 
@@ -170,7 +190,7 @@ func (p *resourceFilter) dropAction(record *define.Record, config Config) {
 	case define.RecordTraces:
 		pdTraces := record.Data.(ptrace.Traces)
 		resourceSpansSlice := pdTraces.ResourceSpans()
-		// 只对 drop action 清洗到 span 维度
+		// 只对 drop action 清洗到 span/logRecord 维度
 		for _, dimension := range config.Drop.Keys {
 			for i := 0; i < resourceSpansSlice.Len(); i++ {
 				resourceSpans := resourceSpansSlice.At(i)
@@ -202,6 +222,13 @@ func (p *resourceFilter) dropAction(record *define.Record, config Config) {
 			for i := 0; i < resourceLogsSlice.Len(); i++ {
 				resourceLogs := resourceLogsSlice.At(i)
 				resourceLogs.Resource().Attributes().Remove(dimension)
+				scopeLogsSlice := resourceLogs.ScopeLogs()
+				for j := 0; j < scopeLogsSlice.Len(); j++ {
+					logRecords := scopeLogsSlice.At(j).LogRecords()
+					for k := 0; k < logRecords.Len(); k++ {
+						logRecords.At(k).Attributes().Remove(dimension)
+					}
+				}
 			}
 		}
 	}
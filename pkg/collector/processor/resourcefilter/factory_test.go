@@ -137,6 +137,37 @@ processor:
 	testkits.AssertAttrsFoundStringVal(t, attrs, "resource_final", "key1::key2:key3:key4")
 }
 
+func TestLogsAssembleAction(t *testing.T) {
+	content := `
+processor:
+    - name: "resource_filter/assemble"
+      config:
+        assemble:
+          - destination: "resource_final"
+            separator: ":"
+            keys:
+              - "resource.resource_key1"
+              - "resource.not_exist"
+              - "resource.resource_key2"
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+
+	g := makeLogsGenerator(1, 10, "string")
+	data := g.Generate()
+	record := define.Record{
+		RecordType: define.RecordLogs,
+		Data:       data,
+	}
+
+	_, err := factory.Process(&record)
+	assert.NoError(t, err)
+
+	attrs := record.Data.(plog.Logs).ResourceLogs().At(0).Resource().Attributes()
+	v1, _ := attrs.Get(resourceKey1)
+	v2, _ := attrs.Get(resourceKey2)
+	testkits.AssertAttrsFoundStringVal(t, attrs, "resource_final", v1.AsString()+"::"+v2.AsString())
+}
+
 func assertDropActionAttrs(t *testing.T, attrs pcommon.Map) {
 	testkits.AssertAttrsNotFound(t, attrs, "resource_key1")
 	testkits.AssertAttrsFound(t, attrs, "resource_key2")
@@ -32,6 +32,29 @@ processor:
       max_spans: 100 # 每个 traces 最多允许的 spans 数量
       status_code: # ERROR|OK|UNSET
       - "ERROR"
+
+  # 尾部采样
+  # 缓存 traces 直至决策窗口结束 命中任意策略则保留整条 trace
+  - name: "sampler/tail"
+    config:
+      type: "tail"
+      decision_wait: "10s" # 决策窗口
+      max_duration: "1m" # 决策结果保留时长 用于处理迟到的 span
+      max_span: 1000 # 每个 traces 最多允许缓存的 spans 数量
+      policies:
+        # 存在状态码为 ERROR 的 span
+        - type: "status_code"
+        # 根 span 耗时超过阈值
+        - type: "latency"
+          threshold: "2s"
+        # span 或 resource 属性匹配 values 为空时只要求属性存在
+        - type: "attribute"
+          key: "http.status_code"
+          values: ["500", "502"]
+        # 按服务概率采样 service 为空时对所有服务生效
+        - type: "service_rate"
+          service: "order"
+          sampling_percentage: 10
 */
 
 package sampler
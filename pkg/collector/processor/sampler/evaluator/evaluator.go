@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor"
 )
 
 type Config struct {
@@ -26,12 +27,34 @@ type Config struct {
 	// status_code evaluator
 	MaxDuration time.Duration `config:"max_duration" mapstructure:"max_duration"`
 	StatusCode  []string      `config:"status_code" mapstructure:"status_code"`
+
+	// tail evaluator
+	DecisionWait time.Duration `config:"decision_wait" mapstructure:"decision_wait"`
+	Policies     []TailPolicy  `config:"policies" mapstructure:"policies"`
+	GcInterval   time.Duration `config:"gc_interval" mapstructure:"gc_interval"`
+}
+
+// TailPolicy 尾部采样策略 traces 命中任意一条策略即保留
+type TailPolicy struct {
+	Type string `config:"type" mapstructure:"type"`
+
+	// latency policy
+	Threshold time.Duration `config:"threshold" mapstructure:"threshold"`
+
+	// attribute policy
+	Key    string   `config:"key" mapstructure:"key"`
+	Values []string `config:"values" mapstructure:"values"`
+
+	// service_rate policy
+	Service            string  `config:"service" mapstructure:"service"`
+	SamplingPercentage float64 `config:"sampling_percentage" mapstructure:"sampling_percentage"`
 }
 
 const (
 	evaluatorTypeAlways     = "always"
 	evaluatorTypeRandom     = "random"
 	evaluatorTypeStatusCode = "status_code"
+	evaluatorTypeTail       = "tail"
 )
 
 type Evaluator interface {
@@ -46,6 +69,8 @@ func New(c Config) Evaluator {
 		return newRandomEvaluator(c)
 	case evaluatorTypeStatusCode:
 		return newStatusCodeEvaluator(c)
+	case evaluatorTypeTail:
+		return newTailEvaluator(c, processor.PublishNonSchedRecords)
 	}
 	return newAlwaysEvaluator() // evaluatorTypeAlways
 }
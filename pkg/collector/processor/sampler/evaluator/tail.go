@@ -0,0 +1,440 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package evaluator
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.8.0"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/batchspliter"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/foreach"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/sampler/queue"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	tailPolicyStatusCode  = "status_code"
+	tailPolicyLatency     = "latency"
+	tailPolicyAttribute   = "attribute"
+	tailPolicyServiceRate = "service_rate"
+
+	defaultDecisionWait = 10 * time.Second
+	defaultTailMaxSpans = 1000
+)
+
+// traceSummary 记录决策窗口内 trace 的统计信息
+// span 数据本身存放在 queue（tracestore）中 内存中仅保留决策所需的信息
+type traceSummary struct {
+	firstSeen    time.Time
+	spanCount    int
+	sampled      bool // 已有 span 命中了策略
+	hasRoot      bool
+	rootDuration time.Duration
+	minStart     pcommon.Timestamp
+	maxEnd       pcommon.Timestamp
+}
+
+func (s *traceSummary) observe(span ptrace.Span) {
+	start, end := span.StartTimestamp(), span.EndTimestamp()
+	if s.minStart == 0 || start < s.minStart {
+		s.minStart = start
+	}
+	if end > s.maxEnd {
+		s.maxEnd = end
+	}
+	if span.ParentSpanID().IsEmpty() && end >= start {
+		s.hasRoot = true
+		s.rootDuration = time.Duration(end - start)
+	}
+}
+
+// Duration 优先返回根 span 的耗时 如果根 span 尚未到达则使用 span 的时间跨度代替
+func (s *traceSummary) Duration() time.Duration {
+	if s.hasRoot {
+		return s.rootDuration
+	}
+	if s.maxEnd < s.minStart {
+		return 0
+	}
+	return time.Duration(s.maxEnd - s.minStart)
+}
+
+// tailPolicy 尾部采样策略
+type tailPolicy interface {
+	// MatchSpan 判断单个 span 是否命中策略 命中即意味着整条 trace 保留
+	MatchSpan(rsAttrs pcommon.Map, span ptrace.Span) bool
+
+	// MatchTrace 在决策窗口结束时根据 trace 统计信息判断是否命中策略
+	MatchTrace(summary *traceSummary) bool
+}
+
+type statusCodePolicy struct{}
+
+func (statusCodePolicy) MatchSpan(_ pcommon.Map, span ptrace.Span) bool {
+	return span.Status().Code() == ptrace.StatusCodeError
+}
+
+func (statusCodePolicy) MatchTrace(*traceSummary) bool { return false }
+
+type latencyPolicy struct {
+	threshold time.Duration
+}
+
+func (latencyPolicy) MatchSpan(pcommon.Map, ptrace.Span) bool { return false }
+
+func (p latencyPolicy) MatchTrace(summary *traceSummary) bool {
+	return summary.Duration() > p.threshold
+}
+
+type attributePolicy struct {
+	key    string
+	values map[string]struct{}
+}
+
+func (p attributePolicy) MatchSpan(rsAttrs pcommon.Map, span ptrace.Span) bool {
+	v, ok := span.Attributes().Get(p.key)
+	if !ok {
+		v, ok = rsAttrs.Get(p.key)
+	}
+	if !ok {
+		return false
+	}
+
+	// 未指定 values 时只要求属性存在
+	if len(p.values) == 0 {
+		return true
+	}
+	_, ok = p.values[v.AsString()]
+	return ok
+}
+
+func (attributePolicy) MatchTrace(*traceSummary) bool { return false }
+
+type serviceRatePolicy struct {
+	service string
+	random  randomEvaluator
+}
+
+func (p serviceRatePolicy) MatchSpan(rsAttrs pcommon.Map, span ptrace.Span) bool {
+	// 未指定 service 时对所有服务生效
+	if p.service != "" {
+		v, ok := rsAttrs.Get(semconv.AttributeServiceName)
+		if !ok || v.AsString() != p.service {
+			return false
+		}
+	}
+	if p.random.keepAll {
+		return true
+	}
+
+	// 基于 traceID 计算 hash 同一条 trace 的所有 span 结果一致
+	tidBytes := span.TraceID().Bytes()
+	return p.random.hash(tidBytes[:], p.random.hashSeed)&bitMaskHashBuckets < p.random.scaledSamplingRate
+}
+
+func (serviceRatePolicy) MatchTrace(*traceSummary) bool { return false }
+
+func newTailPolicies(policies []TailPolicy) []tailPolicy {
+	var ret []tailPolicy
+	for _, p := range policies {
+		switch p.Type {
+		case tailPolicyStatusCode:
+			ret = append(ret, statusCodePolicy{})
+		case tailPolicyLatency:
+			ret = append(ret, latencyPolicy{threshold: p.Threshold})
+		case tailPolicyAttribute:
+			values := make(map[string]struct{})
+			for _, v := range p.Values {
+				values[v] = struct{}{}
+			}
+			ret = append(ret, attributePolicy{key: p.Key, values: values})
+		case tailPolicyServiceRate:
+			random := newRandomEvaluator(Config{SamplingPercentage: p.SamplingPercentage}).(randomEvaluator)
+			ret = append(ret, serviceRatePolicy{service: p.Service, random: random})
+		default:
+			logger.Warnf("unsupported tail sampling policy type: %s", p.Type)
+		}
+	}
+	return ret
+}
+
+type tailDecision struct {
+	keep bool
+	ts   time.Time
+}
+
+// tailEvaluator 尾部采样
+// 缓存 traces 直至决策窗口结束 再根据策略决定整条 trace 保留或丢弃
+// 决策完成的 traces 会随着该 dataID 下一次上报的数据一同发送 同时由定时任务兜底发送 避免 dataID 无新数据时丢失
+type tailEvaluator struct {
+	mut          sync.Mutex
+	traces       map[int32]map[pcommon.TraceID]*traceSummary
+	decisions    map[int32]map[pcommon.TraceID]tailDecision
+	tokens       map[int32]define.Token
+	policies     []tailPolicy
+	q            *queue.Queue
+	maxSpans     int
+	decisionWait time.Duration
+	maxDuration  time.Duration
+	stop         chan struct{}
+	gcInterval   time.Duration
+	publishFunc  func(r *define.Record)
+}
+
+func newTailEvaluator(config Config, publishFunc func(r *define.Record)) *tailEvaluator {
+	maxSpans := config.MaxSpan
+	if maxSpans <= 0 {
+		maxSpans = defaultTailMaxSpans
+	}
+
+	decisionWait := config.DecisionWait
+	if decisionWait <= 0 {
+		decisionWait = defaultDecisionWait
+	}
+
+	// 决策结果需要保留一段时间 用于处理迟到的 span
+	maxDuration := config.MaxDuration
+	if maxDuration < decisionWait {
+		maxDuration = decisionWait * 6
+	}
+
+	eval := &tailEvaluator{
+		traces:       make(map[int32]map[pcommon.TraceID]*traceSummary),
+		decisions:    make(map[int32]map[pcommon.TraceID]tailDecision),
+		tokens:       make(map[int32]define.Token),
+		policies:     newTailPolicies(config.Policies),
+		q:            queue.New(string(queue.PolicyFull), maxSpans),
+		maxSpans:     maxSpans,
+		decisionWait: decisionWait,
+		maxDuration:  maxDuration,
+		stop:         make(chan struct{}),
+		gcInterval:   config.GcInterval,
+		publishFunc:  publishFunc,
+	}
+	go eval.gc()
+
+	if publishFunc != nil {
+		go eval.flush()
+	}
+	return eval
+}
+
+func (e *tailEvaluator) Evaluate(record *define.Record) {
+	switch record.RecordType {
+	case define.RecordTraces:
+		e.processTraces(record)
+	}
+}
+
+func (e *tailEvaluator) Type() string {
+	return evaluatorTypeTail
+}
+
+func (e *tailEvaluator) Stop() {
+	close(e.stop)
+	e.q.Clean()
+}
+
+func (e *tailEvaluator) processTraces(record *define.Record) {
+	dataID := record.Token.TracesDataId
+	pdTraces := record.Data.(ptrace.Traces)
+	now := time.Now()
+
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	e.tokens[dataID] = record.Token
+	pending, ok := e.traces[dataID]
+	if !ok {
+		pending = make(map[pcommon.TraceID]*traceSummary)
+		e.traces[dataID] = pending
+	}
+	decisions, ok := e.decisions[dataID]
+	if !ok {
+		decisions = make(map[pcommon.TraceID]tailDecision)
+		e.decisions[dataID] = decisions
+	}
+
+	// 先遍历一遍更新未决策 traces 的统计信息
+	foreach.SpansWithResourceAttrs(pdTraces.ResourceSpans(), func(rsAttrs pcommon.Map, span ptrace.Span) {
+		traceID := span.TraceID()
+		if _, ok := decisions[traceID]; ok {
+			return
+		}
+
+		summary, ok := pending[traceID]
+		if !ok {
+			summary = &traceSummary{firstSeen: now}
+			pending[traceID] = summary
+		}
+		summary.observe(span)
+		if summary.sampled {
+			return
+		}
+		for _, p := range e.policies {
+			if p.MatchSpan(rsAttrs, span) {
+				summary.sampled = true
+				break
+			}
+		}
+	})
+
+	// 按 span 切分后清空本次上报的数据 已决策保留的 span 会重新追加回来
+	batch := batchspliter.SplitEachSpans(pdTraces)
+	pdTraces.ResourceSpans().RemoveIf(func(ptrace.ResourceSpans) bool { return true })
+
+	for i := 0; i < len(batch); i++ {
+		t := batch[i]
+		traceID, _, ok := queue.IdFromTraces(t)
+		if !ok {
+			continue
+		}
+
+		// 迟到的 span 直接沿用已有的决策结果
+		if d, ok := decisions[traceID]; ok {
+			if d.keep {
+				t.ResourceSpans().MoveAndAppendTo(pdTraces.ResourceSpans())
+			}
+			continue
+		}
+
+		summary := pending[traceID]
+		if summary.spanCount >= e.maxSpans {
+			logger.Debugf("tail evaluator drop span, exceeded max spans, dataID=%v, traceID=%v", dataID, traceID.HexString())
+			continue
+		}
+		summary.spanCount++
+		if err := e.q.Put(dataID, t); err != nil {
+			logger.Warnf("queue failed to put traces, dataID=%v, err: %v", dataID, err)
+		}
+	}
+
+	e.decideExpired(dataID, now, pdTraces.ResourceSpans())
+}
+
+// decideExpired 对决策窗口结束的 traces 作出决策并弹出缓存 保留的 span 追加到 dst
+// 调用方需持有锁
+func (e *tailEvaluator) decideExpired(dataID int32, now time.Time, dst ptrace.ResourceSpansSlice) {
+	pending := e.traces[dataID]
+	decisions := e.decisions[dataID]
+	for traceID, summary := range pending {
+		if now.Sub(summary.firstSeen) < e.decisionWait {
+			continue
+		}
+
+		keep := e.decide(summary)
+		decisions[traceID] = tailDecision{keep: keep, ts: now}
+		delete(pending, traceID)
+
+		popItems := e.q.Pop(dataID, traceID)
+		if !keep {
+			continue
+		}
+		for j := 0; j < len(popItems); j++ {
+			popItems[j].ResourceSpans().MoveAndAppendTo(dst)
+		}
+	}
+}
+
+// flush 定时对决策窗口结束的 traces 作出决策并发送 不依赖该 dataID 是否有新数据上报
+func (e *tailEvaluator) flush() {
+	ticker := time.NewTicker(e.decisionWait / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+
+		case <-ticker.C:
+			now := time.Now()
+			var records []*define.Record
+			e.mut.Lock()
+			for dataID := range e.traces {
+				traces := ptrace.NewTraces()
+				e.decideExpired(dataID, now, traces.ResourceSpans())
+				if traces.SpanCount() == 0 {
+					continue
+				}
+				records = append(records, &define.Record{
+					RecordType: define.RecordTraces,
+					Token:      e.tokens[dataID],
+					Data:       traces,
+				})
+			}
+			e.mut.Unlock()
+
+			for _, r := range records {
+				e.publishFunc(r)
+			}
+		}
+	}
+}
+
+func (e *tailEvaluator) decide(summary *traceSummary) bool {
+	if summary.sampled {
+		return true
+	}
+	for _, p := range e.policies {
+		if p.MatchTrace(summary) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *tailEvaluator) gc() {
+	d := e.gcInterval
+	if d <= 0 {
+		d = time.Minute
+	}
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+
+		case <-ticker.C:
+			now := time.Now()
+			e.mut.Lock()
+			for dataID, decisions := range e.decisions {
+				for traceID, decision := range decisions {
+					if now.Sub(decision.ts) > e.maxDuration {
+						delete(decisions, traceID)
+					}
+				}
+				if len(decisions) == 0 && len(e.traces[dataID]) == 0 {
+					delete(e.decisions, dataID)
+					delete(e.traces, dataID)
+					delete(e.tokens, dataID)
+				}
+			}
+
+			// 未能及时决策的 traces 超时后直接丢弃
+			for dataID, pending := range e.traces {
+				for traceID, summary := range pending {
+					if now.Sub(summary.firstSeen) > e.maxDuration {
+						logger.Debugf("tail evaluator drop expired traces, dataID=%v, traceID=%v", dataID, traceID.HexString())
+						delete(pending, traceID)
+						e.q.Pop(dataID, traceID)
+					}
+				}
+			}
+			e.mut.Unlock()
+		}
+	}
+}
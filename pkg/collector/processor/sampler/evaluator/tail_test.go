@@ -0,0 +1,246 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/random"
+)
+
+func makeTailSpan(rs ptrace.ResourceSpans, traceID pcommon.TraceID, code ptrace.StatusCode) ptrace.Span {
+	span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(random.SpanID())
+	span.SetParentSpanID(random.SpanID())
+	span.Status().SetCode(code)
+	return span
+}
+
+func evaluateTail(evaluator Evaluator, traces ptrace.Traces) {
+	evaluator.Evaluate(&define.Record{
+		RecordType: define.RecordTraces,
+		Token:      define.Token{TracesDataId: 1001},
+		Data:       traces,
+	})
+}
+
+func TestTailEvaluatorStatusCode(t *testing.T) {
+	evaluator := newTailEvaluator(Config{
+		DecisionWait: time.Millisecond * 100,
+		Policies:     []TailPolicy{{Type: tailPolicyStatusCode}},
+	}, nil)
+	defer evaluator.Stop()
+
+	t1 := random.TraceID()
+	t2 := random.TraceID()
+
+	// round1: 所有 span 都被缓存
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	makeTailSpan(rs, t1, ptrace.StatusCodeOk)
+	makeTailSpan(rs, t2, ptrace.StatusCodeOk)
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 0, traces.SpanCount())
+
+	// round2: t1 出现错误 span 但仍在决策窗口内
+	traces = ptrace.NewTraces()
+	rs = traces.ResourceSpans().AppendEmpty()
+	makeTailSpan(rs, t1, ptrace.StatusCodeError)
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 0, traces.SpanCount())
+
+	// round3: 决策窗口结束 t1 整条 trace 保留 t2 丢弃
+	time.Sleep(time.Millisecond * 200)
+	traces = ptrace.NewTraces()
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 2, traces.SpanCount())
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		span := traces.ResourceSpans().At(i).ScopeSpans().At(0).Spans().At(0)
+		assert.Equal(t, t1, span.TraceID())
+	}
+
+	// round4: 迟到的 span 沿用决策结果
+	traces = ptrace.NewTraces()
+	rs = traces.ResourceSpans().AppendEmpty()
+	makeTailSpan(rs, t1, ptrace.StatusCodeOk)
+	makeTailSpan(rs, t2, ptrace.StatusCodeOk)
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 1, traces.SpanCount())
+	assert.Len(t, evaluator.decisions[1001], 2)
+}
+
+func TestTailEvaluatorLatency(t *testing.T) {
+	evaluator := newTailEvaluator(Config{
+		DecisionWait: time.Millisecond * 100,
+		Policies:     []TailPolicy{{Type: tailPolicyLatency, Threshold: time.Second}},
+	}, nil)
+	defer evaluator.Stop()
+
+	t1 := random.TraceID()
+	t2 := random.TraceID()
+	now := time.Now()
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+
+	root1 := makeTailSpan(rs, t1, ptrace.StatusCodeOk)
+	root1.SetParentSpanID(pcommon.NewSpanID([8]byte{}))
+	root1.SetStartTimestamp(pcommon.NewTimestampFromTime(now))
+	root1.SetEndTimestamp(pcommon.NewTimestampFromTime(now.Add(time.Second * 2)))
+
+	root2 := makeTailSpan(rs, t2, ptrace.StatusCodeOk)
+	root2.SetParentSpanID(pcommon.NewSpanID([8]byte{}))
+	root2.SetStartTimestamp(pcommon.NewTimestampFromTime(now))
+	root2.SetEndTimestamp(pcommon.NewTimestampFromTime(now.Add(time.Millisecond)))
+
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 0, traces.SpanCount())
+
+	time.Sleep(time.Millisecond * 200)
+	traces = ptrace.NewTraces()
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 1, traces.SpanCount())
+	assert.Equal(t, t1, traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+}
+
+func TestTailEvaluatorAttribute(t *testing.T) {
+	evaluator := newTailEvaluator(Config{
+		DecisionWait: time.Millisecond * 100,
+		Policies: []TailPolicy{{
+			Type:   tailPolicyAttribute,
+			Key:    "http.method",
+			Values: []string{"POST"},
+		}},
+	}, nil)
+	defer evaluator.Stop()
+
+	t1 := random.TraceID()
+	t2 := random.TraceID()
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	makeTailSpan(rs, t1, ptrace.StatusCodeOk).Attributes().UpsertString("http.method", "POST")
+	makeTailSpan(rs, t1, ptrace.StatusCodeOk)
+	makeTailSpan(rs, t2, ptrace.StatusCodeOk).Attributes().UpsertString("http.method", "GET")
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 0, traces.SpanCount())
+
+	time.Sleep(time.Millisecond * 200)
+	traces = ptrace.NewTraces()
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 2, traces.SpanCount())
+}
+
+func TestTailEvaluatorServiceRate(t *testing.T) {
+	evaluator := newTailEvaluator(Config{
+		DecisionWait: time.Millisecond * 100,
+		Policies: []TailPolicy{
+			{Type: tailPolicyServiceRate, Service: "order", SamplingPercentage: 100},
+			{Type: tailPolicyServiceRate, Service: "user", SamplingPercentage: 0},
+		},
+	}, nil)
+	defer evaluator.Stop()
+
+	traces := ptrace.NewTraces()
+	rs1 := traces.ResourceSpans().AppendEmpty()
+	rs1.Resource().Attributes().UpsertString("service.name", "order")
+	makeTailSpan(rs1, random.TraceID(), ptrace.StatusCodeOk)
+
+	rs2 := traces.ResourceSpans().AppendEmpty()
+	rs2.Resource().Attributes().UpsertString("service.name", "user")
+	makeTailSpan(rs2, random.TraceID(), ptrace.StatusCodeOk)
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 0, traces.SpanCount())
+
+	time.Sleep(time.Millisecond * 200)
+	traces = ptrace.NewTraces()
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 1, traces.SpanCount())
+
+	v, ok := traces.ResourceSpans().At(0).Resource().Attributes().Get("service.name")
+	assert.True(t, ok)
+	assert.Equal(t, "order", v.AsString())
+}
+
+func TestTailEvaluatorGc(t *testing.T) {
+	evaluator := newTailEvaluator(Config{
+		DecisionWait: time.Millisecond * 100,
+		MaxDuration:  time.Millisecond * 200,
+		Policies:     []TailPolicy{{Type: tailPolicyStatusCode}},
+		GcInterval:   time.Millisecond * 100,
+	}, nil)
+	defer evaluator.Stop()
+
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	makeTailSpan(rs, random.TraceID(), ptrace.StatusCodeError)
+	evaluateTail(evaluator, traces)
+
+	time.Sleep(time.Millisecond * 500)
+	evaluator.mut.Lock()
+	defer evaluator.mut.Unlock()
+	assert.Len(t, evaluator.traces[1001], 0)
+}
+
+func TestTailEvaluatorFlush(t *testing.T) {
+	ch := make(chan *define.Record, 10)
+	evaluator := newTailEvaluator(Config{
+		DecisionWait: time.Millisecond * 100,
+		Policies:     []TailPolicy{{Type: tailPolicyStatusCode}},
+	}, func(r *define.Record) {
+		ch <- r
+	})
+	defer evaluator.Stop()
+
+	t1 := random.TraceID()
+	traces := ptrace.NewTraces()
+	rs := traces.ResourceSpans().AppendEmpty()
+	makeTailSpan(rs, t1, ptrace.StatusCodeError)
+	makeTailSpan(rs, t1, ptrace.StatusCodeOk)
+	makeTailSpan(rs, random.TraceID(), ptrace.StatusCodeOk)
+	evaluateTail(evaluator, traces)
+	assert.Equal(t, 0, traces.SpanCount())
+
+	// 决策后不再有新数据上报 保留的 traces 仍会被发送
+	select {
+	case r := <-ch:
+		assert.Equal(t, define.RecordTraces, r.RecordType)
+		assert.Equal(t, int32(1001), r.Token.TracesDataId)
+		flushed := r.Data.(ptrace.Traces)
+		assert.Equal(t, 2, flushed.SpanCount())
+		for i := 0; i < flushed.ResourceSpans().Len(); i++ {
+			assert.Equal(t, t1, flushed.ResourceSpans().At(i).ScopeSpans().At(0).Spans().At(0).TraceID())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("decided traces not flushed")
+	}
+
+	// 决策结果按 dataID 隔离 其他 dataID 的相同 traceID 仍需重新决策
+	traces = ptrace.NewTraces()
+	makeTailSpan(traces.ResourceSpans().AppendEmpty(), t1, ptrace.StatusCodeOk)
+	evaluator.Evaluate(&define.Record{
+		RecordType: define.RecordTraces,
+		Token:      define.Token{TracesDataId: 1002},
+		Data:       traces,
+	})
+	assert.Equal(t, 0, traces.SpanCount())
+
+	evaluator.mut.Lock()
+	defer evaluator.mut.Unlock()
+	assert.Len(t, evaluator.decisions[1001], 2)
+	assert.Len(t, evaluator.traces[1002], 1)
+}
@@ -0,0 +1,54 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package seriesguard
+
+import (
+	"time"
+)
+
+const (
+	ModeDrop   = "drop"
+	ModeReject = "reject"
+
+	defaultWindow = time.Hour
+	defaultTopN   = 10
+)
+
+type Config struct {
+	// MaxSeries 窗口期内允许的最大活跃 series 数 小于等于 0 表示不限制
+	MaxSeries int `config:"max_series" mapstructure:"max_series"`
+
+	// Window 滑动窗口 超过窗口期未再上报的 series 不再计入活跃数
+	Window time.Duration `config:"window" mapstructure:"window"`
+
+	// Mode 超限处理模式
+	// drop: 仅丢弃新增的 series 已有的 series 不受影响
+	// reject: 只要出现新增的超限 series 则丢弃整条记录
+	Mode string `config:"mode" mapstructure:"mode"`
+
+	// TopN 上报超限 series 的指标名/维度排行数量
+	TopN int `config:"top_n" mapstructure:"top_n"`
+}
+
+func (c *Config) Validate() {
+	if c.Window <= 0 {
+		c.Window = defaultWindow
+	}
+	if c.Mode != ModeReject {
+		c.Mode = ModeDrop
+	}
+	if c.TopN <= 0 {
+		c.TopN = defaultTopN
+	}
+}
+
+func (c *Config) Enabled() bool {
+	return c.MaxSeries > 0
+}
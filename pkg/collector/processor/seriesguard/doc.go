@@ -0,0 +1,32 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+/*
+# SeriesGuard: series 基数保护 仅作用于 remotewrite/pushgateway 数据
+# 按 dataid 记录滑动窗口内的活跃 series 超出上限后丢弃新增的 series 已存在的 series 不受影响
+# 配置支持按 token 分层覆盖 重载时保留已记录的活跃 series
+# 全局配置为 reject 模式时作为 PreCheck 处理器在接收阶段校验 超限直接向客户端返回 429 需在 pipeline 中置于调度类处理器之前
+# pushgateway 数据按 MetricFamily 拆分为多条记录 接收阶段无法整体校验 应使用 drop 模式的实例
+#
+# 自监控指标:
+# - series_guard_active_series: 活跃 series 数
+# - series_guard_rejected_series_total: 被拒绝的 series 总数
+# - series_guard_top_metric_series: 活跃 series 数排名前 top_n 的指标
+# - series_guard_top_rejected_label_values: 上报周期内被拒绝 series 中不同取值数排名前 top_n 的维度
+
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 200000 # 窗口期内允许的最大活跃 series 数 小于等于 0 表示不限制
+      window: 1h         # 滑动窗口 超过窗口期未再上报的 series 不再计入
+      mode: drop         # drop: 仅丢弃超限的新增 series / reject: 出现超限的新增 series 时拒绝整条记录
+      top_n: 10          # 排行上报数量
+*/
+
+package seriesguard
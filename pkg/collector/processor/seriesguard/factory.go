@@ -0,0 +1,249 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package seriesguard
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/confengine"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define/prompb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/labels"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/mapstructure"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+func init() {
+	processor.Register(define.ProcessorSeriesGuard, NewFactory)
+}
+
+func NewFactory(conf map[string]interface{}, customized []processor.SubConfigProcessor) (processor.Processor, error) {
+	return newFactory(conf, customized)
+}
+
+func newFactory(conf map[string]interface{}, customized []processor.SubConfigProcessor) (*seriesGuard, error) {
+	configs := confengine.NewTierConfig()
+
+	var c Config
+	if err := mapstructure.Decode(conf, &c); err != nil {
+		return nil, err
+	}
+	c.Validate()
+	configs.SetGlobal(c)
+
+	for _, custom := range customized {
+		var cfg Config
+		if err := mapstructure.Decode(custom.Config.Config, &cfg); err != nil {
+			logger.Errorf("failed to decode config: %v", err)
+			continue
+		}
+		cfg.Validate()
+		configs.Set(custom.Token, custom.Type, custom.ID, cfg)
+	}
+
+	p := &seriesGuard{
+		CommonProcessor: processor.NewCommonProcessor(conf, customized),
+		configs:         configs,
+		preCheck:        c.Mode == ModeReject,
+		trackers:        map[int32]*tracker{},
+		done:            make(chan struct{}),
+	}
+	go p.loop()
+	return p, nil
+}
+
+type seriesGuard struct {
+	processor.CommonProcessor
+	configs *confengine.TierConfig // type: Config
+
+	// preCheck reject 模式下在接收阶段校验 超限时直接向客户端返回错误
+	preCheck bool
+
+	mut      sync.Mutex
+	trackers map[int32]*tracker
+	done     chan struct{}
+}
+
+func (p *seriesGuard) Name() string {
+	return define.ProcessorSeriesGuard
+}
+
+func (p *seriesGuard) IsDerived() bool {
+	return false
+}
+
+func (p *seriesGuard) IsPreCheck() bool {
+	return p.preCheck
+}
+
+func (p *seriesGuard) Clean() {
+	close(p.done)
+}
+
+// Reload 仅替换配置 已记录的活跃 series 保持不变 避免重载后出现短暂的放量
+func (p *seriesGuard) Reload(config map[string]interface{}, customized []processor.SubConfigProcessor) {
+	f, err := newFactory(config, customized)
+	if err != nil {
+		logger.Errorf("failed to reload processor: %v", err)
+		return
+	}
+	f.Clean()
+
+	p.CommonProcessor = f.CommonProcessor
+	p.configs = f.configs
+	p.preCheck = f.preCheck
+}
+
+func (p *seriesGuard) loop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.expireAndReport(time.Now().Unix())
+		}
+	}
+}
+
+func (p *seriesGuard) expireAndReport(now int64) {
+	p.mut.Lock()
+	trackers := make([]*tracker, 0, len(p.trackers))
+	for _, t := range p.trackers {
+		trackers = append(trackers, t)
+	}
+	p.mut.Unlock()
+
+	for _, t := range trackers {
+		t.Expire(now)
+		t.Report()
+	}
+}
+
+func (p *seriesGuard) getTracker(dataID int32) *tracker {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	t, ok := p.trackers[dataID]
+	if !ok {
+		t = newTracker(dataID)
+		p.trackers[dataID] = t
+	}
+	return t
+}
+
+func (p *seriesGuard) Process(record *define.Record) (*define.Record, error) {
+	config := p.configs.GetByToken(record.Token.Original).(Config)
+	// 接收阶段仅携带 token 的校验记录直接放行
+	if !config.Enabled() || record.Data == nil {
+		return nil, nil
+	}
+
+	dataID := record.Token.GetDataID(record.RecordType)
+	t := p.getTracker(dataID)
+	now := time.Now().Unix()
+
+	var total, rejected int
+	switch record.RecordType {
+	case define.RecordRemoteWrite:
+		total, rejected = p.processRemoteWrite(record, t, config, now)
+	case define.RecordPushGateway:
+		total, rejected = p.processPushGateway(record, t, config, now)
+	default:
+		return nil, nil
+	}
+
+	if rejected == 0 {
+		return nil, nil
+	}
+	if config.Mode == ModeReject {
+		return nil, errors.Errorf("series guard rejected the record, dataid=%d, max series allowed: %d", dataID, config.MaxSeries)
+	}
+	if rejected == total {
+		return nil, define.ErrSkipEmptyRecord
+	}
+	return nil, nil
+}
+
+// seriesHash 计算 series 唯一标识 labels 会被排序
+func seriesHash(lbs []label) uint64 {
+	ls := make(labels.Labels, 0, len(lbs))
+	for _, lb := range lbs {
+		ls = append(ls, labels.Label{Name: lb.Name, Value: lb.Value})
+	}
+	sort.Sort(ls)
+	return ls.Hash()
+}
+
+func (p *seriesGuard) processRemoteWrite(record *define.Record, t *tracker, config Config, now int64) (int, int) {
+	data := record.Data.(*define.RemoteWriteData)
+	total := len(data.Timeseries)
+
+	kept := make([]*prompb.TimeSeries, 0, total)
+	for _, ts := range data.Timeseries {
+		var metric string
+		lbs := make([]label, 0, len(ts.Labels))
+		for _, pair := range ts.Labels {
+			if pair.Name == "__name__" {
+				metric = pair.Value
+			}
+			lbs = append(lbs, label{Name: pair.Name, Value: pair.Value})
+		}
+
+		if t.Admit(seriesHash(lbs), metric, lbs, config, now) {
+			kept = append(kept, ts)
+		}
+	}
+
+	if config.Mode == ModeDrop {
+		data.Timeseries = kept
+	}
+	return total, total - len(kept)
+}
+
+func (p *seriesGuard) processPushGateway(record *define.Record, t *tracker, config Config, now int64) (int, int) {
+	data := record.Data.(*define.PushGatewayData)
+	if data.MetricFamilies == nil {
+		return 0, 0
+	}
+
+	metric := data.MetricFamilies.GetName()
+	total := len(data.MetricFamilies.Metric)
+
+	kept := make([]*dto.Metric, 0, total)
+	for _, m := range data.MetricFamilies.Metric {
+		lbs := make([]label, 0, len(m.Label)+len(data.Labels)+1)
+		lbs = append(lbs, label{Name: "__name__", Value: metric})
+		for k, v := range data.Labels {
+			lbs = append(lbs, label{Name: k, Value: v})
+		}
+		for _, pair := range m.Label {
+			lbs = append(lbs, label{Name: pair.GetName(), Value: pair.GetValue()})
+		}
+
+		if t.Admit(seriesHash(lbs), metric, lbs, config, now) {
+			kept = append(kept, m)
+		}
+	}
+
+	rejected := total - len(kept)
+	if config.Mode == ModeDrop {
+		data.MetricFamilies.Metric = kept
+	}
+	return total, rejected
+}
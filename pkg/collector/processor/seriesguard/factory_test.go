@@ -0,0 +1,223 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package seriesguard
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define/prompb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/mapstructure"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor"
+)
+
+func TestFactory(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 100
+      window: 10m
+`
+	psc := processor.MustLoadConfigs(content)
+	obj, err := NewFactory(psc[0].Config, nil)
+	factory := obj.(*seriesGuard)
+	defer factory.Clean()
+	assert.NoError(t, err)
+	assert.Equal(t, psc[0].Config, factory.MainConfig())
+
+	var c Config
+	err = mapstructure.Decode(psc[0].Config, &c)
+	assert.NoError(t, err)
+	c.Validate()
+	assert.Equal(t, Config{MaxSeries: 100, Window: 10 * time.Minute, Mode: ModeDrop, TopN: defaultTopN}, c)
+	assert.Equal(t, c, factory.configs.GetGlobal().(Config))
+
+	assert.Equal(t, define.ProcessorSeriesGuard, factory.Name())
+	assert.False(t, factory.IsDerived())
+	assert.False(t, factory.IsPreCheck())
+
+	factory.Reload(psc[0].Config, nil)
+	assert.Equal(t, psc[0].Config, factory.MainConfig())
+}
+
+func makeRemoteWriteRecord(metric string, n int) *define.Record {
+	var series []*prompb.TimeSeries
+	for i := 0; i < n; i++ {
+		series = append(series, &prompb.TimeSeries{
+			Labels: []*prompb.LabelPair{
+				{Name: "__name__", Value: metric},
+				{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+			},
+			Samples: []*prompb.Sample{{Value: float64(i)}},
+		})
+	}
+	return &define.Record{
+		RecordType: define.RecordRemoteWrite,
+		Token:      define.Token{Original: "token1", MetricsDataId: 1001},
+		Data:       &define.RemoteWriteData{Timeseries: series},
+	}
+}
+
+func TestRemoteWriteDrop(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 5
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+	defer factory.Clean()
+
+	record := makeRemoteWriteRecord("cpu_usage", 8)
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+	assert.Len(t, record.Data.(*define.RemoteWriteData).Timeseries, 5)
+
+	// 已存在的 series 不受影响
+	record = makeRemoteWriteRecord("cpu_usage", 3)
+	_, err = factory.Process(record)
+	assert.NoError(t, err)
+	assert.Len(t, record.Data.(*define.RemoteWriteData).Timeseries, 3)
+
+	// 全部为新增 series 时整条记录丢弃
+	record = makeRemoteWriteRecord("mem_usage", 2)
+	_, err = factory.Process(record)
+	assert.Equal(t, define.ErrSkipEmptyRecord, err)
+}
+
+func TestRemoteWriteReject(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 5
+      mode: reject
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+	defer factory.Clean()
+
+	record := makeRemoteWriteRecord("cpu_usage", 8)
+	assert.True(t, factory.IsPreCheck())
+
+	_, err := factory.Process(record)
+	assert.Error(t, err)
+	assert.Len(t, record.Data.(*define.RemoteWriteData).Timeseries, 8)
+}
+
+func TestPushGatewayDrop(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 2
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+	defer factory.Clean()
+
+	name := "http_requests_total"
+	makeMetric := func(path string) *dto.Metric {
+		labelName := "path"
+		return &dto.Metric{Label: []*dto.LabelPair{{Name: &labelName, Value: &path}}}
+	}
+
+	record := &define.Record{
+		RecordType: define.RecordPushGateway,
+		Token:      define.Token{Original: "token1", MetricsDataId: 1002},
+		Data: &define.PushGatewayData{
+			MetricFamilies: &dto.MetricFamily{
+				Name:   &name,
+				Metric: []*dto.Metric{makeMetric("/a"), makeMetric("/b"), makeMetric("/c")},
+			},
+			Labels: map[string]string{"job": "test"},
+		},
+	}
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+
+	metrics := record.Data.(*define.PushGatewayData).MetricFamilies.Metric
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, "/a", metrics[0].Label[0].GetValue())
+	assert.Equal(t, "/b", metrics[1].Label[0].GetValue())
+}
+
+func TestDisabled(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+`
+	factory := processor.MustCreateFactory(content, NewFactory)
+	defer factory.Clean()
+
+	record := makeRemoteWriteRecord("cpu_usage", 10)
+	_, err := factory.Process(record)
+	assert.NoError(t, err)
+	assert.Len(t, record.Data.(*define.RemoteWriteData).Timeseries, 10)
+}
+
+func TestReloadKeepSeries(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 5
+`
+	psc := processor.MustLoadConfigs(content)
+	obj, err := NewFactory(psc[0].Config, nil)
+	assert.NoError(t, err)
+	factory := obj.(*seriesGuard)
+	defer factory.Clean()
+
+	_, err = factory.Process(makeRemoteWriteRecord("cpu_usage", 5))
+	assert.NoError(t, err)
+
+	// 调大上限后新增 series 可以写入 且原有 series 仍被计数
+	factory.Reload(map[string]interface{}{"max_series": 8}, nil)
+	record := makeRemoteWriteRecord("cpu_usage", 10)
+	_, err = factory.Process(record)
+	assert.NoError(t, err)
+	assert.Len(t, record.Data.(*define.RemoteWriteData).Timeseries, 8)
+	assert.Equal(t, 8, factory.getTracker(1001).Active())
+}
+
+func TestTrackerExpireAndReport(t *testing.T) {
+	tk := newTracker(1003)
+	conf := Config{MaxSeries: 3, Window: time.Minute, TopN: 1}
+
+	lbs := func(i int) []label {
+		return []label{{Name: "__name__", Value: "m1"}, {Name: "pod", Value: fmt.Sprintf("pod-%d", i)}}
+	}
+	for i := 0; i < 5; i++ {
+		tk.Admit(uint64(i), "m1", lbs(i), conf, 100)
+	}
+	assert.Equal(t, 3, tk.Active())
+
+	tk.Report()
+	assert.Equal(t, float64(3), testutil.ToFloat64(activeSeries.WithLabelValues(define.RecordMetrics.S(), "1003")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(topMetricSeries.WithLabelValues(define.RecordMetrics.S(), "1003", "m1")))
+	// 超限的 2 个 series 中 pod 维度有 2 个不同的值
+	assert.Equal(t, float64(2), testutil.ToFloat64(topRejectedLabelValues.WithLabelValues(define.RecordMetrics.S(), "1003", "pod")))
+
+	// 窗口期内更新过的 series 保留
+	tk.Admit(0, "m1", lbs(0), conf, 150)
+	tk.Expire(200)
+	assert.Equal(t, 1, tk.Active())
+
+	tk.Expire(300)
+	assert.Equal(t, 0, tk.Active())
+	assert.True(t, tk.Admit(10, "m2", lbs(10), conf, 300))
+}
@@ -0,0 +1,253 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package seriesguard
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+)
+
+var (
+	activeSeries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "series_guard_active_series",
+			Help:      "Series guard active series count",
+		},
+		[]string{"record_type", "id"},
+	)
+
+	rejectedSeriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "series_guard_rejected_series_total",
+			Help:      "Series guard rejected series total",
+		},
+		[]string{"record_type", "id"},
+	)
+
+	topMetricSeries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "series_guard_top_metric_series",
+			Help:      "Series guard top metrics ordered by active series count",
+		},
+		[]string{"record_type", "id", "metric"},
+	)
+
+	topRejectedLabelValues = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: define.MonitoringNamespace,
+			Name:      "series_guard_top_rejected_label_values",
+			Help:      "Series guard top labels ordered by distinct values of rejected series",
+		},
+		[]string{"record_type", "id", "label"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		activeSeries,
+		rejectedSeriesTotal,
+		topMetricSeries,
+		topRejectedLabelValues,
+	)
+}
+
+var DefaultMetricMonitor = &metricMonitor{}
+
+type metricMonitor struct{}
+
+func (m *metricMonitor) SetActiveSeries(dataId int32, n int) {
+	activeSeries.WithLabelValues(define.RecordMetrics.S(), strconv.Itoa(int(dataId))).Set(float64(n))
+}
+
+func (m *metricMonitor) IncRejectedSeriesCounter(dataId int32) {
+	rejectedSeriesTotal.WithLabelValues(define.RecordMetrics.S(), strconv.Itoa(int(dataId))).Inc()
+}
+
+func (m *metricMonitor) SetTopMetricSeries(dataId int32, metric string, n int) {
+	topMetricSeries.WithLabelValues(define.RecordMetrics.S(), strconv.Itoa(int(dataId)), metric).Set(float64(n))
+}
+
+func (m *metricMonitor) DelTopMetricSeries(dataId int32, metric string) {
+	topMetricSeries.DeleteLabelValues(define.RecordMetrics.S(), strconv.Itoa(int(dataId)), metric)
+}
+
+func (m *metricMonitor) SetTopRejectedLabelValues(dataId int32, label string, n int) {
+	topRejectedLabelValues.WithLabelValues(define.RecordMetrics.S(), strconv.Itoa(int(dataId)), label).Set(float64(n))
+}
+
+func (m *metricMonitor) DelTopRejectedLabelValues(dataId int32, label string) {
+	topRejectedLabelValues.DeleteLabelValues(define.RecordMetrics.S(), strconv.Itoa(int(dataId)), label)
+}
+
+// maxLabelValues 单个维度记录的超限维度值上限 避免统计本身占用过多内存
+const maxLabelValues = 1000
+
+type seriesEntry struct {
+	metric  string
+	updated int64
+}
+
+// tracker 记录单个 dataid 窗口期内的活跃 series
+type tracker struct {
+	mut    sync.Mutex
+	dataID int32
+	conf   Config // 最近一次写入时使用的配置 随配置重载更新
+
+	series  map[uint64]seriesEntry
+	metrics map[string]int // 指标名 -> 活跃 series 数
+
+	// 上报周期内被拒绝的 series 维度值 用于定位高基数维度
+	rejectedLabels map[string]map[string]struct{}
+
+	// 上一次上报的排行 用于清理过期的指标
+	reportedMetrics []string
+	reportedLabels  []string
+}
+
+func newTracker(dataID int32) *tracker {
+	return &tracker{
+		dataID:         dataID,
+		series:         map[uint64]seriesEntry{},
+		metrics:        map[string]int{},
+		rejectedLabels: map[string]map[string]struct{}{},
+	}
+}
+
+type label struct {
+	Name  string
+	Value string
+}
+
+// Admit 判断 series 是否允许写入 已存在的 series 总是允许
+func (t *tracker) Admit(h uint64, metric string, lbs []label, conf Config, now int64) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	t.conf = conf
+
+	if entry, ok := t.series[h]; ok {
+		entry.updated = now
+		t.series[h] = entry
+		return true
+	}
+
+	if len(t.series) >= conf.MaxSeries {
+		DefaultMetricMonitor.IncRejectedSeriesCounter(t.dataID)
+		for _, lb := range lbs {
+			values, ok := t.rejectedLabels[lb.Name]
+			if !ok {
+				values = map[string]struct{}{}
+				t.rejectedLabels[lb.Name] = values
+			}
+			if len(values) < maxLabelValues {
+				values[lb.Value] = struct{}{}
+			}
+		}
+		return false
+	}
+
+	t.series[h] = seriesEntry{metric: metric, updated: now}
+	t.metrics[metric]++
+	return true
+}
+
+func (t *tracker) Active() int {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return len(t.series)
+}
+
+// Expire 清理窗口期外的 series
+func (t *tracker) Expire(now int64) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	before := now - int64(t.conf.Window.Seconds())
+
+	for h, entry := range t.series {
+		if entry.updated >= before {
+			continue
+		}
+		delete(t.series, h)
+		t.metrics[entry.metric]--
+		if t.metrics[entry.metric] <= 0 {
+			delete(t.metrics, entry.metric)
+		}
+	}
+}
+
+type rankItem struct {
+	name  string
+	count int
+}
+
+func topN(m map[string]int, n int) []rankItem {
+	items := make([]rankItem, 0, len(m))
+	for k, v := range m {
+		items = append(items, rankItem{name: k, count: v})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].count != items[j].count {
+			return items[i].count > items[j].count
+		}
+		return items[i].name < items[j].name
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
+
+// Report 上报活跃数及排行 并重置上报周期内的超限统计
+func (t *tracker) Report() {
+	t.mut.Lock()
+	n := t.conf.TopN
+	active := len(t.series)
+	metrics := topN(t.metrics, n)
+
+	labelValues := make(map[string]int, len(t.rejectedLabels))
+	for k, v := range t.rejectedLabels {
+		labelValues[k] = len(v)
+	}
+	labels := topN(labelValues, n)
+	t.rejectedLabels = map[string]map[string]struct{}{}
+
+	prevMetrics, prevLabels := t.reportedMetrics, t.reportedLabels
+	t.reportedMetrics, t.reportedLabels = nil, nil
+	for _, item := range metrics {
+		t.reportedMetrics = append(t.reportedMetrics, item.name)
+	}
+	for _, item := range labels {
+		t.reportedLabels = append(t.reportedLabels, item.name)
+	}
+	t.mut.Unlock()
+
+	DefaultMetricMonitor.SetActiveSeries(t.dataID, active)
+	for _, name := range prevMetrics {
+		DefaultMetricMonitor.DelTopMetricSeries(t.dataID, name)
+	}
+	for _, item := range metrics {
+		DefaultMetricMonitor.SetTopMetricSeries(t.dataID, item.name, item.count)
+	}
+	for _, name := range prevLabels {
+		DefaultMetricMonitor.DelTopRejectedLabelValues(t.dataID, name)
+	}
+	for _, item := range labels {
+		DefaultMetricMonitor.SetTopRejectedLabelValues(t.dataID, item.name, item.count)
+	}
+}
@@ -136,6 +136,10 @@ type rStats struct {
 	prevSum float64
 	buckets []float64
 	updated int64
+
+	// exemplars 与 buckets 一一对应 记录落入该区间的最近一次观测
+	// 仅在有 exemplar 写入时才分配
+	exemplars []metricsbuilder.Exemplar
 }
 
 type recorder struct {
@@ -236,6 +240,11 @@ func (r *recorder) Total() int {
 
 // Set 更新 labels 缓存
 func (r *recorder) Set(lbs labels.Labels, value float64) bool {
+	return r.SetWithExemplar(lbs, value, nil)
+}
+
+// SetWithExemplar 更新 labels 缓存并记录 exemplar exemplar 可为空
+func (r *recorder) SetWithExemplar(lbs labels.Labels, value float64, exemplar *metricsbuilder.Exemplar) bool {
 	if r.stopped.Load() {
 		return false
 	}
@@ -277,6 +286,19 @@ func (r *recorder) Set(lbs labels.Labels, value float64) bool {
 		}
 	}
 
+	// exemplar 归属于观测值首个落入的 bucket
+	if exemplar != nil {
+		if s.exemplars == nil {
+			s.exemplars = make([]metricsbuilder.Exemplar, len(r.buckets))
+		}
+		for i := 0; i < len(r.buckets); i++ {
+			if r.buckets[i] >= value {
+				s.exemplars[i] = *exemplar
+				break
+			}
+		}
+	}
+
 	s.updated = fasttime.UnixTimestamp()
 	r.statsMap[h] = s
 
@@ -341,8 +363,9 @@ func (r *recorder) Bucket() <-chan *define.Record {
 }
 
 type LeValue struct {
-	Le    string
-	Value float64
+	Le       string
+	Value    float64
+	Exemplar *metricsbuilder.Exemplar
 }
 
 func (r *recorder) calc(kind string, k uint64, stat rStats) (rStats, []metricsbuilder.Metric) {
@@ -390,11 +413,18 @@ func (r *recorder) calc(kind string, k uint64, stat rStats) (rStats, []metricsbu
 			if r.buckets[i] == math.MaxFloat64 {
 				le = "+Inf"
 			}
-			leValues = append(leValues, LeValue{
+			lev := LeValue{
 				Le:    le,
 				Value: stat.buckets[i],
-			})
+			}
+			if len(stat.exemplars) > 0 && !stat.exemplars[i].TraceID.IsEmpty() {
+				exemplar := stat.exemplars[i]
+				lev.Exemplar = &exemplar
+			}
+			leValues = append(leValues, lev)
 		}
+		// exemplar 仅上报一次 避免重复关联同一条 trace
+		stat.exemplars = nil
 	}
 
 	logger.Debugf("%s stats: %+v", kind, stat)
@@ -411,6 +441,7 @@ func (r *recorder) calc(kind string, k uint64, stat rStats) (rStats, []metricsbu
 				Val:        lev.Value,
 				Ts:         pcommon.Timestamp(unixNano),
 				Dimensions: dims,
+				Exemplar:   lev.Exemplar,
 			})
 		}
 	}
@@ -661,6 +692,11 @@ func (a *Accumulator) Exceeded() map[int32]int {
 }
 
 func (a *Accumulator) Accumulate(dataID int32, dims map[string]string, value float64) bool {
+	return a.AccumulateWithExemplar(dataID, dims, value, nil)
+}
+
+// AccumulateWithExemplar 累加数据的同时记录 exemplar 仅 bucket 类型会输出 exemplar
+func (a *Accumulator) AccumulateWithExemplar(dataID int32, dims map[string]string, value float64, exemplar *metricsbuilder.Exemplar) bool {
 	if a.stopped.Load() {
 		return false
 	}
@@ -674,7 +710,7 @@ func (a *Accumulator) Accumulate(dataID int32, dims map[string]string, value flo
 	}
 	a.mut.RUnlock()
 	if r != nil {
-		return r.Set(labels.FromMap(dims), value)
+		return r.SetWithExemplar(labels.FromMap(dims), value, exemplar)
 	}
 
 	// 写锁保护
@@ -696,5 +732,5 @@ func (a *Accumulator) Accumulate(dataID int32, dims map[string]string, value flo
 		a.recorders[dataID] = r
 	}
 	a.mut.Unlock()
-	return r.Set(labels.FromMap(dims), value)
+	return r.SetWithExemplar(labels.FromMap(dims), value, exemplar)
 }
@@ -19,11 +19,13 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/labels"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/labelstore"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/metricsbuilder"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/prettyprint"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/random"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/testkits"
@@ -72,6 +74,52 @@ func TestCalcStats(t *testing.T) {
 	r.Set(lbs1, 10)
 }
 
+func TestBucketExemplars(t *testing.T) {
+	r := newRecorder(recorderOptions{
+		metricName: "test_metric",
+		maxSeries:  100,
+		dataID:     1002,
+		buckets:    []float64{1, 5},
+		gcInterval: time.Minute,
+	}, labelstore.GetOrCreateStorage(strconv.Itoa(1002)))
+	defer r.Stop()
+
+	exemplar := metricsbuilder.Exemplar{
+		TraceID: pcommon.NewTraceID([16]byte{1}),
+		SpanID:  pcommon.NewSpanID([8]byte{2}),
+		Ts:      pcommon.NewTimestampFromTime(time.Now()),
+		Val:     2e9,
+	}
+	lbs := labels.Labels{{Name: "label1", Value: "value1"}}
+	r.Set(lbs, 0.5e9)
+	r.SetWithExemplar(lbs, 2e9, &exemplar)
+
+	collect := func() map[string]pmetric.ExemplarSlice {
+		ret := make(map[string]pmetric.ExemplarSlice)
+		for record := range r.Bucket() {
+			dps := record.Data.(pmetric.Metrics).ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints()
+			for i := 0; i < dps.Len(); i++ {
+				le, _ := dps.At(i).Attributes().Get("le")
+				ret[le.AsString()] = dps.At(i).Exemplars()
+			}
+		}
+		return ret
+	}
+
+	exemplars := collect()
+	assert.Len(t, exemplars, 3)
+	assert.Equal(t, 0, exemplars["1000000000"].Len())
+	assert.Equal(t, 0, exemplars["+Inf"].Len())
+	assert.Equal(t, 1, exemplars["5000000000"].Len())
+	assert.Equal(t, exemplar.TraceID, exemplars["5000000000"].At(0).TraceID())
+	assert.Equal(t, exemplar.SpanID, exemplars["5000000000"].At(0).SpanID())
+	assert.Equal(t, 2e9, exemplars["5000000000"].At(0).DoubleVal())
+
+	// exemplar 仅上报一次
+	exemplars = collect()
+	assert.Equal(t, 0, exemplars["5000000000"].Len())
+}
+
 func TestAccumulatorExceeded(t *testing.T) {
 	accumulator := New(&Config{
 		MetricName:      "bk_apm_count",
@@ -31,6 +31,8 @@ type OperationConfig struct {
 	GcInterval      string       `config:"gc_interval" mapstructure:"gc_interval"`
 	Buckets         []float64    `config:"buckets" mapstructure:"buckets"`
 	PublishInterval string       `config:"publish_interval" mapstructure:"publish_interval"`
+	Window          string       `config:"window" mapstructure:"window"`
+	MaxPending      int          `config:"max_pending" mapstructure:"max_pending"`
 }
 
 type RuleConfig struct {
@@ -52,8 +54,9 @@ type ConfigHandler struct {
 	attributeKeys *mapstrings.MapStrings  // key:[type+kind+predicateKey]
 	methodKeys    *mapstrings.MapStrings  // key:[type+kind+predicateKey]
 
-	accumulatorConfig *accumulator.Config
-	extractorConfig   *ExtractorConfig
+	accumulatorConfig  *accumulator.Config
+	extractorConfig    *ExtractorConfig
+	serviceGraphConfig *ServiceGraphConfig
 }
 
 // NewConfigHandler 创建并返回 ConfigHandler 实例 用于管理配置和提取内容
@@ -69,6 +72,7 @@ func NewConfigHandler(config Config) *ConfigHandler {
 	var types []TypeWithName
 	var accumulatorConfig *accumulator.Config
 	var extractorConfig *ExtractorConfig
+	var serviceGraphConfig *ServiceGraphConfig
 	for i := 0; i < len(config.Operations); i++ {
 		conf := config.Operations[i]
 		// accumulator 类型单独处理
@@ -101,6 +105,23 @@ func NewConfigHandler(config Config) *ConfigHandler {
 			}
 			extractorConfig.Validate()
 
+		// service_graph 不依赖 rules 匹配 无需加入 types
+		case ServiceGraphType:
+			gcInterval, _ := time.ParseDuration(conf.GcInterval)
+			publishInterval, _ := time.ParseDuration(conf.PublishInterval)
+			window, _ := time.ParseDuration(conf.Window)
+			serviceGraphConfig = &ServiceGraphConfig{
+				MetricName:      conf.MetricName,
+				MaxSeries:       conf.MaxSeries,
+				GcInterval:      gcInterval,
+				PublishInterval: publishInterval,
+				Buckets:         conf.Buckets,
+				Window:          window,
+				MaxPending:      conf.MaxPending,
+			}
+			serviceGraphConfig.Validate()
+			continue
+
 		default:
 			logger.Errorf("invalid extractor type: %s", conf.Type)
 			continue
@@ -133,14 +154,15 @@ func NewConfigHandler(config Config) *ConfigHandler {
 	}
 
 	return &ConfigHandler{
-		types:             types,
-		predicateKeys:     predicateKeys,
-		resourceKeys:      resourceKeys,
-		attributeKeys:     attributeKeys,
-		methodKeys:        methodKeys,
-		kinds:             kinds,
-		accumulatorConfig: accumulatorConfig,
-		extractorConfig:   extractorConfig,
+		types:              types,
+		predicateKeys:      predicateKeys,
+		resourceKeys:       resourceKeys,
+		attributeKeys:      attributeKeys,
+		methodKeys:         methodKeys,
+		kinds:              kinds,
+		accumulatorConfig:  accumulatorConfig,
+		extractorConfig:    extractorConfig,
+		serviceGraphConfig: serviceGraphConfig,
 	}
 }
 
@@ -166,6 +188,10 @@ func (ch *ConfigHandler) GetExtractorConfig() *ExtractorConfig {
 	return ch.extractorConfig
 }
 
+func (ch *ConfigHandler) GetServiceGraphConfig() *ServiceGraphConfig {
+	return ch.serviceGraphConfig
+}
+
 func (ch *ConfigHandler) GetTypes() []TypeWithName {
 	return ch.types
 }
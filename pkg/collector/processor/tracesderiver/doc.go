@@ -35,6 +35,8 @@ processor:
                   - "kind"
                   - "status.code"
 
+    # bucket 类型会为每个区间附带最近一次落入的 span 作为 exemplar（trace_id/span_id/timestamp/value）
+    # 每个 publish 周期仅上报一次 可通过 exemplar 查询从耗时指标跳转至具体的 trace
     - name: "traces_deriver/bucket"
       config:
         operations:
@@ -59,6 +61,21 @@ processor:
                   - "span_name"
                   - "kind"
                   - "status.code"
+
+    # service_graph 在 window 时间窗口内按 traceID+parentSpanID 配对 CLIENT/SERVER 以及 PRODUCER/CONSUMER spans
+    # 按 from_service/to_service/kind 维度输出 {metric_name}_request_total、{metric_name}_request_failed_total
+    # 以及 {metric_name}_request_duration_bucket 指标
+    - name: "traces_deriver/service_graph"
+      config:
+        operations:
+          - type: "service_graph"
+            metric_name: "bk_apm_service_graph"
+            publish_interval: "10s"
+            gc_interval: "1h"
+            window: "10s" # 配对等待窗口
+            max_pending: 10000 # 等待配对的 span 上限
+            buckets: [0.01, 0.05, 0.1, 0.5, 1, 2, 5]
+            max_series: 1000
 */
 
 package tracesderiver
@@ -11,6 +11,7 @@ package tracesderiver
 
 import (
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.8.0"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/metricsbuilder"
@@ -40,14 +41,19 @@ func NewTracesOperator(conf Config) Operator {
 	if extractorConfig != nil {
 		to.extractor = NewExtractor(extractorConfig)
 	}
+	serviceGraphConfig := ch.GetServiceGraphConfig()
+	if serviceGraphConfig != nil {
+		to.serviceGraph = NewServiceGraph(serviceGraphConfig, processor.PublishNonSchedRecords)
+	}
 
 	return to
 }
 
 type tracesOperator struct {
-	dm          DimensionMatcher
-	accumulator *accumulator.Accumulator
-	extractor   *Extractor
+	dm           DimensionMatcher
+	accumulator  *accumulator.Accumulator
+	extractor    *Extractor
+	serviceGraph *ServiceGraph
 }
 
 func (to tracesOperator) Clean() {
@@ -57,6 +63,9 @@ func (to tracesOperator) Clean() {
 	if to.extractor != nil {
 		to.extractor.Stop()
 	}
+	if to.serviceGraph != nil {
+		to.serviceGraph.Stop()
+	}
 }
 
 func (to tracesOperator) Operate(record *define.Record) *define.Record {
@@ -69,9 +78,18 @@ func (to tracesOperator) Operate(record *define.Record) *define.Record {
 	for i := 0; i < resourceSpansSlice.Len(); i++ {
 		scopeSpansSlice := resourceSpansSlice.At(i).ScopeSpans()
 		resources := to.dm.MatchResource(resourceSpansSlice.At(i))
+		var service string
+		if v, ok := resourceSpansSlice.At(i).Resource().Attributes().Get(semconv.AttributeServiceName); ok {
+			service = v.AsString()
+		}
 		for j := 0; j < scopeSpansSlice.Len(); j++ {
 			spans := scopeSpansSlice.At(j).Spans()
 			for k := 0; k < spans.Len(); k++ {
+				// service graph 处理
+				if to.serviceGraph != nil {
+					to.serviceGraph.Observe(record.Token.MetricsDataId, service, spans.At(k))
+				}
+
 				for _, t := range types {
 					// 如果该 type 没有匹配到任何指标 直接跳过
 					dim, ok := to.dm.Match(t.Type, spans.At(k))
@@ -101,8 +119,15 @@ func (to tracesOperator) Operate(record *define.Record) *define.Record {
 
 					// accumulator 处理
 					if to.accumulator != nil {
-						val := utils.CalcSpanDuration(spans.At(k))
-						to.accumulator.Accumulate(record.Token.MetricsDataId, dim, val)
+						span := spans.At(k)
+						val := utils.CalcSpanDuration(span)
+						// 携带 trace 上下文 便于从指标跳转至 trace
+						to.accumulator.AccumulateWithExemplar(record.Token.MetricsDataId, dim, val, &metricsbuilder.Exemplar{
+							TraceID: span.TraceID(),
+							SpanID:  span.SpanID(),
+							Ts:      span.EndTimestamp(),
+							Val:     val,
+						})
 					}
 				}
 			}
@@ -0,0 +1,232 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tracesderiver
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/tracesderiver/accumulator"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	ServiceGraphType = "service_graph"
+
+	serviceGraphKindRpc       = "rpc"
+	serviceGraphKindMessaging = "messaging"
+
+	defaultServiceGraphMetricName = "bk_apm_service_graph"
+)
+
+type ServiceGraphConfig struct {
+	MetricName      string
+	MaxSeries       int
+	GcInterval      time.Duration
+	PublishInterval time.Duration
+	Buckets         []float64
+	Window          time.Duration
+	MaxPending      int
+}
+
+func (sc *ServiceGraphConfig) Validate() {
+	if sc.MetricName == "" {
+		sc.MetricName = defaultServiceGraphMetricName
+	}
+	if sc.Window <= 0 {
+		sc.Window = 10 * time.Second
+	}
+	if sc.MaxPending <= 0 {
+		sc.MaxPending = 10000 // 10k
+	}
+}
+
+func (sc *ServiceGraphConfig) accumulatorConfig(name, typ string) *accumulator.Config {
+	conf := &accumulator.Config{
+		MetricName:      sc.MetricName + name,
+		MaxSeries:       sc.MaxSeries,
+		GcInterval:      sc.GcInterval,
+		PublishInterval: sc.PublishInterval,
+		Buckets:         sc.Buckets,
+		Type:            typ,
+	}
+	conf.Validate()
+	return conf
+}
+
+type edgeKey struct {
+	dataID  int32
+	traceID pcommon.TraceID
+	spanID  pcommon.SpanID
+}
+
+// pendingEdge 等待配对的调用边
+// client/producer 侧以自身 spanID 作为 key server/consumer 侧以 parentSpanID 作为 key
+type pendingEdge struct {
+	kind        string
+	fromService string
+	toService   string
+	duration    float64
+	hasClient   bool
+	hasServer   bool
+	failed      bool
+	ts          time.Time
+}
+
+// ServiceGraph 在决策窗口内配对 CLIENT/SERVER 以及 PRODUCER/CONSUMER spans
+// 配对成功后按 caller->callee 边累加请求数、错误数以及耗时分布
+type ServiceGraph struct {
+	mut     sync.Mutex
+	conf    *ServiceGraphConfig
+	pending map[edgeKey]*pendingEdge
+	done    chan struct{}
+
+	requests *accumulator.Accumulator
+	failed   *accumulator.Accumulator
+	duration *accumulator.Accumulator
+
+	// emit 配对完成后的回调 默认写入 accumulator
+	emit func(dataID int32, dims map[string]string, failed bool, duration float64)
+}
+
+func NewServiceGraph(conf *ServiceGraphConfig, publishFunc func(r *define.Record)) *ServiceGraph {
+	sg := &ServiceGraph{
+		conf:     conf,
+		pending:  make(map[edgeKey]*pendingEdge),
+		done:     make(chan struct{}),
+		requests: accumulator.New(conf.accumulatorConfig("_request_total", accumulator.TypeCount), publishFunc),
+		failed:   accumulator.New(conf.accumulatorConfig("_request_failed_total", accumulator.TypeCount), publishFunc),
+		duration: accumulator.New(conf.accumulatorConfig("_request_duration_bucket", accumulator.TypeBucket), publishFunc),
+	}
+	sg.emit = sg.accumulate
+
+	go sg.gc()
+	return sg
+}
+
+func (sg *ServiceGraph) accumulate(dataID int32, dims map[string]string, failed bool, duration float64) {
+	sg.requests.Accumulate(dataID, dims, 1)
+	sg.duration.Accumulate(dataID, dims, duration)
+	if failed {
+		sg.failed.Accumulate(dataID, dims, 1)
+	}
+}
+
+// Observe 处理单个 span 仅关心 CLIENT/SERVER/PRODUCER/CONSUMER 类型
+func (sg *ServiceGraph) Observe(dataID int32, service string, span ptrace.Span) {
+	var isClient bool
+	var kind string
+	var spanID pcommon.SpanID
+
+	switch span.Kind() {
+	case ptrace.SpanKindClient:
+		isClient, kind, spanID = true, serviceGraphKindRpc, span.SpanID()
+	case ptrace.SpanKindProducer:
+		isClient, kind, spanID = true, serviceGraphKindMessaging, span.SpanID()
+	case ptrace.SpanKindServer:
+		kind, spanID = serviceGraphKindRpc, span.ParentSpanID()
+	case ptrace.SpanKindConsumer:
+		kind, spanID = serviceGraphKindMessaging, span.ParentSpanID()
+	default:
+		return
+	}
+	if spanID.IsEmpty() {
+		return
+	}
+
+	key := edgeKey{dataID: dataID, traceID: span.TraceID(), spanID: spanID}
+	failed := span.Status().Code() == ptrace.StatusCodeError
+
+	sg.mut.Lock()
+	edge, ok := sg.pending[key]
+	if !ok {
+		if len(sg.pending) >= sg.conf.MaxPending {
+			sg.mut.Unlock()
+			logger.Debugf("service graph drop span, exceeded max pending, dataID=%v", dataID)
+			return
+		}
+		edge = &pendingEdge{kind: kind, ts: time.Now()}
+		sg.pending[key] = edge
+	}
+
+	// 调用耗时以 caller 侧观测到的为准
+	if isClient {
+		edge.hasClient = true
+		edge.fromService = service
+		edge.duration = utils.CalcSpanDuration(span)
+	} else {
+		edge.hasServer = true
+		edge.toService = service
+	}
+	edge.failed = edge.failed || failed
+
+	if !edge.hasClient || !edge.hasServer {
+		sg.mut.Unlock()
+		return
+	}
+	delete(sg.pending, key)
+	sg.mut.Unlock()
+
+	dims := map[string]string{
+		"from_service": edge.fromService,
+		"to_service":   edge.toService,
+		"kind":         edge.kind,
+	}
+	sg.emit(dataID, dims, edge.failed, edge.duration)
+}
+
+func (sg *ServiceGraph) Pending() int {
+	sg.mut.Lock()
+	defer sg.mut.Unlock()
+	return len(sg.pending)
+}
+
+// expire 清理超出窗口仍未配对的 span
+func (sg *ServiceGraph) expire(now time.Time) {
+	sg.mut.Lock()
+	defer sg.mut.Unlock()
+
+	var n int
+	for key, edge := range sg.pending {
+		if now.Sub(edge.ts) > sg.conf.Window {
+			delete(sg.pending, key)
+			n++
+		}
+	}
+	if n > 0 {
+		logger.Debugf("service graph drop %d expired edges", n)
+	}
+}
+
+func (sg *ServiceGraph) gc() {
+	ticker := time.NewTicker(sg.conf.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sg.done:
+			return
+		case now := <-ticker.C:
+			sg.expire(now)
+		}
+	}
+}
+
+func (sg *ServiceGraph) Stop() {
+	close(sg.done)
+	sg.requests.Stop()
+	sg.failed.Stop()
+	sg.duration.Stop()
+}
@@ -0,0 +1,138 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package tracesderiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/random"
+)
+
+type graphEdge struct {
+	dims     map[string]string
+	failed   bool
+	duration float64
+}
+
+func newTestServiceGraph(conf *ServiceGraphConfig) (*ServiceGraph, *[]graphEdge) {
+	conf.Validate()
+	sg := NewServiceGraph(conf, nil)
+
+	var edges []graphEdge
+	sg.emit = func(dataID int32, dims map[string]string, failed bool, duration float64) {
+		edges = append(edges, graphEdge{dims: dims, failed: failed, duration: duration})
+	}
+	return sg, &edges
+}
+
+func appendGraphSpan(traces ptrace.Traces, service string, kind ptrace.SpanKind, traceID pcommon.TraceID, spanID, parentID pcommon.SpanID) ptrace.Span {
+	rs := traces.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().UpsertString("service.name", service)
+	span := rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetKind(kind)
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	span.SetParentSpanID(parentID)
+	return span
+}
+
+func TestServiceGraphPairing(t *testing.T) {
+	sg, edges := newTestServiceGraph(&ServiceGraphConfig{})
+	defer sg.Stop()
+
+	to := tracesOperator{dm: NewSpanDimensionMatcher(NewConfigHandler(Config{})), serviceGraph: sg}
+
+	traceID := random.TraceID()
+	clientID := random.SpanID()
+	producerID := random.SpanID()
+	now := time.Now()
+
+	traces := ptrace.NewTraces()
+	client := appendGraphSpan(traces, "frontend", ptrace.SpanKindClient, traceID, clientID, random.SpanID())
+	client.SetStartTimestamp(pcommon.NewTimestampFromTime(now))
+	client.SetEndTimestamp(pcommon.NewTimestampFromTime(now.Add(time.Second)))
+	appendGraphSpan(traces, "frontend", ptrace.SpanKindProducer, traceID, producerID, random.SpanID())
+	to.Operate(&define.Record{RecordType: define.RecordTraces, Data: traces})
+	assert.Len(t, *edges, 0)
+	assert.Equal(t, 2, sg.Pending())
+
+	// server/consumer 在后续的请求中到达
+	traces = ptrace.NewTraces()
+	server := appendGraphSpan(traces, "backend", ptrace.SpanKindServer, traceID, random.SpanID(), clientID)
+	server.Status().SetCode(ptrace.StatusCodeError)
+	appendGraphSpan(traces, "worker", ptrace.SpanKindConsumer, traceID, random.SpanID(), producerID)
+	appendGraphSpan(traces, "worker", ptrace.SpanKindInternal, traceID, random.SpanID(), producerID)
+	to.Operate(&define.Record{RecordType: define.RecordTraces, Data: traces})
+
+	assert.Equal(t, 0, sg.Pending())
+	assert.Equal(t, []graphEdge{
+		{
+			dims:     map[string]string{"from_service": "frontend", "to_service": "backend", "kind": "rpc"},
+			failed:   true,
+			duration: float64(time.Second),
+		},
+		{
+			dims: map[string]string{"from_service": "frontend", "to_service": "worker", "kind": "messaging"},
+		},
+	}, *edges)
+}
+
+func TestServiceGraphExpired(t *testing.T) {
+	sg, edges := newTestServiceGraph(&ServiceGraphConfig{Window: time.Minute, MaxPending: 1})
+	defer sg.Stop()
+
+	traceID := random.TraceID()
+	clientID := random.SpanID()
+
+	traces := ptrace.NewTraces()
+	client := appendGraphSpan(traces, "frontend", ptrace.SpanKindClient, traceID, clientID, random.SpanID())
+	sg.Observe(1001, "frontend", client)
+
+	// 超过 max_pending 直接丢弃
+	other := appendGraphSpan(traces, "frontend", ptrace.SpanKindClient, traceID, random.SpanID(), random.SpanID())
+	sg.Observe(1001, "frontend", other)
+	assert.Equal(t, 1, sg.Pending())
+
+	// 超出窗口未配对的 span 被清理
+	sg.expire(time.Now().Add(time.Minute * 2))
+	assert.Equal(t, 0, sg.Pending())
+
+	server := appendGraphSpan(traces, "backend", ptrace.SpanKindServer, traceID, random.SpanID(), clientID)
+	sg.Observe(1001, "backend", server)
+	assert.Len(t, *edges, 0)
+	assert.Equal(t, 1, sg.Pending())
+}
+
+func TestServiceGraphConfig(t *testing.T) {
+	ch := NewConfigHandler(Config{
+		Operations: []OperationConfig{{
+			Type:       ServiceGraphType,
+			MetricName: "bk_apm_graph",
+			Window:     "5s",
+		}},
+	})
+	assert.Len(t, ch.GetTypes(), 0)
+	assert.Nil(t, ch.GetAccumulatorConfig())
+
+	conf := ch.GetServiceGraphConfig()
+	assert.Equal(t, "bk_apm_graph", conf.MetricName)
+	assert.Equal(t, 5*time.Second, conf.Window)
+	assert.Equal(t, 10000, conf.MaxPending)
+
+	operator := NewTracesOperator(Config{Operations: []OperationConfig{{Type: ServiceGraphType}}})
+	defer operator.Clean()
+	assert.NotNil(t, operator.(tracesOperator).serviceGraph)
+}
@@ -10,6 +10,8 @@
 package receiver
 
 import (
+	"time"
+
 	"github.com/elastic/beats/libbeat/common/transport/tlscommon"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/confengine"
@@ -24,10 +26,31 @@ type ComponentConfig struct {
 	RemoteWrite ComponentRemoteWrite `config:"remotewrite"`
 	Zipkin      ComponentZipkin      `config:"zipkin"`
 	Skywalking  ComponentSkywalking  `config:"skywalking"`
+	PromScrape  ComponentPromScrape  `config:"promscrape"`
 }
 
 type ComponentJaeger struct {
 	Enabled bool `config:"enabled"`
+
+	// agent 协议 UDP 监听地址 为空则不启用
+	CompactEndpoint string `config:"compact_endpoint"` // thrift compact 默认端口 6831
+	BinaryEndpoint  string `config:"binary_endpoint"`  // thrift binary 默认端口 6832
+
+	// kafka 消费配置 消息格式与 jaeger-collector 写入 kafka 的格式一致 encoding 支持 protobuf/json
+	Kafka KafkaConfig `config:"kafka"`
+}
+
+// KafkaConfig kafka 消费配置 brokers 与 topic 均不为空时启用
+type KafkaConfig struct {
+	Brokers  []string `config:"brokers"`
+	Topic    string   `config:"topic"`
+	GroupID  string   `config:"group_id"`
+	Version  string   `config:"version"`
+	Encoding string   `config:"encoding"`
+}
+
+func (c KafkaConfig) Enabled() bool {
+	return len(c.Brokers) > 0 && c.Topic != ""
 }
 
 type ComponentOtlp struct {
@@ -44,12 +67,31 @@ type ComponentRemoteWrite struct {
 
 type ComponentZipkin struct {
 	Enabled bool `config:"enabled"`
+
+	// kafka 消费配置 消息格式与 zipkin kafka sender 一致 encoding 支持 json/proto/thrift 默认 json
+	Kafka KafkaConfig `config:"kafka"`
 }
 
 type ComponentSkywalking struct {
 	Enabled bool `config:"enabled"`
 }
 
+// ComponentPromScrape 主动拉取 prometheus 指标配置
+type ComponentPromScrape struct {
+	Enabled     bool               `config:"enabled"`
+	Token       string             `config:"token"` // 默认 token 拉取的数据均携带该 token
+	Interval    time.Duration      `config:"interval"`
+	Timeout     time.Duration      `config:"timeout"`
+	MaxBodySize int64              `config:"max_body_size"` // 单次拉取的响应体大小上限 超出则本次拉取失败
+	Targets     []PromScrapeTarget `config:"targets"`
+}
+
+type PromScrapeTarget struct {
+	URL    string            `config:"url"`
+	Token  string            `config:"token"` // 为空则使用默认 token
+	Labels map[string]string `config:"labels"`
+}
+
 type Config struct {
 	HttpServer HttpServerConfig `config:"http_server"`
 	GrpcServer GrpcServerConfig `config:"grpc_server"`
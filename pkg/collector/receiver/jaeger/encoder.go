@@ -13,8 +13,10 @@ import (
 	"context"
 
 	apachethrift "github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger/thrift-gen/agent"
 	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
 	jaegertranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/jaeger"
+	"github.com/pkg/errors"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 )
 
@@ -38,3 +40,53 @@ func (e thriftEncoder) UnmarshalTraces(buf []byte) (ptrace.Traces, error) {
 	}
 	return jaegertranslator.ThriftToTraces(batch)
 }
+
+const agentEmitBatch = "emitBatch"
+
+func newAgentCompactEncoder() agentEncoder {
+	return agentEncoder{typ: "agent.compact", factory: apachethrift.NewTCompactProtocolFactoryConf(nil)}
+}
+
+func newAgentBinaryEncoder() agentEncoder {
+	return agentEncoder{typ: "agent.binary", factory: apachethrift.NewTBinaryProtocolFactoryConf(nil)}
+}
+
+// agentEncoder Agent 协议编码器实现
+// 每个 UDP 包均为一次 Agent.emitBatch 的 oneway 调用
+type agentEncoder struct {
+	typ     string
+	factory apachethrift.TProtocolFactory
+}
+
+func (e agentEncoder) Type() string {
+	return e.typ
+}
+
+func (e agentEncoder) UnmarshalTraces(buf []byte) (ptrace.Traces, error) {
+	ctx := context.Background()
+	transport := apachethrift.NewTMemoryBufferLen(len(buf))
+	if _, err := transport.Write(buf); err != nil {
+		return ptrace.NewTraces(), err
+	}
+
+	protocol := e.factory.GetProtocol(transport)
+	name, _, _, err := protocol.ReadMessageBegin(ctx)
+	if err != nil {
+		return ptrace.NewTraces(), err
+	}
+	if name != agentEmitBatch {
+		return ptrace.NewTraces(), errors.Errorf("unsupported agent method: %s", name)
+	}
+
+	args := agent.NewAgentEmitBatchArgs()
+	if err = args.Read(ctx, protocol); err != nil {
+		return ptrace.NewTraces(), err
+	}
+	if err = protocol.ReadMessageEnd(ctx); err != nil {
+		return ptrace.NewTraces(), err
+	}
+	if args.Batch == nil {
+		return ptrace.NewTraces(), errors.New("empty agent batch")
+	}
+	return jaegertranslator.ThriftToTraces(args.Batch)
+}
@@ -35,6 +35,7 @@ const (
 
 func init() {
 	receiver.RegisterReadyFunc(define.SourceJaeger, Ready)
+	receiver.RegisterStopFunc(define.SourceJaeger, Stop)
 }
 
 func Ready() {
@@ -49,6 +50,28 @@ func Ready() {
 	receiver.RegisterGrpcRoute(func(s *grpc.Server) {
 		api_v2.RegisterCollectorServiceServer(s, GrpcService{})
 	})
+
+	config := receiver.GetComponentConfig().Jaeger
+	if config.CompactEndpoint != "" {
+		if err := udpSvc.Listen(config.CompactEndpoint, newAgentCompactEncoder()); err != nil {
+			logger.Errorf("failed to listen jaeger compact endpoint: %v", err)
+		}
+	}
+	if config.BinaryEndpoint != "" {
+		if err := udpSvc.Listen(config.BinaryEndpoint, newAgentBinaryEncoder()); err != nil {
+			logger.Errorf("failed to listen jaeger binary endpoint: %v", err)
+		}
+	}
+	if config.Kafka.Enabled() {
+		if err := kafkaSvc.Start(config.Kafka); err != nil {
+			logger.Errorf("failed to start jaeger kafka consumer: %v", err)
+		}
+	}
+}
+
+func Stop() {
+	udpSvc.Stop()
+	kafkaSvc.Stop()
 }
 
 type HttpService struct {
@@ -0,0 +1,144 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package jaeger
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/jaegertracing/jaeger/model"
+	jaegertranslator "github.com/open-telemetry/opentelemetry-collector-contrib/pkg/translator/jaeger"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/prettyprint"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	kafkaGroupID         = "bk-collector-jaeger"
+	kafkaEncodingProto   = "protobuf"
+	kafkaEncodingJson    = "json"
+	defaultKafkaEncoding = kafkaEncodingProto
+)
+
+// kafkaSpanEncoder jaeger-collector 写入 kafka 的每条消息均为单个 model.Span
+type kafkaSpanEncoder struct {
+	typ string
+}
+
+func newKafkaSpanEncoder(encoding string) (kafkaSpanEncoder, error) {
+	if encoding == "" {
+		encoding = defaultKafkaEncoding
+	}
+	switch encoding {
+	case kafkaEncodingProto, kafkaEncodingJson:
+		return kafkaSpanEncoder{typ: encoding}, nil
+	}
+	return kafkaSpanEncoder{}, errors.Errorf("unsupported jaeger kafka encoding %s", encoding)
+}
+
+func (e kafkaSpanEncoder) Type() string {
+	return "kafka." + e.typ
+}
+
+func (e kafkaSpanEncoder) UnmarshalTraces(buf []byte) (ptrace.Traces, error) {
+	span := &model.Span{}
+	var err error
+	if e.typ == kafkaEncodingJson {
+		err = jsonpb.Unmarshal(bytes.NewReader(buf), span)
+	} else {
+		err = span.Unmarshal(buf)
+	}
+	if err != nil {
+		return ptrace.NewTraces(), err
+	}
+	return jaegertranslator.ProtoToTraces([]*model.Batch{{Process: span.Process, Spans: []*model.Span{span}}})
+}
+
+// KafkaService 消费 jaeger-collector 写入 kafka 的 span 数据
+// token 与其他协议一样从 process tags（即 resource attributes）中提取
+type KafkaService struct {
+	receiver.Publisher
+	pipeline.Validator
+
+	mut      sync.Mutex
+	consumer *receiver.KafkaConsumer
+}
+
+var kafkaSvc = &KafkaService{}
+
+func (s *KafkaService) Start(conf receiver.KafkaConfig) error {
+	encoder, err := newKafkaSpanEncoder(conf.Encoding)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := receiver.NewKafkaConsumer(conf, kafkaGroupID, func(msg *sarama.ConsumerMessage) {
+		if err := s.handle(msg.Value, encoder); err != nil {
+			logger.WarnRate(time.Minute, msg.Topic, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	s.consumer = consumer
+	s.mut.Unlock()
+
+	consumer.Start()
+	return nil
+}
+
+func (s *KafkaService) handle(buf []byte, encoder kafkaSpanEncoder) error {
+	defer utils.HandleCrash()
+
+	start := time.Now()
+	traces, err := encoder.UnmarshalTraces(buf)
+	if err != nil {
+		metricMonitor.IncDroppedCounter(define.RequestKafka, define.RecordTraces)
+		return errors.Wrapf(err, "failed to parse jaeger kafka message, encoder=%s", encoder.Type())
+	}
+
+	r := &define.Record{
+		RequestType: define.RequestKafka,
+		RecordType:  define.RecordTraces,
+		Data:        traces,
+	}
+	prettyprint.Traces(traces)
+
+	code, processorName, err := s.Validate(r)
+	if err != nil {
+		metricMonitor.IncPreCheckFailedCounter(define.RequestKafka, define.RecordTraces, processorName, r.Token.Original, code)
+		return errors.Wrapf(err, "run pre-check failed, rtype=traces, code=%d", code)
+	}
+
+	s.Publish(r)
+	receiver.RecordHandleMetrics(metricMonitor, r.Token, define.RequestKafka, define.RecordTraces, len(buf), start)
+	return nil
+}
+
+func (s *KafkaService) Stop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.consumer != nil {
+		s.consumer.Stop()
+		s.consumer = nil
+	}
+}
@@ -0,0 +1,105 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package jaeger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/jaegertracing/jaeger/model"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/testkits"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+)
+
+func makeKafkaSpan() *model.Span {
+	return &model.Span{
+		TraceID:       model.NewTraceID(0, 1),
+		SpanID:        model.NewSpanID(2),
+		OperationName: "GET /index",
+		StartTime:     time.Now(),
+		Duration:      time.Millisecond,
+		Process: &model.Process{
+			ServiceName: "legacy",
+			Tags:        []model.KeyValue{model.String("bk.data.token", "token1")},
+		},
+	}
+}
+
+func TestKafkaSpanEncoder(t *testing.T) {
+	span := makeKafkaSpan()
+	pb, err := span.Marshal()
+	assert.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, (&jsonpb.Marshaler{}).Marshal(buf, span))
+
+	cases := map[string][]byte{
+		"":                 pb,
+		kafkaEncodingProto: pb,
+		kafkaEncodingJson:  buf.Bytes(),
+	}
+	for encoding, msg := range cases {
+		encoder, err := newKafkaSpanEncoder(encoding)
+		assert.NoError(t, err)
+
+		traces, err := encoder.UnmarshalTraces(msg)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, traces.SpanCount())
+		attrs := traces.ResourceSpans().At(0).Resource().Attributes()
+		testkits.AssertAttrsFoundStringVal(t, attrs, "bk.data.token", "token1")
+
+		_, err = encoder.UnmarshalTraces([]byte("{-}"))
+		assert.Error(t, err)
+	}
+
+	_, err = newKafkaSpanEncoder("thrift")
+	assert.Error(t, err)
+}
+
+func TestKafkaHandle(t *testing.T) {
+	encoder, err := newKafkaSpanEncoder(kafkaEncodingProto)
+	assert.NoError(t, err)
+	msg, err := makeKafkaSpan().Marshal()
+	assert.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		var records []*define.Record
+		svc := &KafkaService{
+			Publisher: receiver.Publisher{Func: func(record *define.Record) { records = append(records, record) }},
+			Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeOK, "", nil
+			}},
+		}
+		assert.NoError(t, svc.handle(msg, encoder))
+		assert.Len(t, records, 1)
+		assert.Equal(t, define.RequestKafka, records[0].RequestType)
+		assert.Equal(t, 1, records[0].Data.(ptrace.Traces).SpanCount())
+	})
+
+	t.Run("PreCheckFailed", func(t *testing.T) {
+		var n int
+		svc := &KafkaService{
+			Publisher: receiver.Publisher{Func: func(record *define.Record) { n++ }},
+			Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeUnauthorized, define.ProcessorTokenChecker, errors.New("MUST ERROR")
+			}},
+		}
+		assert.Error(t, svc.handle(msg, encoder))
+		assert.Equal(t, 0, n)
+	})
+}
@@ -0,0 +1,115 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package jaeger
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/prettyprint"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+// maxPacketSize 与 jaeger-agent 默认的 UDP 包大小上限保持一致
+const maxPacketSize = 65000
+
+// UdpService 兼容 jaeger-agent 的 UDP 协议 (6831/compact 6832/binary)
+// token 与其他协议一样从 process tags（即 resource attributes）中提取
+type UdpService struct {
+	receiver.Publisher
+	pipeline.Validator
+
+	mut   sync.Mutex
+	conns []net.PacketConn
+}
+
+var udpSvc = &UdpService{}
+
+func (s *UdpService) Listen(endpoint string, encoder agentEncoder) error {
+	conn, err := net.ListenPacket("udp", endpoint)
+	if err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	s.conns = append(s.conns, conn)
+	s.mut.Unlock()
+
+	logger.Infof("jaeger start to listen udp server at: %v, encoder=%s", endpoint, encoder.Type())
+	go s.serve(conn, encoder)
+	return nil
+}
+
+func (s *UdpService) serve(conn net.PacketConn, encoder agentEncoder) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			metricMonitor.IncInternalErrorCounter(define.RequestUdp, define.RecordTraces)
+			logger.Errorf("failed to read jaeger udp packet: %v", err)
+			continue
+		}
+
+		// 解码过程会复制数据 buf 可以复用
+		ip := utils.ParseRequestIP(addr.String())
+		if err := s.handle(buf[:n], ip, encoder); err != nil {
+			logger.WarnRate(time.Minute, ip, err)
+		}
+	}
+}
+
+func (s *UdpService) handle(buf []byte, ip string, encoder agentEncoder) error {
+	defer utils.HandleCrash()
+
+	start := time.Now()
+	traces, err := encoder.UnmarshalTraces(buf)
+	if err != nil {
+		metricMonitor.IncDroppedCounter(define.RequestUdp, define.RecordTraces)
+		return errors.Wrapf(err, "failed to parse jaeger agent packet, ip=%v", ip)
+	}
+
+	r := &define.Record{
+		RequestType:   define.RequestUdp,
+		RequestClient: define.RequestClient{IP: ip},
+		RecordType:    define.RecordTraces,
+		Data:          traces,
+	}
+	prettyprint.Traces(traces)
+
+	code, processorName, err := s.Validate(r)
+	if err != nil {
+		metricMonitor.IncPreCheckFailedCounter(define.RequestUdp, define.RecordTraces, processorName, r.Token.Original, code)
+		return errors.Wrapf(err, "run pre-check failed, rtype=traces, code=%d, ip=%s", code, ip)
+	}
+
+	s.Publish(r)
+	receiver.RecordHandleMetrics(metricMonitor, r.Token, define.RequestUdp, define.RecordTraces, len(buf), start)
+	return nil
+}
+
+func (s *UdpService) Stop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
@@ -0,0 +1,126 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package jaeger
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	apachethrift "github.com/apache/thrift/lib/go/thrift"
+	"github.com/jaegertracing/jaeger/thrift-gen/agent"
+	"github.com/jaegertracing/jaeger/thrift-gen/jaeger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/testkits"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+)
+
+func makeAgentPacket(t *testing.T, factory apachethrift.TProtocolFactory, method string) []byte {
+	ctx := context.Background()
+	batch := &jaeger.Batch{
+		Process: &jaeger.Process{
+			ServiceName: "legacy",
+			Tags: []*jaeger.Tag{
+				{Key: "bk.data.token", VType: jaeger.TagType_STRING, VStr: apachethrift.StringPtr("token1")},
+			},
+		},
+		Spans: []*jaeger.Span{
+			{TraceIdLow: 1, SpanId: 2, OperationName: "GET /index", StartTime: time.Now().UnixMicro(), Duration: 1000},
+		},
+	}
+
+	buf := apachethrift.NewTMemoryBuffer()
+	protocol := factory.GetProtocol(buf)
+	assert.NoError(t, protocol.WriteMessageBegin(ctx, method, apachethrift.ONEWAY, 1))
+	args := agent.AgentEmitBatchArgs{Batch: batch}
+	assert.NoError(t, args.Write(ctx, protocol))
+	assert.NoError(t, protocol.WriteMessageEnd(ctx))
+	assert.NoError(t, protocol.Flush(ctx))
+	return buf.Bytes()
+}
+
+func TestAgentEncoder(t *testing.T) {
+	for _, encoder := range []agentEncoder{newAgentCompactEncoder(), newAgentBinaryEncoder()} {
+		traces, err := encoder.UnmarshalTraces(makeAgentPacket(t, encoder.factory, agentEmitBatch))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, traces.SpanCount())
+		attrs := traces.ResourceSpans().At(0).Resource().Attributes()
+		testkits.AssertAttrsFoundStringVal(t, attrs, "bk.data.token", "token1")
+
+		_, err = encoder.UnmarshalTraces(makeAgentPacket(t, encoder.factory, "emitZipkinBatch"))
+		assert.Error(t, err)
+
+		_, err = encoder.UnmarshalTraces([]byte("{-}"))
+		assert.Error(t, err)
+	}
+}
+
+func TestUdpHandle(t *testing.T) {
+	encoder := newAgentCompactEncoder()
+	packet := makeAgentPacket(t, encoder.factory, agentEmitBatch)
+
+	t.Run("Success", func(t *testing.T) {
+		var n int
+		svc := &UdpService{
+			Publisher: receiver.Publisher{Func: func(record *define.Record) { n++ }},
+			Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeOK, "", nil
+			}},
+		}
+		assert.NoError(t, svc.handle(packet, "127.0.0.1", encoder))
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("PreCheckFailed", func(t *testing.T) {
+		var n int
+		svc := &UdpService{
+			Publisher: receiver.Publisher{Func: func(record *define.Record) { n++ }},
+			Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeUnauthorized, define.ProcessorTokenChecker, errors.New("MUST ERROR")
+			}},
+		}
+		assert.Error(t, svc.handle(packet, "127.0.0.1", encoder))
+		assert.Equal(t, 0, n)
+	})
+}
+
+func TestUdpListen(t *testing.T) {
+	ch := make(chan *define.Record, 1)
+	svc := &UdpService{
+		Publisher: receiver.Publisher{Func: func(record *define.Record) { ch <- record }},
+		Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+			return define.StatusCodeOK, "", nil
+		}},
+	}
+	encoder := newAgentCompactEncoder()
+	assert.NoError(t, svc.Listen("127.0.0.1:0", encoder))
+	defer svc.Stop()
+
+	conn, err := net.Dial("udp", svc.conns[0].LocalAddr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(makeAgentPacket(t, encoder.factory, agentEmitBatch))
+	assert.NoError(t, err)
+
+	select {
+	case r := <-ch:
+		assert.Equal(t, define.RequestUdp, r.RequestType)
+		assert.Equal(t, 1, r.Data.(ptrace.Traces).SpanCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for udp record")
+	}
+}
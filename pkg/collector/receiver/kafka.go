@@ -0,0 +1,138 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package receiver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	defaultKafkaVersion = "2.0.0"
+	kafkaRetryInterval  = 5 * time.Second
+)
+
+// KafkaMessageHandler 处理单条 kafka 消息 返回后消息即被标记为已消费
+type KafkaMessageHandler func(msg *sarama.ConsumerMessage)
+
+// KafkaConsumer 基于 consumer group 的 kafka 消费者 供各 trace 协议复用
+type KafkaConsumer struct {
+	conf   KafkaConfig
+	group  sarama.ConsumerGroup
+	handle KafkaMessageHandler
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewKafkaConsumer 创建消费者 groupID 未配置时使用 defaultGroupID
+func NewKafkaConsumer(conf KafkaConfig, defaultGroupID string, handle KafkaMessageHandler) (*KafkaConsumer, error) {
+	if !conf.Enabled() {
+		return nil, errors.New("kafka brokers or topic not specified")
+	}
+
+	version := conf.Version
+	if version == "" {
+		version = defaultKafkaVersion
+	}
+	kafkaVersion, err := sarama.ParseKafkaVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	saramaConf := sarama.NewConfig()
+	saramaConf.Version = kafkaVersion
+	saramaConf.Consumer.Return.Errors = true
+	saramaConf.Consumer.Offsets.Initial = sarama.OffsetNewest
+
+	groupID := conf.GroupID
+	if groupID == "" {
+		groupID = defaultGroupID
+	}
+	group, err := sarama.NewConsumerGroup(conf.Brokers, groupID, saramaConf)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &KafkaConsumer{
+		conf:   conf,
+		group:  group,
+		handle: handle,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+func (c *KafkaConsumer) Start() {
+	logger.Infof("start to consume kafka topic %s, brokers=%v", c.conf.Topic, c.conf.Brokers)
+
+	c.wg.Add(2)
+	go func() {
+		defer c.wg.Done()
+		for {
+			// rebalance 后 Consume 会返回 需要循环调用
+			if err := c.group.Consume(c.ctx, []string{c.conf.Topic}, c); err != nil {
+				logger.Errorf("failed to consume kafka topic %s: %v", c.conf.Topic, err)
+				select {
+				case <-time.After(kafkaRetryInterval):
+				case <-c.ctx.Done():
+				}
+			}
+			if c.ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer c.wg.Done()
+		for err := range c.group.Errors() {
+			logger.Warnf("kafka consumer group error, topic=%s: %v", c.conf.Topic, err)
+		}
+	}()
+}
+
+func (c *KafkaConsumer) Stop() {
+	c.cancel()
+	if err := c.group.Close(); err != nil {
+		logger.Warnf("failed to close kafka consumer group, topic=%s: %v", c.conf.Topic, err)
+	}
+	c.wg.Wait()
+}
+
+func (c *KafkaConsumer) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+func (c *KafkaConsumer) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (c *KafkaConsumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		c.handle(msg)
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+// KafkaHeader 返回消息中指定 header 的值
+func KafkaHeader(msg *sarama.ConsumerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,308 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promscrape
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultTimeout     = 10 * time.Second
+	defaultMaxBodySize = 10 * 1024 * 1024
+
+	acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+
+	labelInstance = "instance"
+)
+
+func init() {
+	receiver.RegisterReadyFunc(define.SourcePromScrape, Ready)
+	receiver.RegisterReloadFunc(define.SourcePromScrape, Reload)
+	receiver.RegisterStopFunc(define.SourcePromScrape, Stop)
+}
+
+var metricMonitor = receiver.DefaultMetricMonitor.Source(define.SourcePromScrape)
+
+var globalScraper = New()
+
+func Ready() {
+	globalScraper.Start(receiver.GetComponentConfig().PromScrape)
+}
+
+func Reload(config receiver.ComponentConfig) {
+	globalScraper.Reload(config.PromScrape)
+}
+
+func Stop() {
+	globalScraper.Stop()
+}
+
+// Scraper 周期性拉取 targets 的 /metrics 数据 按 MetricFamily 拆分为 RecordPushGateway 推送至 pipeline
+// 与 pushgateway 共用 exporter/converter 的转换逻辑
+type Scraper struct {
+	receiver.Publisher
+	pipeline.Validator
+
+	mut     sync.RWMutex
+	config  receiver.ComponentPromScrape
+	started bool
+	reload  chan struct{}
+	done    chan struct{}
+}
+
+func New() *Scraper {
+	return &Scraper{
+		reload: make(chan struct{}, 1),
+	}
+}
+
+func (s *Scraper) getConfig() receiver.ComponentPromScrape {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.config
+}
+
+func (s *Scraper) setConfig(config receiver.ComponentPromScrape) {
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+
+	s.mut.Lock()
+	s.config = config
+	s.mut.Unlock()
+}
+
+func (s *Scraper) Start(config receiver.ComponentPromScrape) {
+	s.setConfig(config)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	logger.Infof("promscrape start working, targets count=%d", len(config.Targets))
+	s.startLocked()
+}
+
+// Reload 更新 targets 配置 下一轮拉取生效
+// 启动时未开启的情况下 Ready 不会被调用 因此需要在 reload 时根据 enabled 切换拉取循环的启停
+func (s *Scraper) Reload(config receiver.ComponentPromScrape) {
+	s.setConfig(config)
+	logger.Infof("promscrape reload config, enabled=%v, targets count=%d", config.Enabled, len(config.Targets))
+
+	s.mut.Lock()
+	switch {
+	case config.Enabled && !s.started:
+		s.startLocked()
+		s.mut.Unlock()
+		return
+
+	case !config.Enabled && s.started:
+		s.stopLocked()
+		s.mut.Unlock()
+		return
+	}
+	s.mut.Unlock()
+
+	select {
+	case s.reload <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scraper) Stop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.stopLocked()
+}
+
+func (s *Scraper) isStarted() bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return s.started
+}
+
+// startLocked 调用方需持有 s.mut 写锁
+func (s *Scraper) startLocked() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.done = make(chan struct{})
+	go s.loop(s.done)
+}
+
+// stopLocked 调用方需持有 s.mut 写锁
+func (s *Scraper) stopLocked() {
+	if !s.started {
+		return
+	}
+	s.started = false
+	close(s.done)
+}
+
+func (s *Scraper) loop(done chan struct{}) {
+	ticker := time.NewTicker(s.getConfig().Interval)
+	defer ticker.Stop()
+
+	s.scrapeAll()
+	for {
+		select {
+		case <-ticker.C:
+			s.scrapeAll()
+
+		case <-s.reload:
+			ticker.Reset(s.getConfig().Interval)
+
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *Scraper) scrapeAll() {
+	config := s.getConfig()
+	if !config.Enabled {
+		return
+	}
+
+	client := &http.Client{Timeout: config.Timeout}
+	wg := sync.WaitGroup{}
+	for _, target := range config.Targets {
+		wg.Add(1)
+		go func(target receiver.PromScrapeTarget) {
+			defer wg.Done()
+			if err := s.scrape(client, config, target); err != nil {
+				metricMonitor.IncDroppedCounter(define.RequestHttp, define.RecordPushGateway)
+				logger.Warnf("failed to scrape target %s, err: %v", target.URL, err)
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+func (s *Scraper) scrape(client *http.Client, config receiver.ComponentPromScrape, target receiver.PromScrapeTarget) error {
+	defer utils.HandleCrash()
+	start := time.Now()
+
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return errors.Wrap(err, "invalid target url")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status code %d", rsp.StatusCode)
+	}
+
+	// 多读取一个字节用于判断是否超出限制
+	buf := &bytes.Buffer{}
+	if _, err = io.Copy(buf, io.LimitReader(rsp.Body, config.MaxBodySize+1)); err != nil {
+		return errors.Wrap(err, "failed to read body")
+	}
+	bodySize := buf.Len()
+	if int64(bodySize) > config.MaxBodySize {
+		return errors.Errorf("body size exceeds the limit %d", config.MaxBodySize)
+	}
+
+	mfs, err := decodeMetricFamilies(buf, expfmt.ResponseFormat(rsp.Header))
+	if err != nil {
+		return errors.Wrap(err, "failed to decode metric families")
+	}
+
+	token := target.Token
+	if token == "" {
+		token = config.Token
+	}
+
+	// instance 维度缺省时使用 target 地址
+	labels := utils.CloneMap(target.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	if _, ok := labels[labelInstance]; !ok {
+		labels[labelInstance] = u.Host
+	}
+
+	r := &define.Record{
+		RecordType:    define.RecordPushGateway,
+		RequestType:   define.RequestHttp,
+		RequestClient: define.RequestClient{IP: u.Hostname()},
+		Token:         define.Token{Original: token},
+	}
+	code, processorName, err := s.Validate(r)
+	if err != nil {
+		metricMonitor.IncPreCheckFailedCounter(define.RequestHttp, define.RecordPushGateway, processorName, token, code)
+		return errors.Wrapf(err, "run pre-check failed, code=%d", code)
+	}
+
+	for _, mf := range mfs {
+		s.Publish(&define.Record{
+			RecordType:    define.RecordPushGateway,
+			RequestType:   define.RequestHttp,
+			RequestClient: r.RequestClient,
+			Token:         r.Token,
+			Data: &define.PushGatewayData{
+				MetricFamilies: mf,
+				Labels:         utils.CloneMap(labels),
+			},
+		})
+	}
+	receiver.RecordHandleMetrics(metricMonitor, r.Token, define.RequestHttp, define.RecordPushGateway, bodySize, start)
+	return nil
+}
+
+func decodeMetricFamilies(r io.Reader, format expfmt.Format) ([]*dto.MetricFamily, error) {
+	var mfs []*dto.MetricFamily
+	decoder := expfmt.NewDecoder(r, format)
+	for {
+		mf := &dto.MetricFamily{}
+		if err := decoder.Decode(mf); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		mfs = append(mfs, mf)
+	}
+	return mfs, nil
+}
@@ -0,0 +1,198 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package promscrape
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+)
+
+const metricsContent = `
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 10
+# TYPE memory_usage gauge
+memory_usage 1024
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 0.2
+rpc_duration_seconds_sum 12
+rpc_duration_seconds_count 40
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 1
+request_duration_seconds_bucket{le="1"} 3
+request_duration_seconds_bucket{le="+Inf"} 4
+request_duration_seconds_sum 5.5
+request_duration_seconds_count 4
+`
+
+func TestReady(t *testing.T) {
+	assert.NotPanics(t, Ready)
+	assert.NotPanics(t, Stop)
+}
+
+func newTestServer(statusCode int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(define.ContentType, string(expfmt.FmtText))
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(metricsContent))
+	}))
+}
+
+func TestScrape(t *testing.T) {
+	svr := newTestServer(http.StatusOK)
+	defer svr.Close()
+
+	var records []*define.Record
+	scraper := New()
+	scraper.Publisher = receiver.Publisher{Func: func(record *define.Record) {
+		records = append(records, record)
+	}}
+	scraper.Validator = pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+		return define.StatusCodeOK, "", nil
+	}}
+	scraper.setConfig(receiver.ComponentPromScrape{
+		Enabled: true,
+		Token:   "token1",
+		Targets: []receiver.PromScrapeTarget{
+			{URL: svr.URL + "/metrics", Labels: map[string]string{"job": "demo"}},
+		},
+	})
+	scraper.scrapeAll()
+	assert.Len(t, records, 4)
+
+	// 按 MetricFamily 拆分 后续由 pushgateway converter 统一转换
+	types := make(map[string]dto.MetricType)
+	for _, r := range records {
+		assert.Equal(t, define.RecordPushGateway, r.RecordType)
+		assert.Equal(t, "token1", r.Token.Original)
+
+		data := r.Data.(*define.PushGatewayData)
+		assert.Equal(t, "demo", data.Labels["job"])
+		assert.NotEmpty(t, data.Labels["instance"])
+		types[data.MetricFamilies.GetName()] = data.MetricFamilies.GetType()
+	}
+	assert.Equal(t, map[string]dto.MetricType{
+		"http_requests_total":      dto.MetricType_COUNTER,
+		"memory_usage":             dto.MetricType_GAUGE,
+		"rpc_duration_seconds":     dto.MetricType_SUMMARY,
+		"request_duration_seconds": dto.MetricType_HISTOGRAM,
+	}, types)
+
+	// 各 record 持有独立的 labels
+	records[0].Data.(*define.PushGatewayData).Labels["job"] = "changed"
+	assert.Equal(t, "demo", records[1].Data.(*define.PushGatewayData).Labels["job"])
+}
+
+func TestScrapeBodyTooLarge(t *testing.T) {
+	svr := newTestServer(http.StatusOK)
+	defer svr.Close()
+
+	var published bool
+	scraper := New()
+	scraper.Publisher = receiver.Publisher{Func: func(record *define.Record) {
+		published = true
+	}}
+	scraper.Validator = pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+		return define.StatusCodeOK, "", nil
+	}}
+
+	config := receiver.ComponentPromScrape{Token: "token1", MaxBodySize: int64(len(metricsContent) - 1)}
+	target := receiver.PromScrapeTarget{URL: svr.URL + "/metrics"}
+	assert.Error(t, scraper.scrape(http.DefaultClient, config, target))
+	assert.False(t, published)
+
+	config.MaxBodySize = int64(len(metricsContent))
+	assert.NoError(t, scraper.scrape(http.DefaultClient, config, target))
+	assert.True(t, published)
+}
+
+func TestScrapeFailed(t *testing.T) {
+	svr := newTestServer(http.StatusInternalServerError)
+	defer svr.Close()
+
+	scraper := New()
+	config := receiver.ComponentPromScrape{Token: "token1"}
+	target := receiver.PromScrapeTarget{URL: svr.URL + "/metrics"}
+	assert.Error(t, scraper.scrape(http.DefaultClient, config, target))
+}
+
+func TestScrapePreCheckFailed(t *testing.T) {
+	svr := newTestServer(http.StatusOK)
+	defer svr.Close()
+
+	var published bool
+	scraper := New()
+	scraper.Publisher = receiver.Publisher{Func: func(record *define.Record) {
+		published = true
+	}}
+	scraper.Validator = pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+		return define.StatusCodeUnauthorized, define.ProcessorTokenChecker, errors.New("invalid token")
+	}}
+
+	config := receiver.ComponentPromScrape{Token: "token1"}
+	target := receiver.PromScrapeTarget{URL: svr.URL + "/metrics"}
+	assert.Error(t, scraper.scrape(http.DefaultClient, config, target))
+	assert.False(t, published)
+}
+
+func TestScraperReload(t *testing.T) {
+	scraper := New()
+	scraper.Start(receiver.ComponentPromScrape{})
+	defer scraper.Stop()
+
+	scraper.Reload(receiver.ComponentPromScrape{Interval: time.Second})
+	assert.Equal(t, time.Second, scraper.getConfig().Interval)
+	assert.Equal(t, defaultTimeout, scraper.getConfig().Timeout)
+}
+
+func TestScraperReloadEnabled(t *testing.T) {
+	svr := newTestServer(http.StatusOK)
+	defer svr.Close()
+
+	published := make(chan struct{}, 1)
+	scraper := New()
+	scraper.Publisher = receiver.Publisher{Func: func(record *define.Record) {
+		select {
+		case published <- struct{}{}:
+		default:
+		}
+	}}
+	scraper.Validator = pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+		return define.StatusCodeOK, "", nil
+	}}
+	defer scraper.Stop()
+
+	// 启动时未开启 Ready 不会被调用 reload 开启后应拉起拉取循环
+	scraper.Reload(receiver.ComponentPromScrape{
+		Enabled: true,
+		Token:   "token1",
+		Targets: []receiver.PromScrapeTarget{{URL: svr.URL + "/metrics"}},
+	})
+	assert.True(t, scraper.isStarted())
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no records published after reload enabled")
+	}
+
+	scraper.Reload(receiver.ComponentPromScrape{Enabled: false})
+	assert.False(t, scraper.isStarted())
+}
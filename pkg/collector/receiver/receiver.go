@@ -142,12 +142,25 @@ func (r *Receiver) ready() {
 			if GetComponentConfig().Skywalking.Enabled {
 				f()
 			}
+		case define.SourcePromScrape:
+			if GetComponentConfig().PromScrape.Enabled {
+				f()
+			}
 		}
 	}
 }
 
 func (r *Receiver) Reload(conf *confengine.Config) {
 	globalSkywalkingConfig = LoadConfigFrom(conf)
+
+	var c Config
+	if err := conf.UnpackChild(define.ConfigFieldReceiver, &c); err != nil {
+		logger.Errorf("failed to reload receiver config: %v", err)
+		return
+	}
+	for _, f := range componentsReload {
+		f(c.Components)
+	}
 }
 
 func (r *Receiver) startHttpServer() error {
@@ -252,6 +265,10 @@ func (r *Receiver) Start() error {
 }
 
 func (r *Receiver) Stop() error {
+	for _, f := range componentsStop {
+		f()
+	}
+
 	if r.config.HttpServer.Enabled {
 		if err := r.httpServer.Close(); err != nil {
 			return err
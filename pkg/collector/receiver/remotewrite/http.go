@@ -61,41 +61,37 @@ func (s HttpService) Write(w http.ResponseWriter, req *http.Request) {
 		token = req.Header.Get(tokenKey)
 	}
 
-	r := &define.Record{
-		RecordType:    define.RecordRemoteWrite,
-		RequestType:   define.RequestHttp,
-		RequestClient: define.RequestClient{IP: ip},
-		Token:         define.Token{Original: token},
-	}
-	code, processorName, err := s.Validate(r)
-	if err != nil {
-		err = errors.Wrapf(err, "run pre-check failed, code=%d, ip=%s", code, ip)
-		logger.WarnRate(time.Minute, r.Token.Original, err)
-		receiver.WriteResponse(w, define.ContentTypeText, int(code), []byte(err.Error()))
-		metricMonitor.IncPreCheckFailedCounter(define.RequestHttp, define.RecordRemoteWrite, processorName, r.Token.Original, code)
-		return
-	}
-
 	writeReq, size, err := prompb.DecodeWriteRequest(req.Body)
 	if err != nil {
 		receiver.WriteResponse(w, define.ContentTypeText, http.StatusBadRequest, []byte(err.Error()))
 		metricMonitor.IncDroppedCounter(define.RequestHttp, define.RecordRemoteWrite)
-		logger.Warnf("failed to decode write request, code=%d, ip=%v, error: %s", code, ip, err)
+		logger.Warnf("failed to decode write request, ip=%v, error: %s", ip, err)
 		return
 	}
 	defer func() {
 		_ = req.Body.Close()
 	}()
 
-	s.Publish(&define.Record{
+	// 携带数据校验 series_guard 等处理器需要在接收阶段检查 series
+	r := &define.Record{
+		RecordType:    define.RecordRemoteWrite,
 		RequestType:   define.RequestHttp,
 		RequestClient: define.RequestClient{IP: ip},
-		RecordType:    define.RecordRemoteWrite,
-		Token:         r.Token,
+		Token:         define.Token{Original: token},
 		Data: &define.RemoteWriteData{
 			Timeseries: writeReq.Timeseries,
 		},
-	})
+	}
+	code, processorName, err := s.Validate(r)
+	if err != nil {
+		err = errors.Wrapf(err, "run pre-check failed, code=%d, ip=%s", code, ip)
+		logger.WarnRate(time.Minute, r.Token.Original, err)
+		receiver.WriteResponse(w, define.ContentTypeText, int(code), []byte(err.Error()))
+		metricMonitor.IncPreCheckFailedCounter(define.RequestHttp, define.RecordRemoteWrite, processorName, r.Token.Original, code)
+		return
+	}
+
+	s.Publish(r)
 	receiver.RecordHandleMetrics(metricMonitor, r.Token, define.RequestHttp, define.RecordRemoteWrite, size, start)
 	receiver.WriteResponse(w, define.ContentTypeText, http.StatusOK, nil)
 }
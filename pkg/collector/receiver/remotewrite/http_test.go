@@ -21,6 +21,8 @@ import (
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/seriesguard"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
 )
 
@@ -91,3 +93,44 @@ func TestHttpTokenAfterPreCheck(t *testing.T) {
 	assert.Equal(t, rw.Code, http.StatusOK)
 	assert.Equal(t, 1, n)
 }
+
+type testGetter struct {
+	pl        pipeline.Pipeline
+	processor processor.Instance
+}
+
+func (g testGetter) GetProcessor(string) processor.Instance { return g.processor }
+
+func (g testGetter) GetPipeline(define.RecordType) pipeline.Pipeline { return g.pl }
+
+func TestHttpSeriesGuardRejected(t *testing.T) {
+	content := `
+processor:
+  - name: "series_guard/common"
+    config:
+      max_series: 1
+      mode: reject
+`
+	guard := processor.MustCreateFactory(content, seriesguard.NewFactory)
+	defer guard.Clean()
+
+	inst := processor.NewInstance("series_guard/common", guard)
+	svc := HttpService{
+		receiver.Publisher{Func: func(record *define.Record) { t.Fatal("rejected record should not be published") }},
+		pipeline.Validator{Getter: testGetter{
+			pl:        pipeline.NewPipeline("remotewrite_pipeline/common", define.RecordRemoteWrite, inst),
+			processor: inst,
+		}},
+	}
+
+	body, err := os.ReadFile("../../example/fixtures/remotewrite.bytes")
+	assert.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, "http://localhost/prometheus/write", bytes.NewBuffer(body))
+	assert.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	svc.Write(rw, req)
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Contains(t, rw.Body.String(), "series guard rejected")
+}
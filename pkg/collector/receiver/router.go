@@ -34,6 +34,24 @@ func RegisterReadyFunc(source string, f Ready) {
 	componentsReady[source] = f
 }
 
+// Reload 组件配置重载函数 仅需处理运行期可变更的配置
+type Reload func(config ComponentConfig)
+
+var componentsReload = map[string]Reload{}
+
+func RegisterReloadFunc(source string, f Reload) {
+	componentsReload[source] = f
+}
+
+// Stop 组件退出函数 用于清理组件自身启动的后台任务
+type Stop func()
+
+var componentsStop = map[string]Stop{}
+
+func RegisterStopFunc(source string, f Stop) {
+	componentsStop[source] = f
+}
+
 func init() {
 	const statsSource = "stats"
 	mustRegisterHttpGetRoute(statsSource, "/metrics", func(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +77,7 @@ func init() {
 		debug.FreeOSMemory()
 		w.Write([]byte(`{"status": "success"}`))
 	})
+	mustRegisterHttpPostRoute(adminSource, "/-/tap", tapHandler)
 
 	const pprofSource = "pprof"
 	mustRegisterHttpGetRoute(pprofSource, "/debug/pprof/snapshot", pprofsnapshot.HandlerFuncFor())
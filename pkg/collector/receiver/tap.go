@@ -0,0 +1,102 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package receiver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/tap"
+)
+
+// tapHandler 按 token 抓取 pipeline 中每个 processor 执行前后的 record 并以文本流形式返回
+//
+// 参数:
+// - token: 原始 token 必填
+// - type: 数据类型 如 traces/metrics/logs 为空表示不限制
+// - ttl: 会话存活时间 如 30s 上限为 tap.MaxTTL
+// - qps: 每秒最多抓取的 record 数 上限为 tap.MaxQps
+func tapHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	rtype := define.RecordType(r.FormValue("type"))
+
+	var ttl time.Duration
+	if s := r.FormValue("ttl"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			WriteResponse(w, define.ContentTypeText, http.StatusBadRequest, []byte(err.Error()))
+			return
+		}
+		ttl = d
+	}
+
+	var qps float64
+	if s := r.FormValue("qps"); s != "" {
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			WriteResponse(w, define.ContentTypeText, http.StatusBadRequest, []byte(err.Error()))
+			return
+		}
+		qps = f
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteResponse(w, define.ContentTypeText, http.StatusInternalServerError, []byte("streaming unsupported"))
+		return
+	}
+
+	session, err := tap.Start(token, rtype, ttl, float32(qps))
+	if err != nil {
+		WriteResponse(w, define.ContentTypeText, http.StatusBadRequest, []byte(err.Error()))
+		return
+	}
+	defer session.Close()
+
+	w.Header().Set("Content-Type", define.ContentTypeText)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-session.Done():
+			_, _ = fmt.Fprintf(w, "# tap session finished, dropped=%d\n", session.Dropped())
+			flusher.Flush()
+			return
+
+		case evt := <-session.Events():
+			_, _ = w.Write([]byte(formatTapEvent(evt)))
+			flusher.Flush()
+		}
+	}
+}
+
+func formatTapEvent(evt tap.Event) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("==> %s stage=%s phase=%s type=%s token=%s",
+		evt.Time.Format(time.RFC3339Nano), evt.Stage, evt.Phase, evt.RecordType, evt.Token))
+	if evt.Err != "" {
+		sb.WriteString(fmt.Sprintf(" error=%q", evt.Err))
+	}
+	sb.WriteString("\n")
+	for _, line := range evt.Lines {
+		sb.WriteString("    ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
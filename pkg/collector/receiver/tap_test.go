@@ -0,0 +1,58 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package receiver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/tap"
+)
+
+func TestTapHandlerInvalidArgs(t *testing.T) {
+	cases := []string{
+		"http://localhost/-/tap",
+		"http://localhost/-/tap?token=token1&ttl=x",
+		"http://localhost/-/tap?token=token1&qps=x",
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c, nil)
+		rw := httptest.NewRecorder()
+		tapHandler(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code)
+	}
+}
+
+func TestTapHandlerFinished(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/-/tap?token=token1&ttl=100ms", nil)
+	rw := httptest.NewRecorder()
+	tapHandler(rw, req)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), "# tap session finished")
+}
+
+func TestFormatTapEvent(t *testing.T) {
+	s := formatTapEvent(tap.Event{
+		Time:       time.Unix(0, 0).UTC(),
+		Stage:      "attribute_filter/common",
+		Phase:      tap.PhaseAfter,
+		RecordType: define.RecordTraces,
+		Token:      "token1",
+		Err:        errors.New("MUST ERROR").Error(),
+		Lines:      []string{"span1"},
+	})
+	assert.Equal(t, "==> 1970-01-01T00:00:00Z stage=attribute_filter/common phase=after type=traces token=token1 error=\"MUST ERROR\"\n    span1\n", s)
+}
@@ -0,0 +1,201 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package zipkin
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/prettyprint"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const (
+	routeV1Spans = "/api/v1/spans"
+	routeV2Spans = "/api/v2/spans"
+
+	tokenKey      = "X-BK-TOKEN"
+	contentThrift = "application/x-thrift"
+	contentPb     = "application/x-protobuf"
+
+	// defaultResourceKey token_checker 未配置 resource_key 时使用的默认值
+	defaultResourceKey = "bk.data.token"
+)
+
+func init() {
+	receiver.RegisterReadyFunc(define.SourceZipkin, Ready)
+	receiver.RegisterStopFunc(define.SourceZipkin, Stop)
+}
+
+var metricMonitor = receiver.DefaultMetricMonitor.Source(define.SourceZipkin)
+
+func Ready() {
+	receiver.RegisterHttpRoute(define.SourceZipkin, []receiver.RouteWithFunc{
+		{
+			Method:       http.MethodPost,
+			RelativePath: routeV1Spans,
+			HandlerFunc:  httpSvc.V1Spans,
+		},
+		{
+			Method:       http.MethodPost,
+			RelativePath: routeV2Spans,
+			HandlerFunc:  httpSvc.V2Spans,
+		},
+	})
+
+	config := receiver.GetComponentConfig().Zipkin
+	if config.Kafka.Enabled() {
+		if err := kafkaSvc.Start(config.Kafka); err != nil {
+			logger.Errorf("failed to start zipkin kafka consumer: %v", err)
+		}
+	}
+}
+
+func Stop() {
+	kafkaSvc.Stop()
+}
+
+type HttpService struct {
+	receiver.Publisher
+	pipeline.Validator
+}
+
+var httpSvc HttpService
+
+type tracesEncoder interface {
+	Type() string
+	UnmarshalTraces(buf []byte) (ptrace.Traces, error)
+}
+
+func contentType(req *http.Request) string {
+	ctype, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return ctype
+}
+
+// V1Spans 支持 v1 版本的 thrift/json 格式 未指定 Content-Type 时按 json 处理
+func (s HttpService) V1Spans(w http.ResponseWriter, req *http.Request) {
+	var encoder tracesEncoder = newJsonV1Encoder()
+	if contentType(req) == contentThrift {
+		encoder = newThriftV1Encoder()
+	}
+	s.exportTraces(w, req, encoder)
+}
+
+// V2Spans 支持 v2 版本的 protobuf/json 格式 未指定 Content-Type 时按 json 处理
+func (s HttpService) V2Spans(w http.ResponseWriter, req *http.Request) {
+	var encoder tracesEncoder = newJsonV2Encoder()
+	if contentType(req) == contentPb {
+		encoder = newPbV2Encoder()
+	}
+	s.exportTraces(w, req, encoder)
+}
+
+func (s HttpService) exportTraces(w http.ResponseWriter, req *http.Request, encoder tracesEncoder) {
+	defer utils.HandleCrash()
+	ip := utils.ParseRequestIP(req.RemoteAddr)
+
+	start := time.Now()
+	buf := &bytes.Buffer{}
+	_, err := io.Copy(buf, req.Body)
+	if err != nil {
+		metricMonitor.IncInternalErrorCounter(define.RequestHttp, define.RecordTraces)
+		receiver.WriteResponse(w, define.ContentTypeJson, http.StatusInternalServerError, nil)
+		logger.Errorf("failed to read zipkin body: %v", err)
+		return
+	}
+	defer func() {
+		_ = req.Body.Close()
+	}()
+
+	traces, err := encoder.UnmarshalTraces(buf.Bytes())
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse zipkin exported content, encoder=%s, ip=%v", encoder.Type(), ip)
+		logger.Warn(err)
+		metricMonitor.IncDroppedCounter(define.RequestHttp, define.RecordTraces)
+		receiver.WriteResponse(w, define.ContentTypeJson, http.StatusBadRequest, []byte(err.Error()))
+		return
+	}
+
+	token := req.URL.Query().Get(tokenKey)
+	if token == "" {
+		token = req.Header.Get(tokenKey)
+	}
+	fillToken(traces, resourceKey(s.Validator), token)
+
+	r := &define.Record{
+		RequestType:   define.RequestHttp,
+		RequestClient: define.RequestClient{IP: ip},
+		RecordType:    define.RecordTraces,
+		Data:          traces,
+	}
+	prettyprint.Traces(traces)
+
+	code, processorName, err := s.Validate(r)
+	if err != nil {
+		err = errors.Wrapf(err, "run pre-check failed, rtype=traces, code=%d, ip=%s", code, ip)
+		logger.WarnRate(time.Minute, r.Token.Original, err)
+		metricMonitor.IncPreCheckFailedCounter(define.RequestHttp, define.RecordTraces, processorName, r.Token.Original, code)
+		receiver.WriteResponse(w, define.ContentTypeJson, int(code), []byte(err.Error()))
+		return
+	}
+
+	s.Publish(r)
+	receiver.RecordHandleMetrics(metricMonitor, r.Token, define.RequestHttp, define.RecordTraces, buf.Len(), start)
+	receiver.WriteResponse(w, define.ContentTypeJson, http.StatusAccepted, nil)
+}
+
+// resourceKey 读取 traces pipeline 中 token_checker 配置的 resource_key
+func resourceKey(v pipeline.Validator) string {
+	if key := v.ResourceKey(define.RecordTraces); key != "" {
+		return key
+	}
+	return defaultResourceKey
+}
+
+// fillToken 保证 token 位于 resource attributes 的 key 中 与 token_checker 的提取规则保持一致
+// zipkin 没有 resource 的概念 token 可能以 tag 形式出现在 span attributes 中 也可能通过请求参数传递
+// 优先级：resource > span attributes > 请求参数
+func fillToken(traces ptrace.Traces, key, token string) {
+	resourceSpansSlice := traces.ResourceSpans()
+	for i := 0; i < resourceSpansSlice.Len(); i++ {
+		resourceSpans := resourceSpansSlice.At(i)
+		rsAttrs := resourceSpans.Resource().Attributes()
+		if _, ok := rsAttrs.Get(key); ok {
+			continue
+		}
+
+		found := token
+		scopeSpansSlice := resourceSpans.ScopeSpans()
+	loop:
+		for j := 0; j < scopeSpansSlice.Len(); j++ {
+			spans := scopeSpansSlice.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				if v, ok := spans.At(k).Attributes().Get(key); ok {
+					found = v.AsString()
+					break loop
+				}
+			}
+		}
+
+		if found != "" {
+			rsAttrs.UpsertString(key, found)
+		}
+	}
+}
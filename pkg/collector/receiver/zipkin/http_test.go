@@ -0,0 +1,207 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package zipkin
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/testkits"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/processor/tokenchecker"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+)
+
+const (
+	v1JsonContent = `[{
+  "traceId": "5af7183fb1d4cf5f",
+  "name": "get /api",
+  "id": "6b221d5bc9e6496c",
+  "timestamp": 1472470996199000,
+  "duration": 207000,
+  "annotations": [
+    {"timestamp": 1472470996199000, "value": "sr", "endpoint": {"serviceName": "backend", "ipv4": "192.168.99.101", "port": 9000}},
+    {"timestamp": 1472470996406000, "value": "ss", "endpoint": {"serviceName": "backend", "ipv4": "192.168.99.101", "port": 9000}}
+  ],
+  "binaryAnnotations": [
+    {"key": "bk.data.token", "value": "token1", "endpoint": {"serviceName": "backend", "ipv4": "192.168.99.101", "port": 9000}}
+  ]
+}]`
+
+	v2JsonContent = `[{
+  "traceId": "5af7183fb1d4cf5f",
+  "id": "6b221d5bc9e6496c",
+  "kind": "SERVER",
+  "name": "get /api",
+  "timestamp": 1472470996199000,
+  "duration": 207000,
+  "localEndpoint": {"serviceName": "backend", "ipv4": "192.168.99.101", "port": 9000}
+}]`
+)
+
+func TestReady(t *testing.T) {
+	assert.NotPanics(t, Ready)
+}
+
+func newTestHttpService(records *[]*define.Record, code define.StatusCode, err error) HttpService {
+	return HttpService{
+		receiver.Publisher{Func: func(record *define.Record) { *records = append(*records, record) }},
+		pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+			return code, "", err
+		}},
+	}
+}
+
+func firstResourceSpans(r *define.Record) ptrace.ResourceSpans {
+	return r.Data.(ptrace.Traces).ResourceSpans().At(0)
+}
+
+func TestHttpV1Spans(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v1/spans", bytes.NewBufferString(v1JsonContent))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	var records []*define.Record
+	svc := newTestHttpService(&records, define.StatusCodeOK, nil)
+	rw := httptest.NewRecorder()
+	svc.V1Spans(rw, req)
+
+	assert.Equal(t, http.StatusAccepted, rw.Code)
+	assert.Len(t, records, 1)
+
+	// span tag 中的 token 被提升至 resource
+	attrs := firstResourceSpans(records[0]).Resource().Attributes()
+	testkits.AssertAttrsFoundStringVal(t, attrs, "bk.data.token", "token1")
+	testkits.AssertAttrsFoundStringVal(t, attrs, "service.name", "backend")
+}
+
+func TestHttpV2Spans(t *testing.T) {
+	t.Run("QueryToken", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v2/spans?X-BK-TOKEN=token2", bytes.NewBufferString(v2JsonContent))
+		assert.NoError(t, err)
+
+		var records []*define.Record
+		svc := newTestHttpService(&records, define.StatusCodeOK, nil)
+		rw := httptest.NewRecorder()
+		svc.V2Spans(rw, req)
+
+		assert.Equal(t, http.StatusAccepted, rw.Code)
+		assert.Len(t, records, 1)
+		attrs := firstResourceSpans(records[0]).Resource().Attributes()
+		testkits.AssertAttrsFoundStringVal(t, attrs, "bk.data.token", "token2")
+	})
+
+	t.Run("HeaderToken", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v2/spans", bytes.NewBufferString(v2JsonContent))
+		assert.NoError(t, err)
+		req.Header.Set("X-BK-TOKEN", "token3")
+
+		var records []*define.Record
+		svc := newTestHttpService(&records, define.StatusCodeOK, nil)
+		rw := httptest.NewRecorder()
+		svc.V2Spans(rw, req)
+
+		assert.Len(t, records, 1)
+		attrs := firstResourceSpans(records[0]).Resource().Attributes()
+		testkits.AssertAttrsFoundStringVal(t, attrs, "bk.data.token", "token3")
+	})
+}
+
+type testGetter struct {
+	pl        pipeline.Pipeline
+	processor processor.Instance
+}
+
+func (g testGetter) GetProcessor(string) processor.Instance { return g.processor }
+
+func (g testGetter) GetPipeline(define.RecordType) pipeline.Pipeline { return g.pl }
+
+func TestHttpCustomResourceKey(t *testing.T) {
+	content := `
+processor:
+  - name: "token_checker/fixed"
+    config:
+      type: "fixed"
+      resource_key: "custom.token"
+      fixed_token: "token1"
+`
+	checker := processor.MustCreateFactory(content, tokenchecker.NewFactory)
+	inst := processor.NewInstance("token_checker/fixed", checker)
+
+	var records []*define.Record
+	svc := HttpService{
+		receiver.Publisher{Func: func(record *define.Record) { records = append(records, record) }},
+		pipeline.Validator{
+			Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeOK, "", nil
+			},
+			Getter: testGetter{
+				pl:        pipeline.NewPipeline("traces_pipeline/common", define.RecordTraces, inst),
+				processor: inst,
+			},
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v2/spans?X-BK-TOKEN=token2", bytes.NewBufferString(v2JsonContent))
+	assert.NoError(t, err)
+	rw := httptest.NewRecorder()
+	svc.V2Spans(rw, req)
+
+	// token 写入 token_checker 配置的 resource_key 中
+	assert.Len(t, records, 1)
+	attrs := firstResourceSpans(records[0]).Resource().Attributes()
+	testkits.AssertAttrsFoundStringVal(t, attrs, "custom.token", "token2")
+	testkits.AssertAttrsNotFound(t, attrs, "bk.data.token")
+}
+
+func TestHttpInvalidBody(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v2/spans", bytes.NewBufferString("{-}"))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+
+	var records []*define.Record
+	svc := newTestHttpService(&records, define.StatusCodeOK, nil)
+	rw := httptest.NewRecorder()
+	svc.V2Spans(rw, req)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Len(t, records, 0)
+}
+
+func TestHttpReadFailed(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v1/spans", testkits.NewBrokenReader())
+	assert.NoError(t, err)
+
+	var records []*define.Record
+	svc := newTestHttpService(&records, define.StatusCodeOK, nil)
+	rw := httptest.NewRecorder()
+	svc.V1Spans(rw, req)
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	assert.Len(t, records, 0)
+}
+
+func TestHttpPreCheckFailed(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/api/v1/spans", bytes.NewBufferString(v1JsonContent))
+	assert.NoError(t, err)
+
+	var records []*define.Record
+	svc := newTestHttpService(&records, define.StatusCodeTooManyRequests, errors.New("MUST ERROR"))
+	rw := httptest.NewRecorder()
+	svc.V1Spans(rw, req)
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Len(t, records, 0)
+}
@@ -0,0 +1,114 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package zipkin
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/prettyprint"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/utils"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/logger"
+)
+
+const kafkaGroupID = "bk-collector-zipkin"
+
+// newKafkaEncoder 与 zipkin kafka sender 的编码保持一致 每条消息均为 span 列表
+func newKafkaEncoder(encoding string) (tracesEncoder, error) {
+	switch encoding {
+	case "", "json":
+		return newJsonV2Encoder(), nil
+	case "proto":
+		return newPbV2Encoder(), nil
+	case "thrift":
+		return newThriftV1Encoder(), nil
+	}
+	return nil, errors.Errorf("unsupported zipkin kafka encoding %s", encoding)
+}
+
+// KafkaService 消费 zipkin kafka sender 写入的 span 数据
+// 除 resource 及 span attributes 外 token 还可以通过消息 header X-BK-TOKEN 传递
+type KafkaService struct {
+	receiver.Publisher
+	pipeline.Validator
+
+	mut      sync.Mutex
+	consumer *receiver.KafkaConsumer
+}
+
+var kafkaSvc = &KafkaService{}
+
+func (s *KafkaService) Start(conf receiver.KafkaConfig) error {
+	encoder, err := newKafkaEncoder(conf.Encoding)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := receiver.NewKafkaConsumer(conf, kafkaGroupID, func(msg *sarama.ConsumerMessage) {
+		if err := s.handle(msg.Value, receiver.KafkaHeader(msg, tokenKey), encoder); err != nil {
+			logger.WarnRate(time.Minute, msg.Topic, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	s.consumer = consumer
+	s.mut.Unlock()
+
+	consumer.Start()
+	return nil
+}
+
+func (s *KafkaService) handle(buf []byte, token string, encoder tracesEncoder) error {
+	defer utils.HandleCrash()
+
+	start := time.Now()
+	traces, err := encoder.UnmarshalTraces(buf)
+	if err != nil {
+		metricMonitor.IncDroppedCounter(define.RequestKafka, define.RecordTraces)
+		return errors.Wrapf(err, "failed to parse zipkin kafka message, encoder=%s", encoder.Type())
+	}
+	fillToken(traces, resourceKey(s.Validator), token)
+
+	r := &define.Record{
+		RequestType: define.RequestKafka,
+		RecordType:  define.RecordTraces,
+		Data:        traces,
+	}
+	prettyprint.Traces(traces)
+
+	code, processorName, err := s.Validate(r)
+	if err != nil {
+		metricMonitor.IncPreCheckFailedCounter(define.RequestKafka, define.RecordTraces, processorName, r.Token.Original, code)
+		return errors.Wrapf(err, "run pre-check failed, rtype=traces, code=%d", code)
+	}
+
+	s.Publish(r)
+	receiver.RecordHandleMetrics(metricMonitor, r.Token, define.RequestKafka, define.RecordTraces, len(buf), start)
+	return nil
+}
+
+func (s *KafkaService) Stop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.consumer != nil {
+		s.consumer.Stop()
+		s.consumer = nil
+	}
+}
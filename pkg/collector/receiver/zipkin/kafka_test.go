@@ -0,0 +1,70 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package zipkin
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/internal/testkits"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/collector/receiver"
+)
+
+func TestKafkaEncoder(t *testing.T) {
+	for _, encoding := range []string{"", "json", "proto", "thrift"} {
+		_, err := newKafkaEncoder(encoding)
+		assert.NoError(t, err)
+	}
+	_, err := newKafkaEncoder("avro")
+	assert.Error(t, err)
+}
+
+func TestKafkaHandle(t *testing.T) {
+	encoder, err := newKafkaEncoder("json")
+	assert.NoError(t, err)
+
+	t.Run("HeaderToken", func(t *testing.T) {
+		var records []*define.Record
+		svc := &KafkaService{
+			Publisher: receiver.Publisher{Func: func(record *define.Record) { records = append(records, record) }},
+			Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeOK, "", nil
+			}},
+		}
+		assert.NoError(t, svc.handle([]byte(v2JsonContent), "token1", encoder))
+		assert.Len(t, records, 1)
+		assert.Equal(t, define.RequestKafka, records[0].RequestType)
+
+		attrs := records[0].Data.(ptrace.Traces).ResourceSpans().At(0).Resource().Attributes()
+		testkits.AssertAttrsFoundStringVal(t, attrs, "bk.data.token", "token1")
+	})
+
+	t.Run("InvalidMessage", func(t *testing.T) {
+		svc := &KafkaService{}
+		assert.Error(t, svc.handle([]byte("{-}"), "", encoder))
+	})
+
+	t.Run("PreCheckFailed", func(t *testing.T) {
+		var n int
+		svc := &KafkaService{
+			Publisher: receiver.Publisher{Func: func(record *define.Record) { n++ }},
+			Validator: pipeline.Validator{Func: func(record *define.Record) (define.StatusCode, string, error) {
+				return define.StatusCodeUnauthorized, define.ProcessorTokenChecker, errors.New("MUST ERROR")
+			}},
+		}
+		assert.Error(t, svc.handle([]byte(v2JsonContent), "", encoder))
+		assert.Equal(t, 0, n)
+	})
+}
@@ -9,6 +9,14 @@
 
 package config
 
+// 集群发行版
+const (
+	ElasticSearchDistribution = "elasticsearch"
+	OpenSearchDistribution    = "opensearch"
+	// ElasticSearchVersionAuto : 版本号由集群信息接口自动探测
+	ElasticSearchVersionAuto = "auto"
+)
+
 // ElasticSearchMetaClusterInfo :
 type ElasticSearchMetaClusterInfo struct {
 	*SimpleMetaClusterInfo
@@ -38,6 +46,26 @@ func (c *ElasticSearchMetaClusterInfo) SetVersion(val string) {
 	c.StorageConfigHelper.Set("version", val)
 }
 
+// GetDistribution : 集群发行版，elasticsearch 或 opensearch
+func (c *ElasticSearchMetaClusterInfo) GetDistribution() string {
+	distribution, ok := c.ClusterConfigHelper.GetString("distribution")
+	if !ok || distribution == "" {
+		return ElasticSearchDistribution
+	}
+	return distribution
+}
+
+// SetDistribution :
+func (c *ElasticSearchMetaClusterInfo) SetDistribution(val string) {
+	c.ClusterConfigHelper.Set("distribution", val)
+}
+
+// IsDataStream : 是否写入 data stream
+func (c *ElasticSearchMetaClusterInfo) IsDataStream() bool {
+	value, ok := c.StorageConfigHelper.GetBool("data_stream")
+	return ok && value
+}
+
 // GetTarget :
 func (c *ElasticSearchMetaClusterInfo) GetTarget() string {
 	return c.GetIndex()
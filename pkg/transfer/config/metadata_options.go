@@ -55,8 +55,16 @@ const (
 	PipelineConfigOptPayloadEncoding = "encoding"
 	// PipelineConfigOptPayloadEncodingStrict : 严格编码模式(bool)
 	PipelineConfigOptPayloadEncodingStrict = "encoding_strict"
+	// PipelineConfigOptPayloadFormat : payload 格式(json/protobuf/avro)，默认为 json
+	PipelineConfigOptPayloadFormat = "payload_format"
+	// PipelineConfigOptPayloadSchema : protobuf/avro 格式的 schema 配置(map)
+	PipelineConfigOptPayloadSchema = "payload_schema"
 	// PipelineConfigOptTransformFileNameToAliasName : 字段别名映射
 	PipelineConfigOptTransformFileNameToAliasName = "allow_use_alias_name"
+	// PipelineConfigOptEnableDeadLetter : 开启死信投递(bool)，未配置时跟随全局配置
+	PipelineConfigOptEnableDeadLetter = "enable_dead_letter"
+	// PipelineConfigOptDeadLetterSampleRate : 死信采样率(float)，未配置时跟随全局配置
+	PipelineConfigOptDeadLetterSampleRate = "dead_letter_sample_rate"
 	// ResultTableListConfigOptEnableFillDefault : 默认值
 	ResultTableListConfigOptEnableFillDefault = "enable_default_value"
 
@@ -157,6 +165,8 @@ const (
 	ResultTableOptLogSeparatedFields = "separator_field_list"
 	// ResultTableOptLogSeparatorRegexp : 日志正则提取清洗专用，提取字段
 	ResultTableOptLogSeparatorRegexp = "separator_regexp"
+	// ResultTableOptLogChainedSteps : 日志多步清洗专用，按顺序执行的清洗步骤列表
+	ResultTableOptLogChainedSteps = "chained_steps"
 
 	// 事件类
 	// 结果是否可以使用新的自定义维度
@@ -0,0 +1,86 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/cstockton/go-conv"
+)
+
+// RemoteWriteDefaultPath : VictoriaMetrics/Mimir 等通用的 remote write 路径
+const RemoteWriteDefaultPath = "/api/v1/write"
+
+// RemoteWriteMetaClusterInfo :
+type RemoteWriteMetaClusterInfo struct {
+	*SimpleMetaClusterInfo
+}
+
+// GetPath : remote write 请求路径
+func (c *RemoteWriteMetaClusterInfo) GetPath() string {
+	path, ok := c.StorageConfigHelper.GetString("path")
+	if !ok || path == "" {
+		return RemoteWriteDefaultPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// SetPath :
+func (c *RemoteWriteMetaClusterInfo) SetPath(path string) {
+	c.StorageConfigHelper.Set("path", path)
+}
+
+// GetURL :
+func (c *RemoteWriteMetaClusterInfo) GetURL() string {
+	return c.GetAddress() + c.GetPath()
+}
+
+// GetHeaders : 额外的请求头，如多租户场景下的 X-Scope-OrgID
+func (c *RemoteWriteMetaClusterInfo) GetHeaders() map[string]string {
+	headers := make(map[string]string)
+	value, ok := c.StorageConfigHelper.Get("headers")
+	if !ok {
+		return headers
+	}
+	if values, ok := value.(map[string]interface{}); ok {
+		for k, v := range values {
+			headers[k] = conv.String(v)
+		}
+	}
+	return headers
+}
+
+// GetConcurrency : 写入地址的最大并发请求数，未配置时使用默认值
+func (c *RemoteWriteMetaClusterInfo) GetConcurrency(defaultValue int64) int64 {
+	value, ok := c.StorageConfigHelper.Get("concurrency")
+	if !ok {
+		return defaultValue
+	}
+	concurrency, err := conv.DefaultConv.Int64(value)
+	if err != nil || concurrency <= 0 {
+		return defaultValue
+	}
+	return concurrency
+}
+
+// GetTarget :
+func (c *RemoteWriteMetaClusterInfo) GetTarget() string {
+	return c.GetURL()
+}
+
+// AsRemoteWriteCluster :
+func (c *MetaClusterInfo) AsRemoteWriteCluster() *RemoteWriteMetaClusterInfo {
+	return &RemoteWriteMetaClusterInfo{
+		SimpleMetaClusterInfo: NewSimpleMetaClusterInfo(c),
+	}
+}
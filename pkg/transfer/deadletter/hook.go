@@ -0,0 +1,85 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package deadletter
+
+import (
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+const (
+	ConfKeyDeadLetterEnabled        = "dead_letter.enabled"
+	ConfKeyDeadLetterSink           = "dead_letter.sink"
+	ConfKeyDeadLetterSampleRate     = "dead_letter.sample_rate"
+	ConfKeyDeadLetterRateLimit      = "dead_letter.rate_limit"
+	ConfKeyDeadLetterMaxPayloadSize = "dead_letter.max_payload_size"
+	ConfKeyDeadLetterBufferSize     = "dead_letter.buffer_size"
+	ConfKeyDeadLetterBatchSize      = "dead_letter.batch_size"
+	ConfKeyDeadLetterFlushInterval  = "dead_letter.flush_interval"
+
+	ConfKeyDeadLetterFilePath         = "dead_letter.file.path"
+	ConfKeyDeadLetterFileMaxSize      = "dead_letter.file.max_size"
+	ConfKeyDeadLetterFileMaxOpenFiles = "dead_letter.file.max_open_files"
+	ConfKeyDeadLetterFileIdleTimeout  = "dead_letter.file.idle_timeout"
+
+	ConfKeyDeadLetterKafkaHosts   = "dead_letter.kafka.hosts"
+	ConfKeyDeadLetterKafkaTopic   = "dead_letter.kafka.topic"
+	ConfKeyDeadLetterKafkaVersion = "dead_letter.kafka.version"
+)
+
+func initConfiguration(c define.Configuration) {
+	c.SetDefault(ConfKeyDeadLetterEnabled, false)
+	c.SetDefault(ConfKeyDeadLetterSink, SinkFile)
+	c.SetDefault(ConfKeyDeadLetterSampleRate, 1.0)
+	c.SetDefault(ConfKeyDeadLetterRateLimit, 100)
+	c.SetDefault(ConfKeyDeadLetterMaxPayloadSize, 64*1024)
+	c.SetDefault(ConfKeyDeadLetterBufferSize, 10000)
+	c.SetDefault(ConfKeyDeadLetterBatchSize, 100)
+	c.SetDefault(ConfKeyDeadLetterFlushInterval, "1s")
+
+	c.SetDefault(ConfKeyDeadLetterFilePath, "dead_letter/{data_id}.log")
+	c.SetDefault(ConfKeyDeadLetterFileMaxSize, 100*1024*1024)
+	c.SetDefault(ConfKeyDeadLetterFileMaxOpenFiles, 128)
+	c.SetDefault(ConfKeyDeadLetterFileIdleTimeout, "5m")
+
+	c.SetDefault(ConfKeyDeadLetterKafkaHosts, []string{})
+	c.SetDefault(ConfKeyDeadLetterKafkaTopic, "bkmonitor_transfer_dead_letter")
+	c.SetDefault(ConfKeyDeadLetterKafkaVersion, "0.10.2.0")
+}
+
+func loadConfiguration(c define.Configuration) {
+	if !c.GetBool(ConfKeyDeadLetterEnabled) {
+		SetRouter(nil)
+		return
+	}
+
+	sink, err := NewSink(c.GetString(ConfKeyDeadLetterSink), c)
+	if err != nil {
+		logging.Errorf("create dead letter sink %s failed: %v", c.GetString(ConfKeyDeadLetterSink), err)
+		SetRouter(nil)
+		return
+	}
+
+	SetRouter(NewRouter(sink, &Options{
+		SampleRate:     c.GetFloat64(ConfKeyDeadLetterSampleRate),
+		RateLimit:      c.GetInt(ConfKeyDeadLetterRateLimit),
+		MaxPayloadSize: c.GetInt(ConfKeyDeadLetterMaxPayloadSize),
+		BufferSize:     c.GetInt(ConfKeyDeadLetterBufferSize),
+		BatchSize:      c.GetInt(ConfKeyDeadLetterBatchSize),
+		FlushInterval:  c.GetDuration(ConfKeyDeadLetterFlushInterval),
+	}))
+}
+
+func init() {
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPreParse, initConfiguration))
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPostParse, loadConfiguration))
+}
@@ -0,0 +1,319 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package deadletter
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cstockton/go-conv"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// 死信投递状态
+const (
+	StatusSent       = "sent"
+	StatusSampled    = "sampled"
+	StatusLimited    = "limited"
+	StatusBufferFull = "buffer_full"
+	StatusFailed     = "failed"
+)
+
+// MonitorDeadLetterTotal 死信投递计数器
+var MonitorDeadLetterTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: define.AppName,
+	Name:      "dead_letter_total",
+	Help:      "Dead letter payloads",
+}, []string{"id", "processor", "status"})
+
+// Letter : 被处理器拒绝的原始数据
+type Letter struct {
+	DataID        int       `json:"data_id"`
+	Processor     string    `json:"processor"`
+	Error         string    `json:"error"`
+	ConfigVersion string    `json:"config_version"`
+	Time          time.Time `json:"time"`
+	Payload       string    `json:"payload"`
+	PayloadSize   int       `json:"payload_size"`
+	Truncated     bool      `json:"truncated"`
+}
+
+// Options : 死信路由配置
+type Options struct {
+	// SampleRate 采样率，取值 (0, 1]
+	SampleRate float64
+	// RateLimit 单个 data_id 每秒最多投递的条数，小于等于 0 表示不限制
+	RateLimit int
+	// MaxPayloadSize 原始数据最大保留字节数，超出部分截断
+	MaxPayloadSize int
+	BufferSize     int
+	BatchSize      int
+	FlushInterval  time.Duration
+}
+
+// Router : 异步将死信批量写入 sink
+type Router struct {
+	sink    Sink
+	options *Options
+	letters chan *Letter
+	wg      sync.WaitGroup
+
+	limiterLock sync.Mutex
+	limiters    map[int]*rate.Limiter
+}
+
+// NewRouter :
+func NewRouter(sink Sink, options *Options) *Router {
+	if options.BufferSize <= 0 {
+		options.BufferSize = 1
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+
+	r := &Router{
+		sink:     sink,
+		options:  options,
+		letters:  make(chan *Letter, options.BufferSize),
+		limiters: make(map[int]*rate.Limiter),
+	}
+
+	r.wg.Add(1)
+	go r.loop()
+	return r
+}
+
+func (r *Router) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Letter, 0, r.options.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := r.sink.Write(batch)
+		for _, letter := range batch {
+			status := StatusSent
+			if err != nil {
+				status = StatusFailed
+			}
+			MonitorDeadLetterTotal.WithLabelValues(strconv.Itoa(letter.DataID), letter.Processor, status).Inc()
+		}
+		if err != nil {
+			logging.Errorf("write %d dead letters to %s failed: %v", len(batch), r.sink, err)
+		}
+		batch = make([]*Letter, 0, r.options.BatchSize)
+	}
+
+	for {
+		select {
+		case letter, ok := <-r.letters:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, letter)
+			if len(batch) >= r.options.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			r.evictIdle()
+		}
+	}
+}
+
+// idleEvictor : 持有本地资源的 sink 可实现该接口，由 router 定期回收空闲资源
+type idleEvictor interface {
+	EvictIdle() error
+}
+
+func (r *Router) evictIdle() {
+	evictor, ok := r.sink.(idleEvictor)
+	if !ok {
+		return
+	}
+	if err := evictor.EvictIdle(); err != nil {
+		logging.Warnf("evict idle resources of %s failed: %v", r.sink, err)
+	}
+}
+
+func (r *Router) allow(dataID int) bool {
+	if r.options.RateLimit <= 0 {
+		return true
+	}
+
+	r.limiterLock.Lock()
+	limiter, ok := r.limiters[dataID]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(r.options.RateLimit), r.options.RateLimit)
+		r.limiters[dataID] = limiter
+	}
+	r.limiterLock.Unlock()
+
+	return limiter.Allow()
+}
+
+// Route : 经过采样及限流后投递死信，缓冲区满时直接丢弃
+func (r *Router) Route(letter *Letter, sampleRate float64) {
+	if r.admit(letter.DataID, letter.Processor, sampleRate) {
+		r.enqueue(letter)
+	}
+}
+
+// admit : 采样及限流检查，在生成死信之前调用，避免为被丢弃的数据复制原始内容
+func (r *Router) admit(dataID int, processor string, sampleRate float64) bool {
+	id := strconv.Itoa(dataID)
+	if sampleRate < 1 && rand.Float64() >= sampleRate {
+		MonitorDeadLetterTotal.WithLabelValues(id, processor, StatusSampled).Inc()
+		return false
+	}
+
+	if !r.allow(dataID) {
+		MonitorDeadLetterTotal.WithLabelValues(id, processor, StatusLimited).Inc()
+		return false
+	}
+	return true
+}
+
+// enqueue : 写入缓冲区，缓冲区满时直接丢弃
+func (r *Router) enqueue(letter *Letter) {
+	select {
+	case r.letters <- letter:
+	default:
+		MonitorDeadLetterTotal.WithLabelValues(strconv.Itoa(letter.DataID), letter.Processor, StatusBufferFull).Inc()
+	}
+}
+
+// Close : 写出缓冲区中的死信并关闭 sink
+func (r *Router) Close() error {
+	close(r.letters)
+	r.wg.Wait()
+	return r.sink.Close()
+}
+
+// NewLetter : 根据处理失败的原始数据生成死信
+func (r *Router) NewLetter(d define.Payload, err error) *Letter {
+	var data []byte
+	if e := d.To(&data); e != nil {
+		data = []byte(fmt.Sprintf("%+v", d))
+	}
+
+	letter := &Letter{
+		Time:        time.Now(),
+		PayloadSize: len(data),
+	}
+	if err != nil {
+		letter.Error = err.Error()
+	}
+	if r.options.MaxPayloadSize > 0 && len(data) > r.options.MaxPayloadSize {
+		data = data[:r.options.MaxPayloadSize]
+		letter.Truncated = true
+	}
+	// payload 的内容可能被上游复用，这里复制一份
+	letter.Payload = string(data)
+
+	return letter
+}
+
+// ConfigVersion : 根据 pipeline 配置内容计算版本号
+func ConfigVersion(pipe *config.PipelineConfig) string {
+	data, err := json.Marshal(pipe)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha1.Sum(data))[:12]
+}
+
+var (
+	defaultRouter *Router
+	routerLock    sync.RWMutex
+)
+
+// SetRouter : 替换全局死信路由，旧路由会被关闭
+func SetRouter(r *Router) {
+	routerLock.Lock()
+	last := defaultRouter
+	defaultRouter = r
+	routerLock.Unlock()
+
+	if last != nil {
+		if err := last.Close(); err != nil {
+			logging.Warnf("close dead letter router failed: %v", err)
+		}
+	}
+}
+
+// NewHandler : 生成处理器使用的死信投递方法，data_id 关闭死信时返回 nil
+// 投递时使用当前的全局路由，配置重载后无需重建 pipeline
+func NewHandler(processor string, pipe *config.PipelineConfig) define.DeadLetterHandler {
+	if pipe == nil {
+		return nil
+	}
+
+	sampleRate := -1.0
+	helper := utils.NewMapHelper(pipe.Option)
+	if enabled, ok := helper.GetBool(config.PipelineConfigOptEnableDeadLetter); ok && !enabled {
+		return nil
+	}
+	if value, ok := helper.Get(config.PipelineConfigOptDeadLetterSampleRate); ok {
+		sampleRate = conv.Float64(value)
+	}
+
+	var (
+		dataID  = pipe.DataID
+		version = ConfigVersion(pipe)
+	)
+	return func(d define.Payload, err error) {
+		routerLock.RLock()
+		defer routerLock.RUnlock()
+
+		r := defaultRouter
+		if r == nil {
+			return
+		}
+
+		sample := sampleRate
+		if sample < 0 {
+			sample = r.options.SampleRate
+		}
+		// 先检查采样及限流，只为需要投递的数据复制原始内容
+		if !r.admit(dataID, processor, sample) {
+			return
+		}
+
+		letter := r.NewLetter(d, err)
+		letter.DataID = dataID
+		letter.Processor = processor
+		letter.ConfigVersion = version
+		r.enqueue(letter)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(MonitorDeadLetterTotal)
+}
@@ -0,0 +1,174 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package deadletter_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/deadletter"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+type memorySink struct {
+	lock    sync.Mutex
+	letters []*deadletter.Letter
+	closed  bool
+}
+
+func (s *memorySink) String() string { return "memory" }
+
+func (s *memorySink) Write(letters []*deadletter.Letter) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.letters = append(s.letters, letters...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.closed = true
+	return nil
+}
+
+// countingPayload 记录原始数据被读取的次数
+type countingPayload struct {
+	define.Payload
+	reads int
+}
+
+func (p *countingPayload) To(v interface{}) error {
+	p.reads++
+	return p.Payload.To(v)
+}
+
+// RouterSuite
+type RouterSuite struct {
+	suite.Suite
+	sink *memorySink
+	pipe *config.PipelineConfig
+}
+
+func (s *RouterSuite) SetupTest() {
+	s.sink = &memorySink{}
+	s.pipe = config.NewPipelineConfig()
+	s.pipe.DataID = 1001
+	s.pipe.ETLConfig = "bk_standard_v2_time_series"
+}
+
+func (s *RouterSuite) TearDownTest() {
+	deadletter.SetRouter(nil)
+}
+
+func (s *RouterSuite) newRouter(options *deadletter.Options) {
+	if options.FlushInterval == 0 {
+		options.FlushInterval = time.Hour
+	}
+	deadletter.SetRouter(deadletter.NewRouter(s.sink, options))
+}
+
+// TestHandler
+func (s *RouterSuite) TestHandler() {
+	s.newRouter(&deadletter.Options{SampleRate: 1, MaxPayloadSize: 8, BufferSize: 10, BatchSize: 10})
+
+	handler := deadletter.NewHandler("standard", s.pipe)
+	s.NotNil(handler)
+	handler(define.NewJSONPayloadFrom([]byte(`{"time": 1}`), 1), define.ErrValue)
+	handler(define.NewJSONPayloadFrom([]byte(`{}`), 2), nil)
+
+	// 关闭路由时会写出缓冲区中的死信
+	deadletter.SetRouter(nil)
+	s.True(s.sink.closed)
+	s.Len(s.sink.letters, 2)
+
+	letter := s.sink.letters[0]
+	s.Equal(1001, letter.DataID)
+	s.Equal("standard", letter.Processor)
+	s.Equal(define.ErrValue.Error(), letter.Error)
+	s.Equal(deadletter.ConfigVersion(s.pipe), letter.ConfigVersion)
+	s.Equal(`{"time":`, letter.Payload)
+	s.Equal(11, letter.PayloadSize)
+	s.True(letter.Truncated)
+
+	letter = s.sink.letters[1]
+	s.Equal("", letter.Error)
+	s.Equal(`{}`, letter.Payload)
+	s.False(letter.Truncated)
+
+	// 路由关闭后不再投递
+	handler(define.NewJSONPayloadFrom([]byte(`{}`), 3), define.ErrValue)
+	s.Len(s.sink.letters, 2)
+}
+
+// TestOptions
+func (s *RouterSuite) TestOptions() {
+	s.newRouter(&deadletter.Options{SampleRate: 1, BufferSize: 10, BatchSize: 10})
+
+	s.pipe.Option[config.PipelineConfigOptEnableDeadLetter] = false
+	s.Nil(deadletter.NewHandler("standard", s.pipe))
+
+	s.pipe.Option[config.PipelineConfigOptEnableDeadLetter] = true
+	s.pipe.Option[config.PipelineConfigOptDeadLetterSampleRate] = 0
+	handler := deadletter.NewHandler("standard", s.pipe)
+	// 被采样丢弃的数据不会读取原始内容
+	payload := &countingPayload{Payload: define.NewJSONPayloadFrom([]byte(`{}`), 0)}
+	for i := 0; i < 10; i++ {
+		handler(payload, define.ErrValue)
+	}
+	s.Equal(0, payload.reads)
+
+	deadletter.SetRouter(nil)
+	s.Len(s.sink.letters, 0)
+}
+
+// TestRateLimit
+func (s *RouterSuite) TestRateLimit() {
+	s.newRouter(&deadletter.Options{SampleRate: 1, RateLimit: 3, BufferSize: 100, BatchSize: 100})
+
+	handler := deadletter.NewHandler("standard", s.pipe)
+	for i := 0; i < 10; i++ {
+		handler(define.NewJSONPayloadFrom([]byte(`{}`), i), define.ErrValue)
+	}
+
+	deadletter.SetRouter(nil)
+	s.Len(s.sink.letters, 3)
+}
+
+// TestBufferFull
+func (s *RouterSuite) TestBufferFull() {
+	router := deadletter.NewRouter(s.sink, &deadletter.Options{SampleRate: 1, BufferSize: 1, BatchSize: 100, FlushInterval: time.Hour})
+
+	// 缓冲区满时直接丢弃，不阻塞调用方
+	for i := 0; i < 100; i++ {
+		router.Route(&deadletter.Letter{DataID: 1001, Processor: "standard"}, 1)
+	}
+	s.NoError(router.Close())
+	s.True(len(s.sink.letters) < 100)
+}
+
+// TestConfigVersion
+func (s *RouterSuite) TestConfigVersion() {
+	version := deadletter.ConfigVersion(s.pipe)
+	s.Len(version, 12)
+	s.Equal(version, deadletter.ConfigVersion(s.pipe))
+
+	s.pipe.ETLConfig = "bk_flat_batch"
+	s.NotEqual(version, deadletter.ConfigVersion(s.pipe))
+	s.False(strings.Contains(version, " "))
+}
+
+// TestRouterSuite
+func TestRouterSuite(t *testing.T) {
+	suite.Run(t, new(RouterSuite))
+}
@@ -0,0 +1,305 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package deadletter
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+)
+
+const (
+	SinkFile  = "file"
+	SinkKafka = "kafka"
+
+	// dataIDPlaceholder 文件路径及 topic 中的 data_id 占位符
+	dataIDPlaceholder = "{data_id}"
+)
+
+// Sink : 死信存储
+type Sink interface {
+	fmt.Stringer
+	Write(letters []*Letter) error
+	Close() error
+}
+
+// SinkCreator :
+type SinkCreator func(conf define.Configuration) (Sink, error)
+
+var sinkCreators = make(map[string]SinkCreator)
+
+// RegisterSink :
+func RegisterSink(name string, fn SinkCreator) {
+	sinkCreators[name] = fn
+}
+
+// NewSink :
+func NewSink(name string, conf define.Configuration) (Sink, error) {
+	fn, ok := sinkCreators[name]
+	if !ok {
+		return nil, errors.Wrapf(define.ErrItemNotFound, "dead letter sink %s", name)
+	}
+	return fn(conf)
+}
+
+func formatByDataID(template string, dataID int) string {
+	return strings.ReplaceAll(template, dataIDPlaceholder, strconv.Itoa(dataID))
+}
+
+// FileSink : 按 data_id 写入本地文件，每行一条 json，超过大小后轮转为 .1 文件
+// 打开的文件按 LRU 管理，超过 maxOpenFiles 或空闲超过 idleTimeout 的文件会被关闭，再次写入时以追加模式重新打开
+type FileSink struct {
+	lock         sync.Mutex
+	path         string
+	maxSize      int64
+	maxOpenFiles int
+	idleTimeout  time.Duration
+	files        map[string]*list.Element
+	lru          *list.List
+}
+
+type openedFile struct {
+	path     string
+	file     *os.File
+	lastUsed time.Time
+}
+
+// NewFileSink : maxOpenFiles 及 idleTimeout 小于等于 0 时不做对应限制
+func NewFileSink(path string, maxSize int64, maxOpenFiles int, idleTimeout time.Duration) *FileSink {
+	return &FileSink{
+		path:         path,
+		maxSize:      maxSize,
+		maxOpenFiles: maxOpenFiles,
+		idleTimeout:  idleTimeout,
+		files:        make(map[string]*list.Element),
+		lru:          list.New(),
+	}
+}
+
+// String :
+func (s *FileSink) String() string {
+	return fmt.Sprintf("%s:%s", SinkFile, s.path)
+}
+
+// OpenFiles : 当前打开的文件数
+func (s *FileSink) OpenFiles() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lru.Len()
+}
+
+func (s *FileSink) open(path string) (*os.File, error) {
+	if elem, ok := s.files[path]; ok {
+		opened := elem.Value.(*openedFile)
+		opened.lastUsed = time.Now()
+		s.lru.MoveToFront(elem)
+		return opened.file, nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先腾出位置再打开新文件，保证打开的文件数不超过限制
+	if s.maxOpenFiles > 0 {
+		for s.lru.Len() >= s.maxOpenFiles {
+			if err = s.closeElement(s.lru.Back()); err != nil {
+				logging.Warnf("close dead letter file failed: %v", err)
+			}
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.files[path] = s.lru.PushFront(&openedFile{path: path, file: f, lastUsed: time.Now()})
+	return f, nil
+}
+
+func (s *FileSink) closeElement(elem *list.Element) error {
+	opened := s.lru.Remove(elem).(*openedFile)
+	delete(s.files, opened.path)
+	return opened.file.Close()
+}
+
+func (s *FileSink) rotate(path string, f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.maxSize <= 0 || info.Size() < s.maxSize {
+		return nil
+	}
+
+	err = s.closeElement(s.files[path])
+	if err != nil {
+		return err
+	}
+	return os.Rename(path, path+".1")
+}
+
+// EvictIdle : 关闭空闲超过 idleTimeout 的文件
+func (s *FileSink) EvictIdle() error {
+	if s.idleTimeout <= 0 {
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var err error
+	deadline := time.Now().Add(-s.idleTimeout)
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		if elem.Value.(*openedFile).lastUsed.After(deadline) {
+			break
+		}
+		if e := s.closeElement(elem); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Write :
+func (s *FileSink) Write(letters []*Letter) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, letter := range letters {
+		data, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+
+		path := formatByDataID(s.path, letter.DataID)
+		f, err := s.open(path)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(data, '\n'))
+		if err != nil {
+			return err
+		}
+		err = s.rotate(path, f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close :
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var err error
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		if e := s.closeElement(elem); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// KafkaSink : 写入 kafka，topic 支持 data_id 占位符，消息以 data_id 作为 key
+type KafkaSink struct {
+	topic    string
+	producer sarama.SyncProducer
+}
+
+// NewKafkaSink :
+func NewKafkaSink(topic string, producer sarama.SyncProducer) *KafkaSink {
+	return &KafkaSink{
+		topic:    topic,
+		producer: producer,
+	}
+}
+
+// String :
+func (s *KafkaSink) String() string {
+	return fmt.Sprintf("%s:%s", SinkKafka, s.topic)
+}
+
+// Write :
+func (s *KafkaSink) Write(letters []*Letter) error {
+	messages := make([]*sarama.ProducerMessage, 0, len(letters))
+	for _, letter := range letters {
+		data, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, &sarama.ProducerMessage{
+			Topic: formatByDataID(s.topic, letter.DataID),
+			Key:   sarama.StringEncoder(strconv.Itoa(letter.DataID)),
+			Value: sarama.ByteEncoder(data),
+		})
+	}
+	return s.producer.SendMessages(messages)
+}
+
+// Close :
+func (s *KafkaSink) Close() error {
+	return s.producer.Close()
+}
+
+// NewSyncProducer : 单独提出来方便测试
+var NewSyncProducer = func(hosts []string, conf *sarama.Config) (sarama.SyncProducer, error) {
+	return sarama.NewSyncProducer(hosts, conf)
+}
+
+func init() {
+	RegisterSink(SinkFile, func(conf define.Configuration) (Sink, error) {
+		return NewFileSink(
+			conf.GetString(ConfKeyDeadLetterFilePath),
+			conf.GetInt64(ConfKeyDeadLetterFileMaxSize),
+			conf.GetInt(ConfKeyDeadLetterFileMaxOpenFiles),
+			conf.GetDuration(ConfKeyDeadLetterFileIdleTimeout),
+		), nil
+	})
+	RegisterSink(SinkKafka, func(conf define.Configuration) (Sink, error) {
+		hosts := conf.GetStringSlice(ConfKeyDeadLetterKafkaHosts)
+		if len(hosts) == 0 {
+			return nil, errors.Wrapf(define.ErrValue, "%s is empty", ConfKeyDeadLetterKafkaHosts)
+		}
+
+		version, err := sarama.ParseKafkaVersion(conf.GetString(ConfKeyDeadLetterKafkaVersion))
+		if err != nil {
+			return nil, err
+		}
+
+		c := sarama.NewConfig()
+		c.ClientID = define.ProcessID
+		c.Version = version
+		c.Producer.RequiredAcks = sarama.WaitForLocal
+		c.Producer.Return.Successes = true
+		c.Producer.Return.Errors = true
+
+		producer, err := NewSyncProducer(hosts, c)
+		if err != nil {
+			return nil, err
+		}
+		return NewKafkaSink(conf.GetString(ConfKeyDeadLetterKafkaTopic), producer), nil
+	})
+}
@@ -0,0 +1,145 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package deadletter_test
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/deadletter"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+)
+
+// SinkSuite
+type SinkSuite struct {
+	suite.Suite
+}
+
+// TestFileSink
+func (s *SinkSuite) TestFileSink() {
+	dir := s.T().TempDir()
+	sink := deadletter.NewFileSink(filepath.Join(dir, "{data_id}", "dead_letter.log"), 0, 0, 0)
+
+	s.NoError(sink.Write([]*deadletter.Letter{
+		{DataID: 1001, Processor: "standard", Payload: "a"},
+		{DataID: 1002, Processor: "standard", Payload: "b"},
+		{DataID: 1001, Processor: "standard", Payload: "c"},
+	}))
+	s.NoError(sink.Close())
+
+	f, err := os.Open(filepath.Join(dir, "1001", "dead_letter.log"))
+	s.NoError(err)
+	defer f.Close()
+
+	payloads := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var letter deadletter.Letter
+		s.NoError(json.Unmarshal(scanner.Bytes(), &letter))
+		s.Equal(1001, letter.DataID)
+		payloads = append(payloads, letter.Payload)
+	}
+	s.Equal([]string{"a", "c"}, payloads)
+
+	_, err = os.Stat(filepath.Join(dir, "1002", "dead_letter.log"))
+	s.NoError(err)
+}
+
+// TestFileSinkRotate
+func (s *SinkSuite) TestFileSinkRotate() {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "dead_letter.log")
+	sink := deadletter.NewFileSink(path, 10, 0, 0)
+
+	s.NoError(sink.Write([]*deadletter.Letter{{DataID: 1001, Payload: "a"}}))
+	s.NoError(sink.Write([]*deadletter.Letter{{DataID: 1001, Payload: "b"}}))
+	s.NoError(sink.Close())
+
+	_, err := os.Stat(path + ".1")
+	s.NoError(err)
+}
+
+// TestFileSinkEvict
+func (s *SinkSuite) TestFileSinkEvict() {
+	dir := s.T().TempDir()
+	path := filepath.Join(dir, "{data_id}.log")
+	sink := deadletter.NewFileSink(path, 0, 2, time.Hour)
+
+	s.NoError(sink.Write([]*deadletter.Letter{
+		{DataID: 1001, Payload: "a"},
+		{DataID: 1002, Payload: "b"},
+		{DataID: 1003, Payload: "c"},
+	}))
+	s.Equal(2, sink.OpenFiles())
+
+	// 被淘汰的文件再次写入时以追加模式重新打开
+	s.NoError(sink.Write([]*deadletter.Letter{{DataID: 1001, Payload: "d"}}))
+	s.Equal(2, sink.OpenFiles())
+	s.NoError(sink.EvictIdle())
+	s.Equal(2, sink.OpenFiles())
+	s.NoError(sink.Close())
+	s.Equal(0, sink.OpenFiles())
+
+	data, err := os.ReadFile(filepath.Join(dir, "1001.log"))
+	s.NoError(err)
+	s.Equal(2, strings.Count(string(data), "\n"))
+}
+
+// TestFileSinkEvictIdle
+func (s *SinkSuite) TestFileSinkEvictIdle() {
+	dir := s.T().TempDir()
+	sink := deadletter.NewFileSink(filepath.Join(dir, "{data_id}.log"), 0, 0, time.Nanosecond)
+
+	s.NoError(sink.Write([]*deadletter.Letter{{DataID: 1001}, {DataID: 1002}}))
+	s.Equal(2, sink.OpenFiles())
+	time.Sleep(time.Millisecond)
+	s.NoError(sink.EvictIdle())
+	s.Equal(0, sink.OpenFiles())
+	s.NoError(sink.Close())
+}
+
+// TestKafkaSink
+func (s *SinkSuite) TestKafkaSink() {
+	c := sarama.NewConfig()
+	c.Producer.Return.Successes = true
+	producer := mocks.NewSyncProducer(s.T(), c)
+
+	topics := make([]string, 0)
+	for i := 0; i < 2; i++ {
+		producer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(val []byte) error {
+			var letter deadletter.Letter
+			err := json.Unmarshal(val, &letter)
+			topics = append(topics, letter.Processor)
+			return err
+		})
+	}
+
+	sink := deadletter.NewKafkaSink("dead_letter_{data_id}", producer)
+	s.Equal("kafka:dead_letter_{data_id}", sink.String())
+	s.NoError(sink.Write([]*deadletter.Letter{
+		{DataID: 1001, Processor: "standard"},
+		{DataID: 1002, Processor: "flat"},
+	}))
+	s.Equal([]string{"standard", "flat"}, topics)
+	s.NoError(sink.Close())
+}
+
+// TestSinkSuite
+func TestSinkSuite(t *testing.T) {
+	suite.Run(t, new(SinkSuite))
+}
@@ -19,6 +19,19 @@ import (
 
 type ProcessorMonitor struct {
 	*monitor.CounterMixin
+	// DeadLetter 处理失败数据的死信投递，为空时仅计数
+	DeadLetter DeadLetterHandler
+}
+
+// DeadLetterHandler : 接收被处理器拒绝的原始数据及失败原因
+type DeadLetterHandler func(d Payload, err error)
+
+// Reject : 记录处理失败，并将原始数据投递到死信
+func (m *ProcessorMonitor) Reject(d Payload, err error) {
+	m.CounterFails.Inc()
+	if m.DeadLetter != nil && d != nil {
+		m.DeadLetter(d, err)
+	}
 }
 
 var (
@@ -15,10 +15,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"strconv"
+	"sync"
 	"time"
 
 	version "github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
@@ -36,6 +39,7 @@ type BulkHandler struct {
 	uniqueField   []string
 	flushInterval time.Duration
 	writer        BulkWriter
+	writerName    string
 	indexRender   IndexRenderFn
 	transformers  map[string]etl.TransformFn
 }
@@ -100,6 +104,12 @@ func (b *BulkHandler) flush(ctx context.Context, index string, records Records)
 	errs := utils.NewMultiErrors()
 	response, err := b.writer.Write(ctx, index, records)
 
+	code := "error"
+	if response != nil {
+		code = strconv.Itoa(response.StatusCode)
+	}
+	MonitorESBackendBulkRequests.WithLabelValues(b.resultTable.ResultTable, b.writerName, code).Inc()
+
 	var e error
 	var result []byte
 	if response != nil {
@@ -135,7 +145,7 @@ func (b *BulkHandler) flush(ctx context.Context, index string, records Records)
 			logging.MinuteErrorSampling(b.String(), msg)
 			var total int
 			for _, item := range writeResult.Items {
-				index := item.Index
+				index := item.Result()
 				if index.Error != nil {
 					total++
 					cause := index.Error.CausedBy
@@ -202,23 +212,85 @@ func (b *BulkHandler) Close() error {
 	return b.writer.Close()
 }
 
-// BulkHandler
-func NewBulkHandler(cluster *config.ElasticSearchMetaClusterInfo, table *config.MetaResultTableConfig, flushInterval time.Duration, uniqueFields []string, indexRender IndexRenderFn) (*BulkHandler, error) {
-	ver, err := version.NewVersion(cluster.GetVersion())
-	if err != nil {
-		return nil, err
+// detectedVersion : 集群版本探测结果
+type detectedVersion struct {
+	distribution string
+	version      string
+	at           time.Time
+}
+
+var (
+	detectedVersions   sync.Map // address -> detectedVersion
+	detectVersionGroup singleflight.Group
+)
+
+// detectClusterVersion : 按集群地址缓存探测结果，同一集群下的结果表共享一次探测
+func detectClusterVersion(ctx context.Context, address string, conf map[string]interface{}) (string, string, error) {
+	if value, ok := detectedVersions.Load(address); ok {
+		detected := value.(detectedVersion)
+		if time.Since(detected.at) < DetectVersionCacheTTL {
+			return detected.distribution, detected.version, nil
+		}
 	}
 
-	name := fmt.Sprintf("v%d", ver.Segments()[0])
-	logging.Infof("create elasticsearch writer %s by version %s", name, ver.String())
+	value, err, _ := detectVersionGroup.Do(address, func() (interface{}, error) {
+		detectCtx, cancel := context.WithTimeout(ctx, DetectVersionTimeout)
+		defer cancel()
 
+		distribution, versionName, err := DetectVersion(detectCtx, conf)
+		if err != nil {
+			return nil, err
+		}
+		detected := detectedVersion{distribution: distribution, version: versionName, at: time.Now()}
+		detectedVersions.Store(address, detected)
+		logging.Infof("detect %s cluster %s version %s", distribution, address, versionName)
+		return detected, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	detected := value.(detectedVersion)
+	return detected.distribution, detected.version, nil
+}
+
+// NewBulkHandler
+func NewBulkHandler(ctx context.Context, cluster *config.ElasticSearchMetaClusterInfo, table *config.MetaResultTableConfig, flushInterval time.Duration, uniqueFields []string, indexRender IndexRenderFn) (*BulkHandler, error) {
 	authConf := utils.NewMapHelper(cluster.AuthInfo)
-	writer, err := NewBulkWriter(name, map[string]interface{}{
+	writerConf := map[string]interface{}{
 		"Addresses": []string{cluster.GetAddress()},
 		"Username":  authConf.GetOrDefault("username", ""),
 		"Password":  authConf.GetOrDefault("password", ""),
 		"Transport": DefaultTransport,
-	})
+	}
+
+	distribution, versionName := cluster.GetDistribution(), cluster.GetVersion()
+	// 版本配置为 auto 时，通过集群信息接口探测发行版及版本号，探测失败时返回错误由上层重建 backend 时重试
+	if versionName == config.ElasticSearchVersionAuto {
+		var err error
+		distribution, versionName, err = detectClusterVersion(ctx, cluster.GetAddress(), writerConf)
+		if err != nil {
+			MonitorESBackendVersionDetect.WithLabelValues(table.ResultTable, distribution, "failed").Inc()
+			return nil, errors.WithMessagef(err, "detect version of %s", cluster.GetAddress())
+		}
+		MonitorESBackendVersionDetect.WithLabelValues(table.ResultTable, distribution, "success").Inc()
+	}
+
+	ver, err := version.NewVersion(versionName)
+	if err != nil {
+		return nil, err
+	}
+
+	name := WriterName(distribution, ver.Segments()[0])
+	// data stream 仅在 ES8 及 OpenSearch 的 writer 中支持
+	if cluster.IsDataStream() {
+		if name != config.OpenSearchDistribution && ver.Segments()[0] < 8 {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "data stream is not supported by writer %s", name)
+		}
+		writerConf["DataStream"] = true
+	}
+	logging.Infof("create elasticsearch writer %s by %s version %s", name, distribution, ver.String())
+
+	writer, err := NewBulkWriter(name, writerConf)
 	if err != nil {
 		return nil, err
 	}
@@ -244,6 +316,7 @@ func NewBulkHandler(cluster *config.ElasticSearchMetaClusterInfo, table *config.
 		resultTable:   table,
 		flushInterval: flushInterval,
 		writer:        writer,
+		writerName:    name,
 		uniqueField:   uniqueFields,
 		indexRender:   indexRender,
 		transformers:  transformers,
@@ -274,12 +347,19 @@ func NewBackend(ctx context.Context, name string, maxQps int) (define.Backend, e
 	clusterConf := utils.NewMapHelper(cluster.ClusterConfig)
 	clusterConf.SetDefault("version", conf.GetString(ConfKeyDefaultVersion))
 
-	fn, err := ConfigTemplateRender(cluster)
-	if err != nil {
-		return nil, err
+	var fn IndexRenderFn
+	if cluster.IsDataStream() {
+		// data stream 自行按时间滚动 backing index，直接写入固定的名称
+		fn = FixedIndexRender(cluster.GetIndex())
+	} else {
+		var err error
+		fn, err = ConfigTemplateRender(cluster)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	bulk, err := NewBulkHandler(cluster, resultTable, flushInterval, uniqueFields, fn)
+	bulk, err := NewBulkHandler(ctx, cluster, resultTable, flushInterval, uniqueFields, fn)
 	if err != nil {
 		return nil, err
 	}
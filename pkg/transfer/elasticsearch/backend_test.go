@@ -67,7 +67,118 @@ func (s *BulkHandlerSuite) TestNew() {
 		return s.mockBulkWriter, nil
 	}
 	cluster := s.ShipperConfig.AsElasticSearchCluster()
-	handler, err := elasticsearch.NewBulkHandler(cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	handler, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	s.NoError(err)
+	s.NotNil(handler)
+}
+
+// TestNewAutoVersion
+func (s *BulkHandlerSuite) TestNewAutoVersion() {
+	detectVersion := elasticsearch.DetectVersion
+	defer func() {
+		elasticsearch.DetectVersion = detectVersion
+	}()
+	elasticsearch.DetectVersion = func(ctx context.Context, conf map[string]interface{}) (string, string, error) {
+		s.Equal([]string{"http://127.0.0.1:9200"}, conf["Addresses"])
+		return config.OpenSearchDistribution, "2.11.0", nil
+	}
+	elasticsearch.NewBulkWriter = func(version string, config map[string]interface{}) (writer elasticsearch.BulkWriter, e error) {
+		s.Equal("opensearch", version)
+		return s.mockBulkWriter, nil
+	}
+
+	cluster := s.ShipperConfig.AsElasticSearchCluster()
+	cluster.SetVersion(config.ElasticSearchVersionAuto)
+	handler, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	s.NoError(err)
+	s.NotNil(handler)
+}
+
+// TestNewAutoVersionFailed
+func (s *BulkHandlerSuite) TestNewAutoVersionFailed() {
+	detectVersion, timeout, ttl := elasticsearch.DetectVersion, elasticsearch.DetectVersionTimeout, elasticsearch.DetectVersionCacheTTL
+	defer func() {
+		elasticsearch.DetectVersion = detectVersion
+		elasticsearch.DetectVersionTimeout = timeout
+		elasticsearch.DetectVersionCacheTTL = ttl
+	}()
+	elasticsearch.DetectVersionTimeout = 10 * time.Millisecond
+	elasticsearch.DetectVersionCacheTTL = 0
+	elasticsearch.NewBulkWriter = func(version string, config map[string]interface{}) (writer elasticsearch.BulkWriter, e error) {
+		s.Fail("writer should not be created")
+		return s.mockBulkWriter, nil
+	}
+
+	cluster := s.ShipperConfig.AsElasticSearchCluster()
+	cluster.SetVersion(config.ElasticSearchVersionAuto)
+
+	// 探测超时及失败时均返回错误，不回退到默认版本
+	elasticsearch.DetectVersion = func(ctx context.Context, conf map[string]interface{}) (string, string, error) {
+		<-ctx.Done()
+		return "", "", ctx.Err()
+	}
+	_, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	s.Error(err)
+
+	elasticsearch.DetectVersion = func(ctx context.Context, conf map[string]interface{}) (string, string, error) {
+		return "", "", define.ErrOperationForbidden
+	}
+	_, err = elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	s.Error(err)
+}
+
+// TestNewAutoVersionCache
+func (s *BulkHandlerSuite) TestNewAutoVersionCache() {
+	detectVersion := elasticsearch.DetectVersion
+	defer func() {
+		elasticsearch.DetectVersion = detectVersion
+	}()
+
+	var calls int
+	elasticsearch.DetectVersion = func(ctx context.Context, conf map[string]interface{}) (string, string, error) {
+		calls++
+		if calls == 1 {
+			return "", "", define.ErrOperationForbidden
+		}
+		return config.ElasticSearchDistribution, "8.11.0", nil
+	}
+	elasticsearch.NewBulkWriter = func(version string, config map[string]interface{}) (writer elasticsearch.BulkWriter, e error) {
+		s.Equal("v8", version)
+		return s.mockBulkWriter, nil
+	}
+
+	cluster := s.ShipperConfig.AsElasticSearchCluster()
+	cluster.SetDomain("es-version-cache.local")
+	cluster.SetVersion(config.ElasticSearchVersionAuto)
+
+	// 失败的结果不缓存，成功后同一集群不再重复探测
+	_, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	s.Error(err)
+	for i := 0; i < 3; i++ {
+		handler, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+		s.NoError(err)
+		s.NotNil(handler)
+	}
+	s.Equal(2, calls)
+}
+
+// TestNewDataStream
+func (s *BulkHandlerSuite) TestNewDataStream() {
+	cluster := s.ShipperConfig.AsElasticSearchCluster()
+	cluster.StorageConfigHelper.Set("data_stream", true)
+
+	// v7 及以下版本不支持 data stream
+	cluster.SetVersion("7.10.0")
+	_, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	s.Error(err)
+
+	cluster.SetVersion("8.11.0")
+	elasticsearch.NewBulkWriter = func(version string, config map[string]interface{}) (writer elasticsearch.BulkWriter, e error) {
+		s.Equal("v8", version)
+		s.Equal(true, config["DataStream"])
+		return s.mockBulkWriter, nil
+	}
+	handler, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
 	s.NoError(err)
 	s.NotNil(handler)
 }
@@ -96,7 +207,7 @@ func (s *BulkHandlerSuite) TestFormatTime() {
 	)
 
 	cluster := s.ShipperConfig.AsElasticSearchCluster()
-	handler, err := elasticsearch.NewBulkHandler(cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
+	handler, err := elasticsearch.NewBulkHandler(context.Background(), cluster, s.ResultTableConfig, time.Second, nil, s.indexRender)
 	s.NoError(err)
 
 	now := time.Now()
@@ -12,6 +12,7 @@ package elasticsearch
 import (
 	"io"
 	"net/http"
+	"time"
 )
 
 // ESWriter :
@@ -37,3 +38,9 @@ func NewESWriter(transport Transport) *ESWriter {
 
 // DefaultTransport
 var DefaultTransport = http.DefaultTransport
+
+// DetectVersionTimeout : 探测集群版本的超时时间
+var DetectVersionTimeout = 10 * time.Second
+
+// DetectVersionCacheTTL : 集群版本探测结果的缓存时间
+var DetectVersionCacheTTL = time.Hour
@@ -79,17 +79,32 @@ type ESWriteResultError struct {
 	} `json:"caused_by"`
 }
 
+// ESWriteResultItem
+type ESWriteResultItem struct {
+	Index  string              `json:"_index"`
+	Type   string              `json:"_type"`
+	ID     string              `json:"_id"`
+	Status int                 `json:"status"`
+	Error  *ESWriteResultError `json:"error"`
+}
+
+// ESWriteResultItems
+type ESWriteResultItems struct {
+	Index  ESWriteResultItem `json:"index"`
+	Create ESWriteResultItem `json:"create"`
+}
+
+// Result : 写入 data stream 时使用 create 操作，结果在 create 中
+func (i *ESWriteResultItems) Result() ESWriteResultItem {
+	if i.Create.Status != 0 {
+		return i.Create
+	}
+	return i.Index
+}
+
 // ESWriteResult
 type ESWriteResult struct {
-	Took   int  `json:"took"`
-	Errors bool `json:"errors"`
-	Items  []struct {
-		Index struct {
-			Index  string              `json:"_index"`
-			Type   string              `json:"_type"`
-			ID     string              `json:"_id"`
-			Status int                 `json:"status"`
-			Error  *ESWriteResultError `json:"error"`
-		} `json:"index"`
-	} `json:"items"`
+	Took   int                  `json:"took"`
+	Errors bool                 `json:"errors"`
+	Items  []ESWriteResultItems `json:"items"`
 }
@@ -30,6 +30,7 @@ const (
 	ConfKeyExpectContinueTimeout = "elasticsearch.net.expect_continue_timeout"
 	ConfKeyDialTimeout           = "elasticsearch.net.dial_timeout"
 	ConfKeyDialKeepAlive         = "elasticsearch.net.dial_keep_alive_period"
+	ConfKeyDetectVersionTimeout  = "elasticsearch.detect_version_timeout"
 )
 
 func initConfiguration(c define.Configuration) {
@@ -41,6 +42,7 @@ func initConfiguration(c define.Configuration) {
 	c.SetDefault(ConfKeyExpectContinueTimeout, 1*time.Second)
 	c.SetDefault(ConfKeyDialTimeout, 30*time.Second)
 	c.SetDefault(ConfKeyDialKeepAlive, time.Hour)
+	c.SetDefault(ConfKeyDetectVersionTimeout, 10*time.Second)
 
 	c.RegisterAlias("elasticsearch.backend.channel_size", pipeline.ConfKeyPipelineChannelSize)
 	c.RegisterAlias("elasticsearch.backend.wait_delay", pipeline.ConfKeyPipelineFrontendWaitDelay)
@@ -63,6 +65,7 @@ func readConfiguration(c define.Configuration) {
 		ExpectContinueTimeout: c.GetDuration(ConfKeyExpectContinueTimeout),
 		DialContext:           dialer.DialContext,
 	}
+	DetectVersionTimeout = c.GetDuration(ConfKeyDetectVersionTimeout)
 }
 
 func init() {
@@ -0,0 +1,354 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+)
+
+// DataStreamTimestampField : data stream 要求文档中必须包含该时间字段
+const DataStreamTimestampField = "@timestamp"
+
+// HTTPTransport : 直接基于 http 请求集群，多个地址轮询使用
+type HTTPTransport struct {
+	addresses []*url.URL
+	username  string
+	password  string
+	headers   http.Header
+	client    *http.Client
+	next      uint32
+}
+
+// Perform :
+func (t *HTTPTransport) Perform(req *http.Request) (*http.Response, error) {
+	u := t.addresses[int(atomic.AddUint32(&t.next, 1))%len(t.addresses)]
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	if u.Path != "" && u.Path != "/" {
+		req.URL.Path = path.Join(u.Path, req.URL.Path)
+	}
+
+	for key, values := range t.headers {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	return t.client.Do(req)
+}
+
+// HTTPTransportConfig :
+type HTTPTransportConfig struct {
+	Addresses []string
+	Username  string
+	Password  string
+	Transport http.RoundTripper
+	Header    http.Header
+}
+
+// NewHTTPTransport :
+func NewHTTPTransport(config map[string]interface{}) (*HTTPTransport, error) {
+	var c HTTPTransportConfig
+	err := ApplyFields(&c, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Addresses) == 0 {
+		return nil, errors.Wrapf(define.ErrValue, "addresses is empty")
+	}
+
+	addresses := make([]*url.URL, 0, len(c.Addresses))
+	for _, address := range c.Addresses {
+		u, err := url.Parse(strings.TrimRight(address, "/"))
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, u)
+	}
+
+	transport := c.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+
+	return &HTTPTransport{
+		addresses: addresses,
+		username:  c.Username,
+		password:  c.Password,
+		headers:   c.Header,
+		client:    &http.Client{Transport: transport},
+	}, nil
+}
+
+// HTTPWriter : ES8 及 OpenSearch 写入，均已不再支持 _type
+type HTTPWriter struct {
+	*ESWriter
+	contentType string
+	dataStream  bool
+	// 已确认存在的 data stream
+	dataStreams sync.Map
+}
+
+// perform : 发送请求并读取完整的返回内容
+func (w *HTTPWriter) perform(ctx context.Context, method, path string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, path, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	response, err := w.transport.Perform(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		logging.WarnIf("close response error", response.Body.Close())
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return response.StatusCode, body, nil
+}
+
+// IndexTemplates : GET /_index_template 的返回
+type IndexTemplates struct {
+	IndexTemplates []struct {
+		Name          string `json:"name"`
+		IndexTemplate struct {
+			IndexPatterns []string               `json:"index_patterns"`
+			DataStream    map[string]interface{} `json:"data_stream"`
+			Priority      int                    `json:"priority"`
+		} `json:"index_template"`
+	} `json:"index_templates"`
+}
+
+// checkDataStreamTemplate : data stream 依赖预先创建的开启了 data_stream 的 index template，
+// transfer 不负责维护模板（mapping 及生命周期由元数据侧管理），这里按优先级找到命中的模板进行校验
+func (w *HTTPWriter) checkDataStreamTemplate(ctx context.Context, name string) error {
+	code, body, err := w.perform(ctx, http.MethodGet, "/_index_template")
+	if err != nil {
+		return err
+	}
+	if code > 299 {
+		return errors.Wrapf(define.ErrOperationForbidden, "get index template response %d, %s", code, body)
+	}
+
+	var templates IndexTemplates
+	if err = json.Unmarshal(body, &templates); err != nil {
+		return err
+	}
+
+	var (
+		matched    string
+		priority   = -1
+		dataStream bool
+	)
+	for _, template := range templates.IndexTemplates {
+		for _, pattern := range template.IndexTemplate.IndexPatterns {
+			if ok, _ := path.Match(pattern, name); ok && template.IndexTemplate.Priority > priority {
+				matched, priority = template.Name, template.IndexTemplate.Priority
+				dataStream = template.IndexTemplate.DataStream != nil
+			}
+		}
+	}
+
+	if matched == "" {
+		return errors.Wrapf(define.ErrItemNotFound, "index template of data stream %s", name)
+	}
+	if !dataStream {
+		return errors.Wrapf(define.ErrOperationForbidden, "index template %s of %s is not enabled data_stream", matched, name)
+	}
+	return nil
+}
+
+// ensureDataStream : 校验 index template 后创建 data stream，已存在时忽略
+func (w *HTTPWriter) ensureDataStream(ctx context.Context, name string) error {
+	if _, ok := w.dataStreams.Load(name); ok {
+		return nil
+	}
+
+	if err := w.checkDataStreamTemplate(ctx, name); err != nil {
+		return err
+	}
+
+	code, body, err := w.perform(ctx, http.MethodPut, "/_data_stream/"+url.PathEscape(name))
+	if err != nil {
+		return err
+	}
+	if code > 299 && !strings.Contains(string(body), "resource_already_exists_exception") {
+		return errors.Wrapf(define.ErrOperationForbidden, "create data stream %s response %d, %s", name, code, body)
+	}
+
+	logging.Infof("data stream %s is ready", name)
+	w.dataStreams.Store(name, struct{}{})
+	return nil
+}
+
+// Write :
+func (w *HTTPWriter) Write(ctx context.Context, index string, records Records) (*Response, error) {
+	action := BulkActionIndex
+	if w.dataStream {
+		action = BulkActionCreate
+		if err := w.ensureDataStream(ctx, index); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, record := range records {
+		delete(record.Meta, "_type")
+		if !w.dataStream {
+			continue
+		}
+		if document, ok := record.Document.(map[string]interface{}); ok {
+			if _, exists := document[DataStreamTimestampField]; !exists {
+				document[DataStreamTimestampField] = document[define.TimeFieldName]
+			}
+		}
+	}
+
+	body, err := records.AsBodyWithAction(action)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_bulk", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", w.contentType)
+
+	response, err := w.transport.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       response.Body,
+	}, nil
+}
+
+// NewHTTPWriter :
+func NewHTTPWriter(config map[string]interface{}, header http.Header, contentType string) (*HTTPWriter, error) {
+	conf := make(map[string]interface{}, len(config)+1)
+	for key, value := range config {
+		conf[key] = value
+	}
+	conf["Header"] = header
+
+	transport, err := NewHTTPTransport(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	dataStream, _ := config["DataStream"].(bool)
+	return &HTTPWriter{
+		ESWriter:    NewESWriter(transport),
+		contentType: contentType,
+		dataStream:  dataStream,
+	}, nil
+}
+
+// NewESv8Writer : 使用 ES8 的兼容性请求头
+func NewESv8Writer(config map[string]interface{}) (BulkWriter, error) {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.elasticsearch+json;compatible-with=8")
+	return NewHTTPWriter(config, header, "application/vnd.elasticsearch+x-ndjson;compatible-with=8")
+}
+
+// NewOpenSearchWriter :
+func NewOpenSearchWriter(config map[string]interface{}) (BulkWriter, error) {
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	return NewHTTPWriter(config, header, "application/x-ndjson")
+}
+
+// ClusterInfo : 集群信息接口 GET / 的返回
+type ClusterInfo struct {
+	Version struct {
+		Number       string `json:"number"`
+		Distribution string `json:"distribution"`
+	} `json:"version"`
+}
+
+// DetectVersion : 通过集群信息接口获取发行版及版本号
+var DetectVersion = func(ctx context.Context, conf map[string]interface{}) (distribution string, version string, err error) {
+	transport, err := NewHTTPTransport(conf)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return "", "", err
+	}
+	response, err := transport.Perform(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		logging.WarnIf("close response error", response.Body.Close())
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if response.StatusCode > 299 {
+		return "", "", errors.Wrapf(define.ErrOperationForbidden, "cluster info response %d, %s", response.StatusCode, body)
+	}
+
+	var info ClusterInfo
+	err = json.Unmarshal(body, &info)
+	if err != nil {
+		return "", "", err
+	}
+	if info.Version.Number == "" {
+		return "", "", errors.Wrapf(define.ErrValue, "version not found in %s", body)
+	}
+
+	distribution = info.Version.Distribution
+	if distribution == "" {
+		distribution = config.ElasticSearchDistribution
+	}
+	return distribution, info.Version.Number, nil
+}
+
+// WriterName : 根据发行版及版本号获取 writer 名称
+func WriterName(distribution string, major int) string {
+	if distribution == config.OpenSearchDistribution {
+		return config.OpenSearchDistribution
+	}
+	return fmt.Sprintf("v%d", major)
+}
+
+func init() {
+	RegisterBulkWriter("v8", NewESv8Writer)
+	RegisterBulkWriter(config.OpenSearchDistribution, NewOpenSearchWriter)
+}
@@ -0,0 +1,310 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package elasticsearch_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/elasticsearch"
+)
+
+// HTTPWriterSuite
+type HTTPWriterSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	handler  http.HandlerFunc
+	requests []*http.Request
+	bodies   []string
+}
+
+// SetupTest
+func (s *HTTPWriterSuite) SetupTest() {
+	s.requests = nil
+	s.bodies = nil
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, string(body))
+		s.handler(w, r)
+	}))
+}
+
+// TearDownTest
+func (s *HTTPWriterSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *HTTPWriterSuite) writerConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"Addresses": []string{s.server.URL},
+		"Username":  "admin",
+		"Password":  "secret",
+		"Transport": http.DefaultTransport,
+	}
+}
+
+func (s *HTTPWriterSuite) makeRecords() elasticsearch.Records {
+	record := elasticsearch.NewRecord(map[string]interface{}{
+		"log":                "hello",
+		define.TimeFieldName: "2022-01-01T00:00:00Z",
+	})
+	record.SetID("1")
+	record.SetType("rt")
+	return elasticsearch.Records{record}
+}
+
+func (s *HTTPWriterSuite) decodeBulk(body string) []map[string]interface{} {
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		s.NoError(json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// TestV8Write
+func (s *HTTPWriterSuite) TestV8Write() {
+	writer, err := elasticsearch.NewBulkWriter("v8", s.writerConfig())
+	s.NoError(err)
+
+	response, err := writer.Write(context.Background(), "index_1", s.makeRecords())
+	s.NoError(err)
+	s.Equal(http.StatusOK, response.StatusCode)
+	s.NoError(response.Body.Close())
+
+	s.Len(s.requests, 1)
+	req := s.requests[0]
+	s.Equal(http.MethodPost, req.Method)
+	s.Equal("/index_1/_bulk", req.URL.Path)
+	s.Equal("application/vnd.elasticsearch+x-ndjson;compatible-with=8", req.Header.Get("Content-Type"))
+	s.Equal("application/vnd.elasticsearch+json;compatible-with=8", req.Header.Get("Accept"))
+	username, password, ok := req.BasicAuth()
+	s.True(ok)
+	s.Equal("admin", username)
+	s.Equal("secret", password)
+
+	lines := s.decodeBulk(s.bodies[0])
+	s.Len(lines, 2)
+	s.Equal(map[string]interface{}{"index": map[string]interface{}{"_id": "1"}}, lines[0])
+	s.Equal("hello", lines[1]["log"])
+	s.NotContains(lines[1], elasticsearch.DataStreamTimestampField)
+}
+
+// TestOpenSearchWrite
+func (s *HTTPWriterSuite) TestOpenSearchWrite() {
+	writer, err := elasticsearch.NewBulkWriter(config.OpenSearchDistribution, s.writerConfig())
+	s.NoError(err)
+
+	response, err := writer.Write(context.Background(), "index_1", s.makeRecords())
+	s.NoError(err)
+	s.NoError(response.Body.Close())
+
+	s.Len(s.requests, 1)
+	s.Equal("application/x-ndjson", s.requests[0].Header.Get("Content-Type"))
+	s.Equal("application/json", s.requests[0].Header.Get("Accept"))
+	s.NotContains(s.bodies[0], "_type")
+}
+
+const dataStreamTemplates = `{"index_templates":[
+	{"name":"logs","index_template":{"index_patterns":["logs-*"],"priority":100,"data_stream":{}}},
+	{"name":"logs-legacy","index_template":{"index_patterns":["logs-legacy-*"],"priority":200}}
+]}`
+
+// TestDataStream
+func (s *HTTPWriterSuite) TestDataStream() {
+	var created int32
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte(dataStreamTemplates))
+		case http.MethodPut:
+			// 首次创建成功后，模拟其他实例已创建的情况
+			if atomic.AddInt32(&created, 1) > 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":{"type":"resource_already_exists_exception"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+		}
+	}
+
+	conf := s.writerConfig()
+	conf["DataStream"] = true
+	writer, err := elasticsearch.NewBulkWriter("v8", conf)
+	s.NoError(err)
+
+	for i := 0; i < 2; i++ {
+		response, err := writer.Write(context.Background(), "logs-app", s.makeRecords())
+		s.NoError(err)
+		s.NoError(response.Body.Close())
+	}
+
+	// 先校验模板，data stream 只创建一次
+	s.Len(s.requests, 4)
+	s.Equal(http.MethodGet, s.requests[0].Method)
+	s.Equal("/_index_template", s.requests[0].URL.Path)
+	s.Equal(http.MethodPut, s.requests[1].Method)
+	s.Equal("/_data_stream/logs-app", s.requests[1].URL.Path)
+	s.Equal(int32(1), atomic.LoadInt32(&created))
+
+	lines := s.decodeBulk(s.bodies[2])
+	s.Len(lines, 2)
+	s.Contains(lines[0], elasticsearch.BulkActionCreate)
+	s.Equal("2022-01-01T00:00:00Z", lines[1][elasticsearch.DataStreamTimestampField])
+
+	// 已存在的 data stream 不视为错误
+	writer, err = elasticsearch.NewBulkWriter(config.OpenSearchDistribution, conf)
+	s.NoError(err)
+	response, err := writer.Write(context.Background(), "logs-app", s.makeRecords())
+	s.NoError(err)
+	s.NoError(response.Body.Close())
+	s.Equal(int32(2), atomic.LoadInt32(&created))
+}
+
+// TestDataStreamTemplateMissing
+func (s *HTTPWriterSuite) TestDataStreamTemplateMissing() {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(dataStreamTemplates))
+	}
+
+	conf := s.writerConfig()
+	conf["DataStream"] = true
+	writer, err := elasticsearch.NewBulkWriter("v8", conf)
+	s.NoError(err)
+
+	// 没有命中的模板
+	_, err = writer.Write(context.Background(), "metrics-app", s.makeRecords())
+	s.Error(err)
+	// 命中优先级更高但未开启 data_stream 的模板
+	_, err = writer.Write(context.Background(), "logs-legacy-app", s.makeRecords())
+	s.Error(err)
+
+	s.Len(s.requests, 2)
+	for _, req := range s.requests {
+		s.Equal(http.MethodGet, req.Method)
+	}
+}
+
+// TestDataStreamCreateFailed
+func (s *HTTPWriterSuite) TestDataStreamCreateFailed() {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(dataStreamTemplates))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"type":"illegal_argument_exception"}}`))
+	}
+
+	conf := s.writerConfig()
+	conf["DataStream"] = true
+	writer, err := elasticsearch.NewBulkWriter("v8", conf)
+	s.NoError(err)
+
+	_, err = writer.Write(context.Background(), "logs-app", s.makeRecords())
+	s.Error(err)
+	s.Len(s.requests, 2)
+}
+
+// TestDetectVersion
+func (s *HTTPWriterSuite) TestDetectVersion() {
+	cases := []struct {
+		response     string
+		distribution string
+		version      string
+		writer       string
+	}{
+		{`{"version":{"number":"8.11.1","build_flavor":"default"}}`, config.ElasticSearchDistribution, "8.11.1", "v8"},
+		{`{"version":{"number":"7.10.2","distribution":"opensearch"}}`, config.OpenSearchDistribution, "7.10.2", config.OpenSearchDistribution},
+		{`{"version":{"number":"2.11.0","distribution":"opensearch"}}`, config.OpenSearchDistribution, "2.11.0", config.OpenSearchDistribution},
+		{`{"version":{"number":"7.17.0"}}`, config.ElasticSearchDistribution, "7.17.0", "v7"},
+	}
+
+	for _, c := range cases {
+		response := c.response
+		s.handler = func(w http.ResponseWriter, r *http.Request) {
+			s.Equal("/", r.URL.Path)
+			_, _ = w.Write([]byte(response))
+		}
+		distribution, version, err := elasticsearch.DetectVersion(context.Background(), s.writerConfig())
+		s.NoError(err)
+		s.Equal(c.distribution, distribution)
+		s.Equal(c.version, version)
+
+		major := int(version[0] - '0')
+		s.Equal(c.writer, elasticsearch.WriterName(distribution, major))
+	}
+}
+
+// TestDetectVersionFailed
+func (s *HTTPWriterSuite) TestDetectVersionFailed() {
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	_, _, err := elasticsearch.DetectVersion(context.Background(), s.writerConfig())
+	s.Error(err)
+
+	s.handler = func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"name":"node"}`))
+	}
+	_, _, err = elasticsearch.DetectVersion(context.Background(), s.writerConfig())
+	s.Error(err)
+}
+
+// TestRoundRobin
+func (s *HTTPWriterSuite) TestRoundRobin() {
+	var hits int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		_, _ = w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer other.Close()
+
+	conf := s.writerConfig()
+	conf["Addresses"] = []string{s.server.URL, other.URL + "/"}
+	writer, err := elasticsearch.NewBulkWriter("v8", conf)
+	s.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 4; i++ {
+		response, err := writer.Write(ctx, "index_1", s.makeRecords())
+		s.NoError(err)
+		s.NoError(response.Body.Close())
+	}
+	s.Len(s.requests, 2)
+	s.Equal(int32(2), atomic.LoadInt32(&hits))
+}
+
+// TestHTTPWriterSuite
+func TestHTTPWriterSuite(t *testing.T) {
+	suite.Run(t, new(HTTPWriterSuite))
+}
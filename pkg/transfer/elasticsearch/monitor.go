@@ -21,8 +21,22 @@ var MonitorESBackendPartialWrite = prometheus.NewCounterVec(prometheus.CounterOp
 	Help:      "es backend partial write operation",
 }, []string{"base_index"})
 
+var MonitorESBackendBulkRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: define.AppName,
+	Name:      "es_backend_bulk_requests_total",
+	Help:      "es backend bulk requests by writer and status code",
+}, []string{"base_index", "writer", "code"})
+
+var MonitorESBackendVersionDetect = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: define.AppName,
+	Name:      "es_backend_version_detect_total",
+	Help:      "es backend cluster version detect result",
+}, []string{"base_index", "distribution", "status"})
+
 func init() {
 	prometheus.MustRegister(
 		MonitorESBackendPartialWrite,
+		MonitorESBackendBulkRequests,
+		MonitorESBackendVersionDetect,
 	)
 }
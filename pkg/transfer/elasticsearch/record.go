@@ -16,16 +16,27 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
 )
 
+// bulk 操作类型
+const (
+	BulkActionIndex  = "index"
+	BulkActionCreate = "create"
+)
+
 // Records
 type Records []*Record
 
 // ASBody
 func (r Records) AsBody() (io.Reader, error) {
+	return r.AsBodyWithAction(BulkActionIndex)
+}
+
+// AsBodyWithAction : data stream 只支持 create 操作
+func (r Records) AsBodyWithAction(action string) (io.Reader, error) {
 	buffer := bytes.NewBuffer(nil)
 	encoder := json.NewEncoder(buffer)
 	for _, record := range r {
 		err := encoder.Encode(map[string]interface{}{
-			"index": record.Meta,
+			action: record.Meta,
 		})
 		if err != nil {
 			return nil, err
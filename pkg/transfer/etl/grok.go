@@ -0,0 +1,140 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+// grokMaxDepth : 模式嵌套引用的最大深度，避免循环引用
+const grokMaxDepth = 32
+
+var grokReferenceRegexp = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// GrokPatterns : 内置的 grok 模式，语法与 logstash 保持一致，去掉了 RE2 不支持的环视
+var GrokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `(?:[+-]?(?:[0-9]+))`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `(?:%{BASE10NUM})`,
+	"POSINT":            `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":         `\b(?:[0-9]+)\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9])`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"LOGLEVEL":          `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn?(?:ing)?|WARN?(?:ING)?|[Ee]rr?(?:or)?|ERR?(?:OR)?|[Cc]rit?(?:ical)?|CRIT?(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|EMERG(?:ENCY)?|[Ee]merg(?:ency)?)`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+}
+
+type grokCompiler struct {
+	patterns map[string]string
+}
+
+func (c *grokCompiler) expand(pattern string, depth int) (string, error) {
+	if depth > grokMaxDepth {
+		return "", errors.Wrapf(define.ErrValue, "grok pattern nested too deep")
+	}
+
+	var err error
+	result := grokReferenceRegexp.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		matched := grokReferenceRegexp.FindStringSubmatch(reference)
+		definition, ok := c.patterns[matched[1]]
+		if !ok {
+			err = errors.Wrapf(define.ErrItemNotFound, "grok pattern %s", matched[1])
+			return ""
+		}
+
+		var expanded string
+		expanded, err = c.expand(definition, depth+1)
+		if err != nil {
+			return ""
+		}
+		if matched[2] == "" {
+			return "(?:" + expanded + ")"
+		}
+		return "(?P<" + matched[2] + ">" + expanded + ")"
+	})
+	return result, err
+}
+
+// CompileGrok : 将 grok 表达式展开为正则表达式，%{PATTERN:field} 转换为命名分组，custom 中的同名模式覆盖内置模式
+func CompileGrok(pattern string, custom map[string]string) (string, error) {
+	regex, err := CompileGrokRegexp(pattern, custom)
+	if err != nil {
+		return "", err
+	}
+	return regex.String(), nil
+}
+
+// CompileGrokRegexp : 展开并编译 grok 表达式
+func CompileGrokRegexp(pattern string, custom map[string]string) (*regexp.Regexp, error) {
+	patterns := make(map[string]string, len(GrokPatterns)+len(custom))
+	for name, definition := range GrokPatterns {
+		patterns[name] = definition
+	}
+	for name, definition := range custom {
+		patterns[name] = definition
+	}
+
+	compiler := &grokCompiler{patterns: patterns}
+	expanded, err := compiler.expand(pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	regex, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "compile grok %s", pattern)
+	}
+	return regex, nil
+}
+
+// TransformMapByGrok : 按 grok 表达式提取字段
+func TransformMapByGrok(pattern string, custom map[string]string) TransformFn {
+	regex, err := CompileGrokRegexp(pattern, custom)
+	if err != nil {
+		return TransformErrorForever(err)
+	}
+	return TransformMapByRegexpObject(regex)
+}
@@ -0,0 +1,98 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+)
+
+// GrokSuite
+type GrokSuite struct {
+	suite.Suite
+}
+
+// TestTransformMapByGrok
+func (s *GrokSuite) TestTransformMapByGrok() {
+	cases := []struct {
+		pattern string
+		custom  map[string]string
+		value   string
+		result  map[string]interface{}
+	}{
+		{
+			`%{TIMESTAMP_ISO8601:ts} \[%{LOGLEVEL:level}\] %{GREEDYDATA:msg}`, nil,
+			`2022-01-01T00:00:00+08:00 [WARN] disk full`,
+			map[string]interface{}{"ts": "2022-01-01T00:00:00+08:00", "level": "WARN", "msg": "disk full"},
+		},
+		{
+			`%{COMMONAPACHELOG}`, nil,
+			`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+			map[string]interface{}{
+				"clientip": "127.0.0.1", "ident": "-", "auth": "frank", "timestamp": "10/Oct/2000:13:55:36 -0700",
+				"verb": "GET", "request": "/apache_pb.gif", "httpversion": "1.0", "rawrequest": "", "response": "200", "bytes": "2326",
+			},
+		},
+		{
+			`%{TRACE_ID:trace} %{NUMBER:cost}`, map[string]string{"TRACE_ID": `[0-9a-f]{8}`},
+			`0af7651b 1.5`,
+			map[string]interface{}{"trace": "0af7651b", "cost": "1.5"},
+		},
+		{
+			`%{IP:ip}`, nil, `not an ip`,
+			map[string]interface{}{"ip": nil},
+		},
+	}
+
+	for i, c := range cases {
+		result, err := etl.TransformMapByGrok(c.pattern, c.custom)(c.value)
+		s.NoError(err, i)
+		s.Equal(c.result, result, i)
+	}
+}
+
+// TestCompileGrokError
+func (s *GrokSuite) TestCompileGrokError() {
+	_, err := etl.CompileGrok(`%{UNKNOWN:x}`, nil)
+	s.Error(err)
+
+	_, err = etl.CompileGrok(`%{A}`, map[string]string{"A": "%{B}", "B": "%{A}"})
+	s.Error(err)
+
+	_, err = etl.CompileGrok(`%{BAD:x}`, map[string]string{"BAD": "("})
+	s.Error(err)
+
+	_, err = etl.TransformMapByGrok(`%{UNKNOWN:x}`, nil)("value")
+	s.Error(err)
+}
+
+// TestTransformMapByKeyValue
+func (s *GrokSuite) TestTransformMapByKeyValue() {
+	result, err := etl.TransformMapByKeyValue(" ", "=")(`a=1 b="x" c= invalid =2 d=k=v`)
+	s.NoError(err)
+	s.Equal(map[string]interface{}{"a": "1", "b": "x", "c": "", "d": "k=v"}, result)
+
+	result, err = etl.TransformMapByKeyValue("&", ":")(`a:1&b:'2'`)
+	s.NoError(err)
+	s.Equal(map[string]interface{}{"a": "1", "b": "2"}, result)
+
+	// 只去除成对的首尾引号
+	result, err = etl.TransformMapByKeyValue("&", "=")(`a="x"&b="x'&c='"x"'&d="&e=x"&f=""`)
+	s.NoError(err)
+	s.Equal(map[string]interface{}{"a": "x", "b": `"x'`, "c": `"x"`, "d": `"`, "e": `x"`, "f": ""}, result)
+}
+
+// TestGrokSuite
+func TestGrokSuite(t *testing.T) {
+	suite.Run(t, new(GrokSuite))
+}
@@ -0,0 +1,52 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+)
+
+// payload 格式
+const (
+	PayloadFormatJSON     = "json"
+	PayloadFormatProtobuf = "protobuf"
+	PayloadFormatAvro     = "avro"
+)
+
+// SchemaDecoder : 按 schema 将二进制 payload 解析为 Container
+type SchemaDecoder interface {
+	Decode(data []byte) (Container, error)
+}
+
+// SchemaDecoderCreator : 根据 payload_schema 配置创建解析器
+type SchemaDecoderCreator func(schema map[string]interface{}) (SchemaDecoder, error)
+
+var schemaDecoders = make(map[string]SchemaDecoderCreator)
+
+// RegisterSchemaDecoder :
+func RegisterSchemaDecoder(format string, creator SchemaDecoderCreator) {
+	schemaDecoders[format] = creator
+}
+
+// NewSchemaDecoder :
+func NewSchemaDecoder(format string, schema map[string]interface{}) (SchemaDecoder, error) {
+	creator, ok := schemaDecoders[format]
+	if !ok {
+		return nil, errors.Wrapf(define.ErrItemNotFound, "schema decoder %s not found", format)
+	}
+	return creator(schema)
+}
+
+// IsBinaryPayloadFormat : 非 json 格式的 payload 需要先按 schema 解析
+func IsBinaryPayloadFormat(format string) bool {
+	return format != "" && format != PayloadFormatJSON
+}
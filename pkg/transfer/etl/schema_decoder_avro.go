@@ -0,0 +1,471 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cstockton/go-conv"
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/json"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// avro 类型
+const (
+	avroTypeNull    = "null"
+	avroTypeBoolean = "boolean"
+	avroTypeInt     = "int"
+	avroTypeLong    = "long"
+	avroTypeFloat   = "float"
+	avroTypeDouble  = "double"
+	avroTypeBytes   = "bytes"
+	avroTypeString  = "string"
+	avroTypeRecord  = "record"
+	avroTypeError   = "error"
+	avroTypeEnum    = "enum"
+	avroTypeArray   = "array"
+	avroTypeMap     = "map"
+	avroTypeFixed   = "fixed"
+	avroTypeUnion   = "union"
+)
+
+// avroWireMagic : schema registry 消息格式，首字节为 0，随后 4 字节为大端序 schema id
+const (
+	avroWireMagic      = 0
+	avroWireHeaderSize = 5
+)
+
+var errAvroShortBuffer = errors.Wrapf(define.ErrValue, "avro data too short")
+
+// AvroSchema : avro schema 的解析结果
+type AvroSchema struct {
+	Type     string
+	Name     string
+	Fields   []*AvroField
+	Symbols  []string
+	Items    *AvroSchema
+	Values   *AvroSchema
+	Size     int
+	Branches []*AvroSchema
+}
+
+// AvroField :
+type AvroField struct {
+	Name   string
+	Schema *AvroSchema
+}
+
+type avroSchemaParser struct {
+	named map[string]*AvroSchema
+}
+
+func (p *avroSchemaParser) fullName(name, namespace string) string {
+	if strings.Contains(name, ".") || namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func (p *avroSchemaParser) lookup(name, namespace string) (*AvroSchema, error) {
+	if schema, ok := p.named[p.fullName(name, namespace)]; ok {
+		return schema, nil
+	}
+	if schema, ok := p.named[name]; ok {
+		return schema, nil
+	}
+	return nil, errors.Wrapf(define.ErrItemNotFound, "avro type %s", name)
+}
+
+func (p *avroSchemaParser) parse(raw interface{}, namespace string) (*AvroSchema, error) {
+	switch value := raw.(type) {
+	case string:
+		switch value {
+		case avroTypeNull, avroTypeBoolean, avroTypeInt, avroTypeLong, avroTypeFloat, avroTypeDouble, avroTypeBytes, avroTypeString:
+			return &AvroSchema{Type: value}, nil
+		}
+		return p.lookup(value, namespace)
+	case []interface{}:
+		schema := &AvroSchema{Type: avroTypeUnion}
+		for _, item := range value {
+			branch, err := p.parse(item, namespace)
+			if err != nil {
+				return nil, err
+			}
+			schema.Branches = append(schema.Branches, branch)
+		}
+		return schema, nil
+	case map[string]interface{}:
+		return p.parseComplex(value, namespace)
+	default:
+		return nil, errors.Wrapf(define.ErrType, "unknown avro schema %#v", raw)
+	}
+}
+
+func (p *avroSchemaParser) parseNamed(value map[string]interface{}, namespace string, schema *AvroSchema) (string, error) {
+	helper := utils.NewMapHelper(value)
+	name, ok := helper.GetString("name")
+	if !ok || name == "" {
+		return "", errors.Wrapf(define.ErrValue, "avro %s name is empty", schema.Type)
+	}
+	if ns, ok := helper.GetString("namespace"); ok {
+		namespace = ns
+	}
+	schema.Name = p.fullName(name, namespace)
+	p.named[schema.Name] = schema
+	if index := strings.LastIndex(schema.Name, "."); index > 0 {
+		namespace = schema.Name[:index]
+	}
+	return namespace, nil
+}
+
+func (p *avroSchemaParser) parseComplex(value map[string]interface{}, namespace string) (*AvroSchema, error) {
+	typeName, ok := value["type"].(string)
+	if !ok {
+		// 嵌套的类型声明
+		return p.parse(value["type"], namespace)
+	}
+
+	helper := utils.NewMapHelper(value)
+	schema := &AvroSchema{Type: typeName}
+	switch typeName {
+	case avroTypeRecord, avroTypeError:
+		schema.Type = avroTypeRecord
+		ns, err := p.parseNamed(value, namespace, schema)
+		if err != nil {
+			return nil, err
+		}
+		fields, _ := helper.GetArray("fields")
+		for _, item := range fields {
+			field, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, errors.Wrapf(define.ErrType, "avro field %#v", item)
+			}
+			name, _ := field["name"].(string)
+			fieldSchema, err := p.parse(field["type"], ns)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "avro field %s", name)
+			}
+			schema.Fields = append(schema.Fields, &AvroField{Name: name, Schema: fieldSchema})
+		}
+	case avroTypeEnum:
+		if _, err := p.parseNamed(value, namespace, schema); err != nil {
+			return nil, err
+		}
+		symbols, _ := helper.GetArray("symbols")
+		for _, symbol := range symbols {
+			schema.Symbols = append(schema.Symbols, conv.String(symbol))
+		}
+	case avroTypeFixed:
+		if _, err := p.parseNamed(value, namespace, schema); err != nil {
+			return nil, err
+		}
+		size, ok := helper.GetInt("size")
+		if !ok {
+			return nil, errors.Wrapf(define.ErrValue, "avro fixed %s size is empty", schema.Name)
+		}
+		schema.Size = size
+	case avroTypeArray:
+		items, err := p.parse(value["items"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		schema.Items = items
+	case avroTypeMap:
+		values, err := p.parse(value["values"], namespace)
+		if err != nil {
+			return nil, err
+		}
+		schema.Values = values
+	default:
+		// 基础类型及带 logicalType 的基础类型
+		return p.parse(typeName, namespace)
+	}
+	return schema, nil
+}
+
+// ParseAvroSchema : 解析 avro schema，支持 json 字符串或已解析的对象
+func ParseAvroSchema(raw interface{}) (*AvroSchema, error) {
+	if data, ok := raw.(string); ok {
+		// 基础类型可以直接使用类型名，不是合法 json
+		var value interface{}
+		if err := json.Unmarshal([]byte(data), &value); err == nil {
+			raw = value
+		}
+	}
+
+	parser := &avroSchemaParser{named: make(map[string]*AvroSchema)}
+	return parser.parse(raw, "")
+}
+
+type avroReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *avroReader) readLong() (int64, error) {
+	value, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errAvroShortBuffer
+	}
+	r.pos += n
+	return value, nil
+}
+
+func (r *avroReader) readFixed(size int) ([]byte, error) {
+	if size < 0 || r.pos+size > len(r.data) {
+		return nil, errAvroShortBuffer
+	}
+	value := r.data[r.pos : r.pos+size]
+	r.pos += size
+	return value, nil
+}
+
+func (r *avroReader) readBytes() ([]byte, error) {
+	size, err := r.readLong()
+	if err != nil {
+		return nil, err
+	}
+	if size > int64(len(r.data)) {
+		return nil, errAvroShortBuffer
+	}
+	return r.readFixed(int(size))
+}
+
+// readBlocks : array 及 map 按块编码，块大小为负数时后跟块字节数
+func (r *avroReader) readBlocks(fn func() error) error {
+	for {
+		count, err := r.readLong()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			count = -count
+			if _, err = r.readLong(); err != nil {
+				return err
+			}
+		}
+		if count > int64(len(r.data)) {
+			return errAvroShortBuffer
+		}
+		for i := int64(0); i < count; i++ {
+			if err = fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *avroReader) read(schema *AvroSchema) (interface{}, error) {
+	switch schema.Type {
+	case avroTypeNull:
+		return nil, nil
+	case avroTypeBoolean:
+		value, err := r.readFixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return value[0] != 0, nil
+	case avroTypeInt, avroTypeLong:
+		return r.readLong()
+	case avroTypeFloat:
+		value, err := r.readFixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(value))), nil
+	case avroTypeDouble:
+		value, err := r.readFixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(value)), nil
+	case avroTypeBytes:
+		value, err := r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(value), nil
+	case avroTypeString:
+		value, err := r.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(value), nil
+	case avroTypeFixed:
+		value, err := r.readFixed(schema.Size)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(value), nil
+	case avroTypeEnum:
+		index, err := r.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(schema.Symbols)) {
+			return nil, errors.Wrapf(define.ErrValue, "avro enum %s index %d out of range", schema.Name, index)
+		}
+		return schema.Symbols[index], nil
+	case avroTypeUnion:
+		index, err := r.readLong()
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= int64(len(schema.Branches)) {
+			return nil, errors.Wrapf(define.ErrValue, "avro union index %d out of range", index)
+		}
+		return r.read(schema.Branches[index])
+	case avroTypeRecord:
+		result := make(map[string]interface{}, len(schema.Fields))
+		for _, field := range schema.Fields {
+			value, err := r.read(field.Schema)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "avro field %s", field.Name)
+			}
+			result[field.Name] = value
+		}
+		return result, nil
+	case avroTypeArray:
+		result := make([]interface{}, 0)
+		err := r.readBlocks(func() error {
+			value, err := r.read(schema.Items)
+			if err != nil {
+				return err
+			}
+			result = append(result, value)
+			return nil
+		})
+		return result, err
+	case avroTypeMap:
+		result := make(map[string]interface{})
+		err := r.readBlocks(func() error {
+			key, err := r.readBytes()
+			if err != nil {
+				return err
+			}
+			value, err := r.read(schema.Values)
+			if err != nil {
+				return err
+			}
+			result[string(key)] = value
+			return nil
+		})
+		return result, err
+	default:
+		return nil, errors.Wrapf(ErrTypeNotSupported, "avro type %s", schema.Type)
+	}
+}
+
+// DecodeAvro : 按 schema 解析 avro 二进制数据
+func DecodeAvro(schema *AvroSchema, data []byte) (interface{}, error) {
+	reader := &avroReader{data: data}
+	value, err := reader.read(schema)
+	if err != nil {
+		return nil, err
+	}
+	if reader.pos != len(data) {
+		return nil, errors.Wrapf(define.ErrValue, "avro data has %d trailing bytes", len(data)-reader.pos)
+	}
+	return value, nil
+}
+
+// AvroDecoder : 使用内联 schema，或按消息头中的 schema id 从 schemas 中查找 schema
+type AvroDecoder struct {
+	schema  *AvroSchema
+	schemas map[uint32]*AvroSchema
+}
+
+func (d *AvroDecoder) getSchema(data []byte) (*AvroSchema, []byte, error) {
+	if len(d.schemas) == 0 {
+		return d.schema, data, nil
+	}
+
+	if len(data) < avroWireHeaderSize || data[0] != avroWireMagic {
+		return nil, nil, errors.Wrapf(define.ErrValue, "avro schema id not found in message header")
+	}
+	id := binary.BigEndian.Uint32(data[1:avroWireHeaderSize])
+	schema, ok := d.schemas[id]
+	if !ok {
+		return nil, nil, errors.Wrapf(define.ErrItemNotFound, "avro schema id %d", id)
+	}
+	return schema, data[avroWireHeaderSize:], nil
+}
+
+// Decode :
+func (d *AvroDecoder) Decode(data []byte) (Container, error) {
+	schema, data, err := d.getSchema(data)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := DecodeAvro(schema, data)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.Wrapf(define.ErrType, "expect avro record but got %T", value)
+	}
+	return NewMapContainerFrom(result), nil
+}
+
+// NewAvroDecoder : schema 为内联 schema，schemas 为 schema id 到 schema 的映射，作为 schema registry 的替代
+func NewAvroDecoder(schema map[string]interface{}) (SchemaDecoder, error) {
+	helper := utils.NewMapHelper(schema)
+	decoder := &AvroDecoder{
+		schemas: make(map[uint32]*AvroSchema),
+	}
+
+	if value, ok := helper.Get("schema"); ok && value != nil {
+		parsed, err := ParseAvroSchema(value)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "parse avro schema")
+		}
+		decoder.schema = parsed
+	}
+
+	if schemas, ok := helper.Get("schemas"); ok {
+		items, ok := schemas.(map[string]interface{})
+		if !ok {
+			return nil, errors.Wrapf(define.ErrType, "expect schemas type map[string]interface{} but got %T", schemas)
+		}
+		for key, value := range items {
+			id, err := strconv.ParseUint(key, 10, 32)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "parse avro schema id %s", key)
+			}
+			parsed, err := ParseAvroSchema(value)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "parse avro schema %d", id)
+			}
+			decoder.schemas[uint32(id)] = parsed
+		}
+	}
+
+	if decoder.schema == nil && len(decoder.schemas) == 0 {
+		return nil, errors.Wrapf(define.ErrValue, "avro schema is empty")
+	}
+	return decoder, nil
+}
+
+func init() {
+	RegisterSchemaDecoder(PayloadFormatAvro, NewAvroDecoder)
+}
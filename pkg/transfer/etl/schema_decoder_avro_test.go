@@ -0,0 +1,193 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl_test
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+)
+
+type avroWriter struct {
+	data []byte
+}
+
+func (w *avroWriter) long(v int64) *avroWriter {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, v)
+	w.data = append(w.data, buf[:n]...)
+	return w
+}
+
+func (w *avroWriter) str(v string) *avroWriter {
+	w.long(int64(len(v)))
+	w.data = append(w.data, v...)
+	return w
+}
+
+func (w *avroWriter) double(v float64) *avroWriter {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+	w.data = append(w.data, buf...)
+	return w
+}
+
+func (w *avroWriter) boolean(v bool) *avroWriter {
+	if v {
+		w.data = append(w.data, 1)
+	} else {
+		w.data = append(w.data, 0)
+	}
+	return w
+}
+
+const avroTestSchema = `{
+	"type": "record",
+	"name": "Event",
+	"namespace": "bkmonitor",
+	"fields": [
+		{"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "value", "type": "double"},
+		{"name": "ok", "type": "boolean"},
+		{"name": "target", "type": ["null", "string"]},
+		{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["INFO", "ERROR"]}},
+		{"name": "tags", "type": {"type": "map", "values": "string"}},
+		{"name": "items", "type": {"type": "array", "items": {"type": "record", "name": "Item", "fields": [{"name": "id", "type": "int"}]}}},
+		{"name": "next", "type": ["null", "Item"]}
+	]
+}`
+
+// AvroDecoderSuite
+type AvroDecoderSuite struct {
+	suite.Suite
+}
+
+func (s *AvroDecoderSuite) makeEvent() []byte {
+	w := &avroWriter{}
+	w.long(1600000000000).double(1.5).boolean(true)
+	w.long(1).str("127.0.0.1")
+	w.long(1)
+	// map 使用负数块大小
+	tags := (&avroWriter{}).str("k").str("v").data
+	w.long(-1).long(int64(len(tags)))
+	w.data = append(w.data, tags...)
+	w.long(0)
+	w.long(2).long(1).long(2).long(0)
+	w.long(1).long(3)
+	return w.data
+}
+
+// TestInlineSchema
+func (s *AvroDecoderSuite) TestInlineSchema() {
+	decoder, err := etl.NewSchemaDecoder(etl.PayloadFormatAvro, map[string]interface{}{
+		"schema": avroTestSchema,
+	})
+	s.NoError(err)
+
+	container, err := decoder.Decode(s.makeEvent())
+	s.NoError(err)
+	s.Equal(map[string]interface{}{
+		"time":   int64(1600000000000),
+		"value":  1.5,
+		"ok":     true,
+		"target": "127.0.0.1",
+		"level":  "ERROR",
+		"tags":   map[string]interface{}{"k": "v"},
+		"items": []interface{}{
+			map[string]interface{}{"id": int64(1)},
+			map[string]interface{}{"id": int64(2)},
+		},
+		"next": map[string]interface{}{"id": int64(3)},
+	}, etl.ContainerToMap(container))
+}
+
+// TestSchemaRegistry
+func (s *AvroDecoderSuite) TestSchemaRegistry() {
+	decoder, err := etl.NewAvroDecoder(map[string]interface{}{
+		"schemas": map[string]interface{}{
+			"7": avroTestSchema,
+			"8": map[string]interface{}{
+				"type":   "record",
+				"name":   "Simple",
+				"fields": []interface{}{map[string]interface{}{"name": "name", "type": "string"}},
+			},
+		},
+	})
+	s.NoError(err)
+
+	data := append([]byte{0, 0, 0, 0, 7}, s.makeEvent()...)
+	container, err := decoder.Decode(data)
+	s.NoError(err)
+	value, err := container.Get("target")
+	s.NoError(err)
+	s.Equal("127.0.0.1", value)
+
+	data = append([]byte{0, 0, 0, 0, 8}, (&avroWriter{}).str("test").data...)
+	container, err = decoder.Decode(data)
+	s.NoError(err)
+	value, err = container.Get("name")
+	s.NoError(err)
+	s.Equal("test", value)
+
+	// 未知的 schema id
+	_, err = decoder.Decode(append([]byte{0, 0, 0, 0, 9}, s.makeEvent()...))
+	s.Error(err)
+
+	// 缺少消息头
+	_, err = decoder.Decode(s.makeEvent())
+	s.Error(err)
+}
+
+// TestInvalidData
+func (s *AvroDecoderSuite) TestInvalidData() {
+	decoder, err := etl.NewAvroDecoder(map[string]interface{}{
+		"schema": avroTestSchema,
+	})
+	s.NoError(err)
+
+	data := s.makeEvent()
+	_, err = decoder.Decode(data[:len(data)-1])
+	s.Error(err)
+
+	_, err = decoder.Decode(append(data, 0))
+	s.Error(err)
+
+	_, err = decoder.Decode([]byte{})
+	s.Error(err)
+}
+
+// TestInvalidSchema
+func (s *AvroDecoderSuite) TestInvalidSchema() {
+	cases := []map[string]interface{}{
+		{},
+		{"schema": `{"type": "record", "fields": []}`},
+		{"schema": `{"type": "record", "name": "A", "fields": [{"name": "a", "type": "Unknown"}]}`},
+		{"schemas": map[string]interface{}{"x": `"string"`}},
+	}
+	for i, c := range cases {
+		_, err := etl.NewAvroDecoder(c)
+		s.Error(err, i)
+	}
+
+	// 非 record 类型无法转为 Container
+	decoder, err := etl.NewAvroDecoder(map[string]interface{}{"schema": "string"})
+	s.NoError(err)
+	_, err = decoder.Decode((&avroWriter{}).str("test").data)
+	s.Error(err)
+}
+
+// TestAvroDecoderSuite
+func TestAvroDecoderSuite(t *testing.T) {
+	suite.Run(t, new(AvroDecoderSuite))
+}
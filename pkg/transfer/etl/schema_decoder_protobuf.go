@@ -0,0 +1,144 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl
+
+import (
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// ProtobufDecoder : 基于 FileDescriptorSet 动态解析 protobuf 消息
+type ProtobufDecoder struct {
+	descriptor protoreflect.MessageDescriptor
+}
+
+// Decode :
+func (d *ProtobufDecoder) Decode(data []byte) (Container, error) {
+	message := dynamicpb.NewMessage(d.descriptor)
+	err := proto.Unmarshal(data, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewMapContainerFrom(protobufMessageToMap(message)), nil
+}
+
+// protobufMessageToMap : 以 proto 字段名为 key 转换为 map，未设置 presence 的零值字段同样保留，避免指标值为 0 时丢失
+func protobufMessageToMap(message protoreflect.Message) map[string]interface{} {
+	fields := message.Descriptor().Fields()
+	result := make(map[string]interface{}, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		switch {
+		case field.IsList(), field.IsMap():
+			if !message.Has(field) {
+				continue
+			}
+		case field.HasPresence():
+			if !message.Has(field) {
+				continue
+			}
+		}
+		result[string(field.Name())] = protobufFieldValue(field, message.Get(field))
+	}
+	return result
+}
+
+func protobufFieldValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch {
+	case field.IsList():
+		list := value.List()
+		results := make([]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			results = append(results, protobufSingularValue(field, list.Get(i)))
+		}
+		return results
+	case field.IsMap():
+		results := make(map[string]interface{}, value.Map().Len())
+		value.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			results[key.String()] = protobufSingularValue(field.MapValue(), value)
+			return true
+		})
+		return results
+	default:
+		return protobufSingularValue(field, value)
+	}
+}
+
+func protobufSingularValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protobufMessageToMap(value.Message())
+	case protoreflect.EnumKind:
+		number := value.Enum()
+		enum := field.Enum().Values().ByNumber(number)
+		if enum == nil {
+			return int32(number)
+		}
+		return string(enum.Name())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes())
+	default:
+		return value.Interface()
+	}
+}
+
+// NewProtobufDecoder : descriptor_set 为 base64 编码的 FileDescriptorSet，message 为消息全名
+func NewProtobufDecoder(schema map[string]interface{}) (SchemaDecoder, error) {
+	helper := utils.NewMapHelper(schema)
+	encoded, ok := helper.GetString("descriptor_set")
+	if !ok || encoded == "" {
+		return nil, errors.Wrapf(define.ErrValue, "descriptor_set is empty")
+	}
+	name, ok := helper.GetString("message")
+	if !ok || name == "" {
+		return nil, errors.Wrapf(define.ErrValue, "message is empty")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "decode descriptor_set")
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	err = proto.Unmarshal(data, &set)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "unmarshal descriptor_set")
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "load descriptor_set")
+	}
+
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "find message %s", name)
+	}
+	message, ok := descriptor.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.Wrapf(define.ErrType, "%s is not a message", name)
+	}
+
+	return &ProtobufDecoder{descriptor: message}, nil
+}
+
+func init() {
+	RegisterSchemaDecoder(PayloadFormatProtobuf, NewProtobufDecoder)
+}
@@ -0,0 +1,154 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+)
+
+// ProtobufDecoderSuite
+type ProtobufDecoderSuite struct {
+	suite.Suite
+	set *descriptorpb.FileDescriptorSet
+}
+
+func (s *ProtobufDecoderSuite) field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     typ.Enum(),
+		Label:    label.Enum(),
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+// SetupTest
+func (s *ProtobufDecoderSuite) SetupTest() {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	s.set = &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("event.proto"),
+			Package: proto.String("bkmonitor"),
+			Syntax:  proto.String("proto3"),
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Level"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("INFO"), Number: proto.Int32(0)},
+					{Name: proto.String("ERROR"), Number: proto.Int32(1)},
+				},
+			}},
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("Dimension"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						s.field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+					},
+				},
+				{
+					Name: proto.String("Event"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						s.field("time", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional, ""),
+						s.field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, optional, ""),
+						s.field("count", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+						s.field("level", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, optional, ".bkmonitor.Level"),
+						s.field("tags", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, repeated, ""),
+						s.field("dimension", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".bkmonitor.Dimension"),
+						s.field("raw", 7, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional, ""),
+					},
+				},
+			},
+		}},
+	}
+}
+
+func (s *ProtobufDecoderSuite) schema(message string) map[string]interface{} {
+	data, err := proto.Marshal(s.set)
+	s.NoError(err)
+	return map[string]interface{}{
+		"descriptor_set": base64.StdEncoding.EncodeToString(data),
+		"message":        message,
+	}
+}
+
+// TestDecode
+func (s *ProtobufDecoderSuite) TestDecode() {
+	files, err := protodesc.NewFiles(s.set)
+	s.NoError(err)
+	descriptor, err := files.FindDescriptorByName("bkmonitor.Event")
+	s.NoError(err)
+	eventDesc := descriptor.(protoreflect.MessageDescriptor)
+	fields := eventDesc.Fields()
+
+	event := dynamicpb.NewMessage(eventDesc)
+	event.Set(fields.ByName("time"), protoreflect.ValueOfInt64(1600000000))
+	event.Set(fields.ByName("value"), protoreflect.ValueOfFloat64(1.5))
+	event.Set(fields.ByName("level"), protoreflect.ValueOfEnum(1))
+	tags := event.Mutable(fields.ByName("tags")).List()
+	tags.Append(protoreflect.ValueOfString("a"))
+	tags.Append(protoreflect.ValueOfString("b"))
+	dimension := event.Mutable(fields.ByName("dimension")).Message()
+	dimension.Set(dimension.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("host"))
+	event.Set(fields.ByName("raw"), protoreflect.ValueOfBytes([]byte("raw")))
+
+	data, err := proto.Marshal(event)
+	s.NoError(err)
+
+	decoder, err := etl.NewSchemaDecoder(etl.PayloadFormatProtobuf, s.schema("bkmonitor.Event"))
+	s.NoError(err)
+	container, err := decoder.Decode(data)
+	s.NoError(err)
+	s.Equal(map[string]interface{}{
+		"time":      int64(1600000000),
+		"value":     1.5,
+		"count":     int32(0),
+		"level":     "ERROR",
+		"tags":      []interface{}{"a", "b"},
+		"dimension": map[string]interface{}{"name": "host"},
+		"raw":       base64.StdEncoding.EncodeToString([]byte("raw")),
+	}, etl.ContainerToMap(container))
+
+	_, err = decoder.Decode([]byte{0xff, 0xff})
+	s.Error(err)
+}
+
+// TestInvalidSchema
+func (s *ProtobufDecoderSuite) TestInvalidSchema() {
+	cases := []map[string]interface{}{
+		{},
+		{"descriptor_set": "not base64!", "message": "bkmonitor.Event"},
+		s.schema(""),
+		s.schema("bkmonitor.Unknown"),
+		s.schema("bkmonitor.Level"),
+	}
+	for i, c := range cases {
+		_, err := etl.NewProtobufDecoder(c)
+		s.Error(err, i)
+	}
+}
+
+// TestProtobufDecoderSuite
+func TestProtobufDecoderSuite(t *testing.T) {
+	suite.Run(t, new(ProtobufDecoderSuite))
+}
@@ -78,7 +78,11 @@ func TransformMapByRegexp(pattern string) TransformFn {
 	if err != nil {
 		return TransformErrorForever(err)
 	}
+	return TransformMapByRegexpObject(regex)
+}
 
+// TransformMapByRegexpObject : 使用已编译的正则提取字段，便于调用方在创建时处理编译错误
+func TransformMapByRegexpObject(regex *regexp.Regexp) TransformFn {
 	fields := regex.SubexpNames()
 	count := len(fields)
 	return func(from interface{}) (to interface{}, err error) {
@@ -137,6 +141,42 @@ func TransformMapByJsonWithRetainExtraJSON(table *config.MetaResultTableConfig)
 	}
 }
 
+// TransformMapByKeyValue : 按 key=value 格式提取字段，值两端的引号会被去掉
+func TransformMapByKeyValue(fieldSplit, valueSplit string) TransformFn {
+	return func(from interface{}) (to interface{}, err error) {
+		value, err := conv.DefaultConv.String(from)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make(map[string]interface{})
+		for _, pair := range strings.Split(value, fieldSplit) {
+			parts := strings.SplitN(pair, valueSplit, 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key := strings.TrimSpace(parts[0])
+			if key == "" {
+				continue
+			}
+			results[key] = unquote(strings.TrimSpace(parts[1]))
+		}
+		return results, nil
+	}
+}
+
+// unquote : 仅去除首尾成对的引号，保留值内部及不成对的引号
+func unquote(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	quote := value[0]
+	if (quote == '"' || quote == '\'') && value[len(value)-1] == quote {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
 // TransformMapByJSON
 func TransformMapByJSON(from interface{}) (to interface{}, err error) {
 	value, err := conv.DefaultConv.String(from)
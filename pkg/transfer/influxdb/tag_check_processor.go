@@ -205,7 +205,7 @@ func (p *TagCheckProcessor) Process(payload define.Payload, outputChan chan<- de
 	record := new(Record)
 	err := payload.To(record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(payload, err)
 		logging.Warnf("%v error %v dropped payload %+v", p, err, payload)
 		return
 	}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
 )
@@ -419,6 +420,24 @@ func NewConfigBuilderWithWaitDelay(ctx context.Context, name string, frontendWai
 	}
 }
 
+// GetPayloadProcessors : 按 payload 格式及编码选择前置解析节点
+func (b *ConfigBuilder) GetPayloadProcessors(pipe *config.PipelineConfig) []string {
+	processors := make([]string, 0)
+	helper := utils.NewMapHelper(pipe.Option)
+
+	// protobuf/avro 为二进制格式，解析后即为 utf8 编码的 json，不再做字符集转换
+	format, ok := helper.GetString(config.PipelineConfigOptPayloadFormat)
+	if ok && etl.IsBinaryPayloadFormat(format) {
+		return append(processors, "payload_decoder")
+	}
+
+	encoding, ok := helper.GetString(config.PipelineConfigOptPayloadEncoding)
+	if ok && encoding != "" {
+		processors = append(processors, "encoding")
+	}
+	return processors
+}
+
 // FrontendProcessor :
 func (b *ConfigBuilder) FrontendProcessor(ctx context.Context) (Node, error) {
 	mqConf := config.MQConfigFromContext(ctx)
@@ -18,7 +18,6 @@ import (
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
-	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
 )
 
 // LogConfigBuilder
@@ -28,13 +27,7 @@ type LogConfigBuilder struct {
 
 // GetStandardProcessors
 func (b *LogConfigBuilder) GetStandardProcessors(etl string, pipe *config.PipelineConfig, rt *config.MetaResultTableConfig) []string {
-	processors := make([]string, 0)
-
-	helper := utils.NewMapHelper(pipe.Option)
-	encoding, ok := helper.GetString(config.PipelineConfigOptPayloadEncoding)
-	if ok && encoding != "" {
-		processors = append(processors, "encoding")
-	}
+	processors := b.GetPayloadProcessors(pipe)
 
 	processors = append(processors, etl, "log_format")
 
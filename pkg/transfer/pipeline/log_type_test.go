@@ -68,6 +68,23 @@ func (s *LogConfigBuilderSuite) TestGetStandardProcessors() {
 			[]string{},
 			[]string{"encoding"},
 		},
+		{
+			config.PipelineConfig{Option: map[string]interface{}{
+				config.PipelineConfigOptPayloadFormat:   "avro",
+				config.PipelineConfigOptPayloadEncoding: "gbk",
+			}},
+			stdTable,
+			[]string{"payload_decoder", "log_format"},
+			[]string{"encoding"},
+		},
+		{
+			config.PipelineConfig{Option: map[string]interface{}{
+				config.PipelineConfigOptPayloadFormat: "json",
+			}},
+			stdTable,
+			[]string{},
+			[]string{"payload_decoder"},
+		},
 	}
 
 	for i, c := range cases {
@@ -15,6 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/deadletter"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/monitor"
 )
@@ -80,6 +81,7 @@ func NewDataProcessorMonitor(name string, pipe *config.PipelineConfig) *define.P
 				"pipeline": name,
 			}),
 		),
+		DeadLetter: deadletter.NewHandler(name, pipe),
 	}
 }
 
@@ -115,6 +117,8 @@ func NewBackendProcessorMonitor(pipe *config.PipelineConfig, shipper *config.Met
 		labels["target"] = shipper.AsKafkaCluster().GetTarget()
 	} else if shipper.ClusterType == "redis" {
 		labels["target"] = shipper.AsRedisCluster().GetTarget()
+	} else if shipper.ClusterType == "prometheus_remote_write" {
+		labels["target"] = shipper.AsRemoteWriteCluster().GetTarget()
 	} else {
 		labels["target"] = shipper.AsInfluxCluster().GetTarget()
 	}
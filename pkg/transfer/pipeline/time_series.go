@@ -65,13 +65,7 @@ func (b *TSConfigBuilder) GetStandardShipperProcessors(pipe *config.PipelineConf
 
 // GetStandardProcessors
 func (b *TSConfigBuilder) GetStandardProcessors(etl string, pipe *config.PipelineConfig, rt *config.MetaResultTableConfig, frontNode ...string) []string {
-	processors := make([]string, 0)
-
-	helper := utils.NewMapHelper(pipe.Option)
-	encoding, ok := helper.GetString(config.PipelineConfigOptPayloadEncoding)
-	if ok && encoding != "" {
-		processors = append(processors, "encoding")
-	}
+	processors := b.GetPayloadProcessors(pipe)
 
 	for _, node := range frontNode {
 		processors = append(processors, node)
@@ -0,0 +1,404 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/monitor"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// BackendName :
+const BackendName = "prometheus_remote_write"
+
+// targetSemaphore : 同一个写入地址共享的并发限制，配置变更时直接调整上限而不是新建，保证总并发不超限
+type targetSemaphore struct {
+	lock   sync.Mutex
+	limit  int64
+	used   int64
+	refs   int
+	notify chan struct{}
+}
+
+func newTargetSemaphore(limit int64) *targetSemaphore {
+	return &targetSemaphore{limit: limit, notify: make(chan struct{})}
+}
+
+// wakeup : 调用方需持有锁
+func (s *targetSemaphore) wakeup() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// Acquire :
+func (s *targetSemaphore) Acquire(ctx context.Context, n int64) error {
+	for {
+		s.lock.Lock()
+		if s.used+n <= s.limit {
+			s.used += n
+			s.lock.Unlock()
+			return nil
+		}
+		notify := s.notify
+		s.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// TryAcquire :
+func (s *targetSemaphore) TryAcquire(n int64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.used+n > s.limit {
+		return false
+	}
+	s.used += n
+	return true
+}
+
+// Release :
+func (s *targetSemaphore) Release(n int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.used -= n
+	s.wakeup()
+}
+
+func (s *targetSemaphore) setLimit(limit int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.limit != limit {
+		s.limit = limit
+		s.wakeup()
+	}
+}
+
+var (
+	targetSemaphores     = make(map[string]*targetSemaphore)
+	targetSemaphoresLock sync.Mutex
+)
+
+// acquireTargetSemaphore : 同一个写入地址共享并发限制，并发数以最近创建的 handler 的配置为准
+func acquireTargetSemaphore(target string, concurrency int64) *targetSemaphore {
+	targetSemaphoresLock.Lock()
+	defer targetSemaphoresLock.Unlock()
+
+	sem, ok := targetSemaphores[target]
+	if !ok {
+		sem = newTargetSemaphore(concurrency)
+		targetSemaphores[target] = sem
+	} else {
+		sem.setLimit(concurrency)
+	}
+	sem.refs++
+	return sem
+}
+
+// releaseTargetSemaphore : 写入地址不再被任何 handler 使用时移除
+func releaseTargetSemaphore(target string) {
+	targetSemaphoresLock.Lock()
+	defer targetSemaphoresLock.Unlock()
+
+	sem, ok := targetSemaphores[target]
+	if !ok {
+		return
+	}
+	sem.refs--
+	if sem.refs <= 0 {
+		delete(targetSemaphores, target)
+	}
+}
+
+// HTTPError : 非 2xx 响应
+type HTTPError struct {
+	Code       int
+	Message    string
+	RetryAfter time.Duration
+}
+
+// Error :
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("remote write response %d: %s", e.Code, e.Message)
+}
+
+// Retryable : 仅 5xx 及 429 需要重试，其余 4xx 重试也不会成功
+func (e *HTTPError) Retryable() bool {
+	return e.Code >= 500 || e.Code == http.StatusTooManyRequests
+}
+
+// BulkHandler
+type BulkHandler struct {
+	pipeline.BaseBulkHandler
+	url      string
+	headers  map[string]string
+	username string
+	password string
+	client   *http.Client
+	sem      utils.Semaphore
+	release  sync.Once
+
+	retryMax        int
+	retryMinBackoff time.Duration
+	retryMaxBackoff time.Duration
+
+	requestObserver *monitor.TimeObserver
+	retriesCounter  prometheus.Counter
+	seriesCounter   prometheus.Counter
+}
+
+// Handle : 将 record 中的每个指标转换为一条序列，维度作为 label
+func (b *BulkHandler) Handle(ctx context.Context, payload define.Payload, killChan chan<- error) (result interface{}, at time.Time, ok bool) {
+	var record define.ETLRecord
+	err := payload.To(&record)
+	if err != nil {
+		logging.Warnf("%v error %v dropped payload %+v", b, err, payload)
+		return nil, time.Time{}, false
+	}
+	if record.Time == nil {
+		logging.Warnf("%v dropped payload %+v for time is empty", b, payload)
+		return nil, time.Time{}, false
+	}
+
+	at = utils.ParseTimeStamp(*record.Time)
+	timestamp := at.UnixNano() / int64(time.Millisecond)
+
+	labels, dropped := SanitizeLabels(record.Dimensions)
+	if len(dropped) > 0 {
+		logging.Debugf("%v skip reserved or conflicting dimensions %v", b, dropped)
+	}
+
+	series := make([]*TimeSeries, 0, len(record.Metrics))
+	for name, value := range record.Metrics {
+		v, err := etl.TransformFloat64(value)
+		if err != nil || v == nil {
+			logging.Debugf("%v skip metric %s with value %v: %v", b, name, value, err)
+			continue
+		}
+
+		ts := &TimeSeries{
+			Labels:  make([]Label, 0, len(labels)+1),
+			Samples: []Sample{{Value: v.(float64), Timestamp: timestamp}},
+		}
+		for k, lv := range labels {
+			ts.Labels = append(ts.Labels, Label{Name: k, Value: lv})
+		}
+		ts.Labels = append(ts.Labels, Label{Name: "__name__", Value: SanitizeMetricName(name)})
+		ts.SortLabels()
+		series = append(series, ts)
+	}
+
+	if len(series) == 0 {
+		logging.Warnf("%v dropped payload %+v for metric is empty", b, payload)
+		return nil, time.Time{}, false
+	}
+	return series, at, true
+}
+
+func (b *BulkHandler) backoff(attempt int, err *HTTPError) time.Duration {
+	if err != nil && err.RetryAfter > 0 {
+		if err.RetryAfter > b.retryMaxBackoff {
+			return b.retryMaxBackoff
+		}
+		return err.RetryAfter
+	}
+
+	backoff := b.retryMinBackoff << uint(attempt)
+	if backoff <= 0 || backoff > b.retryMaxBackoff {
+		backoff = b.retryMaxBackoff
+	}
+	return backoff
+}
+
+func (b *BulkHandler) send(ctx context.Context, body []byte) error {
+	err := b.sem.Acquire(ctx, 1)
+	if err != nil {
+		return err
+	}
+	defer b.sem.Release(1)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", define.AppName)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range b.headers {
+		req.Header.Set(k, v)
+	}
+	if b.username != "" || b.password != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+
+	record := b.requestObserver.Start()
+	resp, err := b.client.Do(req)
+	record.Finish()
+	if err != nil {
+		MonitorRemoteWriteRequests.WithLabelValues(b.url, "error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+	MonitorRemoteWriteRequests.WithLabelValues(b.url, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	httpErr := &HTTPError{
+		Code:    resp.StatusCode,
+		Message: string(bytes.TrimSpace(message)),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		httpErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return httpErr
+}
+
+// Write : 发送请求，5xx/429 及网络错误按指数退避重试
+func (b *BulkHandler) Write(ctx context.Context, series []*TimeSeries) error {
+	body := snappy.Encode(nil, MarshalWriteRequest(series))
+
+	for attempt := 0; ; attempt++ {
+		err := b.send(ctx, body)
+		if err == nil {
+			b.seriesCounter.Add(float64(len(series)))
+			return nil
+		}
+
+		httpErr, isHTTPErr := err.(*HTTPError)
+		if isHTTPErr && !httpErr.Retryable() {
+			return err
+		}
+		if attempt >= b.retryMax {
+			return err
+		}
+
+		backoff := b.backoff(attempt, httpErr)
+		logging.Warnf("%v retry after %v because of error %v", b, backoff, err)
+		b.retriesCounter.Inc()
+		if _, done := utils.TimeoutOrContextDone(ctx, time.After(backoff)); done {
+			return errors.WithMessagef(err, "%v abort retry because of context done", b)
+		}
+	}
+}
+
+// Flush : 重试耗尽后返回错误，交由 adapter 重试及统计
+func (b *BulkHandler) Flush(ctx context.Context, results []interface{}) (int, error) {
+	series := make([]*TimeSeries, 0, len(results))
+	for _, value := range results {
+		series = append(series, value.([]*TimeSeries)...)
+	}
+
+	err := b.Write(ctx, series)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "write %d series", len(series))
+	}
+	return len(results), nil
+}
+
+// Close :
+func (b *BulkHandler) Close() error {
+	b.release.Do(func() {
+		releaseTargetSemaphore(b.url)
+	})
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+// NewBulkHandler :
+func NewBulkHandler(shipper *config.MetaClusterInfo) *BulkHandler {
+	cluster := shipper.AsRemoteWriteCluster()
+	url := cluster.GetURL()
+
+	auth := config.NewAuthInfo(shipper)
+	username, err := auth.GetUserName()
+	if err != nil {
+		logging.Debugf("%v may not establish connection %v: username", url, define.ErrGetAuth)
+	}
+	password, err := auth.GetPassword()
+	if err != nil {
+		logging.Debugf("%v may not establish connection %v: password", url, define.ErrGetAuth)
+	}
+
+	labels := prometheus.Labels{"target": url}
+	return &BulkHandler{
+		url:             url,
+		headers:         cluster.GetHeaders(),
+		username:        username,
+		password:        password,
+		client:          &http.Client{Timeout: DefaultTimeout},
+		sem:             acquireTargetSemaphore(url, cluster.GetConcurrency(DefaultTargetConcurrency)),
+		retryMax:        DefaultRetryMax,
+		retryMinBackoff: DefaultRetryMinBackoff,
+		retryMaxBackoff: DefaultRetryMaxBackoff,
+		requestObserver: monitor.NewTimeObserver(MonitorRemoteWriteDuration.With(labels)),
+		retriesCounter:  MonitorRemoteWriteRetries.With(labels),
+		seriesCounter:   MonitorRemoteWriteSeries.With(labels),
+	}
+}
+
+// Backend :
+type Backend struct {
+	*pipeline.BulkBackendAdapter
+}
+
+// NewBackend :
+func NewBackend(ctx context.Context, name string, maxQps int) *Backend {
+	return &Backend{
+		BulkBackendAdapter: pipeline.NewBulkBackendDefaultAdapter(
+			ctx, name, NewBulkHandler(config.ShipperConfigFromContext(ctx)), maxQps,
+		),
+	}
+}
+
+func init() {
+	define.RegisterBackend(BackendName, func(ctx context.Context, name string) (define.Backend, error) {
+		if config.FromContext(ctx) == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "config is empty")
+		}
+		if config.ShipperConfigFromContext(ctx) == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "shipper config is empty")
+		}
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+
+		options := utils.NewMapHelper(pipeConfig.Option)
+		maxQps, _ := options.GetInt(config.PipelineConfigOptMaxQps)
+		return NewBackend(ctx, pipeConfig.FormatName(name), maxQps), nil
+	})
+}
@@ -0,0 +1,306 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite_test
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/remotewrite"
+)
+
+// consumeMessage : 遍历 message 中的字段
+func consumeMessage(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, fixed uint64, varint uint64)) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			fn(num, typ, v, 0, 0)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			fn(num, typ, nil, v, 0)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			fn(num, typ, nil, 0, v)
+			b = b[n:]
+		default:
+			panic(typ)
+		}
+	}
+}
+
+func unmarshalWriteRequest(b []byte) []*remotewrite.TimeSeries {
+	series := make([]*remotewrite.TimeSeries, 0)
+	consumeMessage(b, func(_ protowire.Number, _ protowire.Type, value []byte, _, _ uint64) {
+		ts := &remotewrite.TimeSeries{}
+		consumeMessage(value, func(num protowire.Number, _ protowire.Type, value []byte, _, _ uint64) {
+			switch num {
+			case 1:
+				var label remotewrite.Label
+				consumeMessage(value, func(num protowire.Number, _ protowire.Type, value []byte, _, _ uint64) {
+					if num == 1 {
+						label.Name = string(value)
+					} else {
+						label.Value = string(value)
+					}
+				})
+				ts.Labels = append(ts.Labels, label)
+			case 2:
+				var sample remotewrite.Sample
+				consumeMessage(value, func(num protowire.Number, _ protowire.Type, _ []byte, fixed, varint uint64) {
+					if num == 1 {
+						sample.Value = math.Float64frombits(fixed)
+					} else {
+						sample.Timestamp = int64(varint)
+					}
+				})
+				ts.Samples = append(ts.Samples, sample)
+			}
+		})
+		series = append(series, ts)
+	})
+	return series
+}
+
+// BackendSuite
+type BackendSuite struct {
+	suite.Suite
+}
+
+func (s *BackendSuite) SetupTest() {
+	remotewrite.DefaultRetryMinBackoff = time.Millisecond
+	remotewrite.DefaultRetryMaxBackoff = 10 * time.Millisecond
+}
+
+func (s *BackendSuite) newShipper(addr string) *config.MetaClusterInfo {
+	u, err := url.Parse(addr)
+	s.NoError(err)
+	port, err := strconv.Atoi(u.Port())
+	s.NoError(err)
+
+	shipper := config.NewMetaClusterInfo()
+	shipper.ClusterType = remotewrite.BackendName
+	shipper.ClusterConfig["schema"] = u.Scheme
+	shipper.ClusterConfig["domain_name"] = u.Hostname()
+	shipper.ClusterConfig["port"] = port
+	shipper.AuthInfo["username"] = ""
+	shipper.AuthInfo["password"] = ""
+	shipper.StorageConfig["headers"] = map[string]interface{}{"X-Scope-OrgID": "bkcc__2"}
+	return shipper
+}
+
+// TestSanitize
+func (s *BackendSuite) TestSanitize() {
+	cases := []struct {
+		name, label, metric string
+	}{
+		{"usage", "usage", "usage"},
+		{"cpu.usage", "cpu_usage", "cpu_usage"},
+		{"node:cpu", "node_cpu", "node:cpu"},
+		{"1min", "_1min", "_1min"},
+		{"负载", "__", "__"},
+		{"", "_", "_"},
+	}
+	for _, c := range cases {
+		s.Equal(c.label, remotewrite.SanitizeLabelName(c.name), c.name)
+		s.Equal(c.metric, remotewrite.SanitizeMetricName(c.name), c.name)
+	}
+}
+
+// TestMarshalWriteRequest
+func (s *BackendSuite) TestMarshalWriteRequest() {
+	series := []*remotewrite.TimeSeries{
+		{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "usage"}, {Name: "ip", Value: "127.0.0.1"}},
+			Samples: []remotewrite.Sample{{Value: 1.5, Timestamp: 1700000000000}},
+		},
+		{
+			Labels:  []remotewrite.Label{{Name: "__name__", Value: "idle"}},
+			Samples: []remotewrite.Sample{{Value: -2, Timestamp: 1700000000000}},
+		},
+	}
+	s.Equal(series, unmarshalWriteRequest(remotewrite.MarshalWriteRequest(series)))
+}
+
+// TestHandle
+func (s *BackendSuite) TestHandle() {
+	handler := remotewrite.NewBulkHandler(s.newShipper("http://127.0.0.1:8480"))
+
+	payload := define.NewJSONPayloadFrom([]byte(`{
+		"time": 1700000000,
+		"dimensions": {"bk_target_ip": "127.0.0.1", "device.name": "eth0", "empty": ""},
+		"metrics": {"usage": 12.5, "name": "not a number"}
+	}`), 0)
+	result, at, ok := handler.Handle(context.Background(), payload, nil)
+	s.True(ok)
+	s.Equal(int64(1700000000), at.Unix())
+
+	series := result.([]*remotewrite.TimeSeries)
+	s.Len(series, 1)
+	s.Equal([]remotewrite.Label{
+		{Name: "__name__", Value: "usage"},
+		{Name: "bk_target_ip", Value: "127.0.0.1"},
+		{Name: "device_name", Value: "eth0"},
+	}, series[0].Labels)
+	s.Equal([]remotewrite.Sample{{Value: 12.5, Timestamp: 1700000000000}}, series[0].Samples)
+
+	_, _, ok = handler.Handle(context.Background(), define.NewJSONPayloadFrom([]byte(`{"time": 1700000000, "metrics": {}}`), 1), nil)
+	s.False(ok)
+
+	// 保留名称及转换后重名的维度不会产生重复的 label
+	payload = define.NewJSONPayloadFrom([]byte(`{
+		"time": 1700000000,
+		"dimensions": {"__name__": "x", "__meta": "y", "负载": "z", "device.name": "eth0", "device_name": "eth1", "device-name": "eth2"},
+		"metrics": {"usage": 1}
+	}`), 2)
+	result, _, ok = handler.Handle(context.Background(), payload, nil)
+	s.True(ok)
+	s.Equal([]remotewrite.Label{
+		{Name: "__name__", Value: "usage"},
+		{Name: "device_name", Value: "eth1"},
+	}, result.([]*remotewrite.TimeSeries)[0].Labels)
+}
+
+// TestFlush
+func (s *BackendSuite) TestFlush() {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal(config.RemoteWriteDefaultPath, r.URL.Path)
+		s.Equal("snappy", r.Header.Get("Content-Encoding"))
+		s.Equal("bkcc__2", r.Header.Get("X-Scope-OrgID"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		s.NoError(err)
+		data, err := snappy.Decode(nil, body)
+		s.NoError(err)
+		s.Len(unmarshalWriteRequest(data), 2)
+
+		// 前两次返回 5xx/429 触发重试
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	handler := remotewrite.NewBulkHandler(s.newShipper(server.URL))
+	results := []interface{}{
+		[]*remotewrite.TimeSeries{{Labels: []remotewrite.Label{{Name: "__name__", Value: "a"}}, Samples: []remotewrite.Sample{{Value: 1}}}},
+		[]*remotewrite.TimeSeries{{Labels: []remotewrite.Label{{Name: "__name__", Value: "b"}}, Samples: []remotewrite.Sample{{Value: 2}}}},
+	}
+	n, err := handler.Flush(context.Background(), results)
+	s.NoError(err)
+	s.Equal(2, n)
+	s.Equal(int32(3), atomic.LoadInt32(&requests))
+}
+
+// TestFlushNotRetryable
+func (s *BackendSuite) TestFlushNotRetryable() {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	handler := remotewrite.NewBulkHandler(s.newShipper(server.URL))
+	err := handler.Write(context.Background(), []*remotewrite.TimeSeries{{Samples: []remotewrite.Sample{{Value: 1}}}})
+	s.Error(err)
+	s.Equal(http.StatusBadRequest, err.(*remotewrite.HTTPError).Code)
+	s.Equal(int32(1), atomic.LoadInt32(&requests))
+
+	// 失败时返回错误，交由 adapter 重试及统计
+	n, err := handler.Flush(context.Background(), []interface{}{[]*remotewrite.TimeSeries{}})
+	s.Error(err)
+	s.Equal(0, n)
+}
+
+// TestTargetConcurrency
+func (s *BackendSuite) TestTargetConcurrency() {
+	var inflight, maxInflight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInflight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInflight, m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	newHandler := func(concurrency int) *remotewrite.BulkHandler {
+		shipper := s.newShipper(server.URL)
+		shipper.StorageConfig["concurrency"] = concurrency
+		return remotewrite.NewBulkHandler(shipper)
+	}
+	write := func(handlers ...*remotewrite.BulkHandler) int32 {
+		atomic.StoreInt32(&maxInflight, 0)
+		var wg sync.WaitGroup
+		for _, handler := range handlers {
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func(handler *remotewrite.BulkHandler) {
+					defer wg.Done()
+					s.NoError(handler.Write(context.Background(), []*remotewrite.TimeSeries{{Samples: []remotewrite.Sample{{Value: 1}}}}))
+				}(handler)
+			}
+		}
+		wg.Wait()
+		return atomic.LoadInt32(&maxInflight)
+	}
+
+	h1 := newHandler(1)
+	s.Equal(int32(1), write(h1))
+
+	// 重载后新旧 handler 同时存在时共享同一个并发限制，以新的配置为准
+	h2 := newHandler(2)
+	s.Equal(int32(2), write(h1, h2))
+	s.NoError(h1.Close())
+	s.NoError(h1.Close())
+	s.Equal(int32(2), write(h2))
+	s.NoError(h2.Close())
+
+	// 全部关闭后重新创建
+	h3 := newHandler(1)
+	defer h3.Close()
+	s.Equal(int32(1), write(h3))
+}
+
+// TestBackendSuite
+func TestBackendSuite(t *testing.T) {
+	suite.Run(t, new(BackendSuite))
+}
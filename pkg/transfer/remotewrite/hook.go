@@ -0,0 +1,60 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+const (
+	ConfKeyRemoteWriteTimeout           = "remote_write.timeout"
+	ConfKeyRemoteWriteTargetConcurrency = "remote_write.target_concurrency"
+	ConfKeyRemoteWriteRetryMax          = "remote_write.retry.max"
+	ConfKeyRemoteWriteRetryMinBackoff   = "remote_write.retry.min_backoff"
+	ConfKeyRemoteWriteRetryMaxBackoff   = "remote_write.retry.max_backoff"
+)
+
+var (
+	// DefaultTimeout 单次请求超时
+	DefaultTimeout = 10 * time.Second
+	// DefaultTargetConcurrency 同一个写入地址的最大并发请求数
+	DefaultTargetConcurrency int64 = 8
+	// DefaultRetryMax 5xx/429 时的最大重试次数
+	DefaultRetryMax = 3
+	// DefaultRetryMinBackoff 首次重试等待时间，之后指数增长
+	DefaultRetryMinBackoff = 100 * time.Millisecond
+	// DefaultRetryMaxBackoff 重试等待时间上限
+	DefaultRetryMaxBackoff = 5 * time.Second
+)
+
+func initConfiguration(c define.Configuration) {
+	c.SetDefault(ConfKeyRemoteWriteTimeout, DefaultTimeout)
+	c.SetDefault(ConfKeyRemoteWriteTargetConcurrency, DefaultTargetConcurrency)
+	c.SetDefault(ConfKeyRemoteWriteRetryMax, DefaultRetryMax)
+	c.SetDefault(ConfKeyRemoteWriteRetryMinBackoff, DefaultRetryMinBackoff)
+	c.SetDefault(ConfKeyRemoteWriteRetryMaxBackoff, DefaultRetryMaxBackoff)
+}
+
+func readConfiguration(c define.Configuration) {
+	DefaultTimeout = c.GetDuration(ConfKeyRemoteWriteTimeout)
+	DefaultTargetConcurrency = c.GetInt64(ConfKeyRemoteWriteTargetConcurrency)
+	DefaultRetryMax = c.GetInt(ConfKeyRemoteWriteRetryMax)
+	DefaultRetryMinBackoff = c.GetDuration(ConfKeyRemoteWriteRetryMinBackoff)
+	DefaultRetryMaxBackoff = c.GetDuration(ConfKeyRemoteWriteRetryMaxBackoff)
+}
+
+func init() {
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPreParse, initConfiguration))
+	utils.CheckError(eventbus.Subscribe(eventbus.EvSysConfigPostParse, readConfiguration))
+}
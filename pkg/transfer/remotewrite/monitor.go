@@ -0,0 +1,57 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/monitor"
+)
+
+var (
+	// MonitorRemoteWriteRequests remote write 请求计数器
+	MonitorRemoteWriteRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "remote_write_requests_total",
+		Help:      "Remote write requests",
+	}, []string{"target", "code"})
+
+	// MonitorRemoteWriteRetries remote write 重试计数器
+	MonitorRemoteWriteRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "remote_write_retries_total",
+		Help:      "Remote write retries",
+	}, []string{"target"})
+
+	// MonitorRemoteWriteSeries remote write 写入的序列数
+	MonitorRemoteWriteSeries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: define.AppName,
+		Name:      "remote_write_series_total",
+		Help:      "Remote write series",
+	}, []string{"target"})
+
+	// MonitorRemoteWriteDuration remote write 请求耗时
+	MonitorRemoteWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: define.AppName,
+		Name:      "remote_write_request_seconds",
+		Help:      "Remote write request seconds",
+		Buckets:   monitor.DefBuckets,
+	}, []string{"target"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		MonitorRemoteWriteRequests,
+		MonitorRemoteWriteRetries,
+		MonitorRemoteWriteSeries,
+		MonitorRemoteWriteDuration,
+	)
+}
@@ -0,0 +1,88 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 以下结构与 prometheus prompb 中 remote write 协议一致，为避免引入 prometheus 的依赖直接编码
+
+// Label :
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample :
+type Sample struct {
+	Value     float64
+	Timestamp int64 // 毫秒
+}
+
+// TimeSeries :
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+// SortLabels : remote write 协议要求 label 按名称排序
+func (ts *TimeSeries) SortLabels() {
+	sort.Slice(ts.Labels, func(i, j int) bool {
+		return ts.Labels[i].Name < ts.Labels[j].Name
+	})
+}
+
+func appendLabel(b []byte, l Label) []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+	buf = protowire.AppendString(buf, l.Name)
+	buf = protowire.AppendTag(buf, 2, protowire.BytesType)
+	buf = protowire.AppendString(buf, l.Value)
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, buf)
+}
+
+func appendSample(b []byte, s Sample) []byte {
+	var buf []byte
+	buf = protowire.AppendTag(buf, 1, protowire.Fixed64Type)
+	buf = protowire.AppendFixed64(buf, math.Float64bits(s.Value))
+	buf = protowire.AppendTag(buf, 2, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(s.Timestamp))
+
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	return protowire.AppendBytes(b, buf)
+}
+
+func appendTimeSeries(b []byte, ts *TimeSeries) []byte {
+	var buf []byte
+	for _, l := range ts.Labels {
+		buf = appendLabel(buf, l)
+	}
+	for _, s := range ts.Samples {
+		buf = appendSample(buf, s)
+	}
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, buf)
+}
+
+// MarshalWriteRequest : 编码为 prompb.WriteRequest
+func MarshalWriteRequest(series []*TimeSeries) []byte {
+	var b []byte
+	for _, ts := range series {
+		b = appendTimeSeries(b, ts)
+	}
+	return b
+}
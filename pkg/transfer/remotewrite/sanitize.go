@@ -0,0 +1,89 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package remotewrite
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cstockton/go-conv"
+)
+
+// SanitizeLabelName : 将非法字符替换为下划线，保证符合 [a-zA-Z_][a-zA-Z0-9_]*
+func SanitizeLabelName(name string) string {
+	return sanitize(name, false)
+}
+
+// SanitizeMetricName : 指标名额外允许冒号，保证符合 [a-zA-Z_:][a-zA-Z0-9_:]*
+func SanitizeMetricName(name string) string {
+	return sanitize(name, true)
+}
+
+func sanitize(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (allowColon && r == ':')
+		if r >= '0' && r <= '9' {
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			valid = true
+		}
+
+		if valid {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// SanitizeLabels : 将维度转换为 label，跳过空值及 __ 开头的保留名称；
+// 多个维度转换后重名时，优先保留本身即合法的维度，其余按名称排序后保留第一个
+func SanitizeLabels(dimensions map[string]interface{}) (labels map[string]string, dropped []string) {
+	keys := make([]string, 0, len(dimensions))
+	for key := range dimensions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		vi, vj := SanitizeLabelName(keys[i]) == keys[i], SanitizeLabelName(keys[j]) == keys[j]
+		if vi != vj {
+			return vi
+		}
+		return keys[i] < keys[j]
+	})
+
+	labels = make(map[string]string, len(keys))
+	for _, key := range keys {
+		value := dimensions[key]
+		if value == nil {
+			continue
+		}
+		v := conv.String(value)
+		// 空值的 label 与不存在等价
+		if v == "" {
+			continue
+		}
+
+		name := SanitizeLabelName(key)
+		if _, ok := labels[name]; ok || strings.HasPrefix(name, "__") {
+			dropped = append(dropped, key)
+			continue
+		}
+		labels[name] = v
+	}
+	return labels, dropped
+}
@@ -180,7 +180,7 @@ func (p *PerformanceCPUDetailProcessor) Process(d define.Payload, outputChan cha
 	err = d.To(&root)
 	if err != nil {
 		logging.Warnf("%v load %#v error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
@@ -194,32 +194,32 @@ func (p *PerformanceCPUDetailProcessor) Process(d define.Payload, outputChan cha
 	perUsage, err := p.perUsageJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract cpu usage %#v error %v", p, root, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	perStat, err := p.perStatJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract cpu stat %#v error %v", p, root, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
 	usageValue, ok := perUsage.([]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted []interface{} but %#v", p, perUsage)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected []interface{} but %T", perUsage))
 		return
 	}
 	statValue, ok := perStat.([]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted []interface{} but %#v", p, perStat)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected []interface{} but %T", perStat))
 		return
 	}
 
 	if len(usageValue) != len(statValue) {
 		logging.Warnf("usageValue length(%d) is not equal with statValue(%d)", len(usageValue), len(statValue))
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrValue, "usage length %d is not equal with stat length %d", len(usageValue), len(statValue)))
 		return
 	}
 
@@ -250,7 +250,7 @@ func (p *PerformanceCPUDetailProcessor) Process(d define.Payload, outputChan cha
 
 	if err != nil {
 		logging.Warnf("%v extract cpu stat error %#v: %v", p, root, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 }
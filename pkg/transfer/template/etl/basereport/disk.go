@@ -156,7 +156,7 @@ func (p *PerformanceDiskProcessor) Process(d define.Payload, outputChan chan<- d
 	err = d.To(&root)
 	if err != nil {
 		logging.Warnf("%v load %#v disk payload error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
@@ -170,32 +170,32 @@ func (p *PerformanceDiskProcessor) Process(d define.Payload, outputChan chan<- d
 	perUsage, err := p.perUsageJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract cpu usage %#v error %#v", p, root, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	perStat, err := p.perPartitionJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract cpu stat %#v error %v", p, root, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
 	usageValue, ok := perUsage.([]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted []interface{} but %#v", p, perUsage)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected []interface{} but %T", perUsage))
 		return
 	}
 	statValue, ok := perStat.([]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted []interface{} but %#v", p, perStat)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected []interface{} but %T", perStat))
 		return
 	}
 
 	if len(usageValue) != len(statValue) {
 		logging.Warnf("usageValue length(%d) is not equal with statValue(%d)", len(usageValue), len(statValue))
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrValue, "usage length %d is not equal with stat length %d", len(usageValue), len(statValue)))
 		return
 	}
 
@@ -226,7 +226,7 @@ func (p *PerformanceDiskProcessor) Process(d define.Payload, outputChan chan<- d
 	}
 	if err != nil {
 		logging.Warnf("%v handle %#v disk stat error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	p.CounterSuccesses.Inc()
@@ -143,7 +143,7 @@ func (p *PerformanceInodeProcessor) Process(d define.Payload, outputChan chan<-
 	err = d.To(&root)
 	if err != nil {
 		logging.Warnf("%v load %#v inode payload error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
@@ -157,25 +157,25 @@ func (p *PerformanceInodeProcessor) Process(d define.Payload, outputChan chan<-
 	perPart, err := p.perPartitionJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract perPart %#v  error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	perInodes, err := p.perInodeUsageJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract perInodes %#v error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	partValue, ok := perPart.([]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted []interface{} but %#v", p, partValue)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected []interface{} but %T", perPart))
 		return
 	}
 	inodeValue, ok := perInodes.([]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted []interface{} but %#v", p, inodeValue)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected []interface{} but %T", perInodes))
 		return
 	}
 	for key, value := range inodeValue {
@@ -206,7 +206,7 @@ func (p *PerformanceInodeProcessor) Process(d define.Payload, outputChan chan<-
 
 	if err != nil {
 		logging.Warnf("%v extract %v inode stat error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	p.CounterSuccesses.Inc()
@@ -139,7 +139,7 @@ func (p *PerformanceIoProcessor) Process(d define.Payload, outputChan chan<- def
 	err = d.To(&root)
 	if err != nil {
 		logging.Warnf("%v load %#v io payload error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
@@ -153,13 +153,13 @@ func (p *PerformanceIoProcessor) Process(d define.Payload, outputChan chan<- def
 	perIo, err := p.perIostatJPath(root)
 	if err != nil {
 		logging.Warnf("%v extract %#v io usage error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	v, ok := perIo.(map[string]interface{})
 	if !ok {
 		logging.Warnf("%v convert value excepted map[string]interface{} but %#v", p, perIo)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrapf(define.ErrType, "expected map[string]interface{} but %T", perIo))
 		return
 	}
 
@@ -196,7 +196,7 @@ func (p *PerformanceIoProcessor) Process(d define.Payload, outputChan chan<- def
 
 	if handled == 0 {
 		logging.Warnf("%v handle %#v failed", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrap(define.ErrValue, "no io stat handled"))
 		return
 	}
 	p.CounterSuccesses.Inc()
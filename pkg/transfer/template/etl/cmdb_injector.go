@@ -44,7 +44,7 @@ func (p *CMDBLevelInjector) Process(d define.Payload, outputChan chan<- define.P
 	err = d.To(raw)
 
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v convert payload %#v error %v", p, d, err)
 		return
 	}
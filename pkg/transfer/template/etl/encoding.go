@@ -35,14 +35,14 @@ func (p *EncodingHandler) Process(d define.Payload, outputChan chan<- define.Pay
 	err := d.To(&data)
 	if err != nil {
 		logging.Warnf("%v load %#v error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
 	data, err = p.decoder.Bytes(data)
 	if err != nil && p.strict {
 		logging.Warnf("%v decode %#v error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
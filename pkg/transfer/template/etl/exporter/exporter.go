@@ -218,11 +218,11 @@ func (p *Processor) Process(d define.Payload, outputChan chan<- define.Payload,
 	err := d.To(&record)
 	if err != nil {
 		logging.Warnf("%v load payload error %v", p, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	} else if record.PrometheusCollectorMetric == nil || record.Labels == nil {
 		logging.Warnf("%v load payload failed", p)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrap(define.ErrValue, "prometheus metric or labels is empty"))
 		return
 	}
 
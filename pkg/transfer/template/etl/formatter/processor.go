@@ -49,7 +49,7 @@ func (p *Processor) Process(d define.Payload, outputChan chan<- define.Payload,
 	record := new(define.ETLRecord)
 	err := d.To(record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Errorf("%v convert payload %#v error %v", p, d, err)
 		return
 	}
@@ -66,7 +66,7 @@ func (p *Processor) Process(d define.Payload, outputChan chan<- define.Payload,
 	})
 
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.MinuteErrorfSampling(p.String(), "%v handle payload %#v failed: %v", p, d, err)
 		return
 	}
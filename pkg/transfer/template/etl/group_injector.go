@@ -37,7 +37,7 @@ func (p *GroupInjector) Process(d define.Payload, outputChan chan<- define.Paylo
 	raw := new(GroupedRecord)
 	err := d.To(raw)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v convert payload %#v error %v", p, d, err)
 		return
 	}
@@ -0,0 +1,295 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package log
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	template "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// 清洗步骤类型
+const (
+	ChainedStepJSON      = "json"
+	ChainedStepGrok      = "grok"
+	ChainedStepRegexp    = "regexp"
+	ChainedStepSeparator = "separator"
+	ChainedStepKV        = "kv"
+	ChainedStepDate      = "date"
+	ChainedStepDrop      = "drop"
+)
+
+// chainedStep : 单个清洗步骤，提取结果合并到顶层供后续步骤及字段使用
+type chainedStep struct {
+	kind          string
+	field         string
+	target        string
+	fields        []string
+	ignoreFailure bool
+	transform     etl.TransformFn
+}
+
+func (s *chainedStep) apply(container etl.Container) error {
+	if s.kind == ChainedStepDrop {
+		for _, name := range s.fields {
+			err := container.Del(name)
+			if err != nil && errors.Cause(err) != define.ErrItemNotFound {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, err := container.Get(s.field)
+	if err != nil {
+		return errors.WithMessagef(err, "field %s", s.field)
+	}
+
+	result, err := s.transform(value)
+	if err != nil {
+		return err
+	}
+
+	if s.kind == ChainedStepDate {
+		return container.Put(s.target, result)
+	}
+
+	values, ok := result.(map[string]interface{})
+	if !ok {
+		if result == nil {
+			return nil
+		}
+		return errors.Wrapf(define.ErrType, "expect type map[string]interface{} but got %T", result)
+	}
+
+	// 正则类步骤未匹配时所有分组均为空
+	if s.kind == ChainedStepGrok || s.kind == ChainedStepRegexp {
+		matched := false
+		for _, v := range values {
+			matched = matched || v != nil
+		}
+		if !matched {
+			return errors.Wrapf(define.ErrValue, "field %s not matched", s.field)
+		}
+	}
+
+	for key, v := range values {
+		err = container.Put(key, v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getStringListOption : 获取字符串列表配置，未配置时返回空列表，配置了非列表或列表中包含非字符串元素时返回错误
+func getStringListOption(helper *utils.MapHelper, key string) ([]string, error) {
+	value, ok := helper.Get(key)
+	if !ok {
+		return nil, nil
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.Wrapf(define.ErrType, "%s expect string list but got %#v", key, value)
+	}
+
+	results := make([]string, 0, len(values))
+	for index, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Wrapf(define.ErrType, "%s[%d] expect string but got %#v", key, index, v)
+		}
+		results = append(results, s)
+	}
+	return results, nil
+}
+
+func toStringMap(value interface{}) map[string]string {
+	items, _ := value.(map[string]interface{})
+	results := make(map[string]string, len(items))
+	for key, v := range items {
+		if s, ok := v.(string); ok {
+			results[key] = s
+		}
+	}
+	return results
+}
+
+// getStringOption : 获取字符串配置，未配置时使用默认值，配置了非字符串或空字符串时返回错误
+func getStringOption(helper *utils.MapHelper, key, defaultValue string) (string, error) {
+	value, ok := helper.Get(key)
+	if !ok {
+		return defaultValue, nil
+	}
+	result, ok := value.(string)
+	if !ok || result == "" {
+		return "", errors.Wrapf(define.ErrValue, "%s expect non-empty string but got %#v", key, value)
+	}
+	return result, nil
+}
+
+// newChainedStep : 根据配置创建清洗步骤
+func newChainedStep(option map[string]interface{}) (*chainedStep, error) {
+	helper := utils.NewMapHelper(option)
+	kind, _ := helper.GetString("type")
+	field, err := getStringOption(helper, "field", FieldName)
+	if err != nil {
+		return nil, err
+	}
+	step := &chainedStep{
+		kind:  kind,
+		field: field,
+	}
+	step.ignoreFailure, _ = helper.GetBool("ignore_failure")
+
+	switch kind {
+	case ChainedStepJSON:
+		step.transform = etl.TransformMapByJSON
+	case ChainedStepGrok:
+		pattern, ok := helper.GetString("pattern")
+		if !ok || pattern == "" {
+			return nil, errors.Wrapf(define.ErrValue, "grok pattern not set")
+		}
+		regex, err := etl.CompileGrokRegexp(pattern, toStringMap(helper.GetOrDefault("patterns", nil)))
+		if err != nil {
+			return nil, err
+		}
+		step.transform = etl.TransformMapByRegexpObject(regex)
+	case ChainedStepRegexp:
+		pattern, ok := helper.GetString("pattern")
+		if !ok || pattern == "" {
+			return nil, errors.Wrapf(define.ErrValue, "regexp pattern not set")
+		}
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "compile regexp %s", pattern)
+		}
+		step.transform = etl.TransformMapByRegexpObject(regex)
+	case ChainedStepSeparator:
+		separator, ok := helper.GetString("separator")
+		if !ok || separator == "" {
+			return nil, errors.Wrapf(define.ErrValue, "separator not set")
+		}
+		fields, err := getStringListOption(helper, "fields")
+		if err != nil {
+			return nil, err
+		}
+		step.transform = etl.TransformMapBySeparator(separator, fields)
+	case ChainedStepKV:
+		fieldSplit, err := getStringOption(helper, "field_split", " ")
+		if err != nil {
+			return nil, err
+		}
+		valueSplit, err := getStringOption(helper, "value_split", "=")
+		if err != nil {
+			return nil, err
+		}
+		step.transform = etl.TransformMapByKeyValue(fieldSplit, valueSplit)
+	case ChainedStepDate:
+		name, ok := helper.GetString(config.MetaFieldOptTimeFormat)
+		if !ok {
+			return nil, errors.Wrapf(define.ErrValue, "%s not set", config.MetaFieldOptTimeFormat)
+		}
+		if _, ok = define.GetTimeLayout(name); !ok {
+			return nil, errors.Wrapf(define.ErrValue, "unknown layout name %s", name)
+		}
+		timezone, _ := helper.GetInt(config.MetaFieldOptTimeZone)
+		step.target, err = getStringOption(helper, "target_field", define.TimeFieldName)
+		if err != nil {
+			return nil, err
+		}
+		step.transform = etl.TransformTimeByName(name, timezone)
+	case ChainedStepDrop:
+		step.fields, err = getStringListOption(helper, "fields")
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Wrapf(define.ErrType, "unknown step type %s", kind)
+	}
+
+	return step, nil
+}
+
+// newChainedSteps : 解析结果表配置中的清洗步骤列表
+func newChainedSteps(rt *config.MetaResultTableConfig) ([]*chainedStep, error) {
+	options := utils.NewMapHelper(rt.Option)
+	items, ok := options.GetArray(config.ResultTableOptLogChainedSteps)
+	if !ok || len(items) == 0 {
+		return nil, errors.Wrapf(define.ErrOperationForbidden, "chained steps not set")
+	}
+
+	steps := make([]*chainedStep, 0, len(items))
+	for index, item := range items {
+		option, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errors.Wrapf(define.ErrType, "step %d expect type map[string]interface{} but got %T", index, item)
+		}
+		step, err := newChainedStep(option)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "step %d", index)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// NewChainedLogProcessor : 在字段提取之前，对拆分后的每条日志依次执行清洗步骤
+func NewChainedLogProcessor(ctx context.Context, name string) (*template.RecordProcessor, error) {
+	steps, err := newChainedSteps(config.ResultTableConfigFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLogProcessor(ctx, name, func(record *etl.TSSchemaRecord, decoder *etl.PayloadDecoder) {
+		decoder.Register(func(containers []etl.Container) ([]etl.Container, error) {
+			results := make([]etl.Container, 0, len(containers))
+		loop:
+			for _, container := range containers {
+				for index, step := range steps {
+					err := step.apply(container)
+					if err == nil {
+						continue
+					}
+					if step.ignoreFailure {
+						logging.Debugf("%s step %d %s ignore error %v", name, index, step.kind, err)
+						continue
+					}
+					logging.MinuteErrorfSampling(name, "%s step %d %s dropped %v because of error %v", name, index, step.kind, container, err)
+					continue loop
+				}
+				results = append(results, container)
+			}
+			return results, nil
+		})
+	})
+}
+
+func init() {
+	define.RegisterDataProcessor("chained_log", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		rt := config.ResultTableConfigFromContext(ctx)
+		if rt == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "result table config is empty")
+		}
+		return NewChainedLogProcessor(ctx, pipeConfig.FormatName(name))
+	})
+}
@@ -0,0 +1,124 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package log_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
+)
+
+// ChainedLogTest
+type ChainedLogTest struct {
+	testsuite.ETLSuite
+}
+
+func (s *ChainedLogTest) setConfig(steps string) {
+	s.CTX = testsuite.PipelineConfigStringInfoContext(
+		s.CTX, s.PipelineConfig,
+		`{"result_table_list":[{"option":{"chained_steps":`+steps+`},"schema_type":"free","result_table":"2_log.chained_log","field_list":[{"default_value":null,"alias_name":"log","tag":"metric","type":"string","is_config_by_user":true,"field_name":"log","option":{}},{"default_value":null,"field_name":"client","tag":"dimension","type":"string","is_config_by_user":true,"alias_name":"","option":{}},{"default_value":null,"field_name":"method","tag":"dimension","type":"string","is_config_by_user":true,"alias_name":"","option":{}},{"default_value":null,"field_name":"path","tag":"dimension","type":"string","is_config_by_user":true,"alias_name":"","option":{}},{"default_value":null,"field_name":"level","tag":"dimension","type":"string","is_config_by_user":true,"alias_name":"","option":{}},{"default_value":null,"field_name":"user","tag":"metric","type":"string","is_config_by_user":true,"alias_name":"","option":{}},{"default_value":null,"field_name":"cost","tag":"metric","type":"float","is_config_by_user":true,"alias_name":"","option":{}}]}],"source_label":"bk_monitor","type_label":"log","data_id":1200145,"etl_config":"bk_log_chained","option":{"group_info_alias":"_private_"}}`,
+	)
+}
+
+// TestUsage :
+func (s *ChainedLogTest) TestUsage() {
+	s.setConfig(`[
+		{"type":"json"},
+		{"type":"grok","field":"message","pattern":"%{IPORHOST:client} %{WORD:method} %{URIPATHPARAM:path} %{GREEDYDATA:rest}"},
+		{"type":"kv","field":"rest","field_split":" ","value_split":"="},
+		{"type":"date","field":"ts","time_format":"datetime","time_zone":8},
+		{"type":"drop","fields":["message","rest","ts"]}
+	]`)
+
+	processor, err := log.NewChainedLogProcessor(s.CTX, "test")
+	s.NoError(err)
+
+	s.Run(`{"_path_":"/tmp/access.log","_private_":[{"bk_app_code":"bk_log_search"}],"_server_":"127.0.0.1","_value_":["not json","{\"level\":\"INFO\",\"ts\":\"2019-10-08 17:41:49\",\"message\":\"10.0.0.1 GET /api/v1?x=1 user=\\\"admin\\\" cost=0.5\"}"]}`,
+		processor,
+		func(result map[string]interface{}) {
+			s.EqualRecord(result, map[string]interface{}{
+				"dimensions": map[string]interface{}{
+					"client": "10.0.0.1",
+					"method": "GET",
+					"path":   "/api/v1?x=1",
+					"level":  "INFO",
+				},
+				"metrics": map[string]interface{}{
+					"log":            `{"level":"INFO","ts":"2019-10-08 17:41:49","message":"10.0.0.1 GET /api/v1?x=1 user=\"admin\" cost=0.5"}`,
+					"user":           "admin",
+					"cost":           0.5,
+					"_iteration_idx": 1.0,
+				},
+				"time": 1570527709.0,
+				"group_info": []map[string]string{
+					{"bk_app_code": "bk_log_search"},
+				},
+			})
+		},
+	)
+}
+
+// TestIgnoreFailure :
+func (s *ChainedLogTest) TestIgnoreFailure() {
+	s.setConfig(`[
+		{"type":"json","ignore_failure":true},
+		{"type":"regexp","pattern":"^(?P<client>\\S+) (?P<method>\\w+)"},
+		{"type":"grok","pattern":"%{LOGLEVEL:level}$","ignore_failure":true}
+	]`)
+
+	processor, err := log.NewChainedLogProcessor(s.CTX, "test")
+	s.NoError(err)
+
+	s.Run(`{"_private_":[],"_value_":["127.0.0.1 POST done"]}`,
+		processor,
+		func(result map[string]interface{}) {
+			dimensions := result["dimensions"].(map[string]interface{})
+			s.Equal("127.0.0.1", dimensions["client"])
+			s.Equal("POST", dimensions["method"])
+			s.Nil(dimensions["level"])
+		},
+	)
+}
+
+// TestInvalidSteps :
+func (s *ChainedLogTest) TestInvalidSteps() {
+	cases := []string{
+		`[]`,
+		`[{"type":"unknown"}]`,
+		`[{"type":"grok"}]`,
+		`[{"type":"grok","pattern":"%{NOT_EXISTS:x}"}]`,
+		`[{"type":"regexp","pattern":"(?P<x>"}]`,
+		`[{"type":"separator"}]`,
+		`[{"type":"date","field":"ts"}]`,
+		`[{"type":"date","field":"ts","time_format":"unknown"}]`,
+		`["json"]`,
+		`[{"type":"json","field":""}]`,
+		`[{"type":"kv","field_split":""}]`,
+		`[{"type":"kv","value_split":null}]`,
+		`[{"type":"json","field":1}]`,
+		`[{"type":"date","field":"ts","time_format":"datetime","target_field":""}]`,
+		`[{"type":"separator","separator":"|","fields":["a",1]}]`,
+		`[{"type":"separator","separator":"|","fields":"a"}]`,
+		`[{"type":"drop","fields":["a",null]}]`,
+	}
+	for _, c := range cases {
+		s.setConfig(c)
+		_, err := log.NewChainedLogProcessor(s.CTX, "test")
+		s.Error(err, c)
+	}
+}
+
+// TestChainedLogTest :
+func TestChainedLogTest(t *testing.T) {
+	suite.Run(t, new(ChainedLogTest))
+}
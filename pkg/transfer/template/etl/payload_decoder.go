@@ -0,0 +1,87 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/utils"
+)
+
+// PayloadDecodeHandler : 按 schema 将 protobuf/avro 数据解析后转为 json，后续节点无需感知原始格式
+type PayloadDecodeHandler struct {
+	*define.BaseDataProcessor
+	*define.ProcessorMonitor
+	decoder etl.SchemaDecoder
+}
+
+// Process :
+func (p *PayloadDecodeHandler) Process(d define.Payload, outputChan chan<- define.Payload, killChan chan<- error) {
+	var data []byte
+	err := d.To(&data)
+	if err != nil {
+		logging.Warnf("%v load %#v error %v", p, d, err)
+		p.Reject(d, err)
+		return
+	}
+
+	container, err := p.decoder.Decode(data)
+	if err != nil {
+		logging.Warnf("%v decode %#v error %v", p, d, err)
+		p.Reject(d, err)
+		return
+	}
+
+	err = d.From(etl.ContainerToMap(container))
+	if err != nil {
+		logging.Errorf("%v dump payload from %v error: %v", p, d, err)
+		p.Reject(d, err)
+		return
+	}
+
+	outputChan <- d
+	p.CounterSuccesses.Inc()
+}
+
+// NewPayloadDecodeHandler :
+func NewPayloadDecodeHandler(ctx context.Context, name string, format string, schema map[string]interface{}) (*PayloadDecodeHandler, error) {
+	decoder, err := etl.NewSchemaDecoder(format, schema)
+	if err != nil {
+		return nil, err
+	}
+	return &PayloadDecodeHandler{
+		BaseDataProcessor: define.NewBaseDataProcessor(name),
+		ProcessorMonitor:  pipeline.NewDataProcessorMonitor(name, config.PipelineConfigFromContext(ctx)),
+		decoder:           decoder,
+	}, nil
+}
+
+func init() {
+	define.RegisterDataProcessor("payload_decoder", func(ctx context.Context, name string) (define.DataProcessor, error) {
+		pipeConfig := config.PipelineConfigFromContext(ctx)
+		if pipeConfig == nil {
+			return nil, errors.Wrapf(define.ErrOperationForbidden, "pipeline config is empty")
+		}
+		helper := utils.NewMapHelper(pipeConfig.Option)
+		format := helper.MustGetString(config.PipelineConfigOptPayloadFormat)
+		schema, ok := helper.GetOrDefault(config.PipelineConfigOptPayloadSchema, map[string]interface{}{}).(map[string]interface{})
+		if !ok {
+			return nil, errors.Wrapf(define.ErrType, "%s should be a map", config.PipelineConfigOptPayloadSchema)
+		}
+		return NewPayloadDecodeHandler(ctx, name, format, schema)
+	})
+}
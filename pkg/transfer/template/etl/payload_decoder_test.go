@@ -0,0 +1,62 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package etl_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/template/etl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/testsuite"
+)
+
+// PayloadDecodeHandlerSuite
+type PayloadDecodeHandlerSuite struct {
+	testsuite.ETLSuite
+}
+
+// TestAvro
+func (s *PayloadDecodeHandlerSuite) TestAvro() {
+	s.CheckKillChan(s.KillCh)
+
+	processor, err := etl.NewPayloadDecodeHandler(s.CTX, "test", "avro", map[string]interface{}{
+		"schema": `{"type":"record","name":"Log","fields":[{"name":"log","type":"string"},{"name":"count","type":"long"}]}`,
+	})
+	s.NoError(err)
+
+	data := []byte{byte(len("hello") * 2)}
+	data = append(data, "hello"...)
+	data = binary.AppendVarint(data, 10)
+	outputChan := make(chan define.Payload, 1)
+	processor.Process(define.NewJSONPayloadFrom(data, 0), outputChan, s.KillCh)
+	output := <-outputChan
+
+	result := make(map[string]interface{})
+	s.NoError(output.To(&result))
+	s.Equal(map[string]interface{}{"log": "hello", "count": float64(10)}, result)
+
+	// 解析失败的数据被丢弃
+	processor.Process(define.NewJSONPayloadFrom([]byte(`{"log":"hello"}`), 0), outputChan, s.KillCh)
+	s.Len(outputChan, 0)
+}
+
+// TestUnknownFormat
+func (s *PayloadDecodeHandlerSuite) TestUnknownFormat() {
+	_, err := etl.NewPayloadDecodeHandler(s.CTX, "test", "thrift", map[string]interface{}{})
+	s.Error(err)
+}
+
+// TestPayloadDecodeHandlerSuite
+func TestPayloadDecodeHandlerSuite(t *testing.T) {
+	suite.Run(t, new(PayloadDecodeHandlerSuite))
+}
@@ -13,6 +13,7 @@ import (
 	"context"
 
 	"github.com/cstockton/go-conv"
+	"github.com/pkg/errors"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/config"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/define"
@@ -37,16 +38,19 @@ func (p *RecordProcessor) Process(d define.Payload, outputChan chan<- define.Pay
 	containers, err := p.Decode(d)
 	if err != nil {
 		logging.MinuteErrorfSampling(p.String(), "%v load %#v error %v", p, d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 	if len(containers) == 0 {
 		logging.Debugf("%v loaded an empty payload %v", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrap(define.ErrValue, "empty payload"))
 		return
 	}
 
-	handled := 0
+	var (
+		handled   = 0
+		lastError error
+	)
 	for _, from := range containers {
 		if bizID, err := from.Get(define.RecordBizID); err == nil {
 			if _, ok := p.DisabledBizIDs[conv.String(bizID)]; ok {
@@ -59,6 +63,7 @@ func (p *RecordProcessor) Process(d define.Payload, outputChan chan<- define.Pay
 		err = p.schema.Transform(from, to)
 		if err != nil {
 			logging.MinuteErrorfSampling(p.String(), "%v transform %v error %v", p, d, err)
+			lastError = err
 			continue
 		}
 
@@ -74,7 +79,7 @@ func (p *RecordProcessor) Process(d define.Payload, outputChan chan<- define.Pay
 
 	if handled == 0 {
 		logging.Warnf("%v handle %#v failed", p, d)
-		p.CounterFails.Inc()
+		p.Reject(d, lastError)
 	} else {
 		logging.Debugf("%v push %d items from %v", p, handled, d)
 		p.CounterSuccesses.Inc()
@@ -207,16 +207,17 @@ func (p *MetricsReportProcessor) Process(d define.Payload, outputChan chan<- def
 	var record define.ETLRecord
 	if err := d.To(&record); err != nil {
 		logging.Errorf("payload %v to recorder failed: %v", d, err)
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		return
 	}
 
 	var gotNewDimensions bool
+	var invalidMetrics []string
 	now := timeUnix()
 	for metric := range record.Metrics {
 		if !metricNamePattern.MatchString(metric) {
 			logging.Warnf("the metric name: %s does not satisfy the regex: %s", metric, metricNamePattern.String())
-			p.CounterFails.Inc()
+			invalidMetrics = append(invalidMetrics, metric)
 			continue
 		}
 
@@ -267,6 +268,12 @@ func (p *MetricsReportProcessor) Process(d define.Payload, outputChan chan<- def
 		}
 	}
 
+	// 同一条数据只投递一次死信
+	if len(invalidMetrics) > 0 {
+		sort.Strings(invalidMetrics)
+		p.Reject(d, errors.Errorf("metric names %v do not satisfy the regex: %s", invalidMetrics, metricNamePattern.String()))
+	}
+
 	if gotNewDimensions {
 		select {
 		case p.dimensionUpdated <- struct{}{}:
@@ -77,19 +77,19 @@ func (p *Processor) Process(d define.Payload, outputChan chan<- define.Payload,
 	record := new(Record)
 	err := d.To(record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v convert record error %v: %v", p, err, d)
 		return
 	}
 
 	if record.Time == nil {
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrap(define.ErrValue, "record time is empty"))
 		logging.Warnf("%v record time is empty: %v", p, d)
 		return
 	}
 
 	if record.Metrics == nil || len(record.Metrics) == 0 {
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrap(define.ErrValue, "record metrics is empty"))
 		logging.Warnf("%v record metrics is empty: %v", p, d)
 		return
 	}
@@ -102,7 +102,7 @@ func (p *Processor) Process(d define.Payload, outputChan chan<- define.Payload,
 
 	output, err := define.DerivePayload(d, record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v create payload error %v: %v", p, err, d)
 		return
 	}
@@ -43,14 +43,14 @@ func (p *TimestampConvertProcessor) Process(d define.Payload, outputChan chan<-
 	record := new(TimestampRecord)
 	err := d.To(record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v convert record error %v: %v", p, err, d)
 		return
 	}
 
 	// 通过 bkmonitorproxy 上报过来只有 timestamp 字段
 	if record.Timestamp == nil || *record.Timestamp == 0.0 {
-		p.CounterFails.Inc()
+		p.Reject(d, errors.Wrap(define.ErrValue, "record time is empty"))
 		logging.Warnf("%v time series record time is empty: %v", p, d)
 		return
 	}
@@ -73,7 +73,7 @@ func (p *TimestampConvertProcessor) Process(d define.Payload, outputChan chan<-
 
 	output, err := define.DerivePayload(d, record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v create payload error %v: %v", p, err, d)
 		return
 	}
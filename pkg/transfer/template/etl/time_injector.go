@@ -32,7 +32,7 @@ func (p *TimeInjector) Process(d define.Payload, outputChan chan<- define.Payloa
 	record := new(define.ETLRecord)
 	err := d.To(record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v convert payload %#v error %v", p, d, err)
 		return
 	}
@@ -41,7 +41,7 @@ func (p *TimeInjector) Process(d define.Payload, outputChan chan<- define.Payloa
 
 	payload, err := define.DerivePayload(d, record)
 	if err != nil {
-		p.CounterFails.Inc()
+		p.Reject(d, err)
 		logging.Warnf("%v handle %#v failed: %v", p, d, err)
 		return
 	}
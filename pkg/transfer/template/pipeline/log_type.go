@@ -37,6 +37,7 @@ const (
 	TypeLogJson      = "bk_log_json"
 	TypeLogSeparator = "bk_log_separator"
 	TypeLogRegexp    = "bk_log_regexp"
+	TypeLogChained   = "bk_log_chained"
 )
 
 func init() {
@@ -44,4 +45,5 @@ func init() {
 	define.RegisterPipeline(TypeLogJson, StdLogPipelineCreatorByETLName("json_log"))
 	define.RegisterPipeline(TypeLogSeparator, StdLogPipelineCreatorByETLName("separator_log"))
 	define.RegisterPipeline(TypeLogRegexp, StdLogPipelineCreatorByETLName("regexp_log"))
+	define.RegisterPipeline(TypeLogChained, StdLogPipelineCreatorByETLName("chained_log"))
 }
@@ -21,6 +21,7 @@ import (
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/logging"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/pipeline"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/redis"
+	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/remotewrite"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/scheduler"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/shipper"
 	_ "github.com/TencentBlueKing/bkmonitor-datalink/pkg/transfer/shipper/echo"
@@ -0,0 +1,225 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+)
+
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Record 单次查询的审计记录
+type Record struct {
+	Time     time.Time `json:"time"`
+	TraceID  string    `json:"trace_id"`
+	User     string    `json:"user"`
+	Source   string    `json:"source"`
+	SpaceUid string    `json:"space_uid"`
+
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Query 归一化后的查询语句，无法归一化时为原始请求内容
+	Query string `json:"query"`
+	// Truncated 请求内容或查询语句超过长度限制被截断
+	Truncated bool     `json:"truncated,omitempty"`
+	Tables    []string `json:"tables,omitempty"`
+
+	// Duration 请求耗时，单位毫秒
+	Duration int64 `json:"duration"`
+	Series   int64 `json:"series"`
+	Points   int64 `json:"points"`
+	// ResultSize 返回内容大小，单位 byte
+	ResultSize int `json:"result_size"`
+
+	Code      int    `json:"code"`
+	Status    string `json:"status"`
+	ErrorCode string `json:"error_code,omitempty"`
+}
+
+// Recorder 异步写入审计记录，缓冲区满时丢弃记录，不影响查询本身
+type Recorder struct {
+	sink          Sink
+	ch            chan *Record
+	batchSize     int
+	flushInterval time.Duration
+
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewRecorder
+func NewRecorder(sink Sink, bufferSize, batchSize int, flushInterval time.Duration) *Recorder {
+	if bufferSize <= 0 {
+		bufferSize = 10000
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	r := &Recorder{
+		sink:          sink,
+		ch:            make(chan *Record, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+	r.wg.Add(1)
+	go r.loop()
+	return r
+}
+
+// Write 写入缓冲区，返回是否写入成功
+func (r *Recorder) Write(ctx context.Context, record *Record) bool {
+	select {
+	case r.ch <- record:
+		return true
+	default:
+		metric.AuditRecordDroppedInc(ctx, r.sink.Name(), "buffer_full")
+		return false
+	}
+}
+
+// Close 写出缓冲区中剩余的记录并关闭 sink
+func (r *Recorder) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.ch)
+		r.wg.Wait()
+		err = r.sink.Close()
+	})
+	return err
+}
+
+func (r *Recorder) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Record, 0, r.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.sink.Write(batch); err != nil {
+			log.Errorf(context.TODO(), "write %d audit records to %s failed: %s", len(batch), r.sink.Name(), err)
+			metric.AuditRecordDroppedInc(context.TODO(), r.sink.Name(), "write_failed")
+		}
+		batch = make([]*Record, 0, r.batchSize)
+	}
+
+	for {
+		select {
+		case record, ok := <-r.ch:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+var (
+	recorderLock sync.RWMutex
+	recorder     *Recorder
+)
+
+// SetRecorder 替换全局的 Recorder，旧的 Recorder 会被关闭
+func SetRecorder(r *Recorder) {
+	recorderLock.Lock()
+	old := recorder
+	recorder = r
+	recorderLock.Unlock()
+
+	if old != nil {
+		if err := old.Close(); err != nil {
+			log.Errorf(context.TODO(), "close audit recorder failed: %s", err)
+		}
+	}
+}
+
+// Match 判断请求路径是否需要审计
+func Match(path string) bool {
+	for _, p := range Paths {
+		if p != "" && strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	sourcesLock sync.RWMutex
+	sources     = make(map[string]struct{})
+)
+
+// metricSource 限制指标中 source 维度的取值个数，超出 MaxSources 的新来源记为 SourceOther
+func metricSource(source string) string {
+	if MaxSources <= 0 {
+		return source
+	}
+
+	sourcesLock.RLock()
+	_, ok := sources[source]
+	sourcesLock.RUnlock()
+	if ok {
+		return source
+	}
+
+	sourcesLock.Lock()
+	defer sourcesLock.Unlock()
+	if _, ok = sources[source]; ok {
+		return source
+	}
+	if len(sources) >= MaxSources {
+		return SourceOther
+	}
+	sources[source] = struct{}{}
+	return source
+}
+
+// Write 统计按来源和空间聚合的指标，开启审计时写入审计记录
+func Write(ctx context.Context, record *Record) {
+	source := metricSource(record.Source)
+	metric.SourceQueryInc(ctx, source, record.SpaceUid, record.Status)
+	metric.SourceQuerySecond(ctx, time.Duration(record.Duration)*time.Millisecond, source, record.SpaceUid)
+	if record.Points > 0 {
+		metric.SourceQueryPointsAdd(ctx, float64(record.Points), source, record.SpaceUid)
+	}
+
+	recorderLock.RLock()
+	defer recorderLock.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	if MaxQueryLength > 0 && len(record.Query) > MaxQueryLength {
+		record.Query = record.Query[:MaxQueryLength]
+		record.Truncated = true
+	}
+	recorder.Write(ctx, record)
+}
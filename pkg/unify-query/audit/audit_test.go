@@ -0,0 +1,203 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+type memorySink struct {
+	lock    sync.Mutex
+	batches [][]*Record
+	closed  bool
+}
+
+func (s *memorySink) Name() string { return "memory" }
+
+func (s *memorySink) Write(records []*Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	return nil
+}
+
+func readLines(t *testing.T, path string) []*Record {
+	f, err := os.Open(path)
+	assert.Nil(t, err)
+	defer f.Close()
+
+	records := make([]*Record, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		record := &Record{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMatch(t *testing.T) {
+	Paths = []string{"/query", "/api/v1"}
+	defer func() {
+		Paths = nil
+	}()
+
+	assert.True(t, Match("/query/ts"))
+	assert.True(t, Match("/api/v1/query_range"))
+	assert.False(t, Match("/metrics"))
+	assert.False(t, Match("/print"))
+}
+
+func TestRecorder(t *testing.T) {
+	log.InitTestLogger()
+
+	sink := &memorySink{}
+	r := NewRecorder(sink, 10, 2, time.Hour)
+	for i := 0; i < 5; i++ {
+		assert.True(t, r.Write(context.Background(), &Record{SpaceUid: "bkcc__2", Series: int64(i)}))
+	}
+	assert.Nil(t, r.Close())
+
+	// 按 batch size 写出，关闭时写出剩余的记录
+	assert.True(t, sink.closed)
+	assert.Len(t, sink.batches, 3)
+	assert.Len(t, sink.batches[2], 1)
+	assert.Equal(t, int64(4), sink.batches[2][0].Series)
+
+	// 重复关闭不报错
+	assert.Nil(t, r.Close())
+}
+
+func TestRecorderBufferFull(t *testing.T) {
+	log.InitTestLogger()
+
+	block := make(chan struct{})
+	sink := &blockSink{block: block}
+	r := NewRecorder(sink, 1, 1, time.Hour)
+
+	// 第一条被 loop 取走后阻塞在 sink 中，第二条占满缓冲区，第三条被丢弃
+	assert.True(t, r.Write(context.Background(), &Record{}))
+	assert.Eventually(t, func() bool { return len(r.ch) == 0 }, time.Second, time.Millisecond)
+	assert.True(t, r.Write(context.Background(), &Record{}))
+	assert.False(t, r.Write(context.Background(), &Record{}))
+
+	close(block)
+	assert.Nil(t, r.Close())
+}
+
+type blockSink struct {
+	memorySink
+	block chan struct{}
+}
+
+func (s *blockSink) Write(records []*Record) error {
+	<-s.block
+	return s.memorySink.Write(records)
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	record := &Record{SpaceUid: "bkcc__2", Query: "sum(a)", Tables: []string{"system.cpu_summary"}}
+
+	sink, err := NewFileSink(path, 1, 1)
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, sink.Write([]*Record{record}))
+	}
+	// 轮转后历史文件保留在同一目录下，新记录写入新文件
+	assert.Nil(t, sink.logger.Rotate())
+	assert.Nil(t, sink.Write([]*Record{record}))
+	assert.Nil(t, sink.Close())
+
+	records := readLines(t, path)
+	assert.Len(t, records, 1)
+	assert.Equal(t, record, records[0])
+
+	files, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+	assert.Len(t, readLines(t, files[0]), 3)
+
+	_, err = NewFileSink("", 1, 1)
+	assert.NotNil(t, err)
+}
+
+func TestKafkaRestSink(t *testing.T) {
+	var (
+		path        string
+		contentType string
+		body        []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path == "/topics/error" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code":40401,"message":"Topic not found."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+	}))
+	defer server.Close()
+
+	sink := NewKafkaRestSink(server.URL+"/", "audit", time.Second)
+	err := sink.Write([]*Record{{SpaceUid: "bkcc__2", Query: "sum(a)"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "/topics/audit", path)
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", contentType)
+	assert.JSONEq(t, `{"records":[{"key":"bkcc__2","value":{
+		"time":"0001-01-01T00:00:00Z","trace_id":"","user":"","source":"","space_uid":"bkcc__2",
+		"method":"","path":"","query":"sum(a)","duration":0,"series":0,"points":0,"result_size":0,
+		"code":0,"status":""
+	}}]}`, string(body))
+
+	sink = NewKafkaRestSink(server.URL, "error", time.Second)
+	err = sink.Write([]*Record{{}})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "404")
+	assert.Nil(t, sink.Close())
+}
+
+func TestMetricSource(t *testing.T) {
+	MaxSources = 2
+	defer func() {
+		MaxSources = 0
+		sources = make(map[string]struct{})
+	}()
+
+	assert.Equal(t, "a", metricSource("a"))
+	assert.Equal(t, "b", metricSource("b"))
+	assert.Equal(t, SourceOther, metricSource("c"))
+	assert.Equal(t, "a", metricSource("a"))
+}
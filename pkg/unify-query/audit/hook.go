@@ -0,0 +1,105 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+// setDefaultConfig
+func setDefaultConfig() {
+	viper.SetDefault(EnableConfigPath, false)
+	viper.SetDefault(SinkConfigPath, SinkFile)
+	viper.SetDefault(PathsConfigPath, []string{"/query", "/api/v1"})
+	viper.SetDefault(BufferSizeConfigPath, 10000)
+	viper.SetDefault(BatchSizeConfigPath, 100)
+	viper.SetDefault(FlushIntervalConfigPath, "1s")
+	viper.SetDefault(MaxQueryLengthConfigPath, 4096)
+	viper.SetDefault(MaxBodySizeConfigPath, 64<<10)
+	viper.SetDefault(MaxSourcesConfigPath, 100)
+
+	viper.SetDefault(FilePathConfigPath, "audit.log")
+	viper.SetDefault(FileMaxSizeConfigPath, 100) // 单位 MB
+	viper.SetDefault(FileMaxBackupsConfigPath, 5)
+
+	viper.SetDefault(KafkaRestAddressConfigPath, "http://127.0.0.1:8082")
+	viper.SetDefault(KafkaTopicConfigPath, "unify_query_audit")
+	viper.SetDefault(KafkaTimeoutConfigPath, "5s")
+}
+
+// LoadConfig
+func LoadConfig() {
+	Enable = viper.GetBool(EnableConfigPath)
+	Paths = viper.GetStringSlice(PathsConfigPath)
+	MaxQueryLength = viper.GetInt(MaxQueryLengthConfigPath)
+	MaxBodySize = viper.GetInt64(MaxBodySizeConfigPath)
+	MaxSources = viper.GetInt(MaxSourcesConfigPath)
+
+	if !Enable {
+		SetRecorder(nil)
+		return
+	}
+
+	var (
+		sink Sink
+		err  error
+	)
+	switch name := viper.GetString(SinkConfigPath); name {
+	case SinkFile:
+		sink, err = NewFileSink(
+			viper.GetString(FilePathConfigPath),
+			viper.GetInt(FileMaxSizeConfigPath),
+			viper.GetInt(FileMaxBackupsConfigPath),
+		)
+	case SinkKafka:
+		sink = NewKafkaRestSink(
+			viper.GetString(KafkaRestAddressConfigPath),
+			viper.GetString(KafkaTopicConfigPath),
+			viper.GetDuration(KafkaTimeoutConfigPath),
+		)
+	default:
+		err = fmt.Errorf("unknown audit sink: %s", name)
+	}
+	if err != nil {
+		log.Errorf(context.TODO(), "init audit sink failed: %s", err)
+		SetRecorder(nil)
+		return
+	}
+
+	SetRecorder(NewRecorder(
+		sink,
+		viper.GetInt(BufferSizeConfigPath),
+		viper.GetInt(BatchSizeConfigPath),
+		viper.GetDuration(FlushIntervalConfigPath),
+	))
+}
+
+// init
+func init() {
+	if err := eventbus.EventBus.Subscribe(eventbus.EventSignalConfigPreParse, setDefaultConfig); err != nil {
+		fmt.Printf(
+			"failed to subscribe event->[%s] for audit module for default config, maybe audit module won't working.",
+			eventbus.EventSignalConfigPreParse,
+		)
+	}
+
+	if err := eventbus.EventBus.Subscribe(eventbus.EventSignalConfigPostParse, LoadConfig); err != nil {
+		fmt.Printf(
+			"failed to subscribe event->[%s] for audit module for new config, maybe audit module won't working.",
+			eventbus.EventSignalConfigPostParse,
+		)
+	}
+}
@@ -0,0 +1,53 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package audit
+
+const (
+	EnableConfigPath         = "audit.enable"
+	SinkConfigPath           = "audit.sink"
+	PathsConfigPath          = "audit.paths"
+	BufferSizeConfigPath     = "audit.buffer_size"
+	BatchSizeConfigPath      = "audit.batch_size"
+	FlushIntervalConfigPath  = "audit.flush_interval"
+	MaxQueryLengthConfigPath = "audit.max_query_length"
+	MaxBodySizeConfigPath    = "audit.max_body_size"
+	MaxSourcesConfigPath     = "audit.max_sources"
+
+	FilePathConfigPath       = "audit.file.path"
+	FileMaxSizeConfigPath    = "audit.file.max_size"
+	FileMaxBackupsConfigPath = "audit.file.max_backups"
+
+	KafkaRestAddressConfigPath = "audit.kafka.rest_address"
+	KafkaTopicConfigPath       = "audit.kafka.topic"
+	KafkaTimeoutConfigPath     = "audit.kafka.timeout"
+)
+
+const (
+	// SourceOther 超出 MaxSources 限制的来源在指标中的取值
+	SourceOther = "other"
+)
+
+const (
+	SinkFile  = "file"
+	SinkKafka = "kafka"
+)
+
+var (
+	// Enable 是否写入审计记录，指标统计不受该开关影响
+	Enable bool
+	// Paths 需要审计的请求路径前缀
+	Paths []string
+	// MaxQueryLength 审计记录中查询语句的最大长度，超过部分截断
+	MaxQueryLength int
+	// MaxBodySize 审计读取请求内容的最大长度，超过部分不读取
+	MaxBodySize int64
+	// MaxSources 指标中 source 维度的最大取值个数，超出后的来源统一记为 SourceOther
+	MaxSources int
+)
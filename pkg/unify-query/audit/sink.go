@@ -0,0 +1,166 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sink 审计记录的写入目标
+type Sink interface {
+	Name() string
+	Write(records []*Record) error
+	Close() error
+}
+
+// FileSink 按行写入 json 文件，轮转复用 lumberjack，文件超过 maxSize MB 时轮转，保留 maxBackups 个历史文件
+type FileSink struct {
+	lock   sync.Mutex
+	logger *lumberjack.Logger
+}
+
+// NewFileSink
+func NewFileSink(path string, maxSize, maxBackups int) (*FileSink, error) {
+	if path == "" {
+		return nil, fmt.Errorf("audit file path is empty")
+	}
+
+	return &FileSink{
+		logger: &lumberjack.Logger{
+			Filename:   path,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+			LocalTime:  true,
+		},
+	}, nil
+}
+
+// Name
+func (s *FileSink) Name() string {
+	return SinkFile
+}
+
+// Write
+func (s *FileSink) Write(records []*Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err = s.logger.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.logger.Close()
+}
+
+// kafkaRestRecord kafka rest proxy v2 的单条消息
+type kafkaRestRecord struct {
+	Key   string  `json:"key,omitempty"`
+	Value *Record `json:"value"`
+}
+
+// KafkaRestSink 通过 kafka rest proxy 写入 topic，以 space_uid 作为消息的 key
+type KafkaRestSink struct {
+	address string
+	topic   string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewKafkaRestSink
+func NewKafkaRestSink(address, topic string, timeout time.Duration) *KafkaRestSink {
+	return &KafkaRestSink{
+		address: strings.TrimRight(address, "/"),
+		topic:   topic,
+		timeout: timeout,
+		client:  &http.Client{},
+	}
+}
+
+// Name
+func (s *KafkaRestSink) Name() string {
+	return SinkKafka
+}
+
+// Write
+func (s *KafkaRestSink) Write(records []*Record) error {
+	body := struct {
+		Records []kafkaRestRecord `json:"records"`
+	}{
+		Records: make([]kafkaRestRecord, 0, len(records)),
+	}
+	for _, record := range records {
+		body.Records = append(body.Records, kafkaRestRecord{
+			Key:   record.SpaceUid,
+			Value: record,
+		})
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, fmt.Sprintf("%s/topics/%s", s.address, s.topic), bytes.NewReader(data),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kafka rest proxy response %d: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Close
+func (s *KafkaRestSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
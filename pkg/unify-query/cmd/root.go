@@ -27,6 +27,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/influxdb"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/promql"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/redis"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/rule"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/trace"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/tsdb"
 )
@@ -58,6 +59,7 @@ var rootCmd = &cobra.Command{
 			&tsdb.Service{},
 			&promql.Service{},
 			&http.Service{},
+			&rule.Service{},
 			&featureFlag.Service{},
 		}
 		log.Infof(context.TODO(), "http service started.")
@@ -15,4 +15,7 @@ type CMDB interface {
 
 	// GetResourceMatcher 获取目标的关键维度和值
 	GetResourceMatcher(ctx context.Context, lookBackDelta string, spaceUid string, timestamp int64, target Resource, matcher Matcher) (Resource, Matcher, Matchers, error)
+
+	// QueryResourceMatcherRange 按路径约束查询时间范围内的关联资源，返回每一跳的结果及有效时间段
+	QueryResourceMatcherRange(ctx context.Context, opt *RangeQueryOptions) (*RangeQueryResult, error)
 }
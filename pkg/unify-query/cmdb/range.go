@@ -0,0 +1,102 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package cmdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NewTimeRanges 将采样点时间转换为有效时间段，相邻点间隔不超过 step 时视为连续
+func NewTimeRanges(timestamps []int64, step int64) TimeRanges {
+	if len(timestamps) == 0 {
+		return nil
+	}
+	ts := make([]int64, len(timestamps))
+	copy(ts, timestamps)
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+
+	ranges := TimeRanges{{Start: ts[0], End: ts[0]}}
+	for _, t := range ts[1:] {
+		last := &ranges[len(ranges)-1]
+		if t-last.End <= step {
+			last.End = t
+			continue
+		}
+		ranges = append(ranges, TimeRange{Start: t, End: t})
+	}
+	return ranges
+}
+
+// Intersect 求两组时间段的交集
+func (r TimeRanges) Intersect(o TimeRanges) TimeRanges {
+	var (
+		result TimeRanges
+		i, j   int
+	)
+	for i < len(r) && j < len(o) {
+		start, end := r[i].Start, r[i].End
+		if o[j].Start > start {
+			start = o[j].Start
+		}
+		if o[j].End < end {
+			end = o[j].End
+		}
+		if start <= end {
+			result = append(result, TimeRange{Start: start, End: end})
+		}
+		if r[i].End < o[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+// Union 求两组时间段的并集
+func (r TimeRanges) Union(o TimeRanges) TimeRanges {
+	all := make(TimeRanges, 0, len(r)+len(o))
+	all = append(all, r...)
+	all = append(all, o...)
+	if len(all) == 0 {
+		return nil
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+
+	result := TimeRanges{all[0]}
+	for _, tr := range all[1:] {
+		last := &result[len(result)-1]
+		if tr.Start <= last.End {
+			if tr.End > last.End {
+				last.End = tr.End
+			}
+			continue
+		}
+		result = append(result, tr)
+	}
+	return result
+}
+
+// Key 维度映射的唯一标识，按维度名排序
+func (m Matcher) Key() string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s := make([]string, 0, len(keys))
+	for _, k := range keys {
+		s = append(s, fmt.Sprintf("%s=%q", k, m[k]))
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,44 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package cmdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTimeRanges(t *testing.T) {
+	assert.Nil(t, NewTimeRanges(nil, 60))
+	assert.Equal(t,
+		TimeRanges{{Start: 0, End: 120}, {Start: 300, End: 360}},
+		NewTimeRanges([]int64{60, 0, 120, 300, 360}, 60),
+	)
+}
+
+func TestTimeRangesIntersect(t *testing.T) {
+	a := TimeRanges{{Start: 0, End: 100}, {Start: 200, End: 300}}
+	b := TimeRanges{{Start: 50, End: 250}, {Start: 280, End: 400}}
+	assert.Equal(t, TimeRanges{{Start: 50, End: 100}, {Start: 200, End: 250}, {Start: 280, End: 300}}, a.Intersect(b))
+	assert.Nil(t, a.Intersect(TimeRanges{{Start: 120, End: 180}}))
+	assert.Nil(t, a.Intersect(nil))
+}
+
+func TestTimeRangesUnion(t *testing.T) {
+	a := TimeRanges{{Start: 0, End: 100}, {Start: 200, End: 300}}
+	b := TimeRanges{{Start: 50, End: 150}, {Start: 400, End: 500}}
+	assert.Equal(t, TimeRanges{{Start: 0, End: 150}, {Start: 200, End: 300}, {Start: 400, End: 500}}, a.Union(b))
+	assert.Equal(t, a, a.Union(nil))
+	assert.Nil(t, TimeRanges(nil).Union(nil))
+}
+
+func TestMatcherKey(t *testing.T) {
+	assert.Equal(t, `namespace="ns-1",pod="pod-1"`, Matcher{"pod": "pod-1", "namespace": "ns-1"}.Key())
+}
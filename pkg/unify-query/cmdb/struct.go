@@ -55,3 +55,93 @@ type RelationMultiResourceResponseData struct {
 type RelationMultiResourceResponse struct {
 	Data []RelationMultiResourceResponseData `json:"data"`
 }
+
+// TimeRange 关联关系的有效时间段，单位秒，首尾均包含
+type TimeRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// TimeRanges 多个有效时间段，按开始时间排序且互不重叠
+type TimeRanges []TimeRange
+
+// MatcherWithRanges 带有效时间段的维度映射
+type MatcherWithRanges struct {
+	Matcher Matcher    `json:"matcher"`
+	Ranges  TimeRanges `json:"ranges"`
+}
+
+// Hop 关联路径中单跳的查询结果
+type Hop struct {
+	Source     Resource            `json:"source"`
+	Target     Resource            `json:"target"`
+	TargetList []MatcherWithRanges `json:"target_list"`
+	// Truncated 结果数超过 TargetLimit 被截断
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// PathResult 单条关联路径的查询结果
+type PathResult struct {
+	Path       []Resource          `json:"path"`
+	Hops       []Hop               `json:"hops,omitempty"`
+	TargetList []MatcherWithRanges `json:"target_list,omitempty"`
+	Message    string              `json:"message,omitempty"`
+}
+
+// RangeQueryOptions 时间范围关联查询参数
+type RangeQueryOptions struct {
+	SpaceUid      string
+	LookBackDelta string
+	// Start End 查询时间范围，单位秒，相同时为瞬时查询
+	Start int64
+	End   int64
+	Step  string
+
+	Target  Resource
+	Matcher Matcher
+
+	// PathResource 关联路径需要依次经过的资源类型
+	PathResource []Resource
+	// AllPaths 返回所有满足条件的路径，否则只返回第一条有结果的最短路径
+	AllPaths bool
+	// TargetLimit 每一跳最多保留的关联资源数，为 0 时不限制
+	TargetLimit int
+}
+
+// RangeQueryResult 时间范围关联查询结果
+type RangeQueryResult struct {
+	SourceType Resource
+	SourceInfo Matcher
+	Paths      []PathResult
+}
+
+// RelationMultiResourceRangeRequest 时间范围关联查询请求参数
+type RelationMultiResourceRangeRequest struct {
+	QueryList []struct {
+		// Timestamp 瞬时查询时间，未指定 start_time 和 end_time 时使用
+		Timestamp     int64      `json:"timestamp"`
+		StartTime     int64      `json:"start_time"`
+		EndTime       int64      `json:"end_time"`
+		Step          string     `json:"step"`
+		TargetType    Resource   `json:"target_type"`
+		SourceInfo    Matcher    `json:"source_info"`
+		LookBackDelta string     `json:"look_back_delta"`
+		PathResource  []Resource `json:"path_resource"`
+		AllPaths      bool       `json:"all_paths"`
+		TargetLimit   int        `json:"target_limit"`
+	} `json:"query_list"`
+}
+
+type RelationMultiResourceRangeResponseData struct {
+	Code       int          `json:"code"`
+	SourceType Resource     `json:"source_type,omitempty"`
+	SourceInfo Matcher      `json:"source_info,omitempty"`
+	TargetType Resource     `json:"target_type,omitempty"`
+	Paths      []PathResult `json:"paths,omitempty"`
+	Message    string       `json:"message,omitempty"`
+}
+
+// RelationMultiResourceRangeResponse 时间范围关联查询返回
+type RelationMultiResourceRangeResponse struct {
+	Data []RelationMultiResourceRangeResponseData `json:"data"`
+}
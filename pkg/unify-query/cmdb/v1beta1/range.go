@@ -0,0 +1,286 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dominikbraun/graph"
+	promPromql "github.com/prometheus/prometheus/promql"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/cmdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// getPathsWithResource 获取依次经过指定资源的关联路径，按路径长度排序
+// 未指定资源且不需要全部路径时只返回最短路径
+func (r *model) getPathsWithResource(ctx context.Context, source, target cmdb.Resource, pathResource []cmdb.Resource, allPaths bool) (cmdb.Paths, error) {
+	if len(pathResource) == 0 && !allPaths {
+		return r.getPaths(ctx, source, target, nil)
+	}
+
+	for _, pr := range pathResource {
+		if _, err := r.getResource(ctx, pr); err != nil {
+			return nil, err
+		}
+	}
+
+	allGraphPaths, err := graph.AllPathsBetween(r.g, string(source), string(target))
+	if err != nil {
+		return nil, fmt.Errorf("%s => %s error: %s", source, target, err)
+	}
+	sort.SliceStable(allGraphPaths, func(i, j int) bool {
+		if len(allGraphPaths[i]) != len(allGraphPaths[j]) {
+			return len(allGraphPaths[i]) < len(allGraphPaths[j])
+		}
+		return fmt.Sprint(allGraphPaths[i]) < fmt.Sprint(allGraphPaths[j])
+	})
+
+	paths := make(cmdb.Paths, 0, len(allGraphPaths))
+	for _, p := range allGraphPaths {
+		if !containsInOrder(p, pathResource) {
+			continue
+		}
+		path, err := pathParser(p)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s => %s error: no path through %v", source, target, pathResource)
+	}
+	return paths, nil
+}
+
+// QueryResourceMatcherRange 按路径约束查询时间范围内的关联资源
+func (r *model) QueryResourceMatcherRange(ctx context.Context, opt *cmdb.RangeQueryOptions) (*cmdb.RangeQueryResult, error) {
+	var (
+		span oleltrace.Span
+		user = metadata.GetUser(ctx)
+
+		step          = promql.GetDefaultStep()
+		lookBackDelta time.Duration
+		err           error
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-resource-matcher-range")
+	if span != nil {
+		defer span.End()
+	}
+
+	trace.InsertStringIntoSpan("source", user.Source, span)
+	trace.InsertStringIntoSpan("username", user.Name, span)
+	trace.InsertStringIntoSpan("space-uid", opt.SpaceUid, span)
+	trace.InsertIntIntoSpan("start", int(opt.Start), span)
+	trace.InsertIntIntoSpan("end", int(opt.End), span)
+	trace.InsertStringIntoSpan("step", opt.Step, span)
+	trace.InsertStringIntoSpan("target", string(opt.Target), span)
+	trace.InsertStringIntoSpan("matcher", fmt.Sprintf("%v", opt.Matcher), span)
+	trace.InsertStringIntoSpan("path-resource", fmt.Sprintf("%v", opt.PathResource), span)
+
+	queryMatcher := opt.Matcher.Rename()
+	source, indexMatcher, err := r.getResourceFromMatch(ctx, queryMatcher)
+	if err != nil {
+		return nil, fmt.Errorf("get resource error: %s", err)
+	}
+	result := &cmdb.RangeQueryResult{
+		SourceType: source,
+		SourceInfo: indexMatcher,
+	}
+
+	if opt.SpaceUid == "" {
+		return result, fmt.Errorf("space uid is empty")
+	}
+	if opt.Start == 0 || opt.End == 0 {
+		return result, fmt.Errorf("start or end is empty")
+	}
+	if opt.End < opt.Start {
+		return result, fmt.Errorf("end %d is before start %d", opt.End, opt.Start)
+	}
+	if opt.Step != "" {
+		step, err = time.ParseDuration(opt.Step)
+		if err != nil {
+			return result, err
+		}
+		if step <= 0 {
+			return result, fmt.Errorf("invalid step %s", opt.Step)
+		}
+	}
+	if opt.LookBackDelta != "" {
+		lookBackDelta, err = time.ParseDuration(opt.LookBackDelta)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	paths, err := r.getPathsWithResource(ctx, source, opt.Target, opt.PathResource, opt.AllPaths)
+	if err != nil {
+		return result, fmt.Errorf("get paths error: %s", err)
+	}
+	trace.InsertStringIntoSpan("paths", fmt.Sprintf("%v", paths), span)
+
+	sourceRange := cmdb.MatcherWithRanges{
+		Matcher: indexMatcher,
+		Ranges:  cmdb.TimeRanges{{Start: opt.Start, End: opt.End}},
+	}
+	for _, path := range paths {
+		pr := cmdb.PathResult{
+			Path: pathResources(path),
+		}
+		pr.Hops, err = r.getRangeDataWithMatchers(ctx, lookBackDelta, opt, step, path, sourceRange)
+		if err != nil {
+			pr.Message = err.Error()
+		} else {
+			pr.TargetList = pr.Hops[len(pr.Hops)-1].TargetList
+		}
+
+		// 只需要一条路径时，返回第一条有结果的路径
+		if !opt.AllPaths {
+			if err == nil {
+				result.Paths = []cmdb.PathResult{pr}
+				return result, nil
+			}
+			continue
+		}
+		result.Paths = append(result.Paths, pr)
+	}
+
+	if !opt.AllPaths {
+		return result, err
+	}
+	return result, nil
+}
+
+// getRangeDataWithMatchers 按路径逐跳查询时间范围内的关联关系，关联的有效时间为各跳有效时间的交集
+func (r *model) getRangeDataWithMatchers(ctx context.Context, lookBackDelta time.Duration, opt *cmdb.RangeQueryOptions, step time.Duration, path cmdb.Path, source cmdb.MatcherWithRanges) ([]cmdb.Hop, error) {
+	var (
+		start   = time.Unix(opt.Start, 0)
+		end     = time.Unix(opt.End, 0)
+		current = []cmdb.MatcherWithRanges{source}
+		hops    = make([]cmdb.Hop, 0, len(path))
+	)
+
+	for _, p := range path {
+		indexMatchers := make(cmdb.Matchers, 0, len(current))
+		sourceRanges := make(map[string]cmdb.TimeRanges, len(current))
+		for _, c := range current {
+			indexMatchers = append(indexMatchers, c.Matcher)
+			sourceRanges[c.Matcher.Key()] = c.Ranges
+		}
+
+		instance, promQL, err := r.relationQuery(ctx, lookBackDelta, opt.SpaceUid, p, indexMatchers)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := instance.QueryRange(ctx, promQL, start, end, step)
+		if err != nil {
+			return nil, fmt.Errorf("instance query range error: %s", err)
+		}
+
+		hop, err := r.joinHop(ctx, p, res, sourceRanges, step, opt.TargetLimit)
+		if err != nil {
+			return nil, err
+		}
+		if len(hop.TargetList) == 0 {
+			return nil, fmt.Errorf("instance query empty, metric: %s, indexMatcher: %+v", getMetric(p), indexMatchers)
+		}
+		hops = append(hops, hop)
+		current = hop.TargetList
+	}
+
+	return hops, nil
+}
+
+// joinHop 将单跳的查询结果与上一跳的有效时间段求交集，按目标资源合并有效时间段
+func (r *model) joinHop(ctx context.Context, p cmdb.Relation, res promPromql.Matrix, sourceRanges map[string]cmdb.TimeRanges, step time.Duration, limit int) (cmdb.Hop, error) {
+	hop := cmdb.Hop{
+		Source: p.V[0],
+		Target: p.V[1],
+	}
+
+	targets := make(map[string]*cmdb.MatcherWithRanges)
+	for _, rs := range res {
+		matcher := make(cmdb.Matcher, len(rs.Metric))
+		for _, m := range rs.Metric {
+			matcher[m.Name] = m.Value
+		}
+		sourceMatcher, err := r.getIndexMatcher(ctx, p.V[0], matcher)
+		if err != nil {
+			return hop, fmt.Errorf("get index matcher error: %s", err)
+		}
+		targetMatcher, err := r.getIndexMatcher(ctx, p.V[1], matcher)
+		if err != nil {
+			return hop, fmt.Errorf("get index matcher error: %s", err)
+		}
+
+		timestamps := make([]int64, 0, len(rs.Points))
+		for _, point := range rs.Points {
+			timestamps = append(timestamps, point.T/1e3)
+		}
+		ranges := cmdb.NewTimeRanges(timestamps, int64(step.Seconds())).Intersect(sourceRanges[sourceMatcher.Key()])
+		if len(ranges) == 0 {
+			continue
+		}
+
+		key := targetMatcher.Key()
+		if t, ok := targets[key]; ok {
+			t.Ranges = t.Ranges.Union(ranges)
+		} else {
+			targets[key] = &cmdb.MatcherWithRanges{Matcher: targetMatcher, Ranges: ranges}
+		}
+	}
+
+	keys := make([]string, 0, len(targets))
+	for k := range targets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hop.TargetList = make([]cmdb.MatcherWithRanges, 0, len(keys))
+	for _, k := range keys {
+		if limit > 0 && len(hop.TargetList) >= limit {
+			hop.Truncated = true
+			break
+		}
+		hop.TargetList = append(hop.TargetList, *targets[k])
+	}
+	return hop, nil
+}
+
+// containsInOrder 判断路径是否依次经过指定资源
+func containsInOrder(p []string, resources []cmdb.Resource) bool {
+	i := 0
+	for _, v := range p {
+		if i < len(resources) && v == string(resources[i]) {
+			i++
+		}
+	}
+	return i == len(resources)
+}
+
+func pathResources(path cmdb.Path) []cmdb.Resource {
+	if len(path) == 0 {
+		return nil
+	}
+	resources := make([]cmdb.Resource, 0, len(path)+1)
+	resources = append(resources, path[0].V[0])
+	for _, p := range path {
+		resources = append(resources, p.V[1])
+	}
+	return resources
+}
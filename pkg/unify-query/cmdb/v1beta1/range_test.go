@@ -0,0 +1,170 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	promPromql "github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/cmdb"
+)
+
+func TestModel_GetPathsWithResource(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := map[string]struct {
+		source       cmdb.Resource
+		target       cmdb.Resource
+		pathResource []cmdb.Resource
+		allPaths     bool
+		expected     string
+		err          bool
+	}{
+		"shortest path": {
+			source:   "system",
+			target:   "pod",
+			expected: `[[{"V":["system","node"]},{"V":["node","pod"]}]]`,
+		},
+		"all paths": {
+			source:   "system",
+			target:   "pod",
+			allPaths: true,
+			expected: `[[{"V":["system","node"]},{"V":["node","pod"]}]]`,
+		},
+		"through pod": {
+			source:       "system",
+			target:       "deployment",
+			pathResource: []cmdb.Resource{"node", "pod"},
+			expected:     `[[{"V":["system","node"]},{"V":["node","pod"]},{"V":["pod","deployment"]}]]`,
+		},
+		"through wrong order": {
+			source:       "system",
+			target:       "deployment",
+			pathResource: []cmdb.Resource{"pod", "node"},
+			err:          true,
+		},
+		"through replicaset": {
+			source:       "system",
+			target:       "deployment",
+			pathResource: []cmdb.Resource{"replicaset"},
+			err:          true,
+		},
+		"unknown resource": {
+			source:       "system",
+			target:       "pod",
+			pathResource: []cmdb.Resource{"clb"},
+			err:          true,
+		},
+	}
+
+	for n, c := range testCases {
+		t.Run(n, func(t *testing.T) {
+			paths, err := testModel.getPathsWithResource(ctx, c.source, c.target, c.pathResource, c.allPaths)
+			if c.err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			actual, _ := json.Marshal(paths)
+			assert.Equal(t, c.expected, string(actual))
+		})
+	}
+}
+
+func TestPathResources(t *testing.T) {
+	path, err := pathParser([]string{"system", "node", "pod"})
+	assert.Nil(t, err)
+	assert.Equal(t, []cmdb.Resource{"system", "node", "pod"}, pathResources(path))
+	assert.Nil(t, pathResources(nil))
+}
+
+func TestModel_QueryResourceMatcherRangeValidate(t *testing.T) {
+	ctx := context.Background()
+	matcher := cmdb.Matcher{"bcs_cluster_id": "cls", "node": "node-1"}
+
+	testCases := map[string]*cmdb.RangeQueryOptions{
+		"empty space uid": {Start: 1, End: 2, Target: "system", Matcher: matcher},
+		"empty start":     {SpaceUid: "bkcc__2", End: 2, Target: "system", Matcher: matcher},
+		"end before start": {
+			SpaceUid: "bkcc__2", Start: 2, End: 1, Target: "system", Matcher: matcher,
+		},
+		"invalid step": {
+			SpaceUid: "bkcc__2", Start: 1, End: 2, Step: "-1m", Target: "system", Matcher: matcher,
+		},
+		"unknown target": {
+			SpaceUid: "bkcc__2", Start: 1, End: 2, Target: "clb", Matcher: matcher,
+		},
+	}
+
+	for n, opt := range testCases {
+		t.Run(n, func(t *testing.T) {
+			res, err := testModel.QueryResourceMatcherRange(ctx, opt)
+			assert.NotNil(t, err)
+			assert.Equal(t, cmdb.Resource("node"), res.SourceType)
+			assert.Equal(t, matcher, res.SourceInfo)
+			assert.Empty(t, res.Paths)
+		})
+	}
+}
+
+func TestModel_JoinHop(t *testing.T) {
+	ctx := context.Background()
+
+	series := func(pod string, ts ...int64) promPromql.Series {
+		points := make([]promPromql.Point, 0, len(ts))
+		for _, t := range ts {
+			points = append(points, promPromql.Point{T: t * 1e3, V: 1})
+		}
+		return promPromql.Series{
+			Metric: labels.FromStrings("bcs_cluster_id", "cls", "node", "node-1", "namespace", "ns", "pod", pod),
+			Points: points,
+		}
+	}
+
+	res := promPromql.Matrix{
+		// pod-1 一直在 node-1 上
+		series("pod-1", 0, 60, 120, 180),
+		// pod-2 中途被调度走又回来
+		series("pod-2", 0, 60, 240, 300),
+		// pod-3 只在 node-1 不可用时出现
+		series("pod-3", 400),
+	}
+	sourceRanges := map[string]cmdb.TimeRanges{
+		cmdb.Matcher{"bcs_cluster_id": "cls", "node": "node-1"}.Key(): {{Start: 0, End: 360}},
+	}
+
+	hop, err := testModel.joinHop(ctx, cmdb.Relation{V: []cmdb.Resource{"node", "pod"}}, res, sourceRanges, time.Minute, 0)
+	assert.Nil(t, err)
+	assert.False(t, hop.Truncated)
+	assert.Equal(t, []cmdb.MatcherWithRanges{
+		{
+			Matcher: cmdb.Matcher{"bcs_cluster_id": "cls", "namespace": "ns", "pod": "pod-1"},
+			Ranges:  cmdb.TimeRanges{{Start: 0, End: 180}},
+		},
+		{
+			Matcher: cmdb.Matcher{"bcs_cluster_id": "cls", "namespace": "ns", "pod": "pod-2"},
+			Ranges:  cmdb.TimeRanges{{Start: 0, End: 60}, {Start: 240, End: 300}},
+		},
+	}, hop.TargetList)
+
+	hop, err = testModel.joinHop(ctx, cmdb.Relation{V: []cmdb.Resource{"node", "pod"}}, res, sourceRanges, time.Minute, 1)
+	assert.Nil(t, err)
+	assert.True(t, hop.Truncated)
+	assert.Len(t, hop.TargetList, 1)
+
+	_, err = testModel.joinHop(ctx, cmdb.Relation{V: []cmdb.Resource{"node", "system"}}, res, sourceRanges, time.Minute, 0)
+	assert.NotNil(t, err)
+}
@@ -242,90 +242,14 @@ func (r *model) getDataWithMatchers(ctx context.Context, lookBackDeltaStr, space
 	}
 
 	for _, p := range path {
-		if len(p.V) < 2 {
-			return indexMatchers, fmt.Errorf("path format is wrong %v", p)
-		}
-		var instance tsdb.Instance
-
-		metric := getMetric(p)
-		if metric == "" {
-			return indexMatchers, fmt.Errorf("metric is empty %v", p)
-		}
-
-		queryTs := &structured.QueryTs{
-			SpaceUid: spaceUid,
-			QueryList: []*structured.Query{
-				{
-					FieldName:     metric,
-					ReferenceName: ReferenceName,
-				},
-			},
-			MetricMerge: ReferenceName,
-		}
-
-		queryReference, err := queryTs.ToQueryReference(ctx)
+		instance, promQL, err := r.relationQuery(ctx, lookBackDelta, spaceUid, p, indexMatchers)
 		if err != nil {
 			return indexMatchers, err
 		}
 
-		condition := getConditions(false, indexMatchers...)
-		vmCondition := getConditions(true, indexMatchers...)
-		labelsMatcher := make([]*labels.Matcher, 0)
-		for _, im := range indexMatchers {
-			matcher, err := im.ToPromMatcher()
-			if err != nil {
-				return indexMatchers, err
-			}
-			labelsMatcher = append(labelsMatcher, matcher...)
-		}
-
-		for _, qm := range queryReference {
-			for _, ql := range qm.QueryList {
-				ql.Condition = condition
-
-				ql.VmCondition = vmCondition
-				ql.LabelsMatcher = labelsMatcher
-			}
-		}
-
-		metadata.SetQueryReference(ctx, queryReference)
-
+		metric := getMetric(p)
 		end := time.Unix(timestamp, 0)
-
-		referenceNameMetric := make(map[string]string, len(queryTs.QueryList))
-		referenceNameLabelMatcher := make(map[string][]*labels.Matcher, len(queryTs.QueryList))
-
-		ok, vmExpand, err := queryReference.CheckVmQuery(ctx)
-		if ok {
-			if err != nil {
-				return nil, err
-			}
-			if !metadata.GetVMQueryOrFeatureFlag(ctx) {
-				referenceNameMetric = vmExpand.MetricAliasMapping
-				referenceNameLabelMatcher = vmExpand.LabelsMatcher
-			}
-
-			metadata.SetExpand(ctx, vmExpand)
-			instance = prometheus.GetInstance(ctx, &metadata.Query{
-				StorageID: consul.VictoriaMetricsStorageType,
-			})
-			if instance == nil {
-				err = fmt.Errorf("%s storage get error", consul.VictoriaMetricsStorageType)
-				return nil, err
-			}
-		} else {
-			instance = prometheus.NewInstance(ctx, promql.GlobalEngine, &prometheus.QueryRangeStorage{
-				QueryMaxRouting: QueryMaxRouting,
-				Timeout:         Timeout,
-			}, lookBackDelta)
-		}
-
-		promQL, err := queryTs.ToPromExpr(ctx, referenceNameMetric, referenceNameLabelMatcher)
-
-		if err != nil {
-			return nil, fmt.Errorf("query ts to prom expr error: %s", err)
-		}
-		res, err := instance.Query(ctx, promQL.String(), end)
+		res, err := instance.Query(ctx, promQL, end)
 		if err != nil {
 			return nil, fmt.Errorf("instance query error: %s", err)
 		}
@@ -351,6 +275,90 @@ func (r *model) getDataWithMatchers(ctx context.Context, lookBackDeltaStr, space
 	return indexMatchers, nil
 }
 
+// relationQuery 生成单跳关联指标的查询实例和 promql，查询条件为上一跳的关键维度
+func (r *model) relationQuery(ctx context.Context, lookBackDelta time.Duration, spaceUid string, p cmdb.Relation, indexMatchers cmdb.Matchers) (tsdb.Instance, string, error) {
+	if len(p.V) < 2 {
+		return nil, "", fmt.Errorf("path format is wrong %v", p)
+	}
+	var instance tsdb.Instance
+
+	metric := getMetric(p)
+	if metric == "" {
+		return nil, "", fmt.Errorf("metric is empty %v", p)
+	}
+
+	queryTs := &structured.QueryTs{
+		SpaceUid: spaceUid,
+		QueryList: []*structured.Query{
+			{
+				FieldName:     metric,
+				ReferenceName: ReferenceName,
+			},
+		},
+		MetricMerge: ReferenceName,
+	}
+
+	queryReference, err := queryTs.ToQueryReference(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	condition := getConditions(false, indexMatchers...)
+	vmCondition := getConditions(true, indexMatchers...)
+	labelsMatcher := make([]*labels.Matcher, 0)
+	for _, im := range indexMatchers {
+		matcher, err := im.ToPromMatcher()
+		if err != nil {
+			return nil, "", err
+		}
+		labelsMatcher = append(labelsMatcher, matcher...)
+	}
+
+	for _, qm := range queryReference {
+		for _, ql := range qm.QueryList {
+			ql.Condition = condition
+
+			ql.VmCondition = vmCondition
+			ql.LabelsMatcher = labelsMatcher
+		}
+	}
+
+	metadata.SetQueryReference(ctx, queryReference)
+
+	referenceNameMetric := make(map[string]string, len(queryTs.QueryList))
+	referenceNameLabelMatcher := make(map[string][]*labels.Matcher, len(queryTs.QueryList))
+
+	ok, vmExpand, err := queryReference.CheckVmQuery(ctx)
+	if ok {
+		if err != nil {
+			return nil, "", err
+		}
+		if !metadata.GetVMQueryOrFeatureFlag(ctx) {
+			referenceNameMetric = vmExpand.MetricAliasMapping
+			referenceNameLabelMatcher = vmExpand.LabelsMatcher
+		}
+
+		metadata.SetExpand(ctx, vmExpand)
+		instance = prometheus.GetInstance(ctx, &metadata.Query{
+			StorageID: consul.VictoriaMetricsStorageType,
+		})
+		if instance == nil {
+			return nil, "", fmt.Errorf("%s storage get error", consul.VictoriaMetricsStorageType)
+		}
+	} else {
+		instance = prometheus.NewInstance(ctx, promql.GlobalEngine, &prometheus.QueryRangeStorage{
+			QueryMaxRouting: QueryMaxRouting,
+			Timeout:         Timeout,
+		}, lookBackDelta)
+	}
+
+	promQL, err := queryTs.ToPromExpr(ctx, referenceNameMetric, referenceNameLabelMatcher)
+	if err != nil {
+		return nil, "", fmt.Errorf("query ts to prom expr error: %s", err)
+	}
+	return instance, promQL.String(), nil
+}
+
 func (r *model) getIndexMatcher(ctx context.Context, resource cmdb.Resource, matcher cmdb.Matcher) (cmdb.Matcher, error) {
 	index, err := r.getResource(ctx, resource)
 	if len(index) == 0 {
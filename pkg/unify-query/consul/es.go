@@ -32,6 +32,8 @@ type ESTableInfo struct {
 	DateFormat string `json:"date_format"`
 	// 日期步长,单位: h
 	DateStep int `json:"date_step"`
+	// 时间字段，为空时使用默认配置
+	TimeField string `json:"time_field"`
 }
 
 // FormatESTableInfo :
@@ -74,7 +76,7 @@ func GetESStorageInfo() (map[string]*Storage, error) {
 	}
 	esInfos := make(map[string]*Storage)
 	for key, info := range infos {
-		if info.Type != "elasticsearch" {
+		if info.Type != ElasticsearchStorageType {
 			continue
 		}
 		esInfos[key] = info
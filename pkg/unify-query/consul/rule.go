@@ -0,0 +1,71 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	recordingRulePath = "rule/recording"
+)
+
+// RecordingRule 预计算规则，按空间配置
+type RecordingRule struct {
+	// 写入的指标名
+	Record string `json:"record"`
+	// 预计算的 promql
+	Expr string `json:"expr"`
+	// 计算周期，为空时使用默认配置
+	Interval string `json:"interval"`
+	// 附加到结果上的维度
+	Labels map[string]string `json:"labels"`
+	// 写入目标：influxdb 或 victoria_metrics，为空时使用默认配置
+	Target string `json:"target"`
+	// 写入 influxdb 时使用的结果表，格式为 db.measurement
+	TableID string `json:"table_id"`
+}
+
+// FormatRecordingRules 解析预计算规则，key 为空间 uid
+func FormatRecordingRules(kvPairs api.KVPairs) (map[string][]*RecordingRule, error) {
+	result := make(map[string][]*RecordingRule)
+	prefix := fmt.Sprintf("%s/%s/%s/", basePath, dataPath, recordingRulePath)
+	for _, kvPair := range kvPairs {
+		var data []*RecordingRule
+		err := json.Unmarshal(kvPair.Value, &data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", kvPair.Key, err)
+		}
+		key := strings.ReplaceAll(kvPair.Key, prefix, "")
+		result[key] = data
+	}
+	return result, nil
+}
+
+// WatchRecordingRules
+func WatchRecordingRules(ctx context.Context) (<-chan interface{}, error) {
+	path := fmt.Sprintf("%s/%s/%s", basePath, versionPath, recordingRulePath)
+	return WatchChange(ctx, path)
+}
+
+// GetRecordingRules
+func GetRecordingRules() (map[string][]*RecordingRule, error) {
+	path := fmt.Sprintf("%s/%s/%s", basePath, dataPath, recordingRulePath)
+	pairs, err := GetDataWithPrefix(path)
+	if err != nil {
+		return nil, err
+	}
+	return FormatRecordingRules(pairs)
+}
@@ -0,0 +1,45 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package consul_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/likexian/gokit/assert"
+	"github.com/prashantv/gostub"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+// TestGetRecordingRules
+func TestGetRecordingRules(t *testing.T) {
+	log.InitTestLogger()
+	kv := api.KVPairs{
+		{
+			Key:   "bkmonitorv3/unify-query/data/rule/recording/bkcc__2",
+			Value: []byte(`[{"record":"cpu_usage_avg","expr":"avg by (ip) (bkmonitor:system:cpu_summary:usage)","interval":"1m","labels":{"source":"rule"},"target":"influxdb","table_id":"rule.cpu"}]`),
+		},
+	}
+	stubs := gostub.StubFunc(&consul.GetDataWithPrefix, kv, nil)
+	defer stubs.Reset()
+
+	rules, err := consul.GetRecordingRules()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(rules["bkcc__2"]))
+	assert.Equal(t, "cpu_usage_avg", rules["bkcc__2"][0].Record)
+	assert.Equal(t, "rule.cpu", rules["bkcc__2"][0].TableID)
+	assert.Equal(t, "rule", rules["bkcc__2"][0].Labels["source"])
+
+	stubs.StubFunc(&consul.GetDataWithPrefix, api.KVPairs{{Key: "bkmonitorv3/unify-query/data/rule/recording/bkcc__3", Value: []byte(`{`)}}, nil)
+	_, err = consul.GetRecordingRules()
+	assert.NotNil(t, err)
+}
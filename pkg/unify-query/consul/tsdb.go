@@ -14,6 +14,7 @@ const (
 	InfluxDBStorageType        = "influxdb"
 	PrometheusStorageType      = "prometheus"
 	OfflineDataArchive         = "offline_data_archive"
+	ElasticsearchStorageType   = "elasticsearch"
 )
 
 var typeList = []string{VictoriaMetricsStorageType, InfluxDBStorageType}
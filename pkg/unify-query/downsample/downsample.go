@@ -12,23 +12,38 @@ package downsample
 import (
 	"context"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/promql"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 )
 
-// Downsample 数据降采样
-func Downsample(points []promql.Point, factor float64) []promql.Point {
+// Downsample 按 factor 对 points 降采样，function 为空时使用 lttb；
+// NaN 和 stale 标记不参与计算，序列会在这些点以及明显的断点处切分后分别降采样，避免跨越断点连线
+func Downsample(points []promql.Point, factor float64, function string) []promql.Point {
 	var threshold int
 	var downSamplePoints []promql.Point
+
+	fn, ok := GetFunc(function)
+	if !ok {
+		log.Warnf(context.TODO(), "unknown down sample function %s, use %s instead", function, FunctionLTTB)
+		fn, _ = GetFunc(FunctionLTTB)
+	}
+
 	// threshold 最大值
 	threshold = int(math.Ceil(float64(len(points)) * factor))
-	downSamplePoints = lttbFunc(points, threshold)
+	downSamplePoints = make([]promql.Point, 0, threshold)
+	for _, segment := range segments(points) {
+		segmentThreshold := int(math.Ceil(float64(len(segment)) * factor))
+		downSamplePoints = append(downSamplePoints, fn(segment, segmentThreshold)...)
+	}
 
-	log.Infof(context.TODO(), "downsample series done %s %d %s %d %s %d",
+	log.Infof(context.TODO(), "downsample series done %s %s %s %d %s %d %s %d",
+		"function", function,
 		"threshold", threshold,
 		"rawPointCount", len(points),
 		"downsamplePointCount", len(downSamplePoints),
@@ -36,6 +51,55 @@ func Downsample(points []promql.Point, factor float64) []promql.Point {
 	return downSamplePoints
 }
 
+// segments 按 NaN、stale 标记以及超过 2 倍中位间隔的断点切分序列
+func segments(points []promql.Point) [][]promql.Point {
+	var (
+		result  [][]promql.Point
+		current []promql.Point
+	)
+
+	cleaned := make([]promql.Point, 0, len(points))
+	breaks := make(map[int]struct{})
+	for _, p := range points {
+		if math.IsNaN(p.V) || value.IsStaleNaN(p.V) {
+			breaks[len(cleaned)] = struct{}{}
+			continue
+		}
+		cleaned = append(cleaned, p)
+	}
+
+	gap := 2 * medianInterval(cleaned)
+	for i, p := range cleaned {
+		if i > 0 {
+			_, isBreak := breaks[i]
+			if isBreak || (gap > 0 && p.T-cleaned[i-1].T > gap) {
+				result = append(result, current)
+				current = nil
+			}
+		}
+		current = append(current, p)
+	}
+	if len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// medianInterval 相邻两点时间间隔的中位数
+func medianInterval(points []promql.Point) int64 {
+	if len(points) < 2 {
+		return 0
+	}
+	intervals := make([]int64, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		intervals = append(intervals, points[i].T-points[i-1].T)
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i] < intervals[j]
+	})
+	return intervals[len(intervals)/2]
+}
+
 // CheckDownSampleRange 检查降采样周期
 func CheckDownSampleRange(step, downSampleRange string) (bool, float64, error) {
 	var stepTime time.Duration
@@ -0,0 +1,201 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package downsample
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/prometheus/promql"
+)
+
+const (
+	// FunctionLTTB 最大三角形三桶算法，保留曲线形状，默认算法
+	FunctionLTTB = "lttb"
+	// FunctionM4 每个桶保留第一个、最小、最大、最后一个点，保留数据包络
+	FunctionM4 = "m4"
+	// FunctionAvg 每个桶取平均值
+	FunctionAvg = "avg"
+	// FunctionFirst 每个桶取第一个点
+	FunctionFirst = "first"
+	// FunctionLast 每个桶取最后一个点
+	FunctionLast = "last"
+	// FunctionCounter 累加型指标，每个桶取最后一个点，并保留计数器重置前的点
+	FunctionCounter = "counter"
+)
+
+// Func 降采样算法，将按时间排序且不包含 NaN 的 points 降采样为不超过 threshold 个点
+type Func func(points []promql.Point, threshold int) []promql.Point
+
+var (
+	funcLock sync.RWMutex
+	funcs    = map[string]Func{
+		FunctionLTTB:    lttbFunc,
+		FunctionM4:      m4Func,
+		FunctionAvg:     avgFunc,
+		FunctionFirst:   firstFunc,
+		FunctionLast:    lastFunc,
+		FunctionCounter: counterFunc,
+	}
+)
+
+// Register 注册降采样算法，同名算法会被覆盖
+func Register(name string, fn Func) {
+	funcLock.Lock()
+	defer funcLock.Unlock()
+	funcs[name] = fn
+}
+
+// GetFunc 获取降采样算法，name 为空时返回默认的 lttb
+func GetFunc(name string) (Func, bool) {
+	if name == "" {
+		name = FunctionLTTB
+	}
+	funcLock.RLock()
+	defer funcLock.RUnlock()
+	fn, ok := funcs[name]
+	return fn, ok
+}
+
+// CheckFunction 校验降采样算法是否存在
+func CheckFunction(name string) error {
+	if _, ok := GetFunc(name); ok {
+		return nil
+	}
+
+	funcLock.RLock()
+	names := make([]string, 0, len(funcs))
+	for n := range funcs {
+		names = append(names, n)
+	}
+	funcLock.RUnlock()
+	sort.Strings(names)
+
+	return fmt.Errorf("unknown down sample function: %s, support: %s", name, strings.Join(names, ","))
+}
+
+// timeBuckets 按时间将 points 均分为 n 个桶，空桶会被跳过，稀疏的序列不会因此产生虚假的点
+func timeBuckets(points []promql.Point, n int) [][]promql.Point {
+	if n <= 0 || len(points) == 0 {
+		return nil
+	}
+
+	first, last := points[0].T, points[len(points)-1].T
+	width := (last-first)/int64(n) + 1
+
+	buckets := make([][]promql.Point, 0, n)
+	start := 0
+	for i := 1; i <= len(points); i++ {
+		if i == len(points) || (points[i].T-first)/width != (points[start].T-first)/width {
+			buckets = append(buckets, points[start:i])
+			start = i
+		}
+	}
+	return buckets
+}
+
+func m4Func(points []promql.Point, threshold int) []promql.Point {
+	if threshold >= len(points) {
+		return points
+	}
+
+	n := threshold / 4
+	if n < 1 {
+		n = 1
+	}
+
+	out := make([]promql.Point, 0, n*4)
+	for _, bucket := range timeBuckets(points, n) {
+		minIdx, maxIdx := 0, 0
+		for i, p := range bucket {
+			if p.V < bucket[minIdx].V {
+				minIdx = i
+			}
+			if p.V > bucket[maxIdx].V {
+				maxIdx = i
+			}
+		}
+
+		// 按时间顺序输出，重复的点只保留一个
+		idx := []int{0, minIdx, maxIdx, len(bucket) - 1}
+		sort.Ints(idx)
+		for i, v := range idx {
+			if i > 0 && v == idx[i-1] {
+				continue
+			}
+			out = append(out, bucket[v])
+		}
+	}
+	return out
+}
+
+func avgFunc(points []promql.Point, threshold int) []promql.Point {
+	if threshold >= len(points) {
+		return points
+	}
+
+	out := make([]promql.Point, 0, threshold)
+	for _, bucket := range timeBuckets(points, threshold) {
+		var sum float64
+		for _, p := range bucket {
+			sum += p.V
+		}
+		out = append(out, promql.Point{T: bucket[0].T, V: sum / float64(len(bucket))})
+	}
+	return out
+}
+
+func firstFunc(points []promql.Point, threshold int) []promql.Point {
+	if threshold >= len(points) {
+		return points
+	}
+
+	out := make([]promql.Point, 0, threshold)
+	for _, bucket := range timeBuckets(points, threshold) {
+		out = append(out, bucket[0])
+	}
+	return out
+}
+
+func lastFunc(points []promql.Point, threshold int) []promql.Point {
+	if threshold >= len(points) {
+		return points
+	}
+
+	out := make([]promql.Point, 0, threshold)
+	for _, bucket := range timeBuckets(points, threshold) {
+		out = append(out, bucket[len(bucket)-1])
+	}
+	return out
+}
+
+// counterFunc 累加型指标如果直接取平均或者 lttb 选点，会丢失计数器重置带来的增量，
+// 这里每个桶取最后一个点，同时保留每次重置前的点，保证基于结果计算 increase/rate 不失真
+func counterFunc(points []promql.Point, threshold int) []promql.Point {
+	if threshold >= len(points) {
+		return points
+	}
+
+	out := make([]promql.Point, 0, threshold)
+	prev := math.NaN()
+	for _, bucket := range timeBuckets(points, threshold) {
+		for i, p := range bucket {
+			if p.V < prev && i > 0 {
+				out = append(out, bucket[i-1])
+			}
+			prev = p.V
+		}
+		out = append(out, bucket[len(bucket)-1])
+	}
+	return out
+}
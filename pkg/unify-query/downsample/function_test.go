@@ -0,0 +1,147 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package downsample
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+func makePoints(values ...float64) []promql.Point {
+	points := make([]promql.Point, 0, len(values))
+	for i, v := range values {
+		points = append(points, promql.Point{T: int64(i) * 60e3, V: v})
+	}
+	return points
+}
+
+func TestDownsampleFunctions(t *testing.T) {
+	points := makePoints(1, 5, 2, 8, 3, 4, 9, 0)
+
+	testCases := map[string]struct {
+		fn        Func
+		threshold int
+		expected  []promql.Point
+	}{
+		"m4": {
+			fn:        m4Func,
+			threshold: 4,
+			// 只有 1 个桶，保留第一个、最小、最大、最后一个点
+			expected: []promql.Point{{T: 0, V: 1}, {T: 6 * 60e3, V: 9}, {T: 7 * 60e3, V: 0}},
+		},
+		"m4 two buckets": {
+			fn:        m4Func,
+			threshold: 8,
+			expected:  points,
+		},
+		"avg": {
+			fn:        avgFunc,
+			threshold: 4,
+			expected: []promql.Point{
+				{T: 0, V: 3}, {T: 2 * 60e3, V: 5}, {T: 4 * 60e3, V: 3.5}, {T: 6 * 60e3, V: 4.5},
+			},
+		},
+		"first": {
+			fn:        firstFunc,
+			threshold: 2,
+			expected:  []promql.Point{{T: 0, V: 1}, {T: 4 * 60e3, V: 3}},
+		},
+		"last": {
+			fn:        lastFunc,
+			threshold: 2,
+			expected:  []promql.Point{{T: 3 * 60e3, V: 8}, {T: 7 * 60e3, V: 0}},
+		},
+		"threshold bigger than points": {
+			fn:        avgFunc,
+			threshold: 10,
+			expected:  points,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, c.expected, c.fn(points, c.threshold))
+		})
+	}
+}
+
+func TestCounterFunc(t *testing.T) {
+	// 第 5 个点发生计数器重置
+	points := makePoints(10, 20, 30, 40, 5, 15, 25, 35)
+
+	out := counterFunc(points, 2)
+	assert.Equal(t, []promql.Point{
+		{T: 3 * 60e3, V: 40},
+		{T: 7 * 60e3, V: 35},
+	}, out)
+
+	// 重置发生在桶内部时，需要保留重置前的点
+	out = counterFunc(makePoints(10, 20, 30, 40, 5, 15), 2)
+	assert.Equal(t, []promql.Point{
+		{T: 2 * 60e3, V: 30},
+		{T: 3 * 60e3, V: 40},
+		{T: 5 * 60e3, V: 15},
+	}, out)
+}
+
+func TestSegments(t *testing.T) {
+	points := makePoints(1, 2, math.NaN(), 3, 4, math.Float64frombits(value.StaleNaN), 5)
+	// 中位间隔为 1m，超过 2m 视为断点
+	points = append(points, promql.Point{T: 20 * 60e3, V: 6}, promql.Point{T: 21 * 60e3, V: 7})
+
+	result := segments(points)
+	assert.Equal(t, [][]promql.Point{
+		{{T: 0, V: 1}, {T: 60e3, V: 2}},
+		{{T: 3 * 60e3, V: 3}, {T: 4 * 60e3, V: 4}},
+		{{T: 6 * 60e3, V: 5}},
+		{{T: 20 * 60e3, V: 6}, {T: 21 * 60e3, V: 7}},
+	}, result)
+}
+
+func TestDownsample(t *testing.T) {
+	log.InitTestLogger()
+
+	points := makePoints(1, 5, 2, 8, math.NaN(), 3, 4, 9, 0)
+
+	// NaN 两侧分别降采样，结果中不包含 NaN
+	out := Downsample(points, 0.5, FunctionLast)
+	assert.Equal(t, []promql.Point{
+		{T: 1 * 60e3, V: 5}, {T: 3 * 60e3, V: 8},
+		{T: 6 * 60e3, V: 4}, {T: 8 * 60e3, V: 0},
+	}, out)
+
+	// 未知算法降级为 lttb
+	out = Downsample(makePoints(1, 5, 2, 8), 0.5, "unknown")
+	assert.Equal(t, makePoints(1, 5, 2, 8), out)
+}
+
+func TestFunctionRegister(t *testing.T) {
+	assert.Nil(t, CheckFunction(""))
+	assert.Nil(t, CheckFunction(FunctionM4))
+	assert.NotNil(t, CheckFunction("median"))
+
+	Register("median", lastFunc)
+	defer func() {
+		funcLock.Lock()
+		delete(funcs, "median")
+		funcLock.Unlock()
+	}()
+	assert.Nil(t, CheckFunction("median"))
+
+	fn, ok := GetFunc("median")
+	assert.True(t, ok)
+	assert.Equal(t, []promql.Point{{T: 60e3, V: 2}}, fn(makePoints(1, 2), 1))
+}
@@ -27,6 +27,8 @@ type TableInfo struct {
 	DateFormat string
 	// 日期步长,单位: h
 	DateStep int
+	// 时间字段
+	TimeField string
 }
 
 // AliasInfo
@@ -38,9 +38,14 @@ type Ristretto struct {
 }
 
 func NewRistretto() (*Ristretto, error) {
+	return NewRistrettoWithMaxCost(viper.GetInt64(RistrettoMaxCostPath))
+}
+
+// NewRistrettoWithMaxCost 使用独立的容量上限创建缓存，避免不同用途的缓存互相挤占
+func NewRistrettoWithMaxCost(maxCost int64) (*Ristretto, error) {
 	c, err := ristretto.NewCache(&ristretto.Config{
 		NumCounters:        viper.GetInt64(RistrettoNumCountersPath),
-		MaxCost:            viper.GetInt64(RistrettoMaxCostPath),
+		MaxCost:            maxCost,
 		BufferItems:        viper.GetInt64(RistrettoBufferItemsPath),
 		IgnoreInternalCost: viper.GetBool(RistrettoIgnoreInternalCostPath),
 	})
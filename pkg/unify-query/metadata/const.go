@@ -17,10 +17,13 @@ const (
 	MessageKey        = "message"
 	QueriesKey        = "queries"
 	QueryReferenceKey = "query_reference"
+	QueryCostKey      = "query_cost"
 
 	ExceedsMaximumLimit  = "EXCEEDS_MAXIMUM_LIMIT"
 	ExceedsMaximumSlimit = "EXCEEDS_MAXIMUM_SLIMIT"
 
+	ExceedsQueryCostLimit = "EXCEEDS_QUERY_COST_LIMIT"
+
 	SpaceIsNotExists             = "SPACE_IS_NOT_EXISTS"
 	SpaceTableIDFieldIsNotExists = "SPACE_TABLE_ID_FIELD_IS_NOT_EXISTS"
 	TableIDProxyISNotExists      = "TABLE_ID_PROXY_IS_NOT_EXISTS"
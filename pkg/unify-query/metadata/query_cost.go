@@ -0,0 +1,69 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"sync"
+)
+
+// QueryCost 单次查询的开销统计，计数字段需通过 atomic 操作
+type QueryCost struct {
+	SpaceUid string
+	// Query 归一化后的查询语句
+	Query string
+	// Tables 查询涉及的结果表
+	Tables []string
+
+	Series  int64
+	Points  int64
+	Routing int64
+
+	lock sync.Mutex
+	err  error
+}
+
+// SetErr 记录第一个导致查询中断的错误，首次记录时返回 true
+func (c *QueryCost) SetErr(err error) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return false
+	}
+	c.err = err
+	return true
+}
+
+// Err
+func (c *QueryCost) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// SetQueryCost
+func SetQueryCost(ctx context.Context, cost *QueryCost) {
+	if md != nil {
+		md.set(ctx, QueryCostKey, cost)
+	}
+}
+
+// GetQueryCost
+func GetQueryCost(ctx context.Context) *QueryCost {
+	if md != nil {
+		r, ok := md.get(ctx, QueryCostKey)
+		if ok {
+			if v, ok := r.(*QueryCost); ok {
+				return v
+			}
+		}
+	}
+	return nil
+}
@@ -84,6 +84,11 @@ type Query struct {
 	OffsetInfo OffSetInfo // limit等偏移量配置
 
 	SegmentedEnable bool // 是否开启分段查询
+
+	// 用于 es 查询
+	TimeField     string // es 时间字段
+	EsCondition   string // es bool 查询条件，json 格式
+	EsAggregation string // es 时间聚合方法，为空时使用文档数
 }
 
 type QueryList []*Query
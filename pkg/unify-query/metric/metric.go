@@ -86,6 +86,70 @@ var (
 		[]string{"space_uid", "tsdb_type"},
 	)
 
+	resultCacheExtentTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "result_cache_extent_total",
+			Help:      "result cache extent lookup count",
+		},
+		[]string{"space_uid", "status"},
+	)
+
+	queryLimitExceededTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "query_limit_exceeded_total",
+			Help:      "query rejected by space cost limit count",
+		},
+		[]string{"space_uid", "resource"},
+	)
+
+	recordingRuleEvalTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "recording_rule_eval_total",
+			Help:      "recording rule evaluation count",
+		},
+		[]string{"space_uid", "record", "status"},
+	)
+
+	sourceQueryTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "source_query_total",
+			Help:      "query count by source and space",
+		},
+		[]string{"source", "space_uid", "status"},
+	)
+
+	sourceQuerySecondHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "unify_query",
+			Name:      "source_query_seconds",
+			Help:      "query seconds by source and space",
+			Buckets:   DefaultBuckets,
+		},
+		[]string{"source", "space_uid"},
+	)
+
+	sourceQueryPointsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "source_query_points_total",
+			Help:      "query points by source and space",
+		},
+		[]string{"source", "space_uid"},
+	)
+
+	auditRecordDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "unify_query",
+			Name:      "audit_record_dropped_total",
+			Help:      "audit record dropped count",
+		},
+		[]string{"sink", "reason"},
+	)
+
 	vmQuerySpaceUidInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "unify_query",
@@ -116,6 +180,41 @@ func TsDBRequestSecond(ctx context.Context, duration time.Duration, params ...st
 	observe(ctx, metric, err, duration, params...)
 }
 
+func ResultCacheExtentInc(ctx context.Context, params ...string) {
+	metric, err := resultCacheExtentTotal.GetMetricWithLabelValues(params...)
+	counterInc(ctx, metric, err, params...)
+}
+
+func QueryLimitExceededInc(ctx context.Context, params ...string) {
+	metric, err := queryLimitExceededTotal.GetMetricWithLabelValues(params...)
+	counterInc(ctx, metric, err, params...)
+}
+
+func RecordingRuleEvalInc(ctx context.Context, params ...string) {
+	metric, err := recordingRuleEvalTotal.GetMetricWithLabelValues(params...)
+	counterInc(ctx, metric, err, params...)
+}
+
+func SourceQueryInc(ctx context.Context, params ...string) {
+	metric, err := sourceQueryTotal.GetMetricWithLabelValues(params...)
+	counterInc(ctx, metric, err, params...)
+}
+
+func SourceQuerySecond(ctx context.Context, duration time.Duration, params ...string) {
+	metric, err := sourceQuerySecondHistogram.GetMetricWithLabelValues(params...)
+	observe(ctx, metric, err, duration, params...)
+}
+
+func SourceQueryPointsAdd(ctx context.Context, value float64, params ...string) {
+	metric, err := sourceQueryPointsTotal.GetMetricWithLabelValues(params...)
+	counterAdd(ctx, metric, err, value, params...)
+}
+
+func AuditRecordDroppedInc(ctx context.Context, params ...string) {
+	metric, err := auditRecordDroppedTotal.GetMetricWithLabelValues(params...)
+	counterInc(ctx, metric, err, params...)
+}
+
 func ResultTableInfoSet(ctx context.Context, value float64, params ...string) {
 	metric, err := resultTableInfo.GetMetricWithLabelValues(params...)
 	gaugeSet(ctx, metric, err, value, params...)
@@ -137,6 +236,17 @@ func gaugeSet(
 	metric.Set(value)
 }
 
+func counterAdd(
+	ctx context.Context, metric prometheus.Counter, err error, value float64, params ...string,
+) {
+	if err != nil {
+		log.Warnf(ctx, "metric counter:%v failed,error:%s", params, err)
+		return
+	}
+
+	metric.Add(value)
+}
+
 // handleCount
 func counterInc(
 	ctx context.Context, metric prometheus.Counter, err error, params ...string,
@@ -193,5 +303,7 @@ func init() {
 	prometheus.MustRegister(
 		apiRequestTotal, apiRequestSecondHistogram, resultTableInfo,
 		tsDBAndTableIDRequestCount, tsDBRequestSecondHistogram, vmQuerySpaceUidInfo,
+		resultCacheExtentTotal, queryLimitExceededTotal, recordingRuleEvalTotal,
+		sourceQueryTotal, sourceQuerySecondHistogram, sourceQueryPointsTotal, auditRecordDroppedTotal,
 	)
 }
@@ -56,11 +56,13 @@ type Params struct {
 }
 
 func (p *Params) StartTimeUnix() (int64, error) {
-	return strconv.ParseInt(p.Start, 10, 64)
+	t, err := structured.ParseUnixTimestamp(p.Start)
+	return t.Unix(), err
 }
 
 func (p *Params) EndTimeUnix() (int64, error) {
-	return strconv.ParseInt(p.End, 10, 64)
+	t, err := structured.ParseUnixTimestamp(p.End)
+	return t.Unix(), err
 }
 
 // AnalysisQuery
@@ -482,3 +484,29 @@ func QueryAsync(ctx context.Context, infoType InfoType, params *Params, spaceUid
 
 	return result, nil
 }
+
+// QueryAsyncByTableIDs 按结果表查询空间下的信息，各结果表生成的语句合并后统一异步执行
+func QueryAsyncByTableIDs(
+	ctx context.Context, infoType InfoType, params *Params, tableIDs []structured.TableID, spaceUid string,
+) (*influxdb.Tables, error) {
+	sqlInfos := make([]influxdb.SQLInfo, 0, len(tableIDs))
+	for _, tableID := range tableIDs {
+		p := *params
+		p.TableID = tableID
+		p.Metric = ""
+		list, err := makeInfluxQLList(ctx, infoType, &p, spaceUid)
+		if err != nil {
+			log.Errorf(ctx, "get info sql failed,table_id:%s,error:%s", tableID, err)
+			return nil, err
+		}
+		sqlInfos = append(sqlInfos, list...)
+	}
+
+	result, errs := influxdb.QueryInfosAsync(ctx, sqlInfos, "", params.Limit)
+	if len(errs) != 0 {
+		log.Errorf(ctx, "query info failed,error:%v", errs)
+		return nil, errs[0]
+	}
+
+	return result, nil
+}
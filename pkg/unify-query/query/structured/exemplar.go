@@ -120,18 +120,18 @@ func MakeInfluxdbQueryByStruct(
 			)
 		}
 
-		st, err := strconv.Atoi(query.Start)
+		st, err := ParseUnixTimestamp(query.Start)
 		if err != nil {
 			return sqlInfos, fmt.Errorf("invalid start timestamp: %v", query.Start)
 		}
-		et, err := strconv.Atoi(query.End)
+		et, err := ParseUnixTimestamp(query.End)
 		if err != nil {
 			return sqlInfos, fmt.Errorf("invalid end timestamp: %v", query.End)
 		}
 
 		// ns 单位
-		start := strconv.FormatInt(int64(st)*1000000000, 10)
-		stop := strconv.FormatInt(int64(et)*1000000000, 10)
+		start := strconv.FormatInt(st.UnixNano(), 10)
+		stop := strconv.FormatInt(et.UnixNano(), 10)
 		whereList.Append(promql.AndOperator, promql.NewWhere("time", start, promql.UpperEqualOperator, promql.NumType))
 		whereList.Append(promql.AndOperator, promql.NewWhere("time", stop, promql.LowerOperator, promql.NumType))
 
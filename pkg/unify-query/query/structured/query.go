@@ -46,6 +46,8 @@ type CombinedQueryParams struct {
 	Step string `json:"step" example:"1m"`
 	// DownSampleRange 降采样：大于Step才能生效，可以为空
 	DownSampleRange string `json:"down_sample_range,omitempty" example:"5m"`
+	// DownSampleFunction 降采样算法：lttb(默认)、m4、avg、first、last、counter(累加型指标)
+	DownSampleFunction string `json:"down_sample_function,omitempty" example:"lttb"`
 	// MaxSourceResolution 弃用字段，原 argus 场景
 	MaxSourceResolution string `json:"max_source_resolution,omitempty" swaggerignore:"true"`
 }
@@ -0,0 +1,208 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/es"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+)
+
+const (
+	// DataSourceBkLog 日志及事件数据源，数据存储在 es 中
+	DataSourceBkLog = "bklog"
+)
+
+// esAggregation 时间聚合方法对应的 es 聚合方法，count 使用文档数
+var esAggregation = map[string]string{
+	metadata.CountOT: metadata.COUNT,
+	metadata.SumOT:   metadata.SUM,
+	metadata.MinOT:   metadata.MIN,
+	metadata.MaxOT:   metadata.MAX,
+	metadata.AvgOT:   metadata.AVG,
+}
+
+// IsEsQuery 判断是否查询 es 数据源
+func (q *Query) IsEsQuery() bool {
+	return q.DataSource == DataSourceBkLog
+}
+
+// esTimeAggregation 获取 es 查询对应的 promql 时间聚合方法
+// es 实例已经按窗口完成聚合并将时间戳对齐到窗口结束时间，promql 只需要取窗口内最后一个值
+func (q *Query) esTimeAggregation() TimeAggregation {
+	timeAggregation := q.TimeAggregation
+	if timeAggregation.Function != "" {
+		timeAggregation.Function = metadata.LastOT
+	}
+	return timeAggregation
+}
+
+// checkEsTableID 校验 es 结果表已路由到该空间，避免跨空间查询其他业务的日志数据
+func checkEsTableID(ctx context.Context, spaceUid string, tableID TableID) error {
+	spaceFilter, err := NewSpaceFilter(ctx, spaceUid)
+	if err != nil {
+		return err
+	}
+	if _, ok := spaceFilter.TsDB(tableID); !ok {
+		msg := fmt.Sprintf("spaceUid: %s and tableID: %s is not exists", spaceUid, tableID)
+		metadata.SetStatus(ctx, metadata.SpaceTableIDFieldIsNotExists, msg)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// buildEsMetadataQuery 构建 es 数据源的查询结构体
+func (q *Query) buildEsMetadataQuery(
+	ctx context.Context,
+	spaceUid string,
+	queryConditions [][]ConditionField,
+	queryLabelsMatcher []*labels.Matcher,
+) (*metadata.Query, error) {
+	if err := checkEsTableID(ctx, spaceUid, q.TableID); err != nil {
+		return nil, err
+	}
+
+	tableID := string(q.TableID)
+	info, err := es.GetStorageID(tableID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", tableID, err)
+	}
+
+	var aggregation string
+	if q.TimeAggregation.Function != "" {
+		var ok bool
+		aggregation, ok = esAggregation[q.TimeAggregation.Function]
+		if !ok {
+			return nil, fmt.Errorf("time aggregation %s is not supported by %s", q.TimeAggregation.Function, q.DataSource)
+		}
+	}
+
+	esCondition, err := json.Marshal(AllConditions(queryConditions).ESQuery())
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, _, timezone, err := ToTime(q.Start, q.End, q.Step, q.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &metadata.Query{
+		SourceType:    q.DataSource,
+		StorageType:   consul.ElasticsearchStorageType,
+		StorageID:     consul.ElasticsearchStorageType,
+		TableID:       tableID,
+		Field:         q.FieldName,
+		Fields:        []string{q.FieldName},
+		Timezone:      timezone,
+		LabelsMatcher: queryLabelsMatcher,
+		IsHasOr:       len(queryConditions) > 1,
+		OffsetInfo: metadata.OffSetInfo{
+			Limit:  q.Limit,
+			SLimit: q.Slimit,
+		},
+		TimeField:     info.TimeField,
+		EsCondition:   string(esCondition),
+		EsAggregation: aggregation,
+	}
+	query.DB, query.Measurement = q.TableID.Split()
+	query.Measurements = []string{query.Measurement}
+
+	query.AggregateMethodList = make([]metadata.AggrMethod, 0, len(q.AggregateMethodList))
+	for _, aggr := range q.AggregateMethodList {
+		query.AggregateMethodList = append(query.AggregateMethodList, metadata.AggrMethod{
+			Name:       aggr.Method,
+			Dimensions: aggr.Dimensions,
+			Without:    aggr.Without,
+		})
+	}
+
+	return query, nil
+}
+
+// ESQuery 转换为 es bool 查询，组内条件为 and，组间条件为 or
+func (c AllConditions) ESQuery() map[string]interface{} {
+	should := make([]interface{}, 0, len(c))
+	for _, cond := range c {
+		var (
+			filter  = make([]interface{}, 0, len(cond))
+			mustNot = make([]interface{}, 0)
+		)
+		for _, f := range cond {
+			if len(f.Value) == 0 {
+				continue
+			}
+			switch f.Operator {
+			case ConditionEqual, ConditionContains:
+				filter = append(filter, esTerms(f.DimensionName, f.Value))
+			case ConditionNotEqual, ConditionNotContains:
+				mustNot = append(mustNot, esTerms(f.DimensionName, f.Value))
+			case ConditionRegEqual:
+				filter = append(filter, esRegexps(f.DimensionName, f.Value))
+			case ConditionNotRegEqual:
+				mustNot = append(mustNot, esRegexps(f.DimensionName, f.Value))
+			}
+		}
+
+		boolQuery := map[string]interface{}{
+			"filter": filter,
+		}
+		if len(mustNot) > 0 {
+			boolQuery["must_not"] = mustNot
+		}
+		should = append(should, map[string]interface{}{"bool": boolQuery})
+	}
+
+	if len(should) == 0 {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+	if len(should) == 1 {
+		return should[0].(map[string]interface{})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               should,
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+func esTerms(field string, values []string) map[string]interface{} {
+	return map[string]interface{}{
+		"terms": map[string]interface{}{field: values},
+	}
+}
+
+func esRegexps(field string, values []string) map[string]interface{} {
+	if len(values) == 1 {
+		return map[string]interface{}{
+			"regexp": map[string]interface{}{field: values[0]},
+		}
+	}
+	should := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		should = append(should, map[string]interface{}{
+			"regexp": map[string]interface{}{field: v},
+		})
+	}
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should":               should,
+			"minimum_should_match": 1,
+		},
+	}
+}
@@ -0,0 +1,108 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/mock"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+	ir "github.com/TencentBlueKing/bkmonitor-datalink/pkg/utils/router/influxdb"
+)
+
+// TestAllConditionsESQuery
+func TestAllConditionsESQuery(t *testing.T) {
+	testCases := map[string]struct {
+		conditions AllConditions
+		expected   string
+	}{
+		"empty": {
+			expected: `{"match_all":{}}`,
+		},
+		"and": {
+			conditions: AllConditions{
+				{
+					{DimensionName: "level", Operator: ConditionEqual, Value: []string{"error", "warn"}},
+					{DimensionName: "ip", Operator: ConditionNotEqual, Value: []string{"127.0.0.1"}},
+					{DimensionName: "path", Operator: ConditionRegEqual, Value: []string{"/api/.*"}},
+				},
+			},
+			expected: `{"bool":{"filter":[{"terms":{"level":["error","warn"]}},{"regexp":{"path":"/api/.*"}}],"must_not":[{"terms":{"ip":["127.0.0.1"]}}]}}`,
+		},
+		"or": {
+			conditions: AllConditions{
+				{{DimensionName: "level", Operator: ConditionEqual, Value: []string{"error"}}},
+				{{DimensionName: "path", Operator: ConditionNotRegEqual, Value: []string{"a.*", "b.*"}}},
+			},
+			expected: `{"bool":{"minimum_should_match":1,"should":[{"bool":{"filter":[{"terms":{"level":["error"]}}]}},{"bool":{"filter":[],"must_not":[{"bool":{"minimum_should_match":1,"should":[{"regexp":{"path":"a.*"}},{"regexp":{"path":"b.*"}}]}}]}}]}}`,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			res, err := json.Marshal(c.conditions.ESQuery())
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, string(res))
+		})
+	}
+}
+
+// TestEsTimeAggregation
+func TestEsTimeAggregation(t *testing.T) {
+	q := &Query{
+		DataSource:      DataSourceBkLog,
+		TimeAggregation: TimeAggregation{Function: metadata.CountOT, Window: "1m"},
+	}
+	assert.True(t, q.IsEsQuery())
+	assert.Equal(t, metadata.LastOT, q.esTimeAggregation().Function)
+	assert.Equal(t, metadata.CountOT, q.TimeAggregation.Function)
+
+	q.TimeAggregation.Function = metadata.AvgOT
+	assert.Equal(t, metadata.LastOT, q.esTimeAggregation().Function)
+}
+
+// TestEsQueryToMetricSpaceCheck
+func TestEsQueryToMetricSpaceCheck(t *testing.T) {
+	ctx := context.Background()
+	mock.SetRedisClient(ctx, "test")
+
+	mock.SetSpaceAndProxyMockData(
+		ctx, "query_es_test", "query_es_test", "bklog__2", &redis.TsDB{
+			TableID: "2_bklog.access",
+		}, &ir.Proxy{},
+	)
+	mock.SetSpaceAndProxyMockData(
+		ctx, "query_es_test", "query_es_test", "bklog__3", &redis.TsDB{
+			TableID: "3_bklog.access",
+		}, &ir.Proxy{},
+	)
+
+	assert.NoError(t, checkEsTableID(ctx, "bklog__2", "2_bklog.access"))
+
+	// 其他空间的结果表不允许查询
+	err := checkEsTableID(ctx, "bklog__2", "3_bklog.access")
+	assert.EqualError(t, err, "spaceUid: bklog__2 and tableID: 3_bklog.access is not exists")
+
+	q := &Query{
+		DataSource:    DataSourceBkLog,
+		TableID:       "3_bklog.access",
+		FieldName:     "_count",
+		ReferenceName: "a",
+		Start:         "0",
+		End:           "600",
+	}
+	_, err = q.ToQueryMetric(ctx, "bklog__2")
+	assert.EqualError(t, err, "spaceUid: bklog__2 and tableID: 3_bklog.access is not exists")
+}
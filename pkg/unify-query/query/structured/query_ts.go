@@ -51,6 +51,8 @@ type QueryTs struct {
 	Step string `json:"step" example:"1m"`
 	// DownSampleRange 降采样：大于Step才能生效，可以为空
 	DownSampleRange string `json:"down_sample_range,omitempty" example:"5m"`
+	// DownSampleFunction 降采样算法：lttb(默认)、m4、avg、first、last、counter(累加型指标)
+	DownSampleFunction string `json:"down_sample_function,omitempty" example:"lttb"`
 	// Timezone 时区
 	Timezone string `json:"timezone,omitempty" example:"Asia/Shanghai"`
 	// LookBackDelta 偏移量
@@ -75,6 +77,18 @@ func timeOffset(t time.Time, timezone string, step time.Duration) (string, time.
 	return outTimezone, t3, nil
 }
 
+// ParseUnixTimestamp 解析秒级时间戳 支持小数形式 精度保留到毫秒
+func ParseUnixTimestamp(timestamp string) (time.Time, error) {
+	if timeNum, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		return time.Unix(timeNum, 0), nil
+	}
+	f, err := strconv.ParseFloat(timestamp, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(int64(math.Round(f * 1e3))), nil
+}
+
 func ToTime(startStr, endStr, stepStr, timezone string) (time.Time, time.Time, time.Duration, string, error) {
 	var (
 		start    time.Time
@@ -83,16 +97,8 @@ func ToTime(startStr, endStr, stepStr, timezone string) (time.Time, time.Time, t
 		err      error
 	)
 
-	var toTime = func(timestamp string) (time.Time, error) {
-		timeNum, err := strconv.Atoi(timestamp)
-		if err != nil {
-			return time.Time{}, err
-		}
-		return time.Unix(int64(timeNum), 0), nil
-	}
-
 	if startStr != "" {
-		start, err = toTime(startStr)
+		start, err = ParseUnixTimestamp(startStr)
 		if err != nil {
 			return start, stop, interval, timezone, err
 		}
@@ -101,7 +107,7 @@ func ToTime(startStr, endStr, stepStr, timezone string) (time.Time, time.Time, t
 	if endStr == "" {
 		stop = time.Now()
 	} else {
-		stop, err = toTime(endStr)
+		stop, err = ParseUnixTimestamp(endStr)
 		if err != nil {
 			return start, stop, interval, timezone, err
 		}
@@ -206,8 +212,8 @@ func (q *QueryTs) ToPromExpr(ctx context.Context, referenceNameMetric map[string
 }
 
 type Query struct {
-	// DataSource 暂不使用
-	DataSource string `json:"data_source" swaggerignore:"true"`
+	// DataSource 数据源，默认为指标，bklog 为日志及事件
+	DataSource string `json:"data_source" example:""`
 	// TableID 数据实体ID，容器指标可以为空
 	TableID TableID `json:"table_id" example:"system.cpu_summary"`
 	// FieldName 查询指标
@@ -293,6 +299,22 @@ func (q *Query) ToQueryMetric(ctx context.Context, spaceUid string) (*metadata.Q
 	trace.InsertStringIntoSpan("space_uid", spaceUid, span)
 	trace.InsertStringIntoSpan("table_id", string(tableID), span)
 
+	// es 数据源同样需要经过空间路由校验，再通过 table_id 获取存储
+	if q.IsEsQuery() {
+		queryConditions, err := q.Conditions.AnalysisConditions()
+		if err != nil {
+			return nil, err
+		}
+		queryLabelsMatcher, _, _ := q.Conditions.ToProm()
+
+		query, err := q.buildEsMetadataQuery(ctx, spaceUid, queryConditions, queryLabelsMatcher)
+		if err != nil {
+			return nil, err
+		}
+		queryMetric.QueryList = []*metadata.Query{query}
+		return queryMetric, nil
+	}
+
 	tsDBs, err := GetTsDBList(ctx, &TsDBOption{
 		SpaceUid:  spaceUid,
 		TableID:   tableID,
@@ -670,7 +692,11 @@ func (q *Query) ToPromExpr(ctx context.Context, referenceNameMetric map[string]s
 			}
 		}
 
-		result, err = q.TimeAggregation.ToProm(result)
+		timeAggregation := q.TimeAggregation
+		if q.IsEsQuery() {
+			timeAggregation = q.esTimeAggregation()
+		}
+		result, err = timeAggregation.ToProm(result)
 		if err != nil {
 			return nil, err
 		}
@@ -376,3 +376,16 @@ func TestQueryToMetricWithOfflineDataArchiveQuery(t *testing.T) {
 	}
 
 }
+
+func TestParseUnixTimestamp(t *testing.T) {
+	ts, err := ParseUnixTimestamp("1682149980")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1682149980000), ts.UnixMilli())
+
+	ts, err = ParseUnixTimestamp("1682149980.5")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1682149980500), ts.UnixMilli())
+
+	_, err = ParseUnixTimestamp("abc")
+	assert.NotNil(t, err)
+}
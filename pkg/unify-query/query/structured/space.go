@@ -123,6 +123,15 @@ func (s *SpaceFilter) DataList(tableID TableID, fieldName string, isRegexp bool)
 	return filterTsDBs, nil
 }
 
+// TsDB 获取空间下完整 tableID 对应的路由，不对比 field
+func (s *SpaceFilter) TsDB(tableID TableID) (*redis.TsDB, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	tsDB, ok := s.space[string(tableID)]
+	return tsDB, ok
+}
+
 type TsDBOption struct {
 	SpaceUid  string
 	TableID   TableID
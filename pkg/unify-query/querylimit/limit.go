@@ -0,0 +1,263 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package querylimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+)
+
+const (
+	ResourceSeries   = "series"
+	ResourcePoints   = "points"
+	ResourceDuration = "duration"
+)
+
+// Limit 查询开销限制，0 表示不限制
+type Limit struct {
+	// MaxSeries 单次查询从存储中获取的最大序列数
+	MaxSeries int64 `mapstructure:"max_series"`
+	// MaxPoints 单次查询从存储中获取的最大点数
+	MaxPoints int64 `mapstructure:"max_points"`
+	// MaxRouting 单次查询并发请求存储的最大数量
+	MaxRouting int `mapstructure:"max_routing"`
+	// MaxDuration 单次查询的最大耗时
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+}
+
+// GetLimit 获取空间的查询开销限制
+func GetLimit(spaceUid string) Limit {
+	limit := DefaultLimit
+	space, ok := SpaceLimits[spaceUid]
+	if !ok {
+		return limit
+	}
+	if space.MaxSeries > 0 {
+		limit.MaxSeries = space.MaxSeries
+	}
+	if space.MaxPoints > 0 {
+		limit.MaxPoints = space.MaxPoints
+	}
+	if space.MaxRouting > 0 {
+		limit.MaxRouting = space.MaxRouting
+	}
+	if space.MaxDuration > 0 {
+		limit.MaxDuration = space.MaxDuration
+	}
+	return limit
+}
+
+// Error 查询开销超出空间限制
+type Error struct {
+	SpaceUid string
+	Resource string
+	Limit    int64
+	Actual   int64
+}
+
+func (e *Error) Error() string {
+	if e.Resource == ResourceDuration {
+		return fmt.Sprintf(
+			"query exceeds %s limit of space %q: %s",
+			e.Resource, e.SpaceUid, time.Duration(e.Limit),
+		)
+	}
+	return fmt.Sprintf(
+		"query exceeds %s limit of space %q: %d > %d",
+		e.Resource, e.SpaceUid, e.Actual, e.Limit,
+	)
+}
+
+// IsLimitError 判断是否为查询开销超限错误
+func IsLimitError(err error) bool {
+	var e *Error
+	return errors.As(err, &e)
+}
+
+// Tracker 统计单次查询的开销并校验是否超出限制，nil 时不做任何限制
+type Tracker struct {
+	ctx   context.Context
+	cost  *metadata.QueryCost
+	limit Limit
+}
+
+// Start 初始化本次查询的开销统计，并写入 metadata 中
+func Start(ctx context.Context, spaceUid string) *Tracker {
+	cost := &metadata.QueryCost{
+		SpaceUid: spaceUid,
+	}
+	metadata.SetQueryCost(ctx, cost)
+	return &Tracker{
+		ctx:   ctx,
+		cost:  cost,
+		limit: GetLimit(spaceUid),
+	}
+}
+
+// FromContext 获取本次查询的开销统计，未初始化时返回 nil
+func FromContext(ctx context.Context) *Tracker {
+	cost := metadata.GetQueryCost(ctx)
+	if cost == nil {
+		return nil
+	}
+	return &Tracker{
+		ctx:   ctx,
+		cost:  cost,
+		limit: GetLimit(cost.SpaceUid),
+	}
+}
+
+// Limit
+func (t *Tracker) Limit() Limit {
+	if t == nil {
+		return DefaultLimit
+	}
+	return t.limit
+}
+
+// SetQuery 记录归一化后的查询语句
+func (t *Tracker) SetQuery(query string) {
+	if t == nil {
+		return
+	}
+	t.cost.Query = query
+}
+
+// SetTables 记录查询涉及的结果表
+func (t *Tracker) SetTables(tables []string) {
+	if t == nil {
+		return
+	}
+	t.cost.Tables = tables
+}
+
+// MaxRouting 结合空间限制计算存储请求并发数
+func (t *Tracker) MaxRouting(maxRouting int) int {
+	if t == nil || t.limit.MaxRouting <= 0 {
+		return maxRouting
+	}
+	if maxRouting <= 0 || t.limit.MaxRouting < maxRouting {
+		return t.limit.MaxRouting
+	}
+	return maxRouting
+}
+
+// StorageLimit 将空间的序列数和点数限制下推到存储查询的 slimit 和 limit 中，避免存储返回超出限制的数据
+// 各自多取一条用于判断是否超限；limit 仅约束单序列的点数，总点数仍在遍历时校验
+func (t *Tracker) StorageLimit(limit, slimit int64) (int64, int64) {
+	if t == nil {
+		return limit, slimit
+	}
+	if t.limit.MaxPoints > 0 && (limit <= 0 || limit > t.limit.MaxPoints+1) {
+		limit = t.limit.MaxPoints + 1
+	}
+	if t.limit.MaxSeries > 0 && (slimit <= 0 || slimit > t.limit.MaxSeries+1) {
+		slimit = t.limit.MaxSeries + 1
+	}
+	return limit, slimit
+}
+
+// AddRouting 累加存储请求数
+func (t *Tracker) AddRouting(n int) {
+	if t == nil {
+		return
+	}
+	atomic.AddInt64(&t.cost.Routing, int64(n))
+}
+
+// AddSeries 累加序列数，超出限制时返回错误
+func (t *Tracker) AddSeries(n int) error {
+	if t == nil {
+		return nil
+	}
+	actual := atomic.AddInt64(&t.cost.Series, int64(n))
+	if t.limit.MaxSeries > 0 && actual > t.limit.MaxSeries {
+		return t.exceeded(ResourceSeries, t.limit.MaxSeries, actual)
+	}
+	return nil
+}
+
+// AddPoints 累加点数，超出限制时返回错误
+func (t *Tracker) AddPoints(n int) error {
+	if t == nil {
+		return nil
+	}
+	actual := atomic.AddInt64(&t.cost.Points, int64(n))
+	if t.limit.MaxPoints > 0 && actual > t.limit.MaxPoints {
+		return t.exceeded(ResourcePoints, t.limit.MaxPoints, actual)
+	}
+	return nil
+}
+
+// AddValue 按查询结果累加开销，用于无法在存储层统计的查询（如 vm 直查）
+func (t *Tracker) AddValue(value parser.Value) error {
+	if t == nil {
+		return nil
+	}
+
+	var series, points int
+	switch v := value.(type) {
+	case promql.Matrix:
+		series = len(v)
+		for _, s := range v {
+			points += len(s.Points)
+		}
+	case promql.Vector:
+		series = len(v)
+		points = len(v)
+	}
+
+	if err := t.AddSeries(series); err != nil {
+		return err
+	}
+	return t.AddPoints(points)
+}
+
+// Timeout 超过最大耗时时转换为超限错误，其余错误原样返回
+func (t *Tracker) Timeout(ctx context.Context, err error) error {
+	if t == nil || t.limit.MaxDuration <= 0 {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return t.exceeded(ResourceDuration, int64(t.limit.MaxDuration), 0)
+	}
+	return err
+}
+
+// Err 返回查询过程中出现的第一个超限错误
+func (t *Tracker) Err() error {
+	if t == nil {
+		return nil
+	}
+	return t.cost.Err()
+}
+
+func (t *Tracker) exceeded(resource string, limit, actual int64) error {
+	err := &Error{
+		SpaceUid: t.cost.SpaceUid,
+		Resource: resource,
+		Limit:    limit,
+		Actual:   actual,
+	}
+	if t.cost.SetErr(err) {
+		metadata.SetStatus(t.ctx, metadata.ExceedsQueryCostLimit, err.Error())
+		metric.QueryLimitExceededInc(t.ctx, t.cost.SpaceUid, resource)
+	}
+	return err
+}
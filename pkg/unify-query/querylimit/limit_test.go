@@ -0,0 +1,141 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package querylimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+)
+
+func setLimits(t *testing.T, defaultLimit Limit, spaceLimits map[string]Limit) {
+	oldDefault, oldSpaces := DefaultLimit, SpaceLimits
+	DefaultLimit, SpaceLimits = defaultLimit, spaceLimits
+	t.Cleanup(func() {
+		DefaultLimit, SpaceLimits = oldDefault, oldSpaces
+	})
+}
+
+func TestGetLimit(t *testing.T) {
+	setLimits(t, Limit{MaxSeries: 100, MaxPoints: 1000, MaxRouting: 10, MaxDuration: time.Minute}, map[string]Limit{
+		"bkcc__2": {MaxSeries: 10, MaxDuration: time.Second},
+	})
+
+	assert.Equal(t, Limit{MaxSeries: 100, MaxPoints: 1000, MaxRouting: 10, MaxDuration: time.Minute}, GetLimit("bkcc__1"))
+	assert.Equal(t, Limit{MaxSeries: 10, MaxPoints: 1000, MaxRouting: 10, MaxDuration: time.Second}, GetLimit("bkcc__2"))
+}
+
+func TestTrackerStorageLimit(t *testing.T) {
+	metadata.InitMetadata()
+	setLimits(t, Limit{MaxSeries: 2, MaxPoints: 5}, nil)
+
+	var nilTracker *Tracker
+	limit, slimit := nilTracker.StorageLimit(100, 0)
+	assert.Equal(t, int64(100), limit)
+	assert.Equal(t, int64(0), slimit)
+
+	tracker := Start(context.Background(), "bkcc__2")
+	// 未指定或大于限制时下推为限制值 + 1
+	limit, slimit = tracker.StorageLimit(0, 100)
+	assert.Equal(t, int64(6), limit)
+	assert.Equal(t, int64(3), slimit)
+
+	// 小于限制时保持原值
+	limit, slimit = tracker.StorageLimit(1, 1)
+	assert.Equal(t, int64(1), limit)
+	assert.Equal(t, int64(1), slimit)
+}
+
+func TestTracker(t *testing.T) {
+	metadata.InitMetadata()
+	setLimits(t, Limit{MaxSeries: 2, MaxPoints: 5, MaxRouting: 3}, nil)
+
+	ctx := context.Background()
+	tracker := Start(ctx, "bkcc__2")
+	tracker.SetQuery(`sum(a)`)
+
+	assert.Equal(t, 3, tracker.MaxRouting(100))
+	assert.Equal(t, 1, tracker.MaxRouting(1))
+	tracker.AddRouting(2)
+
+	assert.NoError(t, tracker.AddSeries(2))
+	assert.NoError(t, tracker.AddPoints(5))
+	assert.NoError(t, tracker.Err())
+
+	// 通过 metadata 获取到的是同一份统计
+	other := FromContext(ctx)
+	err := other.AddSeries(1)
+	assert.True(t, IsLimitError(err))
+	assert.Equal(t, `query exceeds series limit of space "bkcc__2": 3 > 2`, err.Error())
+	assert.True(t, IsLimitError(fmt.Errorf("wrap: %w", err)))
+	assert.Equal(t, err, tracker.Err())
+
+	// 只记录第一个超限错误
+	assert.Error(t, tracker.AddPoints(1))
+	assert.Equal(t, err, tracker.Err())
+
+	cost := metadata.GetQueryCost(ctx)
+	assert.Equal(t, "sum(a)", cost.Query)
+	assert.Equal(t, int64(3), cost.Series)
+	assert.Equal(t, int64(6), cost.Points)
+	assert.Equal(t, int64(2), cost.Routing)
+
+	status := metadata.GetStatus(ctx)
+	assert.Equal(t, metadata.ExceedsQueryCostLimit, status.Code)
+}
+
+func TestTrackerAddValue(t *testing.T) {
+	metadata.InitMetadata()
+	setLimits(t, Limit{MaxPoints: 3}, nil)
+
+	tracker := Start(context.Background(), "bkcc__2")
+	assert.NoError(t, tracker.AddValue(promql.Vector{
+		{Metric: labels.FromStrings("a", "1"), Point: promql.Point{T: 1, V: 1}},
+	}))
+	err := tracker.AddValue(promql.Matrix{
+		{Metric: labels.FromStrings("a", "1"), Points: []promql.Point{{T: 1, V: 1}, {T: 2, V: 2}, {T: 3, V: 3}}},
+	})
+	assert.True(t, IsLimitError(err))
+}
+
+func TestTrackerTimeout(t *testing.T) {
+	metadata.InitMetadata()
+	setLimits(t, Limit{MaxDuration: time.Millisecond}, nil)
+
+	tracker := Start(context.Background(), "bkcc__2")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	err := tracker.Timeout(ctx, context.DeadlineExceeded)
+	assert.True(t, IsLimitError(err))
+
+	other := errors.New("other")
+	assert.Equal(t, other, tracker.Timeout(context.Background(), other))
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	assert.Equal(t, 5, tracker.MaxRouting(5))
+	assert.NoError(t, tracker.AddSeries(100))
+	assert.NoError(t, tracker.AddPoints(100))
+	assert.NoError(t, tracker.Err())
+	tracker.AddRouting(1)
+	tracker.SetQuery("a")
+}
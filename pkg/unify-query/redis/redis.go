@@ -119,3 +119,23 @@ var Subscribe = func(ctx context.Context, channels ...string) <-chan *goRedis.Me
 	p := globalInstance.client.Subscribe(ctx, channels...)
 	return p.Channel()
 }
+
+var SetNX = func(ctx context.Context, key, val string, expiration time.Duration) (bool, error) {
+	log.Infof(ctx, "[redis] setnx %s", key)
+	res := globalInstance.client.SetNX(ctx, key, val, expiration)
+	return res.Result()
+}
+
+// compareAndDelScript 只删除值相同的 key，避免误删其他实例获取的锁
+var compareAndDelScript = goRedis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var CompareAndDel = func(ctx context.Context, key, val string) (bool, error) {
+	log.Infof(ctx, "[redis] compare and del %s", key)
+	res, err := compareAndDelScript.Run(ctx, globalInstance.client, []string{key}, val).Int()
+	return res > 0, err
+}
@@ -0,0 +1,68 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package resultcache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+// setDefaultConfig
+func setDefaultConfig() {
+	viper.SetDefault(EnableConfigPath, false)
+	viper.SetDefault(TTLConfigPath, "1h")
+	viper.SetDefault(MaxFreshnessConfigPath, "10m")
+	viper.SetDefault(MaxCostConfigPath, 256<<20)
+	viper.SetDefault(RedisEnableConfigPath, false)
+}
+
+// LoadConfig
+func LoadConfig() {
+	Enable = viper.GetBool(EnableConfigPath)
+	TTL = viper.GetDuration(TTLConfigPath)
+	MaxFreshness = viper.GetDuration(MaxFreshnessConfigPath)
+	MaxCost = viper.GetInt64(MaxCostConfigPath)
+	RedisEnable = viper.GetBool(RedisEnableConfigPath)
+
+	if !Enable {
+		SetStore(nil)
+		return
+	}
+
+	store, err := NewStore(MaxCost, RedisEnable)
+	if err != nil {
+		log.Errorf(context.TODO(), "init result cache failed: %s", err)
+		SetStore(nil)
+		return
+	}
+	SetStore(store)
+}
+
+// init
+func init() {
+	if err := eventbus.EventBus.Subscribe(eventbus.EventSignalConfigPreParse, setDefaultConfig); err != nil {
+		fmt.Printf(
+			"failed to subscribe event->[%s] for result cache module for default config, maybe result cache module won't working.",
+			eventbus.EventSignalConfigPreParse,
+		)
+	}
+
+	if err := eventbus.EventBus.Subscribe(eventbus.EventSignalConfigPostParse, LoadConfig); err != nil {
+		fmt.Printf(
+			"failed to subscribe event->[%s] for result cache module for new config, maybe result cache module won't working.",
+			eventbus.EventSignalConfigPostParse,
+		)
+	}
+}
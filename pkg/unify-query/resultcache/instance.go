@@ -0,0 +1,283 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package resultcache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+)
+
+const (
+	hourExtent = time.Hour
+	dayExtent  = 24 * time.Hour
+
+	// maxHourExtents 超过该数量的小时片段时改用天片段，减少缓存查询次数
+	maxHourExtents = 7 * 24
+
+	StatusHit  = "hit"
+	StatusMiss = "miss"
+)
+
+// Instance 为 QueryRange 增加结果缓存，其余方法直接使用被包装的实例
+//
+// 查询范围按 step 对齐切分为小时或天的片段，完整且不在 MaxFreshness 内的片段才会被缓存，
+// 缺失的连续片段合并为一次查询，因此重复查询同一时间窗口时只会查询未缓存的尾部
+type Instance struct {
+	tsdb.Instance
+
+	store  Store
+	prefix string
+	now    func() time.Time
+}
+
+var _ tsdb.Instance = (*Instance)(nil)
+
+// NewInstance 创建带缓存的实例，prefix 需要唯一标识除时间范围之外的查询条件
+// 缓存未开启时直接返回原实例
+func NewInstance(instance tsdb.Instance, prefix string) tsdb.Instance {
+	store := GetStore()
+	if store == nil {
+		return instance
+	}
+	return &Instance{
+		Instance: instance,
+		store:    store,
+		prefix:   prefix,
+		now:      time.Now,
+	}
+}
+
+type extent struct {
+	// start, end 片段内第一个和最后一个计算点，单位 ms
+	start, end int64
+	// queryStart, queryEnd 片段内实际需要的计算点
+	queryStart, queryEnd int64
+	cacheable            bool
+	key                  string
+	matrix               promql.Matrix
+	hit                  bool
+}
+
+// QueryRange
+func (i *Instance) QueryRange(ctx context.Context, qs string, start, end time.Time, step time.Duration) (promql.Matrix, error) {
+	var (
+		span oleltrace.Span
+		user = metadata.GetUser(ctx)
+	)
+
+	size := extentSize(start, end, step)
+	if size == 0 || !cacheableExpr(qs) {
+		return i.Instance.QueryRange(ctx, qs, start, end, step)
+	}
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "result-cache-query-range")
+	if span != nil {
+		defer span.End()
+	}
+
+	extents := i.split(qs, start.UnixMilli(), end.UnixMilli(), step.Milliseconds(), size.Milliseconds())
+
+	var hit, miss int
+	for _, e := range extents {
+		if !e.cacheable {
+			continue
+		}
+		if m, ok := i.store.Get(ctx, e.key); ok {
+			e.matrix = m
+			e.hit = true
+			hit++
+			metric.ResultCacheExtentInc(ctx, user.SpaceUid, StatusHit)
+			continue
+		}
+		miss++
+		metric.ResultCacheExtentInc(ctx, user.SpaceUid, StatusMiss)
+	}
+
+	trace.InsertIntIntoSpan("result-cache-extents", len(extents), span)
+	trace.InsertIntIntoSpan("result-cache-hit", hit, span)
+	trace.InsertIntIntoSpan("result-cache-miss", miss, span)
+
+	// 相邻的未命中片段合并成一次查询
+	for idx := 0; idx < len(extents); {
+		if extents[idx].hit {
+			idx++
+			continue
+		}
+
+		j := idx
+		for j+1 < len(extents) && !extents[j+1].hit {
+			j++
+		}
+
+		from, to := extents[idx].queryStart, extents[j].queryEnd
+		m, err := i.Instance.QueryRange(ctx, qs, time.UnixMilli(from), time.UnixMilli(to), step)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range extents[idx : j+1] {
+			e.matrix = sliceMatrix(m, e.queryStart, e.queryEnd)
+			if e.cacheable && !hasHistogram(e.matrix) {
+				i.store.Set(ctx, e.key, e.matrix, TTL)
+			}
+		}
+		idx = j + 1
+	}
+
+	log.Debugf(ctx, "result cache query range %s, extents: %d, hit: %d, miss: %d", qs, len(extents), hit, miss)
+	return mergeExtents(extents), nil
+}
+
+// split 将 [start, end] 切分成对齐的片段，start 需要按 step 对齐
+func (i *Instance) split(qs string, start, end, step, size int64) []*extent {
+	fresh := i.now().Add(-MaxFreshness).UnixMilli()
+
+	var extents []*extent
+	for es := start - start%size; es <= end; es += size {
+		e := &extent{
+			start: es,
+			end:   es + size - step,
+		}
+		e.queryStart = maxInt64(e.start, start)
+		e.queryEnd = minInt64(e.end, end)
+		if e.queryStart > e.queryEnd {
+			continue
+		}
+
+		e.cacheable = e.queryStart == e.start && e.queryEnd == e.end && e.end < fresh
+		if e.cacheable {
+			e.key = i.key(qs, step, e.start, size)
+		}
+		extents = append(extents, e)
+	}
+	return extents
+}
+
+func (i *Instance) key(qs string, step, start, size int64) string {
+	h := sha1.New()
+	_, _ = fmt.Fprintf(h, "%s|%s|%s|%d|%d|%d", i.GetInstanceType(), i.prefix, qs, step, start, size)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// extentSize 根据 step 选择片段大小，无法对齐时返回 0 表示不使用缓存
+func extentSize(start, end time.Time, step time.Duration) time.Duration {
+	if step <= 0 || start.UnixMilli()%step.Milliseconds() != 0 {
+		return 0
+	}
+
+	if hourExtent%step == 0 && end.Sub(start) <= maxHourExtents*hourExtent {
+		return hourExtent
+	}
+	if dayExtent%step == 0 {
+		return dayExtent
+	}
+	return 0
+}
+
+// cacheableExpr 使用 @ start()/end() 的表达式结果依赖查询范围，不能按片段复用
+func cacheableExpr(qs string) bool {
+	expr, err := parser.ParseExpr(qs)
+	if err != nil {
+		return false
+	}
+
+	cacheable := true
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			if n.StartOrEnd != 0 {
+				cacheable = false
+			}
+		case *parser.SubqueryExpr:
+			if n.StartOrEnd != 0 {
+				cacheable = false
+			}
+		}
+		return nil
+	})
+	return cacheable
+}
+
+// sliceMatrix 截取 [start, end] 内的数据点，没有数据点的 series 会被丢弃
+func sliceMatrix(m promql.Matrix, start, end int64) promql.Matrix {
+	ret := make(promql.Matrix, 0, len(m))
+	for _, s := range m {
+		lo := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T >= start })
+		hi := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].T > end })
+		if lo >= hi {
+			continue
+		}
+		points := make([]promql.Point, hi-lo)
+		copy(points, s.Points[lo:hi])
+		ret = append(ret, promql.Series{Metric: s.Metric, Points: points})
+	}
+	return ret
+}
+
+// mergeExtents 按 series 拼接各片段的数据点，片段需按时间有序
+func mergeExtents(extents []*extent) promql.Matrix {
+	index := make(map[string]int)
+	var ret promql.Matrix
+	for _, e := range extents {
+		for _, s := range e.matrix {
+			key := s.Metric.String()
+			idx, ok := index[key]
+			if !ok {
+				index[key] = len(ret)
+				points := make([]promql.Point, len(s.Points))
+				copy(points, s.Points)
+				ret = append(ret, promql.Series{Metric: s.Metric, Points: points})
+				continue
+			}
+			ret[idx].Points = append(ret[idx].Points, s.Points...)
+		}
+	}
+	sort.Sort(ret)
+	return ret
+}
+
+func hasHistogram(m promql.Matrix) bool {
+	for _, s := range m {
+		for _, p := range s.Points {
+			if p.H != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,148 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package resultcache
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+)
+
+type queryRange struct {
+	start, end int64
+}
+
+// mockInstance 每个计算点的值等于时间戳(秒)，只有一条 series
+type mockInstance struct {
+	tsdb.Instance
+	calls []queryRange
+}
+
+func (m *mockInstance) QueryRange(ctx context.Context, qs string, start, end time.Time, step time.Duration) (promql.Matrix, error) {
+	m.calls = append(m.calls, queryRange{start: start.UnixMilli(), end: end.UnixMilli()})
+	var points []promql.Point
+	for t := start; !t.After(end); t = t.Add(step) {
+		points = append(points, promql.Point{T: t.UnixMilli(), V: float64(t.Unix())})
+	}
+	return promql.Matrix{{Metric: labels.FromStrings("__name__", "a"), Points: points}}, nil
+}
+
+func (m *mockInstance) GetInstanceType() string {
+	return "mock"
+}
+
+func newTestInstance(t *testing.T, inner *mockInstance, now time.Time) *Instance {
+	store, err := NewStore(1<<20, false)
+	assert.NoError(t, err)
+	return &Instance{Instance: inner, store: store, prefix: "test", now: func() time.Time { return now }}
+}
+
+func TestQueryRangeCache(t *testing.T) {
+	log.InitTestLogger()
+	MaxFreshness = 10 * time.Minute
+	TTL = time.Hour
+
+	ctx := context.Background()
+	now := time.Date(2023, 1, 2, 12, 30, 0, 0, time.UTC)
+	start := time.Date(2023, 1, 2, 6, 0, 0, 0, time.UTC)
+	end := now
+	step := time.Minute
+
+	inner := &mockInstance{}
+	instance := newTestInstance(t, inner, now)
+
+	m, err := instance.QueryRange(ctx, "a", start, end, step)
+	assert.NoError(t, err)
+	assert.Len(t, m, 1)
+	assert.Len(t, m[0].Points, 391)
+	// 所有片段都未命中，合并为一次查询
+	assert.Equal(t, []queryRange{{start: start.UnixMilli(), end: end.UnixMilli()}}, inner.calls)
+
+	// ristretto 异步写入
+	time.Sleep(50 * time.Millisecond)
+
+	inner.calls = nil
+	m2, err := instance.QueryRange(ctx, "a", start, end, step)
+	assert.NoError(t, err)
+	assert.Equal(t, m, m2)
+	// 只查询最新未缓存的片段
+	assert.Equal(t, []queryRange{{start: time.Date(2023, 1, 2, 12, 0, 0, 0, time.UTC).UnixMilli(), end: end.UnixMilli()}}, inner.calls)
+
+	for i, p := range m2[0].Points {
+		assert.Equal(t, start.Add(time.Duration(i)*step).UnixMilli(), p.T)
+	}
+}
+
+func TestQueryRangeBypass(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 2, 12, 30, 0, 0, time.UTC)
+
+	inner := &mockInstance{}
+	instance := newTestInstance(t, inner, now)
+
+	// start 未按 step 对齐
+	start := now.Add(-time.Hour).Add(time.Second)
+	_, err := instance.QueryRange(ctx, "a", start, now, time.Minute)
+	assert.NoError(t, err)
+
+	// @ end() 依赖查询范围
+	_, err = instance.QueryRange(ctx, "a @ end()", now.Add(-time.Hour), now, time.Minute)
+	assert.NoError(t, err)
+
+	assert.Len(t, inner.calls, 2)
+}
+
+func TestExtentSize(t *testing.T) {
+	end := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		start time.Time
+		step  time.Duration
+		size  time.Duration
+	}{
+		{start: end.Add(-time.Hour), step: time.Minute, size: hourExtent},
+		{start: end.Add(-30 * 24 * time.Hour), step: time.Minute, size: dayExtent},
+		{start: end.Add(-2 * time.Hour), step: 2 * time.Hour, size: dayExtent},
+		{start: end.Add(-time.Hour), step: 7 * time.Minute, size: 0},
+		{start: end.Add(-time.Hour).Add(time.Second), step: time.Minute, size: 0},
+	}
+	for _, c := range testCases {
+		assert.Equal(t, c.size, extentSize(c.start, end, c.step))
+	}
+}
+
+func TestEncodeMatrix(t *testing.T) {
+	m := promql.Matrix{
+		{
+			Metric: labels.FromStrings("__name__", "a", "pod", "p1"),
+			Points: []promql.Point{{T: 1000, V: 1.5}, {T: 2000, V: math.Inf(1)}},
+		},
+	}
+	b, err := encodeMatrix(m)
+	assert.NoError(t, err)
+
+	ret, err := decodeMatrix(b)
+	assert.NoError(t, err)
+	assert.Equal(t, m, ret)
+
+	m[0].Points[0].V = math.NaN()
+	b, err = encodeMatrix(m)
+	assert.NoError(t, err)
+	ret, err = decodeMatrix(b)
+	assert.NoError(t, err)
+	assert.True(t, math.IsNaN(ret[0].Points[0].V))
+}
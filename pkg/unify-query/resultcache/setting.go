@@ -0,0 +1,34 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package resultcache
+
+import (
+	"time"
+)
+
+const (
+	EnableConfigPath       = "query.result_cache.enable"
+	TTLConfigPath          = "query.result_cache.ttl"
+	MaxFreshnessConfigPath = "query.result_cache.max_freshness"
+	MaxCostConfigPath      = "query.result_cache.max_cost"
+	RedisEnableConfigPath  = "query.result_cache.redis.enable"
+)
+
+var (
+	Enable bool
+	// TTL 缓存片段的过期时间
+	TTL time.Duration
+	// MaxFreshness 最近一段时间内的数据可能仍在写入，不进行缓存
+	MaxFreshness time.Duration
+	// MaxCost 进程内缓存的容量上限，单位 byte
+	MaxCost int64
+	// RedisEnable 是否同时使用 redis 作为二级缓存，便于多实例共享
+	RedisEnable bool
+)
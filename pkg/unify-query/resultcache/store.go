@@ -0,0 +1,175 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package resultcache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	goRedis "github.com/go-redis/redis/v8"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/memcache"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+)
+
+// Store 缓存片段的存储
+type Store interface {
+	Get(ctx context.Context, key string) (promql.Matrix, bool)
+	Set(ctx context.Context, key string, matrix promql.Matrix, ttl time.Duration)
+}
+
+var (
+	globalStore Store
+	storeLock   sync.RWMutex
+)
+
+// SetStore 设置全局缓存，为 nil 时表示关闭缓存
+func SetStore(store Store) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	globalStore = store
+}
+
+// GetStore 获取全局缓存
+func GetStore() Store {
+	storeLock.RLock()
+	defer storeLock.RUnlock()
+	return globalStore
+}
+
+// NewStore 创建进程内缓存 redisEnable 时使用 redis 作为二级缓存
+func NewStore(maxCost int64, redisEnable bool) (Store, error) {
+	c, err := memcache.NewRistrettoWithMaxCost(maxCost)
+	if err != nil {
+		return nil, err
+	}
+	return &store{memory: c, redisEnable: redisEnable}, nil
+}
+
+type store struct {
+	memory      memcache.Cache
+	redisEnable bool
+}
+
+func (s *store) Get(ctx context.Context, key string) (promql.Matrix, bool) {
+	if v, ok := s.memory.Get(key); ok {
+		if m, ok := v.(promql.Matrix); ok {
+			return m, true
+		}
+	}
+
+	if !s.redisEnable || redis.Client() == nil {
+		return nil, false
+	}
+
+	val, err := redis.Get(ctx, redisKey(key))
+	if err != nil {
+		if err != goRedis.Nil {
+			log.Warnf(ctx, "get result cache from redis failed: %s", err)
+		}
+		return nil, false
+	}
+
+	m, err := decodeMatrix([]byte(val))
+	if err != nil {
+		log.Warnf(ctx, "decode result cache failed: %s", err)
+		return nil, false
+	}
+	s.memory.SetWithTTL(key, m, matrixCost(m), TTL)
+	return m, true
+}
+
+func (s *store) Set(ctx context.Context, key string, matrix promql.Matrix, ttl time.Duration) {
+	s.memory.SetWithTTL(key, matrix, matrixCost(matrix), ttl)
+
+	if !s.redisEnable || redis.Client() == nil {
+		return
+	}
+
+	b, err := encodeMatrix(matrix)
+	if err != nil {
+		log.Warnf(ctx, "encode result cache failed: %s", err)
+		return
+	}
+	if _, err = redis.Set(ctx, redisKey(key), string(b), ttl); err != nil {
+		log.Warnf(ctx, "set result cache to redis failed: %s", err)
+	}
+}
+
+func redisKey(key string) string {
+	return fmt.Sprintf("%s:result_cache:%s", redis.ServiceName(), key)
+}
+
+// matrixCost 粗略估算片段占用的内存
+func matrixCost(m promql.Matrix) int64 {
+	var cost int64
+	for _, s := range m {
+		cost += int64(len(s.Points)) * 16
+		for _, l := range s.Metric {
+			cost += int64(len(l.Name) + len(l.Value))
+		}
+	}
+	return cost + 1
+}
+
+type series struct {
+	Labels map[string]string `json:"labels"`
+	T      []int64           `json:"t"`
+	// V 以字符串保存，json 不支持 NaN/Inf
+	V []string `json:"v"`
+}
+
+func encodeMatrix(m promql.Matrix) ([]byte, error) {
+	ss := make([]series, 0, len(m))
+	for _, s := range m {
+		item := series{
+			Labels: s.Metric.Map(),
+			T:      make([]int64, 0, len(s.Points)),
+			V:      make([]string, 0, len(s.Points)),
+		}
+		for _, p := range s.Points {
+			item.T = append(item.T, p.T)
+			item.V = append(item.V, strconv.FormatFloat(p.V, 'f', -1, 64))
+		}
+		ss = append(ss, item)
+	}
+	return json.Marshal(ss)
+}
+
+func decodeMatrix(b []byte) (promql.Matrix, error) {
+	var ss []series
+	if err := json.Unmarshal(b, &ss); err != nil {
+		return nil, err
+	}
+
+	m := make(promql.Matrix, 0, len(ss))
+	for _, s := range ss {
+		if len(s.T) != len(s.V) {
+			return nil, fmt.Errorf("points length mismatch: %d != %d", len(s.T), len(s.V))
+		}
+		points := make([]promql.Point, 0, len(s.T))
+		for i := range s.T {
+			v, err := strconv.ParseFloat(s.V[i], 64)
+			if err != nil {
+				return nil, err
+			}
+			points = append(points, promql.Point{T: s.T[i], V: v})
+		}
+		m = append(m, promql.Series{Metric: labels.FromMap(s.Labels), Points: points})
+	}
+	return m, nil
+}
@@ -0,0 +1,86 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	goRedis "github.com/go-redis/redis/v8"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+)
+
+// Checkpoint 记录规则最后一次计算的时间，用于重启后补算
+type Checkpoint interface {
+	Get(ctx context.Context, key string) (time.Time, bool, error)
+	Set(ctx context.Context, key string, t time.Time) error
+}
+
+// NewMemoryCheckpoint 进程内记录，重启后丢失
+func NewMemoryCheckpoint() Checkpoint {
+	return &memoryCheckpoint{data: make(map[string]time.Time)}
+}
+
+type memoryCheckpoint struct {
+	lock sync.RWMutex
+	data map[string]time.Time
+}
+
+func (c *memoryCheckpoint) Get(_ context.Context, key string) (time.Time, bool, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	t, ok := c.data[key]
+	return t, ok, nil
+}
+
+func (c *memoryCheckpoint) Set(_ context.Context, key string, t time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.data[key] = t
+	return nil
+}
+
+// NewRedisCheckpoint 使用 redis 记录，多实例及重启后共享
+func NewRedisCheckpoint(ttl time.Duration) Checkpoint {
+	return &redisCheckpoint{ttl: ttl}
+}
+
+type redisCheckpoint struct {
+	ttl time.Duration
+}
+
+func (c *redisCheckpoint) redisKey(key string) string {
+	return fmt.Sprintf("%s:recording_rule:%x", redis.ServiceName(), md5.Sum([]byte(key)))
+}
+
+func (c *redisCheckpoint) Get(ctx context.Context, key string) (time.Time, bool, error) {
+	val, err := redis.Get(ctx, c.redisKey(key))
+	if err != nil {
+		if err == goRedis.Nil {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	ms, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(ms), true, nil
+}
+
+func (c *redisCheckpoint) Set(ctx context.Context, key string, t time.Time) error {
+	_, err := redis.Set(ctx, c.redisKey(key), strconv.FormatInt(t.UnixMilli(), 10), c.ttl)
+	return err
+}
@@ -0,0 +1,72 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+)
+
+// Locker 多实例部署时保证同一规则同一时刻只在一个实例上计算
+type Locker interface {
+	// TryLock 获取规则的计算租约，ok 为 false 表示已被其他实例持有，unlock 用于计算完成后释放
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+}
+
+// NewLocalLocker 单实例部署使用，总是获取成功
+func NewLocalLocker() Locker {
+	return localLocker{}
+}
+
+type localLocker struct{}
+
+func (localLocker) TryLock(_ context.Context, _ string, _ time.Duration) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+// NewRedisLocker 使用 redis SET NX 实现的租约，实例异常退出时在 ttl 后自动释放
+func NewRedisLocker() Locker {
+	return &redisLocker{}
+}
+
+type redisLocker struct{}
+
+func (l *redisLocker) redisKey(key string) string {
+	return fmt.Sprintf("%s:recording_rule_lock:%x", redis.ServiceName(), md5.Sum([]byte(key)))
+}
+
+func (l *redisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+	val := hex.EncodeToString(token)
+
+	redisKey := l.redisKey(key)
+	ok, err := redis.SetNX(ctx, redisKey, val, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	unlock := func() {
+		// 计算任务的 ctx 可能已经超时，释放时使用新的 ctx
+		if _, err := redis.CompareAndDel(context.Background(), redisKey, val); err != nil {
+			log.Warnf(ctx, "release recording rule lock %s failed: %s", key, err)
+		}
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,173 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+)
+
+// QueryRangeFunc 在指定空间下执行 promql 范围查询
+type QueryRangeFunc func(
+	ctx context.Context, spaceUid, promQL string, start, end time.Time, step time.Duration,
+) (promql.Matrix, error)
+
+// Options 规则计算配置
+type Options struct {
+	Query      QueryRangeFunc
+	Writers    map[string]Writer
+	Checkpoint Checkpoint
+	// Locker 多实例部署时按规则互斥计算，为空时不加锁
+	Locker Locker
+
+	// Delay 数据入库存在延迟，只计算 now - Delay 之前的周期
+	Delay time.Duration
+	// MaxBackfill 停机后最多补算的时长，为 0 时不限制
+	MaxBackfill time.Duration
+	// MaxPoints 单次查询的最大点数，补算时按此拆分查询
+	MaxPoints int
+	// Timeout 单次计算的超时时间
+	Timeout time.Duration
+}
+
+// Manager 按周期计算预计算规则
+type Manager struct {
+	opt *Options
+	wg  sync.WaitGroup
+}
+
+// NewManager
+func NewManager(opt *Options) *Manager {
+	return &Manager{opt: opt}
+}
+
+// Run 为每个规则启动计算任务，ctx 取消后退出
+func (m *Manager) Run(ctx context.Context, rules []*Rule) {
+	for _, r := range rules {
+		m.wg.Add(1)
+		go func(r *Rule) {
+			defer m.wg.Done()
+			m.run(ctx, r)
+		}(r)
+	}
+}
+
+// Wait 等待所有计算任务退出
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Manager) run(ctx context.Context, r *Rule) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.eval(ctx, r, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) eval(ctx context.Context, r *Rule, now time.Time) {
+	if m.opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.opt.Timeout)
+		defer cancel()
+	}
+
+	// 租约时长需要覆盖单次计算的最长耗时
+	if m.opt.Locker != nil {
+		ttl := r.Interval
+		if m.opt.Timeout > ttl {
+			ttl = m.opt.Timeout
+		}
+		unlock, ok, err := m.opt.Locker.TryLock(ctx, r.Key(), ttl)
+		if err != nil {
+			log.Errorf(ctx, "recording rule %s of %s get lock failed: %s", r.Record, r.SpaceUid, err)
+			return
+		}
+		if !ok {
+			log.Debugf(ctx, "recording rule %s of %s is evaluating by other instance", r.Record, r.SpaceUid)
+			return
+		}
+		defer unlock()
+	}
+
+	status := "success"
+	if err := m.Eval(ctx, r, now); err != nil {
+		status = "failed"
+		log.Errorf(ctx, "recording rule %s of %s eval failed: %s", r.Record, r.SpaceUid, err)
+	}
+	metric.RecordingRuleEvalInc(ctx, r.SpaceUid, r.Record, status)
+}
+
+// Eval 计算 now 之前所有未计算的周期，从上次记录的时间开始补算
+func (m *Manager) Eval(ctx context.Context, r *Rule, now time.Time) error {
+	writer, ok := m.opt.Writers[r.Target]
+	if !ok {
+		return fmt.Errorf("writer of %s is not configured", r.Target)
+	}
+
+	end := now.Add(-m.opt.Delay).Truncate(r.Interval)
+	start := end
+
+	last, ok, err := m.opt.Checkpoint.Get(ctx, r.Key())
+	if err != nil {
+		return err
+	}
+	if ok {
+		start = last.Add(r.Interval)
+		if m.opt.MaxBackfill > 0 {
+			earliest := end.Add(-m.opt.MaxBackfill).Truncate(r.Interval)
+			if start.Before(earliest) {
+				log.Warnf(ctx, "recording rule %s of %s skip backfill from %s to %s", r.Record, r.SpaceUid, start, earliest)
+				start = earliest
+			}
+		}
+	}
+
+	maxPoints := m.opt.MaxPoints
+	if maxPoints <= 0 {
+		maxPoints = 1
+	}
+	for !start.After(end) {
+		chunkEnd := start.Add(time.Duration(maxPoints-1) * r.Interval)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+
+		matrix, err := m.opt.Query(ctx, r.SpaceUid, r.Expr, start, chunkEnd, r.Interval)
+		if err != nil {
+			return err
+		}
+		for i := range matrix {
+			matrix[i].Metric = r.Metric(matrix[i].Metric)
+		}
+		if err = writer.Write(ctx, r, matrix); err != nil {
+			return err
+		}
+		if err = m.opt.Checkpoint.Set(ctx, r.Key(), chunkEnd); err != nil {
+			return err
+		}
+
+		start = chunkEnd.Add(r.Interval)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+type queryCall struct {
+	start, end time.Time
+}
+
+type mockWriter struct {
+	matrix []promql.Matrix
+	err    error
+}
+
+func (w *mockWriter) Write(_ context.Context, _ *Rule, matrix promql.Matrix) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.matrix = append(w.matrix, matrix)
+	return nil
+}
+
+func TestManagerEval(t *testing.T) {
+	log.InitTestLogger()
+
+	var (
+		ctx    = context.Background()
+		calls  []queryCall
+		writer = &mockWriter{}
+		r      = &Rule{
+			SpaceUid: "bkcc__2", Record: "cpu_usage_avg", Expr: "avg(a)", Interval: time.Minute,
+			Labels: map[string]string{"source": "rule"}, Target: TargetInfluxDB,
+		}
+		now = time.Unix(1682150030, 0)
+	)
+
+	m := NewManager(&Options{
+		Query: func(_ context.Context, spaceUid, promQL string, start, end time.Time, step time.Duration) (promql.Matrix, error) {
+			calls = append(calls, queryCall{start: start, end: end})
+			var points []promql.Point
+			for t := start; !t.After(end); t = t.Add(step) {
+				points = append(points, promql.Point{T: t.UnixMilli(), V: 1})
+			}
+			return promql.Matrix{{Metric: labels.FromStrings("ip", "127.0.0.1"), Points: points}}, nil
+		},
+		Writers:     map[string]Writer{TargetInfluxDB: writer},
+		Checkpoint:  NewMemoryCheckpoint(),
+		Delay:       time.Minute,
+		MaxBackfill: 10 * time.Minute,
+		MaxPoints:   3,
+	})
+
+	// 第一次计算只计算最近一个周期
+	err := m.Eval(ctx, r, now)
+	assert.Nil(t, err)
+	assert.Equal(t, []queryCall{{start: time.Unix(1682149920, 0), end: time.Unix(1682149920, 0)}}, calls)
+	assert.Equal(t, "cpu_usage_avg", writer.matrix[0][0].Metric.Get(labels.MetricName))
+	assert.Equal(t, "rule", writer.matrix[0][0].Metric.Get("source"))
+
+	// 同一个周期内不重复计算
+	calls = nil
+	err = m.Eval(ctx, r, now.Add(5*time.Second))
+	assert.Nil(t, err)
+	assert.Empty(t, calls)
+
+	// 停机后按最大点数拆分补算
+	calls = nil
+	err = m.Eval(ctx, r, now.Add(5*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []queryCall{
+		{start: time.Unix(1682149980, 0), end: time.Unix(1682150100, 0)},
+		{start: time.Unix(1682150160, 0), end: time.Unix(1682150220, 0)},
+	}, calls)
+
+	// 超过最大补算时长的部分直接跳过
+	calls = nil
+	err = m.Eval(ctx, r, now.Add(time.Hour+5*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1682153220, 0), calls[0].start)
+	assert.Equal(t, time.Unix(1682153820, 0), calls[len(calls)-1].end)
+
+	// 写入失败时不更新进度，下次继续计算
+	calls = nil
+	writer.err = fmt.Errorf("write failed")
+	err = m.Eval(ctx, r, now.Add(time.Hour+6*time.Minute))
+	assert.NotNil(t, err)
+	writer.err = nil
+	calls = nil
+	err = m.Eval(ctx, r, now.Add(time.Hour+6*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []queryCall{{start: time.Unix(1682153880, 0), end: time.Unix(1682153880, 0)}}, calls)
+
+	// 未配置写入目标
+	err = m.Eval(ctx, &Rule{Target: TargetVictoriaMetrics, Interval: time.Minute}, now)
+	assert.NotNil(t, err)
+}
+
+type mockLocker struct {
+	locked   bool
+	unlocked int
+	ttl      time.Duration
+}
+
+func (l *mockLocker) TryLock(_ context.Context, _ string, ttl time.Duration) (func(), bool, error) {
+	l.ttl = ttl
+	if l.locked {
+		return nil, false, nil
+	}
+	return func() { l.unlocked++ }, true, nil
+}
+
+func TestManagerEvalLocker(t *testing.T) {
+	log.InitTestLogger()
+
+	var (
+		ctx    = context.Background()
+		calls  int
+		locker = &mockLocker{locked: true}
+		r      = &Rule{SpaceUid: "bkcc__2", Record: "cpu_usage_avg", Expr: "avg(a)", Interval: time.Minute, Target: TargetInfluxDB}
+		now    = time.Unix(1682150030, 0)
+	)
+
+	m := NewManager(&Options{
+		Query: func(_ context.Context, _, _ string, _, _ time.Time, _ time.Duration) (promql.Matrix, error) {
+			calls++
+			return nil, nil
+		},
+		Writers:    map[string]Writer{TargetInfluxDB: &mockWriter{}},
+		Checkpoint: NewMemoryCheckpoint(),
+		Locker:     locker,
+		Timeout:    5 * time.Minute,
+	})
+
+	// 其他实例持有租约时不计算
+	m.eval(ctx, r, now)
+	assert.Equal(t, 0, calls)
+	assert.Equal(t, 5*time.Minute, locker.ttl)
+
+	// 获取到租约后计算并释放
+	locker.locked = false
+	m.eval(ctx, r, now)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, locker.unlocked)
+}
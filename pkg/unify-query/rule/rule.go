@@ -0,0 +1,113 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+)
+
+const (
+	TargetInfluxDB        = "influxdb"
+	TargetVictoriaMetrics = "victoria_metrics"
+)
+
+// Rule 解析后的预计算规则
+type Rule struct {
+	SpaceUid string
+	Record   string
+	Expr     string
+	Interval time.Duration
+	Labels   map[string]string
+	Target   string
+
+	// 写入 influxdb 时使用
+	DB          string
+	Measurement string
+}
+
+// NewRule 校验并转换 consul 中的规则配置，interval 和 target 为空时使用默认值
+func NewRule(spaceUid string, r *consul.RecordingRule, interval time.Duration, target string) (*Rule, error) {
+	if r == nil {
+		return nil, fmt.Errorf("rule is empty")
+	}
+	if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+		return nil, fmt.Errorf("invalid record name %q", r.Record)
+	}
+	if _, err := parser.ParseExpr(r.Expr); err != nil {
+		return nil, fmt.Errorf("invalid expr of %s: %s", r.Record, err)
+	}
+
+	rule := &Rule{
+		SpaceUid: spaceUid,
+		Record:   r.Record,
+		Expr:     r.Expr,
+		Interval: interval,
+		Labels:   r.Labels,
+		Target:   target,
+	}
+
+	if r.Interval != "" {
+		d, err := model.ParseDuration(r.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval of %s: %s", r.Record, err)
+		}
+		rule.Interval = time.Duration(d)
+	}
+	if rule.Interval <= 0 {
+		return nil, fmt.Errorf("invalid interval of %s: %s", r.Record, rule.Interval)
+	}
+
+	if r.Target != "" {
+		rule.Target = r.Target
+	}
+	switch rule.Target {
+	case TargetInfluxDB:
+		arr := strings.Split(r.TableID, ".")
+		if len(arr) != 2 || arr[0] == "" || arr[1] == "" {
+			return nil, fmt.Errorf("invalid table_id of %s: %q", r.Record, r.TableID)
+		}
+		rule.DB, rule.Measurement = arr[0], arr[1]
+	case TargetVictoriaMetrics:
+	default:
+		return nil, fmt.Errorf("invalid target of %s: %q", r.Record, rule.Target)
+	}
+
+	return rule, nil
+}
+
+// Key 规则唯一标识，用于记录计算进度
+func (r *Rule) Key() string {
+	keys := make([]string, 0, len(r.Labels))
+	for k, v := range r.Labels {
+		keys = append(keys, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("%s|%s|%s|%s", r.SpaceUid, r.Record, r.Expr, strings.Join(keys, ","))
+}
+
+// Metric 计算结果附加规则的指标名和维度
+func (r *Rule) Metric(lbs labels.Labels) labels.Labels {
+	b := labels.NewBuilder(lbs)
+	b.Del(labels.MetricName)
+	for k, v := range r.Labels {
+		b.Set(k, v)
+	}
+	b.Set(labels.MetricName, r.Record)
+	return b.Labels(nil)
+}
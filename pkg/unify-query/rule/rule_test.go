@@ -0,0 +1,77 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+)
+
+func TestNewRule(t *testing.T) {
+	testCases := map[string]struct {
+		rule *consul.RecordingRule
+		err  bool
+		want *Rule
+	}{
+		"default": {
+			rule: &consul.RecordingRule{Record: "cpu_usage_avg", Expr: "avg(a)", TableID: "rule.cpu"},
+			want: &Rule{
+				SpaceUid: "bkcc__2", Record: "cpu_usage_avg", Expr: "avg(a)", Interval: time.Minute,
+				Target: TargetInfluxDB, DB: "rule", Measurement: "cpu",
+			},
+		},
+		"vm": {
+			rule: &consul.RecordingRule{Record: "cpu_usage_avg", Expr: "avg(a)", Interval: "5m", Target: TargetVictoriaMetrics},
+			want: &Rule{
+				SpaceUid: "bkcc__2", Record: "cpu_usage_avg", Expr: "avg(a)", Interval: 5 * time.Minute,
+				Target: TargetVictoriaMetrics,
+			},
+		},
+		"invalid record": {
+			rule: &consul.RecordingRule{Record: "cpu-usage", Expr: "avg(a)", TableID: "rule.cpu"},
+			err:  true,
+		},
+		"invalid expr": {
+			rule: &consul.RecordingRule{Record: "cpu_usage_avg", Expr: "avg(", TableID: "rule.cpu"},
+			err:  true,
+		},
+		"invalid table id": {
+			rule: &consul.RecordingRule{Record: "cpu_usage_avg", Expr: "avg(a)", TableID: "rule"},
+			err:  true,
+		},
+		"invalid target": {
+			rule: &consul.RecordingRule{Record: "cpu_usage_avg", Expr: "avg(a)", Target: "es"},
+			err:  true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewRule("bkcc__2", c.rule, time.Minute, TargetInfluxDB)
+			if c.err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, r)
+		})
+	}
+}
+
+func TestRuleMetric(t *testing.T) {
+	r := &Rule{Record: "cpu_usage_avg", Labels: map[string]string{"source": "rule"}}
+	lbs := r.Metric(labels.FromStrings(labels.MetricName, "usage", "ip", "127.0.0.1", "source", "raw"))
+	assert.Equal(t, labels.FromStrings(labels.MetricName, "cpu_usage_avg", "ip", "127.0.0.1", "source", "rule"), lbs)
+}
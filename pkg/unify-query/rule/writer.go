@@ -0,0 +1,173 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+)
+
+// Writer 将规则计算结果写回存储
+type Writer interface {
+	Write(ctx context.Context, rule *Rule, matrix promql.Matrix) error
+}
+
+// InfluxDBWriter 通过 influxdb-proxy 的 /write 接口写入，指标名作为 field，其余维度作为 tag
+type InfluxDBWriter struct {
+	Curl     curl.Curl
+	Address  string
+	Username string
+	Password string
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	tagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// lineProtocol 生成 influxdb 行协议，时间精度为 ms，influxdb 不支持 NaN 和 Inf，直接丢弃
+func lineProtocol(rule *Rule, matrix promql.Matrix) string {
+	var b strings.Builder
+	for _, series := range matrix {
+		var tags strings.Builder
+		for _, l := range series.Metric {
+			if l.Name == labels.MetricName || l.Value == "" {
+				continue
+			}
+			tags.WriteString(",")
+			tags.WriteString(tagEscaper.Replace(l.Name))
+			tags.WriteString("=")
+			tags.WriteString(tagEscaper.Replace(l.Value))
+		}
+		for _, p := range series.Points {
+			if math.IsNaN(p.V) || math.IsInf(p.V, 0) {
+				continue
+			}
+			b.WriteString(measurementEscaper.Replace(rule.Measurement))
+			b.WriteString(tags.String())
+			b.WriteString(" ")
+			b.WriteString(tagEscaper.Replace(rule.Record))
+			b.WriteString("=")
+			b.WriteString(strconv.FormatFloat(p.V, 'f', -1, 64))
+			b.WriteString(" ")
+			b.WriteString(strconv.FormatInt(p.T, 10))
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func (w *InfluxDBWriter) Write(ctx context.Context, rule *Rule, matrix promql.Matrix) error {
+	body := lineProtocol(rule, matrix)
+	if body == "" {
+		return nil
+	}
+
+	values := url.Values{}
+	values.Set("db", rule.DB)
+	values.Set("precision", "ms")
+
+	res, err := w.Curl.Request(ctx, curl.Post, curl.Options{
+		UrlPath: fmt.Sprintf("%s/write?%s", strings.TrimRight(w.Address, "/"), values.Encode()),
+		Body:    []byte(body),
+		Headers: map[string]string{
+			"Content-Type": "text/plain; charset=utf-8",
+		},
+		UserName: w.Username,
+		Password: w.Password,
+	})
+	if err != nil {
+		return err
+	}
+	return checkResponse(res)
+}
+
+// VmWriter 通过 prometheus remote write 协议写入 victoriaMetrics
+type VmWriter struct {
+	Curl     curl.Curl
+	Address  string
+	Username string
+	Password string
+}
+
+// writeRequest 生成 remote write 请求，样本需要按时间排序
+func writeRequest(matrix promql.Matrix) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{
+		Timeseries: make([]prompb.TimeSeries, 0, len(matrix)),
+	}
+	for _, series := range matrix {
+		ts := prompb.TimeSeries{
+			Labels:  make([]prompb.Label, 0, len(series.Metric)),
+			Samples: make([]prompb.Sample, 0, len(series.Points)),
+		}
+		for _, l := range series.Metric {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: l.Name, Value: l.Value})
+		}
+		for _, p := range series.Points {
+			ts.Samples = append(ts.Samples, prompb.Sample{Timestamp: p.T, Value: p.V})
+		}
+		sort.Slice(ts.Samples, func(i, j int) bool {
+			return ts.Samples[i].Timestamp < ts.Samples[j].Timestamp
+		})
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+	return req
+}
+
+func (w *VmWriter) Write(ctx context.Context, _ *Rule, matrix promql.Matrix) error {
+	if len(matrix) == 0 {
+		return nil
+	}
+
+	data, err := writeRequest(matrix).Marshal()
+	if err != nil {
+		return err
+	}
+
+	res, err := w.Curl.Request(ctx, curl.Post, curl.Options{
+		UrlPath: w.Address,
+		Body:    snappy.Encode(nil, data),
+		Headers: map[string]string{
+			"Content-Encoding":                  "snappy",
+			"Content-Type":                      "application/x-protobuf",
+			"X-Prometheus-Remote-Write-Version": "0.1.0",
+		},
+		UserName: w.Username,
+		Password: w.Password,
+	})
+	if err != nil {
+		return err
+	}
+	return checkResponse(res)
+}
+
+func checkResponse(res *http.Response) error {
+	defer res.Body.Close()
+	if res.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("write failed, status: %d, body: %s", res.StatusCode, body)
+}
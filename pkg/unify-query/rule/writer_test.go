@@ -0,0 +1,107 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+)
+
+var testMatrix = promql.Matrix{
+	{
+		Metric: labels.FromStrings(labels.MetricName, "cpu_usage_avg", "ip", "127.0.0.1", "path", "a b,c"),
+		Points: []promql.Point{{T: 1682150040000, V: 2}, {T: 1682149980000, V: 1.5}, {T: 1682150100000, V: math.NaN()}},
+	},
+}
+
+func TestLineProtocol(t *testing.T) {
+	r := &Rule{Record: "cpu_usage_avg", DB: "rule", Measurement: "cpu"}
+	assert.Equal(t,
+		"cpu,ip=127.0.0.1,path=a\\ b\\,c cpu_usage_avg=2 1682150040000\n"+
+			"cpu,ip=127.0.0.1,path=a\\ b\\,c cpu_usage_avg=1.5 1682149980000\n",
+		lineProtocol(r, testMatrix),
+	)
+}
+
+func TestInfluxDBWriter(t *testing.T) {
+	log.InitTestLogger()
+
+	var (
+		query string
+		body  []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := &InfluxDBWriter{Curl: &curl.HttpCurl{Log: log.OtLogger}, Address: server.URL}
+	r := &Rule{Record: "cpu_usage_avg", DB: "rule", Measurement: "cpu"}
+	err := w.Write(context.Background(), r, testMatrix)
+	assert.Nil(t, err)
+	assert.Equal(t, "db=rule&precision=ms", query)
+	assert.Equal(t, lineProtocol(r, testMatrix), string(body))
+}
+
+func TestVmWriter(t *testing.T) {
+	log.InitTestLogger()
+
+	var req prompb.WriteRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		compressed, _ := io.ReadAll(r.Body)
+		data, err := snappy.Decode(nil, compressed)
+		if err == nil {
+			err = req.Unmarshal(data)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := &VmWriter{Curl: &curl.HttpCurl{Log: log.OtLogger}, Address: server.URL}
+	err := w.Write(context.Background(), &Rule{}, testMatrix)
+	assert.Nil(t, err)
+	assert.Len(t, req.Timeseries, 1)
+	assert.Equal(t, "cpu_usage_avg", req.Timeseries[0].Labels[0].Value)
+	assert.Len(t, req.Timeseries[0].Samples, 3)
+	assert.Equal(t, int64(1682149980000), req.Timeseries[0].Samples[0].Timestamp)
+
+	w.Address = server.URL + "/unknown"
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("internal error"))
+	})
+	err = w.Write(context.Background(), &Rule{}, testMatrix)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "internal error")
+}
@@ -121,6 +121,7 @@ func (s *Service) reloadTableInfo() error {
 			AliasFormat: value.AliasFormat,
 			DateFormat:  value.DateFormat,
 			DateStep:    value.DateStep,
+			TimeField:   value.TimeField,
 		}
 	}
 	err = es.ReloadTableInfo(infos)
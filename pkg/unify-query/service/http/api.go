@@ -154,6 +154,13 @@ func handlerInfo(c *gin.Context, key infos.InfoType) {
 	trace.InsertStringIntoSpan("request-header", fmt.Sprintf("%+v", c.Request.Header), span)
 	trace.InsertStringIntoSpan("request-data", string(paramsStr), span)
 
+	if key == infos.Series && isStreamRequest(c) {
+		resp.stream(ctx, func(w *streamWriter) (*StreamTrailer, error) {
+			return querySeriesStream(ctx, params, w)
+		})
+		return
+	}
+
 	data, err := queryInfo(ctx, key, params)
 	if err != nil {
 		resp.failed(ctx, err)
@@ -216,80 +223,110 @@ func queryInfo(ctx context.Context, key infos.InfoType, params *infos.Params) (i
 			Values: lvsMap,
 		}
 	case infos.Series:
-		start, err := params.StartTimeUnix()
-		if err != nil {
-			return nil, fmt.Errorf("start time error: %s", err.Error())
-		}
-		end, err := params.EndTimeUnix()
+		keys := make([]string, 0)
+		series := make([][]string, 0)
+		err = selectSeries(ctx, q, params, func(k, values []string) error {
+			keys = k
+			series = append(series, values)
+			return nil
+		})
 		if err != nil {
-			return nil, fmt.Errorf("end time error: %s", err.Error())
+			return nil, err
 		}
 
-		hints := &storage.SelectHints{
-			Start: start * 1e3,
-			End:   end * 1e3,
-			Func:  "series", // There is no series function, this token is used for lookups that don't need samples.
+		data = []*SeriesData{
+			{
+				Keys:   keys,
+				Series: series,
+			},
 		}
+	default:
+		err = fmt.Errorf("error info type %s", key)
+	}
 
-		set := q.Select(true, hints, labelMatchers...)
-		if set.Err() != nil {
-			return nil, set.Err()
-		}
+	if warns != nil {
+		err = fmt.Errorf("warns: %v", warns)
+	}
+	return data, err
+}
 
-		keyExists := make(map[string]struct{}, 0)
-		dataExists := make(map[string]struct{}, 0)
+// selectSeries 迭代 SeriesSet，对每个去重后的 series 按 keys 的顺序回调维度值
+func selectSeries(
+	ctx context.Context, q storage.Querier, params *infos.Params, fn func(keys, values []string) error,
+) error {
+	start, err := params.StartTimeUnix()
+	if err != nil {
+		return fmt.Errorf("start time error: %s", err.Error())
+	}
+	end, err := params.EndTimeUnix()
+	if err != nil {
+		return fmt.Errorf("end time error: %s", err.Error())
+	}
 
-		paramsKeys := make(map[string]struct{}, len(params.Keys))
-		for _, k := range params.Keys {
-			paramsKeys[k] = struct{}{}
-		}
+	labelMatcher, err := labels.NewMatcher(
+		labels.MatchEqual, labels.MetricName, prometheus.ReferenceName,
+	)
+	if err != nil {
+		return err
+	}
 
-		keys := make([]string, 0)
-		series := make([][]string, 0)
+	hints := &storage.SelectHints{
+		Start: start * 1e3,
+		End:   end * 1e3,
+		Func:  "series", // There is no series function, this token is used for lookups that don't need samples.
+	}
 
-		for set.Next() {
-			if len(keys) == 0 {
-				for _, lb := range set.At().Labels() {
-					if len(paramsKeys) > 0 {
-						if _, ok := paramsKeys[lb.Name]; ok {
-							keyExists[lb.Name] = struct{}{}
-							keys = append(keys, lb.Name)
-						}
-					} else if lb.Name != influxdb.BKTaskIndex {
+	set := q.Select(true, hints, labelMatcher)
+	if set.Err() != nil {
+		return set.Err()
+	}
+
+	keyExists := make(map[string]struct{}, 0)
+	dataExists := make(map[string]struct{}, 0)
+
+	paramsKeys := make(map[string]struct{}, len(params.Keys))
+	for _, k := range params.Keys {
+		paramsKeys[k] = struct{}{}
+	}
+
+	keys := make([]string, 0)
+
+	for set.Next() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if len(keys) == 0 {
+			for _, lb := range set.At().Labels() {
+				if len(paramsKeys) > 0 {
+					if _, ok := paramsKeys[lb.Name]; ok {
 						keyExists[lb.Name] = struct{}{}
 						keys = append(keys, lb.Name)
 					}
+				} else if lb.Name != influxdb.BKTaskIndex {
+					keyExists[lb.Name] = struct{}{}
+					keys = append(keys, lb.Name)
 				}
 			}
+		}
 
-			values := make([]string, 0, len(keyExists))
-			buf := ""
-			for _, lb := range set.At().Labels() {
-				if _, ok := keyExists[lb.Name]; ok {
-					values = append(values, lb.Value)
-					buf = fmt.Sprintf("%s%s", buf, lb.Value)
-				}
-			}
-			if _, ok := dataExists[buf]; !ok {
-				series = append(series, values)
-				dataExists[buf] = struct{}{}
+		values := make([]string, 0, len(keyExists))
+		buf := ""
+		for _, lb := range set.At().Labels() {
+			if _, ok := keyExists[lb.Name]; ok {
+				values = append(values, lb.Value)
+				buf = fmt.Sprintf("%s%s", buf, lb.Value)
 			}
 		}
-
-		data = []*SeriesData{
-			{
-				Keys:   keys,
-				Series: series,
-			},
+		if _, ok := dataExists[buf]; !ok {
+			dataExists[buf] = struct{}{}
+			if err = fn(keys, values); err != nil {
+				return err
+			}
 		}
-	default:
-		err = fmt.Errorf("error info type %s", key)
 	}
 
-	if warns != nil {
-		err = fmt.Errorf("warns: %v", warns)
-	}
-	return data, err
+	return set.Err()
 }
 
 func newInfoQuerier(ctx context.Context, params *infos.Params) (storage.Querier, error) {
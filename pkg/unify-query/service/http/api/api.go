@@ -82,3 +82,85 @@ func HandlerAPIRelationMultiResource(c *gin.Context) {
 
 	resp.success(ctx, data)
 }
+
+// HandlerAPIRelationMultiResourceRange
+// @Summary  query relation multi resource with time range
+// @ID       api-relation-multi-resource-range
+// @Produce  json
+// @Param    traceparent            header    string                          false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    X-Bk-Scope-Space-Uid   header    string                          false  "空间UID" default(bkcc__2)
+// @Param    data                  	body      cmdb.RelationMultiResourceRangeRequest			  true   "json data"
+// @Success  200                   	{object}  cmdb.RelationMultiResourceRangeResponse
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /api/v1/relation/multi_resource_range [post]
+func HandlerAPIRelationMultiResourceRange(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		user = metadata.GetUser(ctx)
+		err  error
+
+		resp = &response{
+			c: c,
+		}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "api-relation-multi-resource-range")
+	if span != nil {
+		defer span.End()
+	}
+
+	request := new(cmdb.RelationMultiResourceRangeRequest)
+	err = json.NewDecoder(c.Request.Body).Decode(request)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	paramsBody, _ := json.Marshal(request)
+	trace.InsertStringIntoSpan("params-body", string(paramsBody), span)
+
+	model, err := v1beta1.GetModel(ctx)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	data := new(cmdb.RelationMultiResourceRangeResponse)
+	data.Data = make([]cmdb.RelationMultiResourceRangeResponseData, 0, len(request.QueryList))
+	for _, qry := range request.QueryList {
+		d := cmdb.RelationMultiResourceRangeResponseData{
+			Code:       http.StatusOK,
+			TargetType: qry.TargetType,
+		}
+
+		// 未指定时间范围时按瞬时查询处理
+		start, end := qry.StartTime, qry.EndTime
+		if start == 0 && end == 0 {
+			start, end = qry.Timestamp, qry.Timestamp
+		}
+
+		res, err := model.QueryResourceMatcherRange(ctx, &cmdb.RangeQueryOptions{
+			SpaceUid:      user.SpaceUid,
+			LookBackDelta: qry.LookBackDelta,
+			Start:         start,
+			End:           end,
+			Step:          qry.Step,
+			Target:        qry.TargetType,
+			Matcher:       qry.SourceInfo,
+			PathResource:  qry.PathResource,
+			AllPaths:      qry.AllPaths,
+			TargetLimit:   qry.TargetLimit,
+		})
+		if res != nil {
+			d.SourceType, d.SourceInfo, d.Paths = res.SourceType, res.SourceInfo, res.Paths
+		}
+		if err != nil {
+			d.Message = err.Error()
+			d.Code = http.StatusBadRequest
+		}
+		data.Data = append(data.Data, d)
+	}
+
+	resp.success(ctx, data)
+}
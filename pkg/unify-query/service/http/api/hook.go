@@ -19,10 +19,12 @@ import (
 
 func setDefaultConfig() {
 	viper.SetDefault(RelationMultiResourceConfigPath, "/api/v1/relation/multi_resource")
+	viper.SetDefault(RelationMultiResourceRangeConfigPath, "/api/v1/relation/multi_resource_range")
 }
 
 func loadConfig() {
 	RelationMultiResource = viper.GetString(RelationMultiResourceConfigPath)
+	RelationMultiResourceRange = viper.GetString(RelationMultiResourceRangeConfigPath)
 }
 
 // init
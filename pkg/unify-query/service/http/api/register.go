@@ -21,4 +21,7 @@ func RegisterRelation(ctx context.Context, g *gin.Engine) {
 
 	g.POST(RelationMultiResource, HandlerAPIRelationMultiResource)
 	log.Infof(ctx, "api service register in path -> [%s]", RelationMultiResource)
+
+	g.POST(RelationMultiResourceRange, HandlerAPIRelationMultiResourceRange)
+	log.Infof(ctx, "api service register in path -> [%s]", RelationMultiResourceRange)
 }
@@ -27,7 +27,8 @@ import (
 
 // ErrResponse
 type ErrResponse struct {
-	Err string `json:"error"`
+	Code string `json:"code,omitempty"`
+	Err  string `json:"error"`
 }
 
 // ESRequest
@@ -68,7 +69,7 @@ func HandleESQueryRequest(c *gin.Context) {
 	if err != nil {
 		log.Errorf(context.TODO(), "read es request body failed for->[%s]", err)
 		metric.APIRequestInc(ctx, servicePath, metric.StatusFailed, user.SpaceUid)
-		c.JSON(400, ErrResponse{err.Error()})
+		c.JSON(400, ErrResponse{Err: err.Error()})
 		return
 	}
 	var req *ESRequest
@@ -76,7 +77,7 @@ func HandleESQueryRequest(c *gin.Context) {
 	if err != nil {
 		log.Errorf(context.TODO(), "anaylize es request body failed for->[%s]", err)
 		metric.APIRequestInc(ctx, servicePath, metric.StatusFailed, user.SpaceUid)
-		c.JSON(400, ErrResponse{err.Error()})
+		c.JSON(400, ErrResponse{Err: err.Error()})
 		return
 	}
 	params := &es.Params{
@@ -90,7 +91,7 @@ func HandleESQueryRequest(c *gin.Context) {
 	if err != nil {
 		log.Errorf(context.TODO(), "query es failed for->[%s]", err)
 		metric.APIRequestInc(ctx, servicePath, metric.StatusFailed, user.SpaceUid)
-		c.JSON(400, ErrResponse{err.Error()})
+		c.JSON(400, ErrResponse{Err: err.Error()})
 		return
 	}
 
@@ -0,0 +1,348 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	promPromql "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	servicePromql "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	tsDBInfluxdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+// ExplainTiming 查询阶段耗时
+type ExplainTiming struct {
+	Step     string `json:"step"`
+	Duration string `json:"duration"`
+}
+
+// ExplainQuery 单个结果表的物理查询计划
+type ExplainQuery struct {
+	TableID         string   `json:"table_id"`
+	StorageID       string   `json:"storage_id"`
+	ClusterName     string   `json:"cluster_name"`
+	TagsKey         []string `json:"tags_key,omitempty"`
+	DB              string   `json:"db"`
+	RetentionPolicy string   `json:"retention_policy"`
+	Measurement     string   `json:"measurement"`
+	Measurements    []string `json:"measurements,omitempty"`
+	Field           string   `json:"field"`
+	Fields          []string `json:"fields,omitempty"`
+	IsSingleMetric  bool     `json:"is_single_metric"`
+	SegmentedEnable bool     `json:"segmented_enable"`
+	// AggregateMethods 可下推到存储的聚合方法，从内到外排序
+	AggregateMethods []string            `json:"aggregate_methods,omitempty"`
+	Filters          []map[string]string `json:"filters,omitempty"`
+	LabelsMatcher    []string            `json:"labels_matcher,omitempty"`
+	Condition        string              `json:"condition"`
+	InfluxQL         string              `json:"influxql,omitempty"`
+	VmRt             string              `json:"vm_rt,omitempty"`
+	VmCondition      string              `json:"vm_condition,omitempty"`
+	EsCondition      string              `json:"es_condition,omitempty"`
+	EsAggregation    string              `json:"es_aggregation,omitempty"`
+}
+
+// ExplainReference 单个查询引用的计划
+type ExplainReference struct {
+	ReferenceName string          `json:"reference_name"`
+	MetricName    string          `json:"metric_name"`
+	IsCount       bool            `json:"is_count"`
+	QueryList     []*ExplainQuery `json:"query_list"`
+}
+
+// ExplainData 结构化查询的执行计划
+type ExplainData struct {
+	SpaceUid    string `json:"space_uid"`
+	StorageType string `json:"storage_type"`
+	VmQuery     bool   `json:"vm_query"`
+	// VmResultTable vm 直查时按引用分组的结果表
+	VmResultTable map[string][]string `json:"vm_result_table,omitempty"`
+	PromQL        string              `json:"promql"`
+	Start         string              `json:"start"`
+	End           string              `json:"end"`
+	Step          string              `json:"step"`
+	Instant       bool                `json:"instant"`
+
+	References []*ExplainReference `json:"references"`
+
+	// 以下字段仅在实际执行时返回
+	Executed  bool             `json:"executed"`
+	SeriesNum int              `json:"series_num,omitempty"`
+	PointsNum int              `json:"points_num,omitempty"`
+	Timings   []*ExplainTiming `json:"timings,omitempty"`
+}
+
+// explainHints 按 promql 引擎的规则生成各引用的 SelectHints，用于还原存储实际执行的查询语句
+// 同一引用出现多次时以第一次为准
+func explainHints(plan *queryTsPlan) map[string]*storage.SelectHints {
+	hints := make(map[string]*storage.SelectHints)
+	if plan.promQL == nil {
+		return hints
+	}
+
+	lookBackDelta := plan.lookBackDelta
+	if lookBackDelta <= 0 {
+		lookBackDelta = servicePromql.LookbackDelta
+	}
+
+	var evalRange time.Duration
+	parser.Inspect(plan.promQL, func(node parser.Node, path []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			start, end := plan.start, plan.end
+			if evalRange == 0 {
+				start = start.Add(-lookBackDelta)
+			} else {
+				start = start.Add(-evalRange)
+			}
+			start, end = start.Add(-n.OriginalOffset), end.Add(-n.OriginalOffset)
+
+			if _, ok := hints[n.Name]; !ok {
+				hint := &storage.SelectHints{
+					Start: start.UnixMilli(),
+					End:   end.UnixMilli(),
+					Step:  plan.step.Milliseconds(),
+					Range: evalRange.Milliseconds(),
+					Func:  explainFuncFromPath(path),
+				}
+				if len(path) > 0 {
+					if aggr, ok := path[len(path)-1].(*parser.AggregateExpr); ok {
+						hint.By, hint.Grouping = !aggr.Without, aggr.Grouping
+					}
+				}
+				hints[n.Name] = hint
+			}
+			evalRange = 0
+
+		case *parser.MatrixSelector:
+			evalRange = n.Range
+		}
+		return nil
+	})
+	return hints
+}
+
+// explainFuncFromPath 与 promql 引擎保持一致，向上查找最近的函数或聚合
+func explainFuncFromPath(p []parser.Node) string {
+	for i := len(p) - 1; i >= 0; i-- {
+		switch n := p[i].(type) {
+		case *parser.AggregateExpr:
+			return n.Op.String()
+		case *parser.Call:
+			return n.Func.Name
+		case *parser.BinaryExpr:
+			return ""
+		}
+	}
+	return ""
+}
+
+// explainInfluxQL 生成 influxdb 实际执行的原始数据查询语句，与 QueryRaw 共用同一套生成逻辑
+func explainInfluxQL(ctx context.Context, qry *metadata.Query, hints *storage.SelectHints) string {
+	if hints == nil {
+		return ""
+	}
+	instance, _ := prometheus.GetInstance(ctx, qry).(*tsDBInfluxdb.Instance)
+	return strings.Join(instance.ExplainRaw(ctx, qry, hints), "; ")
+}
+
+// newExplainData 将执行计划转换为返回结构
+func newExplainData(ctx context.Context, query *structured.QueryTs, plan *queryTsPlan) *ExplainData {
+	data := &ExplainData{
+		SpaceUid:    query.SpaceUid,
+		StorageType: plan.instance.GetInstanceType(),
+		VmQuery:     plan.vmQuery,
+		PromQL:      plan.promQL.String(),
+		Start:       plan.start.String(),
+		End:         plan.end.String(),
+		Step:        plan.step.String(),
+		Instant:     query.Instant,
+		References:  make([]*ExplainReference, 0, len(plan.reference)),
+	}
+	if plan.vmQuery && plan.vmExpand != nil {
+		data.VmResultTable = plan.vmExpand.ResultTableGroup
+	}
+
+	hints := explainHints(plan)
+	for name, ref := range plan.reference {
+		reference := &ExplainReference{
+			ReferenceName: name,
+			MetricName:    ref.MetricName,
+			IsCount:       ref.IsCount,
+			QueryList:     make([]*ExplainQuery, 0, len(ref.QueryList)),
+		}
+		for _, qry := range ref.QueryList {
+			eq := &ExplainQuery{
+				TableID:         qry.TableID,
+				StorageID:       qry.StorageID,
+				ClusterName:     qry.ClusterName,
+				TagsKey:         qry.TagsKey,
+				DB:              qry.DB,
+				RetentionPolicy: qry.RetentionPolicy,
+				Measurement:     qry.Measurement,
+				Measurements:    qry.Measurements,
+				Field:           qry.Field,
+				Fields:          qry.Fields,
+				IsSingleMetric:  qry.IsSingleMetric,
+				SegmentedEnable: qry.SegmentedEnable,
+				Filters:         qry.Filters,
+				Condition:       qry.Condition,
+				VmRt:            qry.VmRt,
+				VmCondition:     qry.VmCondition,
+				EsCondition:     qry.EsCondition,
+				EsAggregation:   qry.EsAggregation,
+			}
+			for _, aggr := range qry.AggregateMethodList {
+				eq.AggregateMethods = append(eq.AggregateMethods, aggr.Name)
+			}
+			for _, m := range qry.LabelsMatcher {
+				eq.LabelsMatcher = append(eq.LabelsMatcher, m.String())
+			}
+			if !plan.vmQuery && qry.StorageType != consul.ElasticsearchStorageType {
+				eq.InfluxQL = explainInfluxQL(ctx, qry, hints[name])
+			}
+			reference.QueryList = append(reference.QueryList, eq)
+		}
+		data.References = append(data.References, reference)
+	}
+	sort.Slice(data.References, func(i, j int) bool {
+		return data.References[i].ReferenceName < data.References[j].ReferenceName
+	})
+
+	return data
+}
+
+// queryTsExplain 生成结构化查询的执行计划，execute 为 true 时实际执行并返回各阶段耗时
+func queryTsExplain(ctx context.Context, query *structured.QueryTs, execute bool) (*ExplainData, error) {
+	var (
+		span oleltrace.Span
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-ts-explain")
+	if span != nil {
+		defer span.End()
+	}
+
+	plan, err := buildQueryTsPlan(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	data := newExplainData(ctx, query, plan)
+	if !execute {
+		return data, nil
+	}
+
+	res, err := executeQueryTsPlan(ctx, query, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Executed = true
+	data.Timings = plan.timings
+	switch v := res.(type) {
+	case promPromql.Matrix:
+		data.SeriesNum = len(v)
+		for _, series := range v {
+			data.PointsNum += len(series.Points)
+		}
+	case promPromql.Vector:
+		data.SeriesNum = len(v)
+		data.PointsNum = len(v)
+	}
+
+	return data, nil
+}
+
+// HandlerQueryTsExplain
+// @Summary  explain query monitor by ts
+// @ID       ts-query-explain-request
+// @Produce  json
+// @Param    traceparent            header    string                          false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                          false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                          false  "空间UID" default(bkcc__2)
+// @Param    execute                query     bool                            false  "是否实际执行查询"
+// @Param    data                  	body      structured.QueryTs  			  true   "json data"
+// @Success  200                   	{object}  ExplainData
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/explain [post]
+func HandlerQueryTsExplain(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &response{
+			c: c,
+		}
+		user = metadata.GetUser(ctx)
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-query-ts-explain")
+	if span != nil {
+		defer span.End()
+	}
+
+	trace.InsertStringIntoSpan("request-url", c.Request.URL.String(), span)
+	trace.InsertStringIntoSpan("query-source", user.Key, span)
+	trace.InsertStringIntoSpan("query-space-uid", user.SpaceUid, span)
+
+	var (
+		execute bool
+		err     error
+	)
+	if v := c.Query("execute"); v != "" {
+		execute, err = strconv.ParseBool(v)
+		if err != nil {
+			resp.failed(ctx, fmt.Errorf("invalid execute param: %s", v))
+			return
+		}
+	}
+
+	// 解析请求 body
+	query := &structured.QueryTs{}
+	err = json.NewDecoder(c.Request.Body).Decode(query)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		resp.failed(ctx, err)
+		return
+	}
+
+	// metadata 中的 spaceUid 是从 header 头信息中获取
+	if user.SpaceUid != "" {
+		query.SpaceUid = user.SpaceUid
+	}
+
+	queryStr, _ := json.Marshal(query)
+	trace.InsertStringIntoSpan("query-body", string(queryStr), span)
+
+	res, err := queryTsExplain(ctx, query, execute)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	resp.success(ctx, res)
+}
@@ -0,0 +1,132 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+func TestNewExplainData(t *testing.T) {
+	log.InitTestLogger()
+	start := time.Unix(1682149980, 0)
+	end := start.Add(time.Hour)
+
+	promQL, err := parser.ParseExpr(`sum(a)`)
+	assert.Nil(t, err)
+
+	plan := &queryTsPlan{
+		reference: metadata.QueryReference{
+			"b": {
+				ReferenceName: "b",
+				MetricName:    "usage",
+				QueryList: []*metadata.Query{
+					{TableID: "system.disk", DB: "system", Measurement: "disk", Field: "usage"},
+				},
+			},
+			"a": {
+				ReferenceName: "a",
+				MetricName:    "usage",
+				QueryList: []*metadata.Query{
+					{
+						TableID:         "system.cpu_summary",
+						StorageID:       "2",
+						ClusterName:     "default",
+						DB:              "system",
+						RetentionPolicy: "autogen",
+						Measurement:     "cpu_summary",
+						Field:           "usage",
+						Condition:       "bk_biz_id='2'",
+						LabelsMatcher: []*labels.Matcher{
+							labels.MustNewMatcher(labels.MatchEqual, "bk_biz_id", "2"),
+						},
+						AggregateMethodList: []metadata.AggrMethod{{Name: "sum"}},
+					},
+				},
+			},
+		},
+		instance: prometheus.NewInstance(context.Background(), nil, nil, 0),
+		promQL:   promQL,
+		start:    start,
+		end:      end,
+		step:     time.Minute,
+	}
+
+	data := newExplainData(context.Background(), &structured.QueryTs{SpaceUid: "bkcc__2"}, plan)
+	assert.Equal(t, "bkcc__2", data.SpaceUid)
+	assert.Equal(t, "sum(a)", data.PromQL)
+	assert.False(t, data.Executed)
+	assert.Len(t, data.References, 2)
+	assert.Equal(t, "a", data.References[0].ReferenceName)
+	assert.Equal(t, "b", data.References[1].ReferenceName)
+
+	qry := data.References[0].QueryList[0]
+	assert.Equal(t, "autogen", qry.RetentionPolicy)
+	assert.Equal(t, []string{"sum"}, qry.AggregateMethods)
+	assert.Equal(t, []string{`bk_biz_id="2"`}, qry.LabelsMatcher)
+	assert.Equal(t,
+		`select "usage" as _value, time as _time,*::tag from cpu_summary where time > 1682149680000000000 and time < 1682153580000000000 and bk_biz_id='2' `,
+		qry.InfluxQL,
+	)
+
+	// 与 QueryRaw 共用生成逻辑，limit、slimit 以及时区同样生效
+	plan.reference["a"].QueryList[0].OffsetInfo = metadata.OffSetInfo{Limit: 10, SLimit: 2}
+	plan.reference["a"].QueryList[0].Timezone = "Asia/Shanghai"
+	data = newExplainData(context.Background(), &structured.QueryTs{SpaceUid: "bkcc__2"}, plan)
+	assert.Equal(t,
+		`select "usage" as _value, time as _time,*::tag from cpu_summary where time > 1682149680000000000 and time < 1682153580000000000 and bk_biz_id='2'  limit 10 slimit 2 tz('Asia/Shanghai')`,
+		data.References[0].QueryList[0].InfluxQL,
+	)
+
+	// vm 直查不生成 influxql
+	plan.vmQuery = true
+	plan.vmExpand = &metadata.VmExpand{
+		ResultTableGroup: map[string][]string{"a": {"2_bkmonitor_time_series_1"}},
+	}
+	data = newExplainData(context.Background(), &structured.QueryTs{}, plan)
+	assert.Equal(t, map[string][]string{"a": {"2_bkmonitor_time_series_1"}}, data.VmResultTable)
+	assert.Empty(t, data.References[0].QueryList[0].InfluxQL)
+}
+
+func TestHandlerQueryTsExplainBadRequest(t *testing.T) {
+	log.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	g := gin.New()
+	g.POST("/query/ts/explain", HandlerQueryTsExplain)
+
+	testCases := map[string]string{
+		"execute=abc":  "invalid execute param",
+		"execute=true": "EOF",
+	}
+	for params, msg := range testCases {
+		t.Run(params, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/query/ts/explain?"+params, strings.NewReader(""))
+			rw := httptest.NewRecorder()
+			g.ServeHTTP(rw, req)
+			assert.Equal(t, http.StatusBadRequest, rw.Code)
+			assert.Contains(t, rw.Body.String(), msg)
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 	"unsafe"
 
@@ -32,12 +33,46 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/resultcache"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
 )
 
 func queryExemplar(ctx context.Context, query *structured.QueryTs) (interface{}, error) {
+	var (
+		resp = &PromData{}
+	)
+
+	totalTables, err := queryExemplarTables(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := &promql.Tables{
+		Tables: make([]*promql.Table, 0, totalTables.Length()),
+	}
+	for _, table := range totalTables.Tables {
+		tables.Add(&promql.Table{
+			Name:        table.Name,
+			MetricName:  table.MetricName,
+			Headers:     table.Headers,
+			Types:       table.Types,
+			GroupKeys:   table.GroupKeys,
+			GroupValues: table.GroupValues,
+			Data:        table.Data,
+		})
+	}
+
+	if err = resp.Fill(tables); err != nil {
+		return nil, err
+	}
+	return resp, err
+}
+
+// queryExemplarTables 查询 exemplar 并合并为 influxdb 表格式
+func queryExemplarTables(ctx context.Context, query *structured.QueryTs) (*influxdb.Tables, error) {
 	var (
 		err  error
 		span oleltrace.Span
@@ -45,7 +80,6 @@ func queryExemplar(ctx context.Context, query *structured.QueryTs) (interface{},
 		tablesCh = make(chan *influxdb.Tables, 1)
 		recvDone = make(chan struct{})
 
-		resp        = &PromData{}
 		totalTables = influxdb.NewTables()
 	)
 
@@ -122,45 +156,139 @@ func queryExemplar(ctx context.Context, query *structured.QueryTs) (interface{},
 	close(tablesCh)
 	<-recvDone
 
-	tables := &promql.Tables{
-		Tables: make([]*promql.Table, 0, totalTables.Length()),
+	return totalTables, nil
+}
+
+func queryTs(ctx context.Context, query *structured.QueryTs) (interface{}, error) {
+	var (
+		err  error
+		span oleltrace.Span
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-ts")
+	if span != nil {
+		defer span.End()
 	}
-	for _, table := range totalTables.Tables {
-		tables.Add(&promql.Table{
-			Name:        table.Name,
-			MetricName:  table.MetricName,
-			Headers:     table.Headers,
-			Types:       table.Types,
-			GroupKeys:   table.GroupKeys,
-			GroupValues: table.GroupValues,
-			Data:        table.Data,
-		})
+
+	res, err := queryTsValue(ctx, query)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = resp.Fill(tables); err != nil {
+	tables := promql.NewTables()
+	seriesNum := 0
+	pointsNum := 0
+
+	switch v := res.(type) {
+	case promPromql.Matrix:
+		for index, series := range v {
+			tables.Add(promql.NewTable(index, series))
+
+			seriesNum++
+			pointsNum += len(series.Points)
+		}
+	case promPromql.Vector:
+		for index, series := range v {
+			tables.Add(promql.NewTableWithSample(index, series))
+
+			seriesNum++
+			pointsNum++
+		}
+	default:
+		err = fmt.Errorf("data type wrong: %T", v)
 		return nil, err
 	}
-	return resp, err
+
+	trace.InsertIntIntoSpan("resp-series-num", seriesNum, span)
+	trace.InsertIntIntoSpan("resp-points-num", pointsNum, span)
+
+	resp := NewPromData(query.ResultColumns)
+	err = resp.Fill(tables)
+	if err != nil {
+		return nil, err
+	}
+
+	if factor, ok := queryTsDownsampleFactor(query); ok {
+		resp.Downsample(factor, query.DownSampleFunction)
+	}
+
+	resp.Status = metadata.GetStatus(ctx)
+	return resp, nil
 }
 
-func queryTs(ctx context.Context, query *structured.QueryTs) (interface{}, error) {
+// queryTsDownsampleFactor 判断结构化查询的结果是否需要降采样，返回降采样系数
+func queryTsDownsampleFactor(query *structured.QueryTs) (float64, bool) {
+	ok, factor, err := downsample.CheckDownSampleRange(query.Step, query.DownSampleRange)
+	if ok && err == nil {
+		var info *TimeInfo
+		if info, err = getTimeInfo(&structured.CombinedQueryParams{
+			Start: query.Start,
+			End:   query.End,
+			Step:  query.DownSampleRange,
+		}); err == nil {
+			log.Debugf(context.TODO(), "respData to downsample: %+v", info)
+			return factor, true
+		}
+	}
+	return 0, false
+}
+
+// queryTsPlan 结构化查询经过路由后的执行计划
+type queryTsPlan struct {
+	reference metadata.QueryReference
+	vmQuery   bool
+	vmExpand  *metadata.VmExpand
+
+	instance tsdb.Instance
+	promQL   parser.Expr
+
+	start         time.Time
+	end           time.Time
+	step          time.Duration
+	lookBackDelta time.Duration
+
+	// timings 各阶段耗时，按执行顺序排列
+	timings []*ExplainTiming
+}
+
+// tables 执行计划涉及的结果表，去重后排序
+func (p *queryTsPlan) tables() []string {
+	exists := make(map[string]struct{})
+	tables := make([]string, 0)
+	for _, ref := range p.reference {
+		for _, qry := range ref.QueryList {
+			if _, ok := exists[qry.TableID]; ok || qry.TableID == "" {
+				continue
+			}
+			exists[qry.TableID] = struct{}{}
+			tables = append(tables, qry.TableID)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// addTiming 记录从 begin 开始到当前的阶段耗时
+func (p *queryTsPlan) addTiming(step string, begin time.Time) {
+	p.timings = append(p.timings, &ExplainTiming{
+		Step:     step,
+		Duration: time.Since(begin).String(),
+	})
+}
+
+// buildQueryTsPlan 完成结构化查询的转换和路由，不进行实际查询
+func buildQueryTsPlan(ctx context.Context, query *structured.QueryTs) (*queryTsPlan, error) {
 	var (
 		err  error
 		span oleltrace.Span
 
-		instance tsdb.Instance
-		ok       bool
-
-		res any
-
 		user = metadata.GetUser(ctx)
+		plan = &queryTsPlan{}
 
-		lookBackDelta time.Duration
-
-		promQL parser.Expr
+		begin time.Time
 	)
 
-	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-ts")
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-ts-plan")
 	if span != nil {
 		defer span.End()
 	}
@@ -168,6 +296,10 @@ func queryTs(ctx context.Context, query *structured.QueryTs) (interface{}, error
 	qStr, _ := json.Marshal(query)
 	trace.InsertStringIntoSpan("query-ts", string(qStr), span)
 
+	if err = downsample.CheckFunction(query.DownSampleFunction); err != nil {
+		return nil, err
+	}
+
 	// 验证 queryList 限制长度
 	if DefaultQueryListLimit > 0 && len(query.QueryList) > DefaultQueryListLimit {
 		err = fmt.Errorf("the number of query lists cannot be greater than %d", DefaultQueryListLimit)
@@ -181,61 +313,65 @@ func queryTs(ctx context.Context, query *structured.QueryTs) (interface{}, error
 	}
 
 	if query.LookBackDelta != "" {
-		lookBackDelta, err = time.ParseDuration(query.LookBackDelta)
+		plan.lookBackDelta, err = time.ParseDuration(query.LookBackDelta)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	queryReference, err := query.ToQueryReference(ctx)
+	begin = time.Now()
+	plan.reference, err = query.ToQueryReference(ctx)
 	if err != nil {
 		return nil, err
 	}
+	plan.addTiming("to_query_reference", begin)
 
-	start, end, step, timezone, err := structured.ToTime(query.Start, query.End, query.Step, query.Timezone)
+	var timezone string
+	plan.start, plan.end, plan.step, timezone, err = structured.ToTime(query.Start, query.End, query.Step, query.Timezone)
 	if err != nil {
 		return nil, err
 	}
 	query.Timezone = timezone
 
-	qrStr, _ := json.Marshal(queryReference)
+	qrStr, _ := json.Marshal(plan.reference)
 	trace.InsertStringIntoSpan("query-reference", string(qrStr), span)
 
 	referenceNameMetric := make(map[string]string, len(query.QueryList))
 	referenceNameLabelMatcher := make(map[string][]*labels.Matcher, len(query.QueryList))
 
 	// 判断是否是直查
-	ok, vmExpand, err := queryReference.CheckVmQuery(ctx)
+	begin = time.Now()
+	plan.vmQuery, plan.vmExpand, err = plan.reference.CheckVmQuery(ctx)
 	if err != nil {
 		log.Errorf(ctx, fmt.Sprintf("check vm query: %s", err.Error()))
 	}
-	if ok {
+	if plan.vmQuery {
 		if err != nil {
 			return nil, err
 		}
 		if !metadata.GetVMQueryOrFeatureFlag(ctx) {
-			referenceNameMetric = vmExpand.MetricAliasMapping
-			referenceNameLabelMatcher = vmExpand.LabelsMatcher
+			referenceNameMetric = plan.vmExpand.MetricAliasMapping
+			referenceNameLabelMatcher = plan.vmExpand.LabelsMatcher
 		}
 
-		metadata.SetExpand(ctx, vmExpand)
-		instance = prometheus.GetInstance(ctx, &metadata.Query{
+		metadata.SetExpand(ctx, plan.vmExpand)
+		plan.instance = prometheus.GetInstance(ctx, &metadata.Query{
 			StorageID: consul.VictoriaMetricsStorageType,
 		})
-		if instance == nil {
+		if plan.instance == nil {
 			err = fmt.Errorf("%s storage get error", consul.VictoriaMetricsStorageType)
 			return nil, err
 		}
 
-		for _, ref := range queryReference {
+		for _, ref := range plan.reference {
 			for _, qry := range ref.QueryList {
 				metric.TsDBAndTableIDRequestCountInc(
-					ctx, user.SpaceUid, qry.TableID, instance.GetInstanceType(), "query_ts",
+					ctx, user.SpaceUid, qry.TableID, plan.instance.GetInstanceType(), "query_ts",
 				)
 			}
 		}
 	} else {
-		err = metadata.SetQueryReference(ctx, queryReference)
+		err = metadata.SetQueryReference(ctx, plan.reference)
 
 		if err != nil {
 			return nil, err
@@ -244,82 +380,121 @@ func queryTs(ctx context.Context, query *structured.QueryTs) (interface{}, error
 		trace.InsertIntIntoSpan("query-max-routing", QueryMaxRouting, span)
 		trace.InsertStringIntoSpan("singleflight-timeout", SingleflightTimeout.String(), span)
 
-		instance = prometheus.NewInstance(ctx, promql.GlobalEngine, &prometheus.QueryRangeStorage{
+		plan.instance = prometheus.NewInstance(ctx, promql.GlobalEngine, &prometheus.QueryRangeStorage{
 			QueryMaxRouting: QueryMaxRouting,
 			Timeout:         SingleflightTimeout,
-		}, lookBackDelta)
+		}, plan.lookBackDelta)
 	}
+	plan.addTiming("routing", begin)
 
-	promQL, err = query.ToPromExpr(ctx, referenceNameMetric, referenceNameLabelMatcher)
+	begin = time.Now()
+	plan.promQL, err = query.ToPromExpr(ctx, referenceNameMetric, referenceNameLabelMatcher)
 	if err != nil {
 		return nil, err
 	}
+	plan.addTiming("to_prom_expr", begin)
 
-	trace.InsertStringIntoSpan("vm-expand", fmt.Sprintf("%+v", vmExpand), span)
-	trace.InsertStringIntoSpan("storage-type", instance.GetInstanceType(), span)
+	trace.InsertStringIntoSpan("vm-expand", fmt.Sprintf("%+v", plan.vmExpand), span)
+	trace.InsertStringIntoSpan("storage-type", plan.instance.GetInstanceType(), span)
 
-	if query.Instant {
-		res, err = instance.Query(ctx, promQL.String(), end)
-	} else {
-		res, err = instance.QueryRange(ctx, promQL.String(), start, end, step)
-	}
+	return plan, nil
+}
+
+// queryTsValue 执行结构化查询，返回 promql 引擎的原始结果 Matrix 或 Vector
+func queryTsValue(ctx context.Context, query *structured.QueryTs) (parser.Value, error) {
+	plan, err := buildQueryTsPlan(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	return executeQueryTsPlan(ctx, query, plan)
+}
 
-	trace.InsertStringIntoSpan("promql", promQL.String(), span)
-	trace.InsertStringIntoSpan("start", start.String(), span)
-	trace.InsertStringIntoSpan("end", end.String(), span)
-	trace.InsertStringIntoSpan("step", step.String(), span)
-
-	tables := promql.NewTables()
-	seriesNum := 0
-	pointsNum := 0
+// executeQueryTsPlan 按执行计划查询存储
+func executeQueryTsPlan(ctx context.Context, query *structured.QueryTs, plan *queryTsPlan) (parser.Value, error) {
+	var (
+		span oleltrace.Span
+		user = metadata.GetUser(ctx)
+	)
 
-	switch v := res.(type) {
-	case promPromql.Matrix:
-		for index, series := range v {
-			tables.Add(promql.NewTable(index, series))
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-ts-value")
+	if span != nil {
+		defer span.End()
+	}
 
-			seriesNum++
-			pointsNum += len(series.Points)
-		}
-	case promPromql.Vector:
-		for index, series := range v {
-			tables.Add(promql.NewTableWithSample(index, series))
+	tracker := querylimit.Start(ctx, user.SpaceUid)
+	ctx, cancel := startQueryTsTracker(ctx, tracker, plan)
+	defer cancel()
 
-			seriesNum++
-			pointsNum++
-		}
-	default:
-		err = fmt.Errorf("data type wrong: %T", v)
+	res, err := evalQueryTsPlan(ctx, query, plan, tracker, plan.start, plan.end)
+	if err != nil {
 		return nil, err
 	}
 
-	trace.InsertIntIntoSpan("resp-series-num", seriesNum, span)
-	trace.InsertIntIntoSpan("resp-points-num", pointsNum, span)
+	trace.InsertStringIntoSpan("promql", plan.promQL.String(), span)
+	trace.InsertStringIntoSpan("start", plan.start.String(), span)
+	trace.InsertStringIntoSpan("end", plan.end.String(), span)
+	trace.InsertStringIntoSpan("step", plan.step.String(), span)
 
-	resp := NewPromData(query.ResultColumns)
-	err = resp.Fill(tables)
+	return res, nil
+}
+
+// startQueryTsTracker 记录查询语句及路由的结果表，按空间限制设置查询超时
+func startQueryTsTracker(
+	ctx context.Context, tracker *querylimit.Tracker, plan *queryTsPlan,
+) (context.Context, context.CancelFunc) {
+	tracker.SetQuery(plan.promQL.String())
+	tracker.SetTables(plan.tables())
+	if maxDuration := tracker.Limit().MaxDuration; maxDuration > 0 {
+		return context.WithTimeout(ctx, maxDuration)
+	}
+	return ctx, func() {}
+}
+
+// evalQueryTsPlan 计算 [start, end] 范围内的查询结果，瞬时查询只使用 end
+func evalQueryTsPlan(
+	ctx context.Context, query *structured.QueryTs, plan *queryTsPlan, tracker *querylimit.Tracker,
+	start, end time.Time,
+) (parser.Value, error) {
+	var (
+		err error
+		res parser.Value
+
+		instance = plan.instance
+		promQL   = plan.promQL.String()
+	)
+
+	begin := time.Now()
+	if query.Instant {
+		res, err = instance.Query(ctx, promQL, end)
+	} else {
+		instance = resultcache.NewInstance(instance, resultCachePrefix(query, plan.lookBackDelta))
+		res, err = instance.QueryRange(ctx, promQL, start, end, plan.step)
+	}
+	plan.addTiming("execute", begin)
+
+	// 存储层触发的超限错误可能被引擎包装或忽略，以统计结果为准
+	if limitErr := tracker.Err(); limitErr != nil {
+		return nil, limitErr
+	}
 	if err != nil {
-		return nil, err
+		return nil, tracker.Timeout(ctx, err)
 	}
 
-	var factor float64
-	if ok, factor, err = downsample.CheckDownSampleRange(query.Step, query.DownSampleRange); ok && err != nil {
-		var info *TimeInfo
-		if info, err = getTimeInfo(&structured.CombinedQueryParams{
-			Start: query.Start,
-			End:   query.End,
-			Step:  query.DownSampleRange,
-		}); err == nil {
-			log.Debugf(context.TODO(), "respData to downsample: %+v", info)
-			resp.Downsample(factor)
+	// vm 直查无法在存储层统计，按结果进行校验
+	if plan.vmQuery {
+		if err = tracker.AddValue(res); err != nil {
+			return nil, err
 		}
 	}
+	return res, nil
+}
 
-	resp.Status = metadata.GetStatus(ctx)
-	return resp, nil
+// resultCachePrefix 查询结果缓存的 key 前缀，由除时间范围外的所有查询条件组成
+func resultCachePrefix(query *structured.QueryTs, lookBackDelta time.Duration) string {
+	q := *query
+	q.Start, q.End = "", ""
+	b, _ := json.Marshal(q)
+	return fmt.Sprintf("%s|%s", lookBackDelta, b)
 }
 
 func structToPromQL(ctx context.Context, query *structured.QueryTs) (*structured.QueryPromQL, error) {
@@ -617,6 +792,13 @@ func HandlerQueryTs(c *gin.Context) {
 	trace.InsertStringIntoSpan("query-body", string(queryStr), span)
 	trace.InsertIntIntoSpan("query-body-size", len(queryStr), span)
 
+	if isStreamRequest(c) {
+		resp.stream(ctx, func(w *streamWriter) (*StreamTrailer, error) {
+			return queryTsStream(ctx, query, w)
+		})
+		return
+	}
+
 	res, err := queryTs(ctx, query)
 	if err != nil {
 		resp.failed(ctx, err)
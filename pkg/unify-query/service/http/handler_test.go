@@ -20,6 +20,7 @@ import (
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/downsample"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/featureFlag"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
@@ -546,6 +547,36 @@ func TestQueryTs(t *testing.T) {
 	}
 }
 
+// TestQueryTsDownsample 结构化查询指定降采样周期时按指定算法降采样
+func TestQueryTsDownsample(t *testing.T) {
+	ctx := context.Background()
+	log.InitTestLogger()
+	mockData(ctx, "handler_test", "handler_test")
+
+	body := `{"space_uid":"influxdb","query_list":[{"data_source":"","table_id":"system.cpu_summary","field_name":"usage","function":[{"method":"mean","dimensions":[]}],"time_aggregation":{"function":"avg_over_time","window":"60s"},"reference_name":"a","conditions":{"field_list":[],"condition_list":[]},"keep_columns":["_time","a"]}],"metric_merge":"a","start_time":"1677081600","end_time":"1677085600","step":"60s"%s}`
+	run := func(extra string) *PromData {
+		query := &structured.QueryTs{}
+		err := json.Unmarshal([]byte(fmt.Sprintf(body, extra)), query)
+		assert.Nil(t, err)
+
+		res, err := queryTs(ctx, query)
+		assert.Nil(t, err)
+		data, ok := res.(*PromData)
+		assert.True(t, ok)
+		assert.Len(t, data.Tables, 1)
+		return data
+	}
+
+	raw := run("").Tables[0].GetPromPoints()
+	first := run(`,"down_sample_range":"5m","down_sample_function":"first"`).Tables[0].GetPromPoints()
+	last := run(`,"down_sample_range":"5m","down_sample_function":"last"`).Tables[0].GetPromPoints()
+
+	assert.Less(t, len(first), len(raw))
+	assert.Equal(t, downsample.Downsample(raw, 0.2, downsample.FunctionFirst), first)
+	assert.Equal(t, downsample.Downsample(raw, 0.2, downsample.FunctionLast), last)
+	assert.NotEqual(t, first, last)
+}
+
 // TestQueryExemplar comment lint rebel
 func TestQueryExemplar(t *testing.T) {
 	ctx := context.Background()
@@ -41,8 +41,11 @@ func setDefaultConfig() {
 
 	viper.SetDefault(FluxHandlePromqlPathConfigPath, "/query/promql")
 	viper.SetDefault(ESHandlePathConfigPath, "/query/es")
+	viper.SetDefault(PromAPIHandlePathConfigPath, "/api/v1")
 	viper.SetDefault(TSQueryHandlePathConfigPath, "/query/ts")
 	viper.SetDefault(TSQueryExemplarHandlePathConfigPath, "/query/ts/exemplar")
+	viper.SetDefault(TSQueryExplainHandlePathConfigPath, "/query/ts/explain")
+	viper.SetDefault(TSQueryRawHandlePathConfigPath, "/query/ts/raw")
 	viper.SetDefault(TSQueryPromQLHandlePathConfigPath, "/query/ts/promql")
 	viper.SetDefault(TSQueryInfoHandlePathConfigPath, "/query/ts/info")
 	viper.SetDefault(TSQueryStructToPromQLHandlePathConfigPath, "/query/ts/struct_to_promql")
@@ -65,6 +68,9 @@ func setDefaultConfig() {
 
 	viper.SetDefault(QueryMaxRoutingConfigPath, 2)
 
+	viper.SetDefault(StreamFlushLinesConfigPath, 100)
+	viper.SetDefault(StreamMaxLinePointsConfigPath, 10000)
+
 }
 
 // LoadConfig
@@ -86,6 +92,9 @@ func LoadConfig() {
 
 	QueryMaxRouting = viper.GetInt(QueryMaxRoutingConfigPath)
 
+	StreamFlushLines = viper.GetInt(StreamFlushLinesConfigPath)
+	StreamMaxLinePoints = viper.GetInt(StreamMaxLinePointsConfigPath)
+
 	infos.SetDefaultLimit(DefaultInfoLimit)
 	promql.SetSegmented(&promql.Segmented{
 		Enable:      viper.GetBool(SegmentedEnable),
@@ -0,0 +1,113 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/audit"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+)
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Audit 记录查询的审计日志以及按来源、空间聚合的指标，需要注册在 Timer 之后以获取用户信息
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !audit.Match(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		var (
+			ctx       = c.Request.Context()
+			start     = time.Now()
+			body      []byte
+			truncated bool
+		)
+
+		// 读取请求内容用于审计，最多读取 MaxBodySize，读取的部分与剩余内容拼接后放回供后续处理
+		if audit.Enable && c.Request.Body != nil {
+			reader := io.Reader(c.Request.Body)
+			if audit.MaxBodySize > 0 {
+				reader = io.LimitReader(reader, audit.MaxBodySize+1)
+			}
+			body, _ = io.ReadAll(reader)
+			c.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body),
+				Closer: c.Request.Body,
+			}
+			if audit.MaxBodySize > 0 && int64(len(body)) > audit.MaxBodySize {
+				body = body[:audit.MaxBodySize]
+				truncated = true
+			}
+		}
+
+		c.Next()
+
+		user := metadata.GetUser(ctx)
+		record := &audit.Record{
+			Time:       start,
+			TraceID:    oleltrace.SpanFromContext(ctx).SpanContext().TraceID().String(),
+			User:       user.Name,
+			Source:     user.Source,
+			SpaceUid:   user.SpaceUid,
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Duration:   time.Since(start).Milliseconds(),
+			ResultSize: c.Writer.Size(),
+			Code:       c.Writer.Status(),
+			Status:     audit.StatusSuccess,
+		}
+		if record.Code >= http.StatusBadRequest {
+			record.Status = audit.StatusFailed
+		}
+		if status := metadata.GetStatus(ctx); status != nil {
+			record.ErrorCode = status.Code
+		}
+
+		if cost := metadata.GetQueryCost(ctx); cost != nil {
+			record.Query = cost.Query
+			record.Tables = cost.Tables
+			record.Series = atomic.LoadInt64(&cost.Series)
+			record.Points = atomic.LoadInt64(&cost.Points)
+			if cost.SpaceUid != "" {
+				record.SpaceUid = cost.SpaceUid
+			}
+		}
+
+		// 没有经过查询转换的请求，记录原始请求内容
+		if record.Query == "" {
+			record.Query = c.Request.URL.RawQuery
+			record.Truncated = truncated
+			if len(body) > 0 {
+				buf := new(bytes.Buffer)
+				if err := json.Compact(buf, body); err == nil {
+					record.Query = buf.String()
+				} else {
+					record.Query = string(body)
+				}
+			}
+		}
+
+		audit.Write(ctx, record)
+	}
+}
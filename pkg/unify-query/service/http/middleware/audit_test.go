@@ -0,0 +1,155 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/audit"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
+)
+
+type auditSink struct {
+	lock    sync.Mutex
+	records []*audit.Record
+}
+
+func (s *auditSink) Name() string { return "test" }
+
+func (s *auditSink) Write(records []*audit.Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *auditSink) Close() error { return nil }
+
+func TestAudit(t *testing.T) {
+	log.InitTestLogger()
+	metadata.InitMetadata()
+
+	audit.Enable = true
+	audit.Paths = []string{"/query"}
+	sink := &auditSink{}
+	audit.SetRecorder(audit.NewRecorder(sink, 10, 10, time.Hour))
+	defer func() {
+		audit.Enable = false
+		audit.Paths = nil
+	}()
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.Use(func(c *gin.Context) {
+		metadata.SetUser(c.Request.Context(), c.GetHeader(metadata.BkQuerySourceHeader), c.GetHeader(metadata.SpaceUIDHeader))
+		c.Next()
+	}, Audit())
+
+	var body string
+	g.POST("/query/ts", func(c *gin.Context) {
+		b, _ := c.GetRawData()
+		body = string(b)
+
+		tracker := querylimit.Start(c.Request.Context(), "bkcc__2")
+		tracker.SetQuery(`sum(a)`)
+		tracker.SetTables([]string{"system.cpu_summary"})
+		_ = tracker.AddSeries(2)
+		_ = tracker.AddPoints(10)
+		c.JSON(http.StatusOK, gin.H{"series": []string{}})
+	})
+	g.POST("/query/ts/info/tag_keys", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "wrong"})
+	})
+	g.GET("/metrics", func(c *gin.Context) {
+		c.String(http.StatusOK, "")
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/query/ts/info/tag_keys", strings.NewReader("{\n  \"table_id\": \"system.cpu\"\n}")),
+		httptest.NewRequest(http.MethodPost, "/query/ts", strings.NewReader(`{"query_list": []}`)),
+		httptest.NewRequest(http.MethodGet, "/metrics", nil),
+	} {
+		req.Header.Set(metadata.BkQuerySourceHeader, "username:admin")
+		req.Header.Set(metadata.SpaceUIDHeader, "bkcc__2")
+		g.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// 审计读取请求内容后不影响后续处理
+	assert.Equal(t, `{"query_list": []}`, body)
+
+	audit.SetRecorder(nil)
+	assert.Len(t, sink.records, 2)
+
+	// 没有查询统计时记录压缩后的原始请求
+	record := sink.records[0]
+	assert.Equal(t, `{"table_id":"system.cpu"}`, record.Query)
+	assert.Equal(t, http.StatusBadRequest, record.Code)
+	assert.Equal(t, audit.StatusFailed, record.Status)
+
+	record = sink.records[1]
+	assert.Equal(t, "admin", record.User)
+	assert.Equal(t, "username", record.Source)
+	assert.Equal(t, "bkcc__2", record.SpaceUid)
+	assert.Equal(t, "/query/ts", record.Path)
+	assert.Equal(t, "sum(a)", record.Query)
+	assert.Equal(t, []string{"system.cpu_summary"}, record.Tables)
+	assert.Equal(t, int64(2), record.Series)
+	assert.Equal(t, int64(10), record.Points)
+	assert.Equal(t, http.StatusOK, record.Code)
+	assert.Equal(t, audit.StatusSuccess, record.Status)
+	assert.Equal(t, len(`{"series":[]}`), record.ResultSize)
+}
+
+func TestAuditBodyTruncated(t *testing.T) {
+	log.InitTestLogger()
+	metadata.InitMetadata()
+
+	audit.Enable = true
+	audit.Paths = []string{"/query"}
+	audit.MaxBodySize = 8
+	sink := &auditSink{}
+	audit.SetRecorder(audit.NewRecorder(sink, 10, 10, time.Hour))
+	defer func() {
+		audit.Enable = false
+		audit.Paths = nil
+		audit.MaxBodySize = 0
+	}()
+
+	gin.SetMode(gin.TestMode)
+	g := gin.New()
+	g.Use(Audit())
+
+	var body string
+	g.POST("/query/raw", func(c *gin.Context) {
+		b, _ := c.GetRawData()
+		body = string(b)
+		c.String(http.StatusOK, "")
+	})
+
+	g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/query/raw", strings.NewReader(`{"query_string": "*"}`)))
+
+	// 审计只读取部分内容，后续处理仍能读取完整的请求
+	assert.Equal(t, `{"query_string": "*"}`, body)
+
+	audit.SetRecorder(nil)
+	assert.Len(t, sink.records, 1)
+	assert.Equal(t, `{"query_`, sink.records[0].Query)
+	assert.True(t, sink.records[0].Truncated)
+}
@@ -13,6 +13,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -84,12 +85,20 @@ func Timer(p *Params) gin.HandlerFunc {
 
 				// 记录慢查询
 				if p.SlowQueryThreshold > 0 && sub.Milliseconds() > p.SlowQueryThreshold.Milliseconds() {
-					log.Errorf(ctx,
-						fmt.Sprintf(
-							"slow query log request: %s, duration: %s",
-							c.Request.URL.Path, sub.String(),
-						),
+					slowLog := fmt.Sprintf(
+						"slow query log request: %s, duration: %s",
+						c.Request.URL.Path, sub.String(),
 					)
+					// 带上查询语句和开销统计，便于定位高开销查询
+					if cost := metadata.GetQueryCost(ctx); cost != nil {
+						slowLog = fmt.Sprintf(
+							"%s, space_uid: %s, query: %s, series: %d, points: %d, routing: %d",
+							slowLog, cost.SpaceUid, cost.Query,
+							atomic.LoadInt64(&cost.Series), atomic.LoadInt64(&cost.Points),
+							atomic.LoadInt64(&cost.Routing),
+						)
+					}
+					log.Errorf(ctx, slowLog)
 				}
 				trace.InsertIntIntoSpan("http-api-query-cost", int(sub.Milliseconds()), span)
 
@@ -0,0 +1,825 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	promPromql "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/infos"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+// 兼容 prometheus http api 的接口，空间信息仍然通过 X-Bk-Scope-Space-Uid 请求头传递
+// 参考：https://prometheus.io/docs/prometheus/latest/querying/api/
+
+const (
+	promAPIStatusSuccess = "success"
+	promAPIStatusError   = "error"
+
+	promAPIErrorBadData   = "bad_data"
+	promAPIErrorExecution = "execution"
+
+	// promAPIMaxPoints 与 prometheus 保持一致，单条 series 最多返回的点数
+	promAPIMaxPoints = 11000
+
+	promAPIMatchParam = "match[]"
+
+	// promAPISpaceMaxTables 未提供 match[] 时最多查询的结果表数量，超过则要求提供 match[]
+	promAPISpaceMaxTables = 100
+)
+
+// promAPIResponse prometheus api 的统一返回格式
+type promAPIResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// promAPIQueryData query/query_range 的返回数据
+type promAPIQueryData struct {
+	ResultType parser.ValueType `json:"resultType"`
+	Result     parser.Value     `json:"result"`
+}
+
+// promAPIExemplar query_exemplars 的返回数据
+type promAPIExemplar struct {
+	SeriesLabels map[string]string     `json:"seriesLabels"`
+	Exemplars    []promAPIExemplarItem `json:"exemplars"`
+}
+
+type promAPIExemplarItem struct {
+	Labels    map[string]string `json:"labels"`
+	Value     string            `json:"value"`
+	Timestamp float64           `json:"timestamp"`
+}
+
+type promAPIError struct {
+	typ string
+	err error
+}
+
+func (e *promAPIError) Error() string {
+	return e.err.Error()
+}
+
+func badData(err error) error {
+	return &promAPIError{typ: promAPIErrorBadData, err: err}
+}
+
+type promAPIResp struct {
+	c *gin.Context
+}
+
+func (r *promAPIResp) failed(ctx context.Context, err error) {
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusFailed, user.SpaceUid)
+
+	code, typ := http.StatusUnprocessableEntity, promAPIErrorExecution
+	if e, ok := err.(*promAPIError); ok && e.typ == promAPIErrorBadData {
+		code, typ = http.StatusBadRequest, promAPIErrorBadData
+	}
+	r.c.JSON(code, promAPIResponse{
+		Status:    promAPIStatusError,
+		ErrorType: typ,
+		Error:     err.Error(),
+	})
+}
+
+func (r *promAPIResp) success(ctx context.Context, data interface{}) {
+	user := metadata.GetUser(ctx)
+	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusSuccess, user.SpaceUid)
+	r.c.JSON(http.StatusOK, promAPIResponse{
+		Status: promAPIStatusSuccess,
+		Data:   data,
+	})
+}
+
+// parsePromAPITime 支持 unix 时间戳(可带小数)和 rfc3339 两种格式
+func parsePromAPITime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, ns := math.Modf(t)
+		return time.Unix(int64(sec), int64(ns*float64(time.Second))), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// parsePromAPIDuration 支持秒数(可带小数)和 prometheus duration 两种格式
+func parsePromAPIDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+		return time.Duration(ts), nil
+	}
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
+
+func promAPITimeParam(c *gin.Context, name string, defaultValue time.Time) (time.Time, error) {
+	val := c.Request.FormValue(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+	t, err := parsePromAPITime(val)
+	if err != nil {
+		return t, badData(fmt.Errorf("invalid parameter %q: %s", name, err))
+	}
+	return t, nil
+}
+
+// unixString 保留毫秒精度 结构化查询的 start/end 支持小数形式的秒级时间戳
+func unixString(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1e3, 'f', -1, 64)
+}
+
+// promAPIQueryTs 将 promql 转换为结构化查询后执行，返回 promql 引擎的原始结果
+func promAPIQueryTs(ctx context.Context, queryPromQL *structured.QueryPromQL) (parser.Value, error) {
+	if queryPromQL.PromQL == "" {
+		return nil, badData(fmt.Errorf("invalid parameter \"query\": query is empty"))
+	}
+	if _, err := parser.ParseExpr(queryPromQL.PromQL); err != nil {
+		return nil, badData(fmt.Errorf("invalid parameter \"query\": %s", err))
+	}
+
+	query, err := promQLToStruct(ctx, queryPromQL)
+	if err != nil {
+		return nil, badData(err)
+	}
+	return queryTsValue(ctx, query)
+}
+
+// HandlerPromAPIQuery 兼容 prometheus 的瞬时查询 /api/v1/query
+func HandlerPromAPIQuery(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &promAPIResp{c: c}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-prom-api-query")
+	if span != nil {
+		defer span.End()
+	}
+
+	ts, err := promAPITimeParam(c, "time", time.Now())
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	queryPromQL := &structured.QueryPromQL{
+		PromQL:  c.Request.FormValue("query"),
+		Start:   unixString(ts),
+		End:     unixString(ts),
+		Instant: true,
+	}
+
+	trace.InsertStringIntoSpan("query-promql", queryPromQL.PromQL, span)
+	trace.InsertStringIntoSpan("query-time", ts.String(), span)
+
+	res, err := promAPIQueryTs(ctx, queryPromQL)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	if v, ok := res.(promPromql.Vector); ok && v == nil {
+		res = promPromql.Vector{}
+	}
+	resp.success(ctx, promAPIQueryData{ResultType: res.Type(), Result: res})
+}
+
+// HandlerPromAPIQueryRange 兼容 prometheus 的范围查询 /api/v1/query_range
+func HandlerPromAPIQueryRange(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &promAPIResp{c: c}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-prom-api-query-range")
+	if span != nil {
+		defer span.End()
+	}
+
+	start, err := parsePromAPITime(c.Request.FormValue("start"))
+	if err != nil {
+		resp.failed(ctx, badData(fmt.Errorf("invalid parameter \"start\": %s", err)))
+		return
+	}
+	end, err := parsePromAPITime(c.Request.FormValue("end"))
+	if err != nil {
+		resp.failed(ctx, badData(fmt.Errorf("invalid parameter \"end\": %s", err)))
+		return
+	}
+	if end.Before(start) {
+		resp.failed(ctx, badData(fmt.Errorf("invalid parameter \"end\": end timestamp must not be before start time")))
+		return
+	}
+
+	step, err := parsePromAPIDuration(c.Request.FormValue("step"))
+	if err != nil {
+		resp.failed(ctx, badData(fmt.Errorf("invalid parameter \"step\": %s", err)))
+		return
+	}
+	if step <= 0 {
+		resp.failed(ctx, badData(fmt.Errorf("zero or negative query resolution step widths are not accepted. Try a positive integer")))
+		return
+	}
+	if end.Sub(start)/step > promAPIMaxPoints {
+		resp.failed(ctx, badData(fmt.Errorf("exceeded maximum resolution of 11,000 points per timeseries. Try decreasing the query resolution (?step=XX)")))
+		return
+	}
+
+	queryPromQL := &structured.QueryPromQL{
+		PromQL: c.Request.FormValue("query"),
+		Start:  unixString(start),
+		End:    unixString(end),
+		Step:   model.Duration(step).String(),
+	}
+
+	trace.InsertStringIntoSpan("query-promql", queryPromQL.PromQL, span)
+	trace.InsertStringIntoSpan("query-start", start.String(), span)
+	trace.InsertStringIntoSpan("query-end", end.String(), span)
+	trace.InsertStringIntoSpan("query-step", step.String(), span)
+
+	res, err := promAPIQueryTs(ctx, queryPromQL)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	if m, ok := res.(promPromql.Matrix); ok && m == nil {
+		res = promPromql.Matrix{}
+	}
+	resp.success(ctx, promAPIQueryData{ResultType: res.Type(), Result: res})
+}
+
+// promAPIMatch 解析后的 match[] 参数
+type promAPIMatch struct {
+	metric string
+	params *infos.Params
+}
+
+// parsePromAPIMatches 将 match[] 转换为 info 查询参数，每个 selector 都需要指定指标名用于路由
+func parsePromAPIMatches(ctx context.Context, c *gin.Context) ([]*promAPIMatch, error) {
+	start, err := promAPITimeParam(c, "start", time.Now().Add(-time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	end, err := promAPITimeParam(c, "end", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	_ = c.Request.ParseForm()
+	matches := c.Request.Form[promAPIMatchParam]
+	if len(matches) == 0 {
+		return nil, badData(fmt.Errorf("no match[] parameter provided"))
+	}
+
+	ret := make([]*promAPIMatch, 0, len(matches))
+	for _, m := range matches {
+		matchers, err := parser.ParseMetricSelector(m)
+		if err != nil {
+			return nil, badData(err)
+		}
+		metricName, fields, err := structured.LabelMatcherToConditions(matchers)
+		if err != nil {
+			return nil, badData(err)
+		}
+		if metricName == "" {
+			return nil, badData(fmt.Errorf("match[] %s must specify metric name", m))
+		}
+
+		route, err := structured.MakeRouteFromMetricName(metricName)
+		if err != nil {
+			return nil, badData(err)
+		}
+
+		params := &infos.Params{
+			TableID: route.TableID(),
+			Metric:  route.MetricName(),
+			Start:   unixString(start),
+			End:     unixString(end),
+		}
+		params.Conditions.FieldList = fields
+		for i := 0; i < len(fields)-1; i++ {
+			params.Conditions.ConditionList = append(params.Conditions.ConditionList, structured.ConditionAnd)
+		}
+		ret = append(ret, &promAPIMatch{metric: metricName, params: params})
+	}
+	return ret, nil
+}
+
+// promAPISpace 未提供 match[] 时查询的空间范围，按结果表批量查询而不是逐个指标查询
+type promAPISpace struct {
+	spaceUid string
+	tableIDs []structured.TableID
+	metrics  []string
+	start    time.Time
+	end      time.Time
+}
+
+// parsePromAPISpace 未提供 match[] 时解析空间下的结果表及指标，提供了 match[] 则返回 nil
+func parsePromAPISpace(ctx context.Context, c *gin.Context) (*promAPISpace, error) {
+	_ = c.Request.ParseForm()
+	if len(c.Request.Form[promAPIMatchParam]) > 0 {
+		return nil, nil
+	}
+
+	start, err := promAPITimeParam(c, "start", time.Now().Add(-time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	end, err := promAPITimeParam(c, "end", time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user := metadata.GetUser(ctx)
+	if user.SpaceUid == "" {
+		return nil, badData(fmt.Errorf("space uid is empty"))
+	}
+
+	spaceRouter, err := influxdb.GetSpaceRouter("", "")
+	if err != nil {
+		return nil, err
+	}
+	space := spaceRouter.Get(ctx, user.SpaceUid)
+	if len(space) > promAPISpaceMaxTables {
+		return nil, badData(fmt.Errorf(
+			"space %s has %d result tables, exceeds the limit %d, match[] is required",
+			user.SpaceUid, len(space), promAPISpaceMaxTables,
+		))
+	}
+
+	s := &promAPISpace{
+		spaceUid: user.SpaceUid,
+		tableIDs: make([]structured.TableID, 0, len(space)),
+		start:    start,
+		end:      end,
+	}
+	for tableID, tsDB := range space {
+		s.tableIDs = append(s.tableIDs, structured.TableID(tableID))
+		s.metrics = append(s.metrics, tsDB.Field...)
+	}
+	sort.Slice(s.tableIDs, func(i, j int) bool {
+		return s.tableIDs[i] < s.tableIDs[j]
+	})
+	return s, nil
+}
+
+// query 各结果表的语句合并为一次异步查询
+func (s *promAPISpace) query(ctx context.Context, infoType infos.InfoType, keys ...string) (interface{}, error) {
+	params := &infos.Params{
+		Keys:  keys,
+		Start: unixString(s.start),
+		End:   unixString(s.end),
+	}
+	tables, err := infos.QueryAsyncByTableIDs(ctx, infoType, params, s.tableIDs, s.spaceUid)
+	if err != nil {
+		return nil, err
+	}
+	return convertInfoData(ctx, infoType, params, tables)
+}
+
+// labelNames 空间下全部结果表的维度名
+func (s *promAPISpace) labelNames(ctx context.Context) ([]string, error) {
+	data, err := s.query(ctx, infos.TagKeys)
+	if err != nil {
+		return nil, err
+	}
+	names, _ := data.([]string)
+	return names, nil
+}
+
+// labelValues 空间下全部结果表的维度值，__name__ 直接返回路由中的指标
+func (s *promAPISpace) labelValues(ctx context.Context, name string) ([]string, error) {
+	if name == labels.MetricName {
+		return s.metrics, nil
+	}
+
+	data, err := s.query(ctx, infos.TagValues, name)
+	if err != nil {
+		return nil, err
+	}
+	if values, ok := data.(*TagValuesData); ok {
+		return values.Values[name], nil
+	}
+	return nil, nil
+}
+
+// promAPIReferenceMatcher info 查询统一使用引用名作为指标名
+func promAPIReferenceMatcher() *labels.Matcher {
+	return labels.MustNewMatcher(labels.MatchEqual, labels.MetricName, prometheus.ReferenceName)
+}
+
+// HandlerPromAPISeries 兼容 prometheus 的 /api/v1/series
+func HandlerPromAPISeries(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &promAPIResp{c: c}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-prom-api-series")
+	if span != nil {
+		defer span.End()
+	}
+
+	matches, err := parsePromAPIMatches(ctx, c)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	if isStreamRequest(c) {
+		resp.stream(ctx, func(w *streamWriter) (*StreamTrailer, error) {
+			trailer := &StreamTrailer{}
+			err := promAPISelectSeries(ctx, matches, func(lbs labels.Labels) error {
+				trailer.SeriesNum++
+				return w.write(lbs)
+			})
+			return trailer, err
+		})
+		return
+	}
+
+	data := make([]labels.Labels, 0)
+	err = promAPISelectSeries(ctx, matches, func(lbs labels.Labels) error {
+		data = append(data, lbs)
+		return nil
+	})
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	resp.success(ctx, data)
+}
+
+// promAPISelectSeries 迭代各个 match[] 对应的 SeriesSet，对去重后的 series 回调
+func promAPISelectSeries(ctx context.Context, matches []*promAPIMatch, fn func(lbs labels.Labels) error) error {
+	exists := make(map[string]struct{})
+	for _, m := range matches {
+		q, err := newInfoQuerier(ctx, m.params)
+		if err != nil {
+			return err
+		}
+
+		start, _ := m.params.StartTimeUnix()
+		end, _ := m.params.EndTimeUnix()
+		set := q.Select(true, &storage.SelectHints{
+			Start: start * 1e3,
+			End:   end * 1e3,
+			Func:  "series",
+		}, promAPIReferenceMatcher())
+
+		for set.Next() {
+			builder := labels.NewBuilder(set.At().Labels())
+			builder.Del(influxdb.BKTaskIndex)
+			// 指标名还原为请求中的名称
+			builder.Set(labels.MetricName, m.metric)
+			lbs := builder.Labels(nil)
+
+			key := lbs.String()
+			if _, ok := exists[key]; ok {
+				continue
+			}
+			exists[key] = struct{}{}
+			if err = fn(lbs); err != nil {
+				return err
+			}
+		}
+		if set.Err() != nil {
+			return set.Err()
+		}
+	}
+	return nil
+}
+
+// HandlerPromAPILabels 兼容 prometheus 的 /api/v1/labels 未提供 match[] 时按结果表返回空间下的维度
+func HandlerPromAPILabels(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &promAPIResp{c: c}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-prom-api-labels")
+	if span != nil {
+		defer span.End()
+	}
+
+	space, err := parsePromAPISpace(ctx, c)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	names := map[string]struct{}{labels.MetricName: {}}
+	if space != nil {
+		lbs, err := space.labelNames(ctx)
+		if err != nil {
+			resp.failed(ctx, err)
+			return
+		}
+		for _, name := range lbs {
+			if name == influxdb.BKTaskIndex {
+				continue
+			}
+			names[name] = struct{}{}
+		}
+		resp.success(ctx, sortedKeys(names))
+		return
+	}
+
+	matches, err := parsePromAPIMatches(ctx, c)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	for _, m := range matches {
+		q, err := newInfoQuerier(ctx, m.params)
+		if err != nil {
+			resp.failed(ctx, err)
+			return
+		}
+		lbs, _, err := q.LabelNames(promAPIReferenceMatcher())
+		if err != nil {
+			resp.failed(ctx, err)
+			return
+		}
+		for _, name := range lbs {
+			if name == influxdb.BKTaskIndex {
+				continue
+			}
+			names[name] = struct{}{}
+		}
+	}
+
+	resp.success(ctx, sortedKeys(names))
+}
+
+// HandlerPromAPILabelValues 兼容 prometheus 的 /api/v1/label/:label_name/values
+// __name__ 直接返回 match[] 中的指标名 未提供 match[] 时返回空间下的全部指标
+func HandlerPromAPILabelValues(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &promAPIResp{c: c}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-prom-api-label-values")
+	if span != nil {
+		defer span.End()
+	}
+
+	name := c.Param("label_name")
+	if !model.LabelNameRE.MatchString(name) {
+		resp.failed(ctx, badData(fmt.Errorf("invalid label name: %q", name)))
+		return
+	}
+
+	space, err := parsePromAPISpace(ctx, c)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	values := make(map[string]struct{})
+	if space != nil {
+		lvs, err := space.labelValues(ctx, name)
+		if err != nil {
+			resp.failed(ctx, err)
+			return
+		}
+		for _, v := range lvs {
+			values[v] = struct{}{}
+		}
+		resp.success(ctx, sortedKeys(values))
+		return
+	}
+
+	matches, err := parsePromAPIMatches(ctx, c)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	for _, m := range matches {
+		if name == labels.MetricName {
+			values[m.metric] = struct{}{}
+			continue
+		}
+
+		q, err := newInfoQuerier(ctx, m.params)
+		if err != nil {
+			resp.failed(ctx, err)
+			return
+		}
+		lvs, _, err := q.LabelValues(name, promAPIReferenceMatcher())
+		if err != nil {
+			resp.failed(ctx, err)
+			return
+		}
+		for _, v := range lvs {
+			values[v] = struct{}{}
+		}
+	}
+
+	resp.success(ctx, sortedKeys(values))
+}
+
+// HandlerPromAPIQueryExemplars 兼容 prometheus 的 /api/v1/query_exemplars
+// exemplar 标签中的 bk_trace_id/bk_span_id 转换为 trace_id/span_id
+func HandlerPromAPIQueryExemplars(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &promAPIResp{c: c}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-prom-api-query-exemplars")
+	if span != nil {
+		defer span.End()
+	}
+
+	start, err := promAPITimeParam(c, "start", time.Now().Add(-time.Hour))
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+	end, err := promAPITimeParam(c, "end", time.Now())
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	qs := c.Request.FormValue("query")
+	if _, err = parser.ParseExpr(qs); err != nil {
+		resp.failed(ctx, badData(fmt.Errorf("invalid parameter \"query\": %s", err)))
+		return
+	}
+
+	query, err := promQLToStruct(ctx, &structured.QueryPromQL{
+		PromQL: qs,
+		Start:  unixString(start),
+		End:    unixString(end),
+	})
+	if err != nil {
+		resp.failed(ctx, badData(err))
+		return
+	}
+	for _, q := range query.QueryList {
+		q.FieldList = []string{
+			exemplarTraceIDField, exemplarSpanIDField, exemplarTraceValueField, exemplarTraceTimestampField,
+		}
+	}
+
+	trace.InsertStringIntoSpan("query-promql", qs, span)
+
+	tables, err := queryExemplarTables(ctx, query)
+	if err != nil {
+		resp.failed(ctx, err)
+		return
+	}
+
+	resp.success(ctx, convertPromAPIExemplars(tables))
+}
+
+const (
+	exemplarTraceIDField        = "bk_trace_id"
+	exemplarSpanIDField         = "bk_span_id"
+	exemplarTraceValueField     = "bk_trace_value"
+	exemplarTraceTimestampField = "bk_trace_timestamp"
+)
+
+// convertPromAPIExemplars 将 influxdb 表格式转换为 prometheus exemplar 格式
+func convertPromAPIExemplars(tables *influxdb.Tables) []promAPIExemplar {
+	ret := make([]promAPIExemplar, 0, tables.Length())
+	for _, table := range tables.Tables {
+		seriesLabels := make(map[string]string, len(table.GroupKeys)+1)
+		for i, k := range table.GroupKeys {
+			seriesLabels[k] = table.GroupValues[i]
+		}
+		seriesLabels[labels.MetricName] = table.MetricName
+
+		index := make(map[string]int, len(table.Headers))
+		for i, h := range table.Headers {
+			index[h] = i
+		}
+
+		exemplars := make([]promAPIExemplarItem, 0, len(table.Data))
+		for _, row := range table.Data {
+			item := promAPIExemplarItem{Labels: make(map[string]string)}
+			if i, ok := index[exemplarTraceIDField]; ok && row[i] != nil {
+				item.Labels["trace_id"] = fmt.Sprint(row[i])
+			}
+			if i, ok := index[exemplarSpanIDField]; ok && row[i] != nil {
+				item.Labels["span_id"] = fmt.Sprint(row[i])
+			}
+
+			v, ok := exemplarFloat(row, index, exemplarTraceValueField)
+			if !ok {
+				v, _ = exemplarFloat(row, index, influxdb.ResultColumnName)
+			}
+			item.Value = strconv.FormatFloat(v, 'f', -1, 64)
+
+			if ts, ok := exemplarFloat(row, index, exemplarTraceTimestampField); ok {
+				// bk_trace_timestamp 单位为 ms
+				item.Timestamp = ts / 1e3
+			} else if i, ok := index[influxdb.TimeColumnName]; ok {
+				if t, ok := exemplarTime(row[i]); ok {
+					item.Timestamp = float64(t.UnixMilli()) / 1e3
+				}
+			}
+			exemplars = append(exemplars, item)
+		}
+
+		ret = append(ret, promAPIExemplar{SeriesLabels: seriesLabels, Exemplars: exemplars})
+	}
+	return ret
+}
+
+func exemplarFloat(row []interface{}, index map[string]int, column string) (float64, bool) {
+	i, ok := index[column]
+	if !ok || i >= len(row) {
+		return 0, false
+	}
+
+	switch v := row[i].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// exemplarTime 基于不同的通信协议(json/x-msgpack)会有不同的时间格式
+func exemplarTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case string:
+		ret, err := time.Parse(time.RFC3339Nano, t)
+		return ret, err == nil
+	case time.Time:
+		return t, true
+	case float64:
+		return time.Unix(0, int64(t)), true
+	}
+	return time.Time{}, false
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
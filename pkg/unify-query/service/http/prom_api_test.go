@@ -0,0 +1,188 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+)
+
+func TestParsePromAPITime(t *testing.T) {
+	ts, err := parsePromAPITime("1682149980.5")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1682149980500), ts.UnixMilli())
+
+	ts, err = parsePromAPITime("2023-04-22T07:53:00Z")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1682149980), ts.Unix())
+
+	_, err = parsePromAPITime("abc")
+	assert.NotNil(t, err)
+}
+
+func TestParsePromAPIDuration(t *testing.T) {
+	d, err := parsePromAPIDuration("15")
+	assert.Nil(t, err)
+	assert.Equal(t, 15*time.Second, d)
+
+	d, err = parsePromAPIDuration("1m")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, d)
+
+	_, err = parsePromAPIDuration("1x")
+	assert.NotNil(t, err)
+}
+
+func TestUnixString(t *testing.T) {
+	assert.Equal(t, "1682149980", unixString(time.Unix(1682149980, 0)))
+	assert.Equal(t, "1682149980.5", unixString(time.UnixMilli(1682149980500)))
+	assert.Equal(t, "1682149980.123", unixString(time.Unix(1682149980, 123456789)))
+}
+
+func TestPromAPISpace(t *testing.T) {
+	log.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+	MockSpace(t)
+
+	ctx := context.Background()
+	metadata.SetUser(ctx, "", "bkcc__2")
+
+	rw := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rw)
+
+	// 未提供 match[] 时按结果表查询空间
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/labels?start=0&end=600", nil)
+	space, err := parsePromAPISpace(ctx, c)
+	assert.Nil(t, err)
+	assert.Equal(t, []structured.TableID{
+		"2_bkmonitor_time_series_1582626.__default__",
+		"64_bkmonitor_time_series_1573412.__default__",
+		"system.cpu_summary",
+	}, space.tableIDs)
+	assert.Equal(t, int64(0), space.start.Unix())
+	assert.Equal(t, int64(600), space.end.Unix())
+
+	// __name__ 直接取路由中的指标，不需要查询存储
+	metrics, err := space.labelValues(ctx, labels.MetricName)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{
+		"metric", "metric2",
+		"bkbcs_workqueue_adds_total", "container_cpu_usage_seconds_total_value", "container_cpu_system_seconds_total",
+		"jvm_memory_bytes_used", "jvm_memory_bytes_max",
+	}, metrics)
+
+	// 提供了 match[] 则按 selector 查询
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/labels?match[]=metric", nil)
+	space, err = parsePromAPISpace(ctx, c)
+	assert.Nil(t, err)
+	assert.Nil(t, space)
+
+	// series 仍然要求 match[]
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/series", nil)
+	_, err = parsePromAPIMatches(ctx, c)
+	assert.NotNil(t, err)
+}
+
+func TestPromAPISpaceTooManyTables(t *testing.T) {
+	log.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	space := make(redis.Space)
+	for i := 0; i <= promAPISpaceMaxTables; i++ {
+		tableID := fmt.Sprintf("system.table_%d", i)
+		space[tableID] = &redis.TsDB{TableID: tableID, Field: []string{"value"}}
+	}
+
+	ctx := context.Background()
+	sr, _ := influxdb.GetSpaceRouter("", "")
+	sr.Add(ctx, "bkcc__too_many_tables", space)
+	metadata.SetUser(ctx, "", "bkcc__too_many_tables")
+
+	rw := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rw)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/labels", nil)
+
+	_, err := parsePromAPISpace(ctx, c)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "match[] is required")
+}
+
+func TestPromAPIBadData(t *testing.T) {
+	log.InitTestLogger()
+	gin.SetMode(gin.TestMode)
+
+	g := gin.New()
+	registerPromAPIService(g)
+
+	testCases := map[string]string{
+		"/api/v1/query?query=up&time=abc":                        "invalid parameter \"time\"",
+		"/api/v1/query?query=sum(":                               "invalid parameter \"query\"",
+		"/api/v1/query_range?query=up&start=1&end=0&step=1":      "end timestamp must not be before start time",
+		"/api/v1/query_range?query=up&start=0&end=100&step=0":    "zero or negative query resolution step",
+		"/api/v1/query_range?query=up&start=0&end=100000&step=1": "exceeded maximum resolution",
+		"/api/v1/series":                                     "no match[] parameter provided",
+		"/api/v1/labels?match[]={job=\"a\"}":                 "must specify metric name",
+		"/api/v1/label/a-b/values?match[]=up":                "invalid label name",
+		"/api/v1/query_exemplars?query=sum(&start=0&end=100": "invalid parameter \"query\"",
+	}
+	for url, msg := range testCases {
+		t.Run(url, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			rw := httptest.NewRecorder()
+			g.ServeHTTP(rw, req)
+			assert.Equal(t, http.StatusBadRequest, rw.Code)
+
+			var resp promAPIResponse
+			assert.Nil(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+			assert.Equal(t, promAPIStatusError, resp.Status)
+			assert.Equal(t, promAPIErrorBadData, resp.ErrorType)
+			assert.Contains(t, resp.Error, msg)
+		})
+	}
+}
+
+func TestConvertPromAPIExemplars(t *testing.T) {
+	tables := influxdb.NewTables()
+	tables.Add(&influxdb.Table{
+		MetricName:  "bk_apm_duration",
+		Headers:     []string{influxdb.ResultColumnName, influxdb.TimeColumnName, "bk_trace_id", "bk_span_id", "bk_trace_value", "bk_trace_timestamp"},
+		GroupKeys:   []string{"service_name"},
+		GroupValues: []string{"api"},
+		Data: [][]interface{}{
+			{json.Number("1"), "2023-04-22T07:53:00Z", "t1", "s1", json.Number("19"), json.Number("1682149980123")},
+			{json.Number("2"), "2023-04-22T07:53:00Z", "t2", "s2", nil, nil},
+		},
+	})
+
+	out, err := json.Marshal(convertPromAPIExemplars(tables))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `[{
+		"seriesLabels": {"__name__": "bk_apm_duration", "service_name": "api"},
+		"exemplars": [
+			{"labels": {"trace_id": "t1", "span_id": "s1"}, "value": "19", "timestamp": 1682149980.123},
+			{"labels": {"trace_id": "t2", "span_id": "s2"}, "value": "2", "timestamp": 1682149980}
+		]
+	}]`, string(out))
+}
@@ -52,54 +52,59 @@ func NewPromData(dimensions []string) *PromData {
 func (d *PromData) Fill(tables *promql.Tables) error {
 	d.Tables = make([]*TablesItem, 0)
 	for index, table := range tables.Tables {
-		tableItem := new(TablesItem)
-		tableItem.Name = fmt.Sprintf("_result%d", index)
-		tableItem.MetricName = table.MetricName
-		tableItem.Columns = make([]string, 0, len(table.Headers))
-		tableItem.Types = make([]string, 0, len(table.Headers))
-		tableItem.GroupKeys = table.GroupKeys
-		tableItem.GroupValues = table.GroupValues
-		keyMap := make(map[string]bool)
-		for _, key := range table.GroupKeys {
-			keyMap[key] = true
-		}
+		d.Tables = append(d.Tables, d.newTablesItem(index, table))
+	}
+	return nil
 
-		indexList := make([]int, 0, len(table.Headers))
-		for index, header := range table.Headers {
-			// 是key则不输出
-			if _, ok := keyMap[header]; ok {
-				continue
-			}
-			if len(d.dimensions) != 0 {
-				if _, ok := d.dimensions[header]; !ok {
-					continue
-				}
-			}
-			// 记录需要返回的字段及其索引
-			tableItem.Columns = append(tableItem.Columns, header)
-			tableItem.Types = append(tableItem.Types, table.Types[index])
-			indexList = append(indexList, index)
+}
+
+// newTablesItem 按返回维度将单个 table 转换为返回格式，流式返回时逐条调用
+func (d *PromData) newTablesItem(index int, table *promql.Table) *TablesItem {
+	tableItem := new(TablesItem)
+	tableItem.Name = fmt.Sprintf("_result%d", index)
+	tableItem.MetricName = table.MetricName
+	tableItem.Columns = make([]string, 0, len(table.Headers))
+	tableItem.Types = make([]string, 0, len(table.Headers))
+	tableItem.GroupKeys = table.GroupKeys
+	tableItem.GroupValues = table.GroupValues
+	keyMap := make(map[string]bool)
+	for _, key := range table.GroupKeys {
+		keyMap[key] = true
+	}
+
+	indexList := make([]int, 0, len(table.Headers))
+	for index, header := range table.Headers {
+		// 是key则不输出
+		if _, ok := keyMap[header]; ok {
+			continue
 		}
-		values := make([][]interface{}, 0)
-		for _, data := range table.Data {
-			value := make([]interface{}, len(indexList))
-			for valueIndex, headerIndex := range indexList {
-				value[valueIndex] = data[headerIndex]
+		if len(d.dimensions) != 0 {
+			if _, ok := d.dimensions[header]; !ok {
+				continue
 			}
-
-			values = append(values, value)
 		}
-		tableItem.Values = values
-		d.Tables = append(d.Tables, tableItem)
+		// 记录需要返回的字段及其索引
+		tableItem.Columns = append(tableItem.Columns, header)
+		tableItem.Types = append(tableItem.Types, table.Types[index])
+		indexList = append(indexList, index)
 	}
-	return nil
+	values := make([][]interface{}, 0)
+	for _, data := range table.Data {
+		value := make([]interface{}, len(indexList))
+		for valueIndex, headerIndex := range indexList {
+			value[valueIndex] = data[headerIndex]
+		}
 
+		values = append(values, value)
+	}
+	tableItem.Values = values
+	return tableItem
 }
 
 // Downsample 对结果数据进行降采样
-func (d *PromData) Downsample(factor float64) {
+func (d *PromData) Downsample(factor float64, function string) {
 	for _, table := range d.Tables {
-		points := downsample.Downsample(table.GetPromPoints(), factor)
+		points := downsample.Downsample(table.GetPromPoints(), factor, function)
 		table.SetValuesByPoints(points)
 	}
 }
@@ -11,6 +11,7 @@ package http
 
 import (
 	"context"
+	"net/http"
 	"path"
 
 	"github.com/gin-gonic/gin"
@@ -51,6 +52,20 @@ func registerTSQueryExemplarService(g *gin.Engine) {
 	log.Infof(context.TODO(), "ts service register in path->[%s]", servicePath)
 }
 
+// registerTSQueryExplainService: /query/ts/explain
+func registerTSQueryExplainService(g *gin.Engine) {
+	servicePath := viper.GetString(TSQueryExplainHandlePathConfigPath)
+	g.POST(servicePath, HandlerQueryTsExplain)
+	log.Infof(context.TODO(), "ts service register in path->[%s]", servicePath)
+}
+
+// registerTSQueryRawService: /query/ts/raw
+func registerTSQueryRawService(g *gin.Engine) {
+	servicePath := viper.GetString(TSQueryRawHandlePathConfigPath)
+	g.POST(servicePath, HandlerQueryRaw)
+	log.Infof(context.TODO(), "ts service register in path->[%s]", servicePath)
+}
+
 // registerTSQueryStructToPromQLService: /query/ts/struct_to_promql
 func registerTSQueryStructToPromQLService(g *gin.Engine) {
 	servicePath := viper.GetString(TSQueryStructToPromQLHandlePathConfigPath)
@@ -100,6 +115,22 @@ func registerLabelValuesService(g *gin.Engine) {
 	log.Infof(context.TODO(), "ts service register in path->[%s]", servicePath)
 }
 
+// registerPromAPIService: /api/v1 兼容 prometheus http api
+func registerPromAPIService(g *gin.Engine) {
+	servicePath := viper.GetString(PromAPIHandlePathConfigPath)
+	group := g.Group(servicePath)
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		group.Handle(method, "/query", HandlerPromAPIQuery)
+		group.Handle(method, "/query_range", HandlerPromAPIQueryRange)
+		group.Handle(method, "/series", HandlerPromAPISeries)
+		group.Handle(method, "/labels", HandlerPromAPILabels)
+		group.Handle(method, "/query_exemplars", HandlerPromAPIQueryExemplars)
+	}
+	group.GET("/label/:label_name/values", HandlerPromAPILabelValues)
+
+	log.Infof(context.TODO(), "prometheus api service register in path->[%s]", servicePath)
+}
+
 // registerPrint: /print
 func registerPrint(g *gin.Engine) {
 	servicePath := viper.GetString(PrintHandlePathConfigPath)
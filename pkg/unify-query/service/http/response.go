@@ -20,6 +20,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
 )
 
 type response struct {
@@ -30,6 +31,15 @@ func (r *response) failed(ctx context.Context, err error) {
 	log.Errorf(ctx, err.Error())
 	user := metadata.GetUser(ctx)
 	metric.APIRequestInc(ctx, r.c.Request.URL.Path, metric.StatusFailed, user.SpaceUid)
+
+	// 查询开销超限时返回独立的状态码，便于调用方区分
+	if querylimit.IsLimitError(err) {
+		r.c.JSON(http.StatusUnprocessableEntity, ErrResponse{
+			Code: metadata.ExceedsQueryCostLimit,
+			Err:  err.Error(),
+		})
+		return
+	}
 	r.c.JSON(http.StatusBadRequest, ErrResponse{
 		Err: err.Error(),
 	})
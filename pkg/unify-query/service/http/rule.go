@@ -0,0 +1,70 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	promPromql "github.com/prometheus/prometheus/promql"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// RecordingRuleSource 预计算规则查询的来源
+const RecordingRuleSource = "recording_rule"
+
+// QueryRange 在指定空间下执行 promql 范围查询，复用结构化查询的执行流程，供预计算规则使用
+func QueryRange(
+	ctx context.Context, spaceUid, promQL string, start, end time.Time, step time.Duration,
+) (promPromql.Matrix, error) {
+	var (
+		span oleltrace.Span
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "recording-rule-query-range")
+	if span != nil {
+		defer span.End()
+	}
+
+	metadata.SetUser(ctx, RecordingRuleSource, spaceUid)
+
+	trace.InsertStringIntoSpan("query-space-uid", spaceUid, span)
+	trace.InsertStringIntoSpan("query-promql", promQL, span)
+	trace.InsertStringIntoSpan("query-start", start.String(), span)
+	trace.InsertStringIntoSpan("query-end", end.String(), span)
+	trace.InsertStringIntoSpan("query-step", step.String(), span)
+
+	query, err := promQLToStruct(ctx, &structured.QueryPromQL{
+		PromQL: promQL,
+		Start:  unixString(start),
+		End:    unixString(end),
+		Step:   model.Duration(step).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := queryTsValue(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix, ok := res.(promPromql.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result type %s", res.Type())
+	}
+	return matrix, nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/audit"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/http/api"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/http/middleware"
@@ -78,6 +79,7 @@ func (s *Service) Reload(ctx context.Context) {
 		middleware.Timer(&middleware.Params{
 			SlowQueryThreshold: SlowQueryThreshold,
 		}),
+		middleware.Audit(),
 	)
 	log.Debugf(context.TODO(), "middleware register done.")
 
@@ -86,12 +88,15 @@ func (s *Service) Reload(ctx context.Context) {
 	// ts查询底层依赖flux实现，所以没有自己的服务
 	registerTSQueryService(s.g)
 	registerTSQueryExemplarService(s.g)
+	registerTSQueryExplainService(s.g)
+	registerTSQueryRawService(s.g)
 	registerTSQueryPromQLService(s.g)
 	registerTSQueryStructToPromQLService(s.g)
 	registerTSQueryPromQLToStructService(s.g)
 	registerLabelValuesService(s.g)
 	registerTSQueryInfoService(s.g)
 	registerESService(s.g)
+	registerPromAPIService(s.g)
 	registerProfile(s.g)
 	registerPrint(s.g)
 	registerInfluxDBPrint(s.g)
@@ -143,4 +148,7 @@ func (s *Service) Wait() {
 func (s *Service) Close() {
 	s.cancelFunc()
 	log.Infof(context.TODO(), "http service context cancel func called.")
+
+	// 写出缓冲区中剩余的审计记录
+	audit.SetRecorder(nil)
 }
@@ -39,6 +39,8 @@ const (
 	TSQueryHandlePathConfigPath               = "http.path.ts"
 	TSQueryInfoHandlePathConfigPath           = "http.path.ts_info"
 	TSQueryExemplarHandlePathConfigPath       = "http.path.ts_exemplar"
+	TSQueryExplainHandlePathConfigPath        = "http.path.ts_explain"
+	TSQueryRawHandlePathConfigPath            = "http.path.ts_raw"
 	TSQueryPromQLHandlePathConfigPath         = "http.path.ts_promql"
 	TSQueryStructToPromQLHandlePathConfigPath = "http.path.ts_struct_to_promql"
 	TSQueryPromQLToStructHandlePathConfigPath = "http.path.ts_promql_to_struct"
@@ -49,10 +51,15 @@ const (
 	SpacePrintHandlePathConfigPath            = "http.path.space_print"
 	FeatureFlagHandlePathConfigPath           = "http.path.feature_flag_path"
 	ESHandlePathConfigPath                    = "http.path.es"
+	PromAPIHandlePathConfigPath               = "http.path.prom_api"
 
 	// 查询配置
 	InfoDefaultLimit = "http.info.limit"
 
+	// 流式返回配置
+	StreamFlushLinesConfigPath    = "http.stream.flush_lines"
+	StreamMaxLinePointsConfigPath = "http.stream.max_line_points"
+
 	// 分段查询配置
 	SegmentedEnable      = "http.segmented.enable"
 	SegmentedMaxRoutines = "http.segmented.max_routines"
@@ -76,4 +83,9 @@ var (
 	DefaultInfoLimit int
 
 	QueryMaxRouting int
+
+	// StreamFlushLines 流式返回时每写入多少行刷新一次缓冲区
+	StreamFlushLines int
+	// StreamMaxLinePoints 原始数据流式返回时单行最多包含的点数，超过则拆分为多行
+	StreamMaxLinePoints int
 )
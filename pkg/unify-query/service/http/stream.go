@@ -0,0 +1,501 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	promPromql "github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/downsample"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/infos"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
+	servicePromql "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/promql"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/prometheus"
+)
+
+const (
+	// StreamFormatNDJSON 通过 ?stream=ndjson 开启流式返回
+	StreamFormatNDJSON = "ndjson"
+
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// isStreamRequest 判断请求是否要求流式返回，支持 query 参数 stream=ndjson 或 Accept: application/x-ndjson
+func isStreamRequest(c *gin.Context) bool {
+	if c.Query("stream") == StreamFormatNDJSON {
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+}
+
+// StreamTrailer 流式返回的最后一行，调用方以 done 判断数据是否完整
+type StreamTrailer struct {
+	Done      bool             `json:"done"`
+	SeriesNum int              `json:"series_num"`
+	PointsNum int              `json:"points_num"`
+	Status    *metadata.Status `json:"status,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// streamWriter 按行写入 json，每 flushLines 行刷新一次缓冲区
+type streamWriter struct {
+	c   *gin.Context
+	enc *json.Encoder
+
+	started    bool
+	lines      int
+	flushLines int
+}
+
+func newStreamWriter(c *gin.Context) *streamWriter {
+	flushLines := StreamFlushLines
+	if flushLines <= 0 {
+		flushLines = 1
+	}
+	return &streamWriter{
+		c:          c,
+		enc:        json.NewEncoder(c.Writer),
+		flushLines: flushLines,
+	}
+}
+
+// write 写入一行数据，第一次写入时输出状态码和 header
+func (w *streamWriter) write(v interface{}) error {
+	if !w.started {
+		w.c.Header("Content-Type", ndjsonContentType)
+		w.c.Header("X-Content-Type-Options", "nosniff")
+		w.c.Status(http.StatusOK)
+		w.started = true
+	}
+
+	if err := w.enc.Encode(v); err != nil {
+		return err
+	}
+
+	w.lines++
+	if w.lines%w.flushLines == 0 {
+		w.c.Writer.Flush()
+	}
+	return nil
+}
+
+// flush 将缓冲区中剩余的数据写出
+func (w *streamWriter) flush() {
+	if w.started {
+		w.c.Writer.Flush()
+	}
+}
+
+// streamResponse 以 NDJSON 格式流式返回，fn 中逐行写入数据，完成后追加 StreamTrailer；
+// 在写出第一行之前出错则按 failed 正常返回错误，之后出错只能在 trailer 中携带错误信息
+func streamResponse(
+	ctx context.Context, c *gin.Context, failed func(context.Context, error),
+	fn func(w *streamWriter) (*StreamTrailer, error),
+) {
+	var (
+		span oleltrace.Span
+		user = metadata.GetUser(ctx)
+		w    = newStreamWriter(c)
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "stream-response")
+	if span != nil {
+		defer span.End()
+	}
+
+	trailer, err := fn(w)
+	if trailer == nil {
+		trailer = &StreamTrailer{}
+	}
+	trace.InsertIntIntoSpan("stream-lines", w.lines, span)
+	trace.InsertIntIntoSpan("stream-series-num", trailer.SeriesNum, span)
+	trace.InsertIntIntoSpan("stream-points-num", trailer.PointsNum, span)
+
+	if err != nil {
+		if !w.started {
+			failed(ctx, err)
+			return
+		}
+		log.Errorf(ctx, err.Error())
+		metric.APIRequestInc(ctx, c.Request.URL.Path, metric.StatusFailed, user.SpaceUid)
+		trailer.Error = err.Error()
+	} else {
+		metric.APIRequestInc(ctx, c.Request.URL.Path, metric.StatusSuccess, user.SpaceUid)
+		trailer.Done = true
+	}
+
+	if err = w.write(trailer); err != nil {
+		log.Errorf(ctx, "write stream trailer error: %s", err)
+	}
+	w.flush()
+}
+
+func (r *response) stream(ctx context.Context, fn func(w *streamWriter) (*StreamTrailer, error)) {
+	streamResponse(ctx, r.c, r.failed, fn)
+}
+
+func (r *promAPIResp) stream(ctx context.Context, fn func(w *streamWriter) (*StreamTrailer, error)) {
+	streamResponse(ctx, r.c, r.failed, fn)
+}
+
+// streamWindows 将范围查询按 StreamMaxLinePoints 个步长拆分为多个时间窗口依次计算，避免在内存中构建完整的结果
+// 各步长的计算相互独立，拆分后结果不变；使用了 @ start()/end() 的查询依赖整体时间范围，不做拆分
+func streamWindows(query *structured.QueryTs, plan *queryTsPlan) [][2]time.Time {
+	if query.Instant || plan.step <= 0 || hasStartOrEnd(plan.promQL) {
+		return [][2]time.Time{{plan.start, plan.end}}
+	}
+
+	maxLinePoints := StreamMaxLinePoints
+	if maxLinePoints <= 0 {
+		maxLinePoints = 10000
+	}
+	window := time.Duration(maxLinePoints-1) * plan.step
+
+	windows := make([][2]time.Time, 0)
+	for start := plan.start; !start.After(plan.end); start = start.Add(window + plan.step) {
+		end := start.Add(window)
+		if end.After(plan.end) {
+			end = plan.end
+		}
+		windows = append(windows, [2]time.Time{start, end})
+	}
+	return windows
+}
+
+// hasStartOrEnd 判断查询是否使用了 @ start() 或 @ end()
+func hasStartOrEnd(expr parser.Expr) bool {
+	var found bool
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			found = found || n.StartOrEnd != 0
+		case *parser.SubqueryExpr:
+			found = found || n.StartOrEnd != 0
+		}
+		return nil
+	})
+	return found
+}
+
+// queryTsStream 结构化查询的流式返回，按时间窗口依次计算，每个窗口内每个 series 输出一行 TablesItem
+func queryTsStream(ctx context.Context, query *structured.QueryTs, w *streamWriter) (*StreamTrailer, error) {
+	var (
+		span    oleltrace.Span
+		user    = metadata.GetUser(ctx)
+		trailer = &StreamTrailer{}
+
+		// 同一个 series 在多个时间窗口中输出，按 labels hash 统计 series 数
+		seriesHash = make(map[uint64]struct{})
+		index      int
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-ts-stream")
+	if span != nil {
+		defer span.End()
+	}
+
+	plan, err := buildQueryTsPlan(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := querylimit.Start(ctx, user.SpaceUid)
+	ctx, cancel := startQueryTsTracker(ctx, tracker, plan)
+	defer cancel()
+
+	resp := NewPromData(query.ResultColumns)
+	factor, downSample := queryTsDownsampleFactor(query)
+
+	writeTable := func(table *promql.Table) error {
+		item := resp.newTablesItem(index, table)
+		if downSample {
+			item.SetValuesByPoints(downsample.Downsample(item.GetPromPoints(), factor, query.DownSampleFunction))
+		}
+		index++
+		return w.write(item)
+	}
+
+	windows := streamWindows(query, plan)
+	trace.InsertIntIntoSpan("stream-windows", len(windows), span)
+
+	for _, window := range windows {
+		res, err := evalQueryTsPlan(ctx, query, plan, tracker, window[0], window[1])
+		if err != nil {
+			return trailer, err
+		}
+
+		switch v := res.(type) {
+		case promPromql.Matrix:
+			for i, series := range v {
+				if err = writeTable(promql.NewTable(index, series)); err != nil {
+					return trailer, err
+				}
+				seriesHash[series.Metric.Hash()] = struct{}{}
+				trailer.PointsNum += len(series.Points)
+
+				// 已写出的 series 不再持有引用，尽早释放内存
+				v[i] = promPromql.Series{}
+			}
+		case promPromql.Vector:
+			for _, sample := range v {
+				if err = writeTable(promql.NewTableWithSample(index, sample)); err != nil {
+					return trailer, err
+				}
+				seriesHash[sample.Metric.Hash()] = struct{}{}
+				trailer.PointsNum++
+			}
+		default:
+			return trailer, fmt.Errorf("data type wrong: %T", v)
+		}
+		trailer.SeriesNum = len(seriesHash)
+	}
+
+	trace.InsertIntIntoSpan("resp-series-num", trailer.SeriesNum, span)
+	trace.InsertIntIntoSpan("resp-points-num", trailer.PointsNum, span)
+
+	trailer.Status = metadata.GetStatus(ctx)
+	return trailer, nil
+}
+
+// RawSeries 原始数据流式返回的单行数据，点数超过 StreamMaxLinePoints 时同一个 series 会拆分为多行
+type RawSeries struct {
+	Reference string            `json:"reference"`
+	Labels    map[string]string `json:"labels"`
+	// Values 每个点为 [毫秒时间戳, 值]，值与 prometheus 一致格式化为字符串以支持 NaN、Inf
+	Values [][2]interface{} `json:"values"`
+}
+
+// HandlerQueryRaw
+// @Summary  query raw data by ts
+// @ID       ts-query-raw-request
+// @Produce  application/x-ndjson
+// @Param    traceparent            header    string                          false  "TraceID" default(00-3967ac0f1648bf0216b27631730d7eb9-8e3c31d5109e78dd-01)
+// @Param    Bk-Query-Source   		header    string                          false  "来源" default(username:goodman)
+// @Param    X-Bk-Scope-Space-Uid   header    string                          false  "空间UID" default(bkcc__2)
+// @Param    data                  	body      structured.QueryTs  			  true   "json data"
+// @Success  200                   	{object}  RawSeries
+// @Failure  400                   	{object}  ErrResponse
+// @Router   /query/ts/raw [post]
+func HandlerQueryRaw(c *gin.Context) {
+	var (
+		ctx  = c.Request.Context()
+		span oleltrace.Span
+		resp = &response{
+			c: c,
+		}
+		user = metadata.GetUser(ctx)
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "handler-query-raw")
+	if span != nil {
+		defer span.End()
+	}
+
+	trace.InsertStringIntoSpan("request-url", c.Request.URL.String(), span)
+	trace.InsertStringIntoSpan("request-header", fmt.Sprintf("%+v", c.Request.Header), span)
+	trace.InsertStringIntoSpan("query-source", user.Key, span)
+	trace.InsertStringIntoSpan("query-space-uid", user.SpaceUid, span)
+
+	query := &structured.QueryTs{}
+	err := json.NewDecoder(c.Request.Body).Decode(query)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		resp.failed(ctx, err)
+		return
+	}
+
+	if user.SpaceUid != "" {
+		query.SpaceUid = user.SpaceUid
+	}
+
+	queryStr, _ := json.Marshal(query)
+	trace.InsertStringIntoSpan("query-body", string(queryStr), span)
+
+	// 原始数据量无法预估，只支持流式返回
+	resp.stream(ctx, func(w *streamWriter) (*StreamTrailer, error) {
+		return queryRawStream(ctx, query, w)
+	})
+}
+
+// queryRawStream 跳过 promql 计算，直接迭代存储返回的 SeriesSet 输出原始数据
+func queryRawStream(ctx context.Context, query *structured.QueryTs, w *streamWriter) (*StreamTrailer, error) {
+	var (
+		span    oleltrace.Span
+		user    = metadata.GetUser(ctx)
+		trailer = &StreamTrailer{}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-raw-stream")
+	if span != nil {
+		defer span.End()
+	}
+
+	plan, err := buildQueryTsPlan(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if plan.vmQuery {
+		return nil, fmt.Errorf("raw query is not supported by %s", plan.instance.GetInstanceType())
+	}
+
+	tracker := querylimit.Start(ctx, user.SpaceUid)
+	ctx, cancel := startQueryTsTracker(ctx, tracker, plan)
+	defer cancel()
+
+	// 瞬时查询按 lookBackDelta 往前取原始数据
+	start, end := plan.start, plan.end
+	if query.Instant {
+		lookBackDelta := plan.lookBackDelta
+		if lookBackDelta == 0 {
+			lookBackDelta = servicePromql.LookbackDelta
+		}
+		start = end.Add(-lookBackDelta)
+	}
+
+	hints := &storage.SelectHints{
+		Start: start.UnixMilli(),
+		End:   end.UnixMilli(),
+		Step:  plan.step.Milliseconds(),
+	}
+
+	referenceNames := make([]string, 0, len(plan.reference))
+	for name := range plan.reference {
+		referenceNames = append(referenceNames, name)
+	}
+	sort.Strings(referenceNames)
+
+	// 按存储依次迭代，不合并各存储的结果，series 读取后直接写出
+	for _, name := range referenceNames {
+		err = prometheus.SelectRaw(ctx, hints, name, func(set storage.SeriesSet) error {
+			return writeRawSeriesSet(ctx, name, set, w, trailer)
+		})
+		if err != nil {
+			return trailer, err
+		}
+	}
+
+	if err = tracker.Err(); err != nil {
+		return trailer, err
+	}
+
+	trace.InsertIntIntoSpan("resp-series-num", trailer.SeriesNum, span)
+	trace.InsertIntIntoSpan("resp-points-num", trailer.PointsNum, span)
+
+	trailer.Status = metadata.GetStatus(ctx)
+	return trailer, nil
+}
+
+// writeRawSeriesSet 逐个迭代 series 和点写出，不在内存中缓存完整结果
+func writeRawSeriesSet(
+	ctx context.Context, reference string, set storage.SeriesSet, w *streamWriter, trailer *StreamTrailer,
+) error {
+	var (
+		it            chunkenc.Iterator
+		maxLinePoints = StreamMaxLinePoints
+	)
+	if maxLinePoints <= 0 {
+		maxLinePoints = 10000
+	}
+
+	for set.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		series := set.At()
+		line := &RawSeries{
+			Reference: reference,
+			Labels:    series.Labels().Map(),
+		}
+
+		it = series.Iterator(it)
+		for it.Next() != chunkenc.ValNone {
+			t, v := it.At()
+			line.Values = append(line.Values, [2]interface{}{t, strconv.FormatFloat(v, 'f', -1, 64)})
+			if len(line.Values) >= maxLinePoints {
+				trailer.PointsNum += len(line.Values)
+				if err := w.write(line); err != nil {
+					return err
+				}
+				line.Values = line.Values[:0]
+			}
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+
+		if len(line.Values) > 0 {
+			trailer.PointsNum += len(line.Values)
+			if err := w.write(line); err != nil {
+				return err
+			}
+		}
+		trailer.SeriesNum++
+	}
+
+	return set.Err()
+}
+
+// StreamSeriesItem series 流式返回的单行数据，只有第一行携带 keys
+type StreamSeriesItem struct {
+	Keys   []string `json:"keys,omitempty"`
+	Values []string `json:"values"`
+}
+
+// querySeriesStream info series 的流式返回，每个去重后的 series 输出一行
+func querySeriesStream(ctx context.Context, params *infos.Params, w *streamWriter) (*StreamTrailer, error) {
+	var (
+		span    oleltrace.Span
+		trailer = &StreamTrailer{}
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "query-series-stream")
+	if span != nil {
+		defer span.End()
+	}
+
+	q, err := newInfoQuerier(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	err = selectSeries(ctx, q, params, func(keys, values []string) error {
+		item := &StreamSeriesItem{
+			Values: values,
+		}
+		if trailer.SeriesNum == 0 {
+			item.Keys = keys
+		}
+		trailer.SeriesNum++
+		return w.write(item)
+	})
+
+	trace.InsertIntIntoSpan("resp-series-num", trailer.SeriesNum, span)
+	return trailer, err
+}
@@ -0,0 +1,261 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/structured"
+)
+
+type testSample struct {
+	t int64
+	v float64
+}
+
+func (s testSample) T() int64                      { return s.t }
+func (s testSample) V() float64                    { return s.v }
+func (s testSample) H() *histogram.Histogram       { return nil }
+func (s testSample) FH() *histogram.FloatHistogram { return nil }
+func (s testSample) Type() chunkenc.ValueType      { return chunkenc.ValFloat }
+
+type testSeriesSet struct {
+	series []storage.Series
+	index  int
+}
+
+func (s *testSeriesSet) Next() bool {
+	s.index++
+	return s.index <= len(s.series)
+}
+
+func (s *testSeriesSet) At() storage.Series         { return s.series[s.index-1] }
+func (s *testSeriesSet) Err() error                 { return nil }
+func (s *testSeriesSet) Warnings() storage.Warnings { return nil }
+
+func newTestStreamContext(target string, header map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, target, nil)
+	for k, v := range header {
+		c.Request.Header.Set(k, v)
+	}
+	return c, recorder
+}
+
+func readStreamLines(t *testing.T, body string) []map[string]interface{} {
+	lines := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestIsStreamRequest(t *testing.T) {
+	testCases := map[string]struct {
+		target string
+		header map[string]string
+		stream bool
+	}{
+		"default": {
+			target: "/query/ts",
+		},
+		"query param": {
+			target: "/query/ts?stream=ndjson",
+			stream: true,
+		},
+		"unknown format": {
+			target: "/query/ts?stream=csv",
+		},
+		"accept header": {
+			target: "/query/ts",
+			header: map[string]string{"Accept": "application/x-ndjson"},
+			stream: true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, _ := newTestStreamContext(c.target, c.header)
+			assert.Equal(t, c.stream, isStreamRequest(ctx))
+		})
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	log.InitTestLogger()
+	StreamFlushLines = 2
+
+	testCases := map[string]struct {
+		rows    int
+		err     error
+		code    int
+		lines   int
+		done    bool
+		failed  bool
+		errLine string
+	}{
+		"success": {
+			rows:  3,
+			code:  http.StatusOK,
+			lines: 4,
+			done:  true,
+		},
+		"empty": {
+			code:  http.StatusOK,
+			lines: 1,
+			done:  true,
+		},
+		"failed before write": {
+			err:    errors.New("route error"),
+			code:   http.StatusBadRequest,
+			failed: true,
+		},
+		"failed after write": {
+			rows:    2,
+			err:     errors.New("storage error"),
+			code:    http.StatusOK,
+			lines:   3,
+			errLine: "storage error",
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ctx, recorder := newTestStreamContext("/query/ts?stream=ndjson", nil)
+			resp := &response{c: ctx}
+
+			resp.stream(context.Background(), func(w *streamWriter) (*StreamTrailer, error) {
+				trailer := &StreamTrailer{}
+				for i := 0; i < c.rows; i++ {
+					if err := w.write(&StreamSeriesItem{Values: []string{"v"}}); err != nil {
+						return trailer, err
+					}
+					trailer.SeriesNum++
+				}
+				return trailer, c.err
+			})
+
+			assert.Equal(t, c.code, recorder.Code)
+			if c.failed {
+				assert.Contains(t, recorder.Body.String(), c.err.Error())
+				return
+			}
+
+			assert.Equal(t, ndjsonContentType, recorder.Header().Get("Content-Type"))
+			lines := readStreamLines(t, recorder.Body.String())
+			assert.Len(t, lines, c.lines)
+
+			trailer := lines[len(lines)-1]
+			assert.Equal(t, c.done, trailer["done"])
+			assert.Equal(t, float64(c.rows), trailer["series_num"])
+			if c.errLine != "" {
+				assert.Equal(t, c.errLine, trailer["error"])
+			}
+		})
+	}
+}
+
+func TestWriteRawSeriesSet(t *testing.T) {
+	log.InitTestLogger()
+	StreamMaxLinePoints = 2
+
+	samples := make([]tsdbutil.Sample, 0)
+	for i := 0; i < 5; i++ {
+		samples = append(samples, testSample{t: int64(i) * 1e3, v: float64(i)})
+	}
+	set := &testSeriesSet{
+		series: []storage.Series{
+			storage.NewListSeries(labels.FromStrings("a", "1"), samples),
+			storage.NewListSeries(labels.FromStrings("a", "2"), []tsdbutil.Sample{testSample{t: 0, v: math.NaN()}}),
+			storage.NewListSeries(labels.FromStrings("a", "3"), nil),
+		},
+	}
+
+	ctx, recorder := newTestStreamContext("/query/ts/raw", nil)
+	w := newStreamWriter(ctx)
+	trailer := &StreamTrailer{}
+
+	err := writeRawSeriesSet(context.Background(), "a", set, w, trailer)
+	assert.Nil(t, err)
+	w.flush()
+
+	assert.Equal(t, 3, trailer.SeriesNum)
+	assert.Equal(t, 6, trailer.PointsNum)
+
+	lines := make([]*RawSeries, 0)
+	scanner := bufio.NewScanner(strings.NewReader(recorder.Body.String()))
+	for scanner.Scan() {
+		line := &RawSeries{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), line))
+		lines = append(lines, line)
+	}
+
+	// 第一个 series 按 2 个点一行拆分为 3 行，没有点的 series 不输出
+	assert.Len(t, lines, 4)
+	for i, expected := range []struct {
+		labels string
+		points int
+	}{{"1", 2}, {"1", 2}, {"1", 1}, {"2", 1}} {
+		assert.Equal(t, "a", lines[i].Reference)
+		assert.Equal(t, expected.labels, lines[i].Labels["a"])
+		assert.Len(t, lines[i].Values, expected.points)
+	}
+	assert.Equal(t, float64(4e3), lines[2].Values[0][0])
+	assert.Equal(t, "4", lines[2].Values[0][1])
+	// NaN 等特殊值格式化为字符串，json 编码不会失败
+	assert.Equal(t, "NaN", lines[3].Values[0][1])
+}
+
+func TestStreamWindows(t *testing.T) {
+	StreamMaxLinePoints = 3
+	start := time.Unix(1682149980, 0)
+
+	expr, err := parser.ParseExpr(`sum(rate(a[1m]))`)
+	assert.Nil(t, err)
+	plan := &queryTsPlan{promQL: expr, start: start, end: start.Add(7 * time.Minute), step: time.Minute}
+
+	// 8 个步长按 3 个一组拆分，窗口首尾相接且不重叠
+	assert.Equal(t, [][2]time.Time{
+		{start, start.Add(2 * time.Minute)},
+		{start.Add(3 * time.Minute), start.Add(5 * time.Minute)},
+		{start.Add(6 * time.Minute), start.Add(7 * time.Minute)},
+	}, streamWindows(&structured.QueryTs{}, plan))
+
+	// 瞬时查询及使用 @ end() 的查询不拆分
+	whole := [][2]time.Time{{plan.start, plan.end}}
+	assert.Equal(t, whole, streamWindows(&structured.QueryTs{Instant: true}, plan))
+
+	plan.promQL, err = parser.ParseExpr(`sum(a @ end())`)
+	assert.Nil(t, err)
+	assert.Equal(t, whole, streamWindows(&structured.QueryTs{}, plan))
+}
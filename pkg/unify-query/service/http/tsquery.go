@@ -15,7 +15,6 @@ import (
 	"io"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/gddo/httputil/header"
@@ -403,7 +402,7 @@ func handleTSQuery(
 	// 降采样逻辑，根据DownSampleRange，如果大于Step则进行降采样处理
 	var ok bool
 	var factor float64
-	if ok, factor, err = downsample.CheckDownSampleRange(query.Step, query.DownSampleRange); ok && err != nil {
+	if ok, factor, err = downsample.CheckDownSampleRange(query.Step, query.DownSampleRange); ok && err == nil {
 		var info *TimeInfo
 		if info, err = getTimeInfo(&structured.CombinedQueryParams{
 			Start: query.Start,
@@ -411,7 +410,7 @@ func handleTSQuery(
 			Step:  query.DownSampleRange,
 		}); err == nil {
 			log.Debugf(context.TODO(), "respData to downsample: %+v", info)
-			respData.Downsample(factor)
+			respData.Downsample(factor, query.DownSampleFunction)
 		}
 	}
 
@@ -466,10 +465,10 @@ func handleTsQueryParams(ctx context.Context, queryStmt string, onlyParse bool,
 		}
 	}
 
-	si, _ := strconv.ParseInt(query.Start, 10, 64)
-	ei, _ := strconv.ParseInt(query.End, 10, 64)
-	startStr := time.Unix(si, 0).String()
-	endStr := time.Unix(ei, 0).String()
+	st, _ := structured.ParseUnixTimestamp(query.Start)
+	et, _ := structured.ParseUnixTimestamp(query.End)
+	startStr := st.String()
+	endStr := et.String()
 
 	trace.InsertStringIntoSpan("start-str", startStr, span)
 	trace.InsertStringIntoSpan("end-str", endStr, span)
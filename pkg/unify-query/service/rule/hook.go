@@ -0,0 +1,73 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/eventbus"
+	inner "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/rule"
+)
+
+// setDefaultConfig
+func setDefaultConfig() {
+	viper.SetDefault(EnableConfigPath, false)
+	viper.SetDefault(DefaultIntervalConfigPath, "1m")
+	viper.SetDefault(DefaultTargetConfigPath, inner.TargetInfluxDB)
+	viper.SetDefault(EvalDelayConfigPath, "1m")
+	viper.SetDefault(MaxBackfillConfigPath, "6h")
+	viper.SetDefault(MaxPointsConfigPath, 1000)
+	viper.SetDefault(TimeoutConfigPath, "5m")
+	viper.SetDefault(CheckpointTTLConfigPath, "168h")
+	viper.SetDefault(CheckpointStorageConfigPath, CheckpointStorageRedis)
+
+	viper.SetDefault(InfluxDBAddressConfigPath, "http://bk-influxdb-proxy:10203")
+	viper.SetDefault(VmAddressConfigPath, "")
+}
+
+// LoadConfig
+func LoadConfig() {
+	Enable = viper.GetBool(EnableConfigPath)
+	DefaultInterval = viper.GetDuration(DefaultIntervalConfigPath)
+	DefaultTarget = viper.GetString(DefaultTargetConfigPath)
+	EvalDelay = viper.GetDuration(EvalDelayConfigPath)
+	MaxBackfill = viper.GetDuration(MaxBackfillConfigPath)
+	MaxPoints = viper.GetInt(MaxPointsConfigPath)
+	Timeout = viper.GetDuration(TimeoutConfigPath)
+	CheckpointTTL = viper.GetDuration(CheckpointTTLConfigPath)
+	CheckpointStorage = viper.GetString(CheckpointStorageConfigPath)
+
+	InfluxDBAddress = viper.GetString(InfluxDBAddressConfigPath)
+	InfluxDBUsername = viper.GetString(InfluxDBUsernameConfigPath)
+	InfluxDBPassword = viper.GetString(InfluxDBPasswordConfigPath)
+
+	VmAddress = viper.GetString(VmAddressConfigPath)
+	VmUsername = viper.GetString(VmUsernameConfigPath)
+	VmPassword = viper.GetString(VmPasswordConfigPath)
+}
+
+// init
+func init() {
+	if err := eventbus.EventBus.Subscribe(eventbus.EventSignalConfigPreParse, setDefaultConfig); err != nil {
+		fmt.Printf(
+			"failed to subscribe event->[%s] for rule module for default config, maybe rule module won't working.",
+			eventbus.EventSignalConfigPreParse,
+		)
+	}
+
+	if err := eventbus.EventBus.Subscribe(eventbus.EventSignalConfigPostParse, LoadConfig); err != nil {
+		fmt.Printf(
+			"failed to subscribe event->[%s] for rule module for new config, maybe rule module won't working.",
+			eventbus.EventSignalConfigPostParse,
+		)
+	}
+}
@@ -0,0 +1,226 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/curl"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/redis"
+	inner "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/rule"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/service/http"
+)
+
+// Service 预计算规则服务，规则变更时重新启动全部计算任务
+type Service struct {
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	wg         *sync.WaitGroup
+
+	manager       *inner.Manager
+	managerCancel context.CancelFunc
+	rulesHash     string
+
+	checkpoint inner.Checkpoint
+	locker     inner.Locker
+}
+
+// Type
+func (s *Service) Type() string {
+	return "rule"
+}
+
+// Start
+func (s *Service) Start(ctx context.Context) {
+	s.Reload(ctx)
+}
+
+// Reload
+func (s *Service) Reload(ctx context.Context) {
+	if s.wg == nil {
+		s.wg = new(sync.WaitGroup)
+	}
+	// 关闭上一次的服务
+	if s.cancelFunc != nil {
+		s.cancelFunc()
+	}
+
+	log.Debugf(context.TODO(), "waiting for rule service close")
+	s.Wait()
+
+	if !Enable {
+		log.Infof(context.TODO(), "rule service is disabled")
+		return
+	}
+
+	var err error
+	s.checkpoint, s.locker, err = newCheckpointAndLocker()
+	if err != nil {
+		log.Errorf(context.TODO(), "start rule service failed for->[%s]", err)
+		return
+	}
+
+	s.ctx, s.cancelFunc = context.WithCancel(ctx)
+	s.rulesHash = ""
+
+	err = s.loopReloadRules(s.ctx)
+	if err != nil {
+		log.Errorf(context.TODO(), "start loop reload rules failed for->[%s]", err)
+		return
+	}
+
+	log.Warnf(context.TODO(), "rule service reloaded or start success.")
+}
+
+// Wait
+func (s *Service) Wait() {
+	if s.wg != nil {
+		s.wg.Wait()
+	}
+}
+
+// Close
+func (s *Service) Close() {
+	if s.cancelFunc != nil {
+		s.cancelFunc()
+	}
+	log.Infof(context.TODO(), "rule service context cancel func called.")
+}
+
+// loopReloadRules 监听 consul 中的规则变更
+func (s *Service) loopReloadRules(ctx context.Context) error {
+	ch, err := consul.WatchRecordingRules(ctx)
+	if err != nil {
+		return err
+	}
+	s.reloadRules(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.stopManager()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Warnf(context.TODO(), "rule reload loop exit")
+				return
+			case <-ch:
+				log.Debugf(context.TODO(), "get recording rule changed notify")
+				s.reloadRules(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadRules 加载规则，有变更时重启计算任务
+func (s *Service) reloadRules(ctx context.Context) {
+	data, err := consul.GetRecordingRules()
+	if err != nil {
+		log.Errorf(context.TODO(), "get recording rules from consul failed,error:%s", err)
+		return
+	}
+	hash := consul.HashIt(data)
+	if hash == s.rulesHash {
+		log.Debugf(context.TODO(), "recording rules hash not changed")
+		return
+	}
+	s.rulesHash = hash
+
+	rules := make([]*inner.Rule, 0)
+	for spaceUid, list := range data {
+		for _, r := range list {
+			rule, err := inner.NewRule(spaceUid, r, DefaultInterval, DefaultTarget)
+			if err != nil {
+				log.Errorf(context.TODO(), "invalid recording rule of %s: %s", spaceUid, err)
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Key() < rules[j].Key()
+	})
+
+	s.stopManager()
+
+	managerCtx, cancel := context.WithCancel(ctx)
+	s.manager = inner.NewManager(s.options())
+	s.managerCancel = cancel
+	s.manager.Run(managerCtx, rules)
+
+	log.Infof(context.TODO(), "recording rule manager started with %d rules", len(rules))
+}
+
+func (s *Service) stopManager() {
+	if s.managerCancel != nil {
+		s.managerCancel()
+	}
+	if s.manager != nil {
+		s.manager.Wait()
+	}
+	s.manager, s.managerCancel = nil, nil
+}
+
+// newCheckpointAndLocker 按配置创建计算进度及计算租约的存储
+func newCheckpointAndLocker() (inner.Checkpoint, inner.Locker, error) {
+	switch CheckpointStorage {
+	case CheckpointStorageRedis:
+		if redis.Client() == nil {
+			return nil, nil, fmt.Errorf(
+				"redis is not configured, set %s to %s for single instance deployment",
+				CheckpointStorageConfigPath, CheckpointStorageMemory,
+			)
+		}
+		return inner.NewRedisCheckpoint(CheckpointTTL), inner.NewRedisLocker(), nil
+	case CheckpointStorageMemory:
+		// 计算进度只记录在内存中，重启后不会补算，多实例部署时会重复计算
+		log.Warnf(context.TODO(), "recording rule checkpoint will be kept in memory, only single instance is supported")
+		return inner.NewMemoryCheckpoint(), inner.NewLocalLocker(), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown %s: %s", CheckpointStorageConfigPath, CheckpointStorage)
+	}
+}
+
+func (s *Service) options() *inner.Options {
+	writers := make(map[string]inner.Writer)
+	if InfluxDBAddress != "" {
+		writers[inner.TargetInfluxDB] = &inner.InfluxDBWriter{
+			Curl:     &curl.HttpCurl{Log: log.OtLogger},
+			Address:  InfluxDBAddress,
+			Username: InfluxDBUsername,
+			Password: InfluxDBPassword,
+		}
+	}
+	if VmAddress != "" {
+		writers[inner.TargetVictoriaMetrics] = &inner.VmWriter{
+			Curl:     &curl.HttpCurl{Log: log.OtLogger},
+			Address:  VmAddress,
+			Username: VmUsername,
+			Password: VmPassword,
+		}
+	}
+
+	return &inner.Options{
+		Query:       http.QueryRange,
+		Writers:     writers,
+		Checkpoint:  s.checkpoint,
+		Locker:      s.locker,
+		Delay:       EvalDelay,
+		MaxBackfill: MaxBackfill,
+		MaxPoints:   MaxPoints,
+		Timeout:     Timeout,
+	}
+}
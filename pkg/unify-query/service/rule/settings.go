@@ -0,0 +1,67 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package rule
+
+import "time"
+
+const (
+	EnableConfigPath          = "rule.enable"
+	DefaultIntervalConfigPath = "rule.default_interval"
+	DefaultTargetConfigPath   = "rule.default_target"
+	EvalDelayConfigPath       = "rule.eval_delay"
+	MaxBackfillConfigPath     = "rule.max_backfill"
+	MaxPointsConfigPath       = "rule.max_points"
+	TimeoutConfigPath         = "rule.timeout"
+	CheckpointTTLConfigPath   = "rule.checkpoint_ttl"
+	// CheckpointStorageConfigPath 计算进度的存储方式，redis 或 memory
+	CheckpointStorageConfigPath = "rule.checkpoint_storage"
+
+	InfluxDBAddressConfigPath  = "rule.influxdb.address"
+	InfluxDBUsernameConfigPath = "rule.influxdb.username"
+	InfluxDBPasswordConfigPath = "rule.influxdb.password"
+
+	VmAddressConfigPath  = "rule.victoria_metrics.address"
+	VmUsernameConfigPath = "rule.victoria_metrics.username"
+	VmPasswordConfigPath = "rule.victoria_metrics.password"
+)
+
+const (
+	// CheckpointStorageRedis 计算进度及计算租约记录在 redis 中，支持多实例部署
+	CheckpointStorageRedis = "redis"
+	// CheckpointStorageMemory 计算进度记录在内存中，重启后不会补算，只能单实例部署
+	CheckpointStorageMemory = "memory"
+)
+
+var (
+	Enable          bool
+	DefaultInterval time.Duration
+	DefaultTarget   string
+	// EvalDelay 等待数据入库的时间
+	EvalDelay time.Duration
+	// MaxBackfill 停机后最多补算的时长
+	MaxBackfill time.Duration
+	// MaxPoints 补算时单次查询的最大点数
+	MaxPoints int
+	Timeout   time.Duration
+	// CheckpointTTL 计算进度在 redis 中的过期时间
+	CheckpointTTL time.Duration
+	// CheckpointStorage 计算进度的存储方式
+	CheckpointStorage string
+
+	// InfluxDBAddress influxdb-proxy 地址
+	InfluxDBAddress  string
+	InfluxDBUsername string
+	InfluxDBPassword string
+
+	// VmAddress victoriaMetrics remote write 地址
+	VmAddress  string
+	VmUsername string
+	VmPassword string
+)
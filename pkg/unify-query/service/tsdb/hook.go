@@ -15,6 +15,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/eventbus"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/elasticsearch"
 )
 
 // setDefaultConfig 配置初始化参数
@@ -54,6 +55,9 @@ func setDefaultConfig() {
 	viper.SetDefault(OfflineDataArchiveTimeoutConfigPath, "10m")
 	viper.SetDefault(OfflineDataArchiveGrpcMaxCallRecvMsgSizeConfigPath, 1024*1024*10)
 	viper.SetDefault(OfflineDataArchiveGrpcMaxCallSendMsgSizeConfigPath, 1024*1024*10)
+
+	viper.SetDefault(EsMaxSizeConfigPath, 10000)
+	viper.SetDefault(EsTimeFieldConfigPath, elasticsearch.DefaultTimeField)
 }
 
 // initConfig 加载配置
@@ -90,6 +94,9 @@ func initConfig() {
 	OfflineDataArchiveTimeout = viper.GetDuration(OfflineDataArchiveTimeoutConfigPath)
 	OfflineDataArchiveGrpcMaxCallRecvMsgSize = viper.GetInt(OfflineDataArchiveGrpcMaxCallRecvMsgSizeConfigPath)
 	OfflineDataArchiveGrpcMaxCallSendMsgSize = viper.GetInt(OfflineDataArchiveGrpcMaxCallSendMsgSizeConfigPath)
+
+	EsMaxSize = viper.GetInt(EsMaxSizeConfigPath)
+	EsTimeField = viper.GetString(EsTimeFieldConfigPath)
 }
 
 // init 初始化，通过 eventBus 加载配置读取前和读取后操作
@@ -18,6 +18,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	inner "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/elasticsearch"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/offlineDataArchive"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/victoriaMetrics"
 )
@@ -171,6 +172,15 @@ func (s *Service) reloadStorage() error {
 		},
 	})
 
+	// 增加全局 es storage 查询，具体的 es 集群通过 table_id 获取
+	inner.SetStorage(consul.ElasticsearchStorageType, &inner.Storage{
+		Type: consul.ElasticsearchStorageType,
+		Instance: &elasticsearch.Instance{
+			MaxSize:   EsMaxSize,
+			TimeField: EsTimeField,
+		},
+	})
+
 	// 增加全局 vm storage 查询
 	inner.SetStorage(consul.VictoriaMetricsStorageType, &inner.Storage{
 		Type: consul.VictoriaMetricsStorageType,
@@ -52,6 +52,10 @@ const (
 
 	OfflineDataArchiveGrpcMaxCallRecvMsgSizeConfigPath = "offline_data_archive.grpc_max_call_recv_msg_size"
 	OfflineDataArchiveGrpcMaxCallSendMsgSizeConfigPath = "offline_data_archive.grpc_max_call_send_msg_size"
+
+	// Elasticsearch 配置
+	EsMaxSizeConfigPath   = "es.max_size"
+	EsTimeFieldConfigPath = "es.time_field"
 )
 
 var (
@@ -96,4 +100,7 @@ var (
 
 	OfflineDataArchiveGrpcMaxCallRecvMsgSize int
 	OfflineDataArchiveGrpcMaxCallSendMsgSize int
+
+	EsMaxSize   int
+	EsTimeField string
 )
@@ -0,0 +1,462 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/storage"
+	promRemote "github.com/prometheus/prometheus/storage/remote"
+	oleltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/consul"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/influxdb/decoder"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	queryEs "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/query/es"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
+)
+
+// searchResponse es 查询返回，只解析聚合结果
+type searchResponse struct {
+	Error        json.RawMessage        `json:"error"`
+	Aggregations map[string]interface{} `json:"aggregations"`
+}
+
+// interval 计算 date_histogram 的聚合周期，与 influxdb 降采样保持一致，取 step 和 window 中较小的一个
+func interval(query *metadata.Query, hints *storage.SelectHints) time.Duration {
+	_, grouping, _ := query.GetDownSampleFunc(hints)
+	if grouping > 0 {
+		return grouping
+	}
+	if hints.Step > 0 {
+		return time.Duration(hints.Step) * time.Millisecond
+	}
+	if hints.Range > 0 {
+		return time.Duration(hints.Range) * time.Millisecond
+	}
+	return DefaultInterval
+}
+
+// dimensions 获取需要下推到 es 的分组维度，只取最内层的聚合方法
+func dimensions(query *metadata.Query) []string {
+	if len(query.AggregateMethodList) == 0 {
+		return nil
+	}
+	method := query.AggregateMethodList[0]
+	if method.Without {
+		return nil
+	}
+	return method.Dimensions
+}
+
+// makeBody 生成 es 聚合查询语句：按维度 terms 分组后再按时间 date_histogram 聚合
+func (i *Instance) makeBody(query *metadata.Query, hints *storage.SelectHints) (string, error) {
+	timeField := query.TimeField
+	if timeField == "" {
+		timeField = i.TimeField
+	}
+	if timeField == "" {
+		timeField = DefaultTimeField
+	}
+
+	filter := []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{
+				timeField: map[string]interface{}{
+					"gte":    hints.Start,
+					"lte":    hints.End,
+					"format": "epoch_millis",
+				},
+			},
+		},
+	}
+	if query.EsCondition != "" {
+		var cond map[string]interface{}
+		if err := json.Unmarshal([]byte(query.EsCondition), &cond); err != nil {
+			return "", err
+		}
+		filter = append(filter, cond)
+	}
+
+	dateHistogram := map[string]interface{}{
+		"field":          timeField,
+		"fixed_interval": fmt.Sprintf("%dms", interval(query, hints).Milliseconds()),
+		"min_doc_count":  0,
+	}
+	if query.Timezone != "" {
+		dateHistogram["time_zone"] = query.Timezone
+	}
+	timeAgg := map[string]interface{}{
+		"date_histogram": dateHistogram,
+	}
+	// count 直接使用桶的文档数 avg 需要按窗口合并 所以分别获取 sum 和 count
+	switch query.EsAggregation {
+	case "", metadata.COUNT:
+	case metadata.AVG:
+		timeAgg["aggs"] = map[string]interface{}{
+			sumAggName: map[string]interface{}{
+				"sum": map[string]interface{}{
+					"field": query.Field,
+				},
+			},
+			countAggName: map[string]interface{}{
+				"value_count": map[string]interface{}{
+					"field": query.Field,
+				},
+			},
+		}
+	default:
+		timeAgg["aggs"] = map[string]interface{}{
+			valueAggName: map[string]interface{}{
+				query.EsAggregation: map[string]interface{}{
+					"field": query.Field,
+				},
+			},
+		}
+	}
+
+	size := i.MaxSize
+	if query.OffsetInfo.SLimit > 0 {
+		size = query.OffsetInfo.SLimit
+	}
+
+	aggs := map[string]interface{}{
+		timeAggName: timeAgg,
+	}
+	dims := dimensions(query)
+	for idx := len(dims) - 1; idx >= 0; idx-- {
+		terms := map[string]interface{}{
+			"field": dims[idx],
+		}
+		if size > 0 {
+			terms["size"] = size
+		}
+		aggs = map[string]interface{}{
+			fmt.Sprintf(dimensionAggName, idx): map[string]interface{}{
+				"terms": terms,
+				"aggs":  aggs,
+			},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filter,
+			},
+		},
+		"aggs": aggs,
+	})
+	return string(body), err
+}
+
+// parseResponse 将 es 聚合结果转换为 prometheus 序列
+// 时间桶按 window 合并为窗口聚合值 时间戳对齐到窗口结束时间
+func parseResponse(res string, query *metadata.Query, interval, window time.Duration) (*prompb.QueryResult, error) {
+	var resp searchResponse
+	dec := json.NewDecoder(strings.NewReader(res))
+	dec.UseNumber()
+	if err := dec.Decode(&resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Error) > 0 {
+		return nil, fmt.Errorf("es query error: %s", resp.Error)
+	}
+
+	result := &prompb.QueryResult{}
+	if resp.Aggregations == nil {
+		return result, nil
+	}
+
+	if window < interval {
+		window = interval
+	}
+	w := &windowAggregator{
+		aggregation: query.EsAggregation,
+		interval:    interval.Milliseconds(),
+		window:      window.Milliseconds(),
+	}
+	err := walkBuckets(resp.Aggregations, dimensions(query), 0, nil, w, result)
+	return result, err
+}
+
+// walkBuckets 逐层展开维度桶，叶子节点为时间桶
+func walkBuckets(
+	agg map[string]interface{}, dims []string, depth int, lbs []prompb.Label, w *windowAggregator, result *prompb.QueryResult,
+) error {
+	if depth < len(dims) {
+		for _, bucket := range buckets(agg, fmt.Sprintf(dimensionAggName, depth)) {
+			nlbs := make([]prompb.Label, len(lbs), len(lbs)+1)
+			copy(nlbs, lbs)
+			nlbs = append(nlbs, prompb.Label{
+				Name:  dims[depth],
+				Value: fmt.Sprintf("%v", bucket["key"]),
+			})
+			if err := walkBuckets(bucket, dims, depth+1, nlbs, w, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	points, err := w.points(buckets(agg, timeAggName))
+	if err != nil {
+		return err
+	}
+	samples := w.samples(points)
+	if len(samples) == 0 {
+		return nil
+	}
+
+	sort.Slice(lbs, func(i, j int) bool {
+		return lbs[i].Name < lbs[j].Name
+	})
+	result.Timeseries = append(result.Timeseries, &prompb.TimeSeries{
+		Labels:  lbs,
+		Samples: samples,
+	})
+	return nil
+}
+
+// bucketPoint 单个时间桶的聚合结果 avg 使用 sum 和 count
+type bucketPoint struct {
+	start int64
+	value float64
+	sum   float64
+	count float64
+	ok    bool
+}
+
+// windowAggregator 将时间桶合并为窗口聚合值 时间单位为毫秒
+type windowAggregator struct {
+	aggregation string
+	interval    int64
+	window      int64
+}
+
+func (w *windowAggregator) points(timeBuckets []map[string]interface{}) ([]bucketPoint, error) {
+	points := make([]bucketPoint, 0, len(timeBuckets))
+	for _, bucket := range timeBuckets {
+		start, err := toFloat(bucket["key"])
+		if err != nil {
+			return nil, err
+		}
+		p := bucketPoint{start: int64(start)}
+
+		switch w.aggregation {
+		case "", metadata.COUNT:
+			if p.value, err = toFloat(bucket["doc_count"]); err != nil {
+				return nil, err
+			}
+			p.ok = true
+		case metadata.AVG:
+			sum, sumOk := aggValue(bucket, sumAggName)
+			count, countOk := aggValue(bucket, countAggName)
+			if sumOk && countOk {
+				if p.sum, err = toFloat(sum); err != nil {
+					return nil, err
+				}
+				if p.count, err = toFloat(count); err != nil {
+					return nil, err
+				}
+				p.ok = p.count > 0
+			}
+		default:
+			// 空桶没有聚合值
+			if v, ok := aggValue(bucket, valueAggName); ok {
+				if p.value, err = toFloat(v); err != nil {
+					return nil, err
+				}
+				p.ok = true
+			}
+		}
+		points = append(points, p)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].start < points[j].start
+	})
+	return points, nil
+}
+
+// samples 每个时间桶结束时输出以其为结尾的窗口聚合值
+func (w *windowAggregator) samples(points []bucketPoint) []prompb.Sample {
+	samples := make([]prompb.Sample, 0, len(points))
+	for i := range points {
+		end := points[i].start + w.interval
+
+		var (
+			value, sum, count float64
+			ok                bool
+		)
+		for j := i; j >= 0 && points[j].start >= end-w.window; j-- {
+			p := points[j]
+			if !p.ok {
+				continue
+			}
+			switch w.aggregation {
+			case "", metadata.COUNT, metadata.SUM:
+				value += p.value
+			case metadata.MIN:
+				if !ok || p.value < value {
+					value = p.value
+				}
+			case metadata.MAX:
+				if !ok || p.value > value {
+					value = p.value
+				}
+			case metadata.AVG:
+				sum += p.sum
+				count += p.count
+			}
+			ok = true
+		}
+		if !ok {
+			continue
+		}
+		if w.aggregation == metadata.AVG {
+			value = sum / count
+		}
+		samples = append(samples, prompb.Sample{
+			Timestamp: end,
+			Value:     value,
+		})
+	}
+	return samples
+}
+
+func aggValue(bucket map[string]interface{}, name string) (interface{}, bool) {
+	v, ok := bucket[name].(map[string]interface{})
+	if !ok || v["value"] == nil {
+		return nil, false
+	}
+	return v["value"], true
+}
+
+func buckets(agg map[string]interface{}, name string) []map[string]interface{} {
+	sub, ok := agg[name].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	list, ok := sub["buckets"].([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, b := range list {
+		if bucket, ok := b.(map[string]interface{}); ok {
+			result = append(result, bucket)
+		}
+	}
+	return result
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	default:
+		return 0, fmt.Errorf("invalid number type %T", v)
+	}
+}
+
+// QueryRaw 按时间聚合查询 es，返回聚合后的序列
+func (i *Instance) QueryRaw(
+	ctx context.Context,
+	query *metadata.Query,
+	hints *storage.SelectHints,
+	matchers ...*labels.Matcher,
+) storage.SeriesSet {
+	var (
+		span oleltrace.Span
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "elasticsearch-query-raw")
+	if span != nil {
+		defer span.End()
+	}
+
+	user := metadata.GetUser(ctx)
+	trace.InsertStringIntoSpan("query-space-uid", user.SpaceUid, span)
+	trace.InsertStringIntoSpan("query-source", user.Source, span)
+	trace.InsertStringIntoSpan("query-table-id", query.TableID, span)
+	trace.InsertStringIntoSpan("query-field", query.Field, span)
+	trace.InsertStringIntoSpan("query-aggregation", query.EsAggregation, span)
+
+	body, err := i.makeBody(query, hints)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		return storage.ErrSeriesSet(err)
+	}
+	trace.InsertStringIntoSpan("query-body", body, span)
+
+	res, err := queryEs.Query(&queryEs.Params{
+		TableID: query.TableID,
+		Body:    body,
+		Start:   hints.Start / 1e3,
+		End:     hints.End / 1e3,
+	})
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		return storage.ErrSeriesSet(err)
+	}
+
+	window := time.Duration(hints.Range) * time.Millisecond
+	result, err := parseResponse(res, query, interval(query, hints), window)
+	if err != nil {
+		log.Errorf(ctx, err.Error())
+		return storage.ErrSeriesSet(err)
+	}
+
+	trace.InsertIntIntoSpan("resp-series-num", len(result.Timeseries), span)
+	return promRemote.FromQueryResult(true, result)
+}
+
+func (i *Instance) QueryRange(ctx context.Context, promql string, start, end time.Time, step time.Duration) (promql.Matrix, error) {
+	return nil, nil
+}
+
+func (i *Instance) Query(ctx context.Context, promql string, end time.Time) (promql.Vector, error) {
+	return nil, nil
+}
+
+func (i *Instance) QueryExemplar(ctx context.Context, fields []string, query *metadata.Query, start, end time.Time, matchers ...*labels.Matcher) (*decoder.Response, error) {
+	return nil, nil
+}
+
+// LabelNames es 数据源不支持维度查询
+func (i *Instance) LabelNames(ctx context.Context, query *metadata.Query, start, end time.Time, matchers ...*labels.Matcher) ([]string, error) {
+	return nil, ErrNotSupported
+}
+
+// LabelValues es 数据源不支持维度查询
+func (i *Instance) LabelValues(ctx context.Context, query *metadata.Query, name string, start, end time.Time, matchers ...*labels.Matcher) ([]string, error) {
+	return nil, ErrNotSupported
+}
+
+// Series es 数据源不支持 series 查询
+func (i *Instance) Series(ctx context.Context, query *metadata.Query, start, end time.Time, matchers ...*labels.Matcher) storage.SeriesSet {
+	return storage.ErrSeriesSet(ErrNotSupported)
+}
+
+func (i *Instance) GetInstanceType() string {
+	return consul.ElasticsearchStorageType
+}
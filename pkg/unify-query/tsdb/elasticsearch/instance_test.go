@@ -0,0 +1,193 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+)
+
+func TestMakeBody(t *testing.T) {
+	ins := &Instance{MaxSize: 100}
+	hints := &storage.SelectHints{
+		Start: 1682149980000,
+		End:   1682150580000,
+		Step:  60000,
+	}
+
+	testCases := map[string]struct {
+		query    *metadata.Query
+		expected string
+	}{
+		"count": {
+			query: &metadata.Query{
+				EsCondition: `{"bool":{"filter":[{"terms":{"level":["error"]}}]}}`,
+			},
+			expected: `{"aggs":{"time":{"date_histogram":{"field":"dtEventTimeStamp","fixed_interval":"60000ms","min_doc_count":0}}},"query":{"bool":{"filter":[{"range":{"dtEventTimeStamp":{"format":"epoch_millis","gte":1682149980000,"lte":1682150580000}}},{"bool":{"filter":[{"terms":{"level":["error"]}}]}}]}},"size":0}`,
+		},
+		"sum by dimensions": {
+			query: &metadata.Query{
+				Field:         "cost",
+				TimeField:     "time",
+				EsAggregation: metadata.SUM,
+				AggregateMethodList: []metadata.AggrMethod{
+					{Name: "sum", Dimensions: []string{"ip", "level"}},
+				},
+			},
+			expected: `{"aggs":{"dimension_0":{"aggs":{"dimension_1":{"aggs":{"time":{"aggs":{"value":{"sum":{"field":"cost"}}},"date_histogram":{"field":"time","fixed_interval":"60000ms","min_doc_count":0}}},"terms":{"field":"level","size":100}}},"terms":{"field":"ip","size":100}}},"query":{"bool":{"filter":[{"range":{"time":{"format":"epoch_millis","gte":1682149980000,"lte":1682150580000}}}]}},"size":0}`,
+		},
+		"avg": {
+			query: &metadata.Query{
+				Field:         "cost",
+				TimeField:     "time",
+				EsAggregation: metadata.AVG,
+			},
+			expected: `{"aggs":{"time":{"aggs":{"count":{"value_count":{"field":"cost"}},"sum":{"sum":{"field":"cost"}}},"date_histogram":{"field":"time","fixed_interval":"60000ms","min_doc_count":0}}},"query":{"bool":{"filter":[{"range":{"time":{"format":"epoch_millis","gte":1682149980000,"lte":1682150580000}}}]}},"size":0}`,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			body, err := ins.makeBody(c.query, hints)
+			assert.Nil(t, err)
+			assert.Equal(t, c.expected, body)
+		})
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	testCases := map[string]struct {
+		res      string
+		query    *metadata.Query
+		window   time.Duration
+		expected []*prompb.TimeSeries
+		err      bool
+	}{
+		"count": {
+			res:   `{"aggregations":{"time":{"buckets":[{"key":1682149980000,"doc_count":3},{"key":1682150040000,"doc_count":0}]}}}`,
+			query: &metadata.Query{},
+			expected: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{},
+					Samples: []prompb.Sample{
+						{Timestamp: 1682150040000, Value: 3},
+						{Timestamp: 1682150100000, Value: 0},
+					},
+				},
+			},
+		},
+		"count with window": {
+			res:    `{"aggregations":{"time":{"buckets":[{"key":1682149980000,"doc_count":3},{"key":1682150040000,"doc_count":1},{"key":1682150100000,"doc_count":2}]}}}`,
+			query:  &metadata.Query{},
+			window: 2 * time.Minute,
+			expected: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{},
+					Samples: []prompb.Sample{
+						{Timestamp: 1682150040000, Value: 3},
+						{Timestamp: 1682150100000, Value: 4},
+						{Timestamp: 1682150160000, Value: 3},
+					},
+				},
+			},
+		},
+		"max with window": {
+			res:    `{"aggregations":{"time":{"buckets":[{"key":1682149980000,"doc_count":1,"value":{"value":5}},{"key":1682150040000,"doc_count":0,"value":{"value":null}},{"key":1682150100000,"doc_count":1,"value":{"value":2}}]}}}`,
+			query:  &metadata.Query{EsAggregation: metadata.MAX},
+			window: 2 * time.Minute,
+			expected: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{},
+					Samples: []prompb.Sample{
+						{Timestamp: 1682150040000, Value: 5},
+						{Timestamp: 1682150100000, Value: 5},
+						{Timestamp: 1682150160000, Value: 2},
+					},
+				},
+			},
+		},
+		"avg by dimensions": {
+			res: `{"aggregations":{"dimension_0":{"buckets":[{"key":"127.0.0.1","time":{"buckets":[{"key":1682149980000,"doc_count":2,"sum":{"value":3},"count":{"value":2}},{"key":1682150040000,"doc_count":0,"sum":{"value":0},"count":{"value":0}}]}}]}}}`,
+			query: &metadata.Query{
+				EsAggregation: metadata.AVG,
+				AggregateMethodList: []metadata.AggrMethod{
+					{Name: "avg", Dimensions: []string{"ip"}},
+				},
+			},
+			expected: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{{Name: "ip", Value: "127.0.0.1"}},
+					Samples: []prompb.Sample{
+						{Timestamp: 1682150040000, Value: 1.5},
+					},
+				},
+			},
+		},
+		"avg with window": {
+			// 窗口内的平均值由 sum 和 count 计算 而不是对桶的平均值再取平均
+			res:    `{"aggregations":{"time":{"buckets":[{"key":1682149980000,"doc_count":1,"sum":{"value":10},"count":{"value":1}},{"key":1682150040000,"doc_count":3,"sum":{"value":6},"count":{"value":3}}]}}}`,
+			query:  &metadata.Query{EsAggregation: metadata.AVG},
+			window: 2 * time.Minute,
+			expected: []*prompb.TimeSeries{
+				{
+					Labels: []prompb.Label{},
+					Samples: []prompb.Sample{
+						{Timestamp: 1682150040000, Value: 10},
+						{Timestamp: 1682150100000, Value: 4},
+					},
+				},
+			},
+		},
+		"error": {
+			res:   `{"error":{"type":"index_not_found_exception"}}`,
+			query: &metadata.Query{},
+			err:   true,
+		},
+	}
+
+	for name, c := range testCases {
+		t.Run(name, func(t *testing.T) {
+			result, err := parseResponse(c.res, c.query, time.Minute, c.window)
+			if c.err {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, len(c.expected), len(result.Timeseries))
+			for i, ts := range result.Timeseries {
+				assert.ElementsMatch(t, c.expected[i].Labels, ts.Labels)
+				assert.Equal(t, c.expected[i].Samples, ts.Samples)
+			}
+		})
+	}
+}
+
+func TestNotSupported(t *testing.T) {
+	ctx := context.Background()
+	ins := &Instance{}
+
+	_, err := ins.LabelNames(ctx, &metadata.Query{}, time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	_, err = ins.LabelValues(ctx, &metadata.Query{}, "ip", time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	set := ins.Series(ctx, &metadata.Query{}, time.Time{}, time.Time{})
+	assert.NotNil(t, set)
+	assert.False(t, set.Next())
+	assert.ErrorIs(t, set.Err(), ErrNotSupported)
+}
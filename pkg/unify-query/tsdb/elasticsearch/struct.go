@@ -0,0 +1,42 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package elasticsearch
+
+import (
+	"errors"
+	"time"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
+)
+
+const (
+	// DefaultTimeField 默认时间字段
+	DefaultTimeField = "dtEventTimeStamp"
+	// DefaultInterval 无法从查询中获取聚合周期时使用的默认周期
+	DefaultInterval = time.Minute
+
+	dimensionAggName = "dimension_%d"
+	timeAggName      = "time"
+	valueAggName     = "value"
+	sumAggName       = "sum"
+	countAggName     = "count"
+)
+
+// ErrNotSupported es 数据源仅支持聚合查询
+var ErrNotSupported = errors.New("elasticsearch storage does not support this operation")
+
+var _ tsdb.Instance = (*Instance)(nil)
+
+type Instance struct {
+	// MaxSize 单个维度聚合返回的最大桶数
+	MaxSize int
+	// TimeField 结果表未配置时间字段时使用的默认值
+	TimeField string
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
 )
@@ -354,22 +355,19 @@ func (i *Instance) downSampleCheck(
 	return newFuncName != ""
 }
 
-func (i *Instance) query(
-	ctx context.Context,
-	query *metadata.Query,
-	hints *storage.SelectHints,
-	withFieldTag bool,
-	matchers ...*labels.Matcher,
-) (*prompb.QueryResult, error) {
-	var (
-		cancel        context.CancelFunc
-		span          oleltrace.Span
-		startAnaylize time.Time
-
-		seriesNum = 0
-		pointNum  = 0
+// RawQL influxdb 原始数据查询语句
+type RawQL struct {
+	SQL   string
+	Where string
+	// IsCount 是否下推为 count 聚合
+	IsCount bool
+	// ExpandTag 需要补充到结果中的维度
+	ExpandTag []prompb.Label
+}
 
-		isCount   bool
+// MakeRawQL 生成原始数据查询语句，包含聚合下推、分组、limit/slimit 以及时区，QueryRaw 与执行计划共用
+func MakeRawQL(query *metadata.Query, hints *storage.SelectHints, withFieldTag bool, limit, slimit int64) *RawQL {
+	var (
 		sLimitStr string
 		limitStr  string
 		timezone  string
@@ -378,12 +376,8 @@ func (i *Instance) query(
 		aggField    string
 		groupingStr string
 
-		expandTag []prompb.Label
+		ql = &RawQL{}
 	)
-	ctx, span = trace.IntoContext(ctx, trace.TracerName, "influxdb-influxql-query-raw")
-	if span != nil {
-		defer span.End()
-	}
 
 	bkTaskIndex := query.TableID
 	if bkTaskIndex == "" {
@@ -412,11 +406,11 @@ func (i *Instance) query(
 			groupingStr = " group by " + strings.Join(groupList, ", ")
 		}
 
-		isCount = newFuncName == metadata.COUNT
+		ql.IsCount = newFuncName == metadata.COUNT
 		withTag = ""
 		aggField = fmt.Sprintf(`%s("%s")`, newFuncName, query.Field)
 
-		expandTag = []prompb.Label{
+		ql.ExpandTag = []prompb.Label{
 			{
 				Name:  BKTaskIndex,
 				Value: bkTaskIndex,
@@ -425,7 +419,7 @@ func (i *Instance) query(
 	} else {
 		aggField = fmt.Sprintf(`"%s"`, query.Field)
 		if withFieldTag {
-			expandTag = []prompb.Label{
+			ql.ExpandTag = []prompb.Label{
 				{
 					Name:  BKTaskIndex,
 					Value: bkTaskIndex,
@@ -434,13 +428,11 @@ func (i *Instance) query(
 		}
 	}
 
-	where := fmt.Sprintf("time > %d and time < %d", hints.Start*1e6, hints.End*1e6)
+	ql.Where = fmt.Sprintf("time > %d and time < %d", hints.Start*1e6, hints.End*1e6)
 	if query.Condition != "" {
-		where = fmt.Sprintf("%s and %s", where, query.Condition)
+		ql.Where = fmt.Sprintf("%s and %s", ql.Where, query.Condition)
 	}
 
-	limit, slimit := i.getLimitAndSlimit(query.OffsetInfo.Limit, query.OffsetInfo.SLimit)
-
 	if limit > 0 {
 		sLimitStr = fmt.Sprintf(` slimit %d`, slimit)
 	}
@@ -451,11 +443,86 @@ func (i *Instance) query(
 		timezone = fmt.Sprintf(` tz('%s')`, query.Timezone)
 	}
 
-	sql := fmt.Sprintf(
+	ql.SQL = fmt.Sprintf(
 		"select %s as %s, time as %s%s from %s where %s %s%s%s%s",
 		aggField, influxdb.ResultColumnName, influxdb.TimeColumnName, withTag, influxql.QuoteIdent(query.Measurement),
-		where, groupingStr, limitStr, sLimitStr, timezone,
+		ql.Where, groupingStr, limitStr, sLimitStr, timezone,
+	)
+	return ql
+}
+
+// rawQL 按实例配置及空间查询限制计算 limit/slimit 后生成查询语句，实例为空时仅使用查询自身的 limit/slimit
+func (i *Instance) rawQL(ctx context.Context, query *metadata.Query, hints *storage.SelectHints, withFieldTag bool) *RawQL {
+	limit, slimit := int64(query.OffsetInfo.Limit), int64(query.OffsetInfo.SLimit)
+	if i != nil {
+		limit, slimit = i.getLimitAndSlimit(query.OffsetInfo.Limit, query.OffsetInfo.SLimit)
+	}
+	limit, slimit = querylimit.FromContext(ctx).StorageLimit(limit, slimit)
+	return MakeRawQL(query, hints, withFieldTag, limit, slimit)
+}
+
+// splitQuery 复制 Query 对象，简化 field、measure 取值
+func splitQuery(query *metadata.Query, measurement, field string) *metadata.Query {
+	return &metadata.Query{
+		TableID:             query.TableID,
+		RetentionPolicy:     query.RetentionPolicy,
+		DB:                  query.DB,
+		Measurement:         measurement,
+		Field:               field,
+		Timezone:            query.Timezone,
+		LabelsMatcher:       query.LabelsMatcher,
+		IsHasOr:             query.IsHasOr,
+		AggregateMethodList: query.AggregateMethodList,
+		Condition:           query.Condition,
+		Filters:             query.Filters,
+		OffsetInfo:          query.OffsetInfo,
+		SegmentedEnable:     query.SegmentedEnable,
+	}
+}
+
+// ExplainRaw 返回 QueryRaw 通过 http 执行的查询语句，指标模糊匹配时每个 measurement + field 各一条
+// 实例为空时（如存储未注册）不应用实例的 limit/slimit 上限
+func (i *Instance) ExplainRaw(ctx context.Context, query *metadata.Query, hints *storage.SelectHints) []string {
+	measurements, fields := query.Measurements, query.Fields
+	if len(measurements) == 0 && query.Measurement != "" {
+		measurements = []string{query.Measurement}
+	}
+	if len(fields) == 0 && query.Field != "" {
+		fields = []string{query.Field}
+	}
+
+	multiFieldsFlag := len(measurements) > 1 || len(fields) > 1
+	sqls := make([]string, 0, len(measurements)*len(fields))
+	for _, measurement := range measurements {
+		for _, field := range fields {
+			sqls = append(sqls, i.rawQL(ctx, splitQuery(query, measurement, field), hints, multiFieldsFlag).SQL)
+		}
+	}
+	return sqls
+}
+
+func (i *Instance) query(
+	ctx context.Context,
+	query *metadata.Query,
+	hints *storage.SelectHints,
+	withFieldTag bool,
+	matchers ...*labels.Matcher,
+) (*prompb.QueryResult, error) {
+	var (
+		cancel        context.CancelFunc
+		span          oleltrace.Span
+		startAnaylize time.Time
+
+		seriesNum = 0
+		pointNum  = 0
 	)
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "influxdb-influxql-query-raw")
+	if span != nil {
+		defer span.End()
+	}
+
+	ql := i.rawQL(ctx, query, hints, withFieldTag)
+	sql, where, isCount, expandTag := ql.SQL, ql.Where, ql.IsCount, ql.ExpandTag
 
 	values := &url.Values{}
 	values.Set("db", query.DB)
@@ -695,6 +762,7 @@ func (i *Instance) QueryRaw(
 	}
 
 	limit, slimit := i.getLimitAndSlimit(query.OffsetInfo.Limit, query.OffsetInfo.SLimit)
+	limit, slimit = querylimit.FromContext(ctx).StorageLimit(limit, slimit)
 
 	user := metadata.GetUser(ctx)
 	trace.InsertStringIntoSpan("query-space-uid", user.SpaceUid, span)
@@ -730,22 +798,7 @@ func (i *Instance) QueryRaw(
 				set = i.grpcStream(ctx, query.DB, query.RetentionPolicy, measurement, field, where, slimit, limit)
 			} else {
 				// 复制 Query 对象，简化 field、measure 取值，传入查询方法
-				query := &metadata.Query{
-					TableID:             query.TableID,
-					RetentionPolicy:     query.RetentionPolicy,
-					DB:                  query.DB,
-					Measurement:         measurement,
-					Field:               field,
-					Timezone:            query.Timezone,
-					LabelsMatcher:       query.LabelsMatcher,
-					IsHasOr:             query.IsHasOr,
-					AggregateMethodList: query.AggregateMethodList,
-					Condition:           query.Condition,
-					Filters:             query.Filters,
-					OffsetInfo:          query.OffsetInfo,
-					SegmentedEnable:     query.SegmentedEnable,
-				}
-				res, err := i.query(ctx, query, hints, multiFieldsFlag, matchers...)
+				res, err := i.query(ctx, splitQuery(query, measurement, field), hints, multiFieldsFlag, matchers...)
 				if err != nil {
 					log.Errorf(ctx, err.Error())
 					continue
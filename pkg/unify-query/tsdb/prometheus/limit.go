@@ -0,0 +1,99 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package prometheus
+
+import (
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
+)
+
+// limitSeriesSet 在遍历过程中统计序列数和点数，超出空间限制时中断
+// 存储侧的 slimit/limit 已在 QueryRaw 中按空间限制下推，这里负责跨存储合并后的总量校验
+type limitSeriesSet struct {
+	storage.SeriesSet
+	tracker *querylimit.Tracker
+	err     error
+}
+
+func newLimitSeriesSet(set storage.SeriesSet, tracker *querylimit.Tracker) storage.SeriesSet {
+	if tracker == nil {
+		return set
+	}
+	return &limitSeriesSet{
+		SeriesSet: set,
+		tracker:   tracker,
+	}
+}
+
+func (s *limitSeriesSet) Next() bool {
+	if s.err != nil || !s.SeriesSet.Next() {
+		return false
+	}
+	if s.err = s.tracker.AddSeries(1); s.err != nil {
+		return false
+	}
+	return true
+}
+
+func (s *limitSeriesSet) At() storage.Series {
+	return &limitSeries{
+		Series:  s.SeriesSet.At(),
+		tracker: s.tracker,
+	}
+}
+
+func (s *limitSeriesSet) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.SeriesSet.Err()
+}
+
+type limitSeries struct {
+	storage.Series
+	tracker *querylimit.Tracker
+}
+
+func (s *limitSeries) Iterator(it chunkenc.Iterator) chunkenc.Iterator {
+	return &limitIterator{
+		Iterator: s.Series.Iterator(it),
+		tracker:  s.tracker,
+	}
+}
+
+// limitIterator 统计遍历的点数
+type limitIterator struct {
+	chunkenc.Iterator
+	tracker *querylimit.Tracker
+	err     error
+}
+
+func (it *limitIterator) Next() chunkenc.ValueType {
+	if it.err != nil {
+		return chunkenc.ValNone
+	}
+	vt := it.Iterator.Next()
+	if vt == chunkenc.ValNone {
+		return vt
+	}
+	if it.err = it.tracker.AddPoints(1); it.err != nil {
+		return chunkenc.ValNone
+	}
+	return vt
+}
+
+func (it *limitIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.Iterator.Err()
+}
@@ -0,0 +1,80 @@
+// Tencent is pleased to support the open source community by making
+// 蓝鲸智云 - 监控平台 (BlueKing - Monitor) available.
+// Copyright (C) 2022 THL A29 Limited, a Tencent company. All rights reserved.
+// Licensed under the MIT License (the "License"); you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at http://opensource.org/licenses/MIT
+// Unless required by applicable law or agreed to in writing, software distributed under the License is distributed on
+// an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied. See the License for the
+// specific language governing permissions and limitations under the License.
+
+package prometheus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
+)
+
+func TestLimitSeriesSet(t *testing.T) {
+	metadata.InitMetadata()
+
+	newSet := func() storage.SeriesSet {
+		samples := []prompb.Sample{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}, {Timestamp: 3, Value: 3}}
+		return remote.FromQueryResult(true, &prompb.QueryResult{
+			Timeseries: []*prompb.TimeSeries{
+				{Labels: []prompb.Label{{Name: "a", Value: "1"}}, Samples: samples},
+				{Labels: []prompb.Label{{Name: "a", Value: "2"}}, Samples: samples},
+			},
+		})
+	}
+	count := func(set storage.SeriesSet) (series, points int) {
+		for set.Next() {
+			series++
+			it := set.At().Iterator(nil)
+			for it.Next() != chunkenc.ValNone {
+				points++
+			}
+			if it.Err() != nil {
+				return
+			}
+		}
+		return
+	}
+
+	oldLimit := querylimit.DefaultLimit
+	defer func() {
+		querylimit.DefaultLimit = oldLimit
+	}()
+
+	querylimit.DefaultLimit = querylimit.Limit{}
+	set := newLimitSeriesSet(newSet(), querylimit.Start(context.Background(), "bkcc__2"))
+	series, points := count(set)
+	assert.Equal(t, 2, series)
+	assert.Equal(t, 6, points)
+	assert.NoError(t, set.Err())
+
+	querylimit.DefaultLimit = querylimit.Limit{MaxSeries: 1}
+	set = newLimitSeriesSet(newSet(), querylimit.Start(context.Background(), "bkcc__2"))
+	series, _ = count(set)
+	assert.Equal(t, 1, series)
+	assert.True(t, querylimit.IsLimitError(set.Err()))
+
+	querylimit.DefaultLimit = querylimit.Limit{MaxPoints: 4}
+	tracker := querylimit.Start(context.Background(), "bkcc__2")
+	set = newLimitSeriesSet(newSet(), tracker)
+	_, points = count(set)
+	assert.Equal(t, 4, points)
+	assert.True(t, querylimit.IsLimitError(tracker.Err()))
+
+	// 未初始化统计时不做包装
+	set = newSet()
+	assert.Equal(t, set, newLimitSeriesSet(set, nil))
+}
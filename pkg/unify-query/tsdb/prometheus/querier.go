@@ -27,6 +27,7 @@ import (
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/log"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metadata"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/metric"
+	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/querylimit"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/trace"
 	"github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb"
 	tsDBInfluxdb "github.com/TencentBlueKing/bkmonitor-datalink/pkg/unify-query/tsdb/influxdb"
@@ -121,6 +122,7 @@ func (q *Querier) selectFn(hints *storage.SelectHints, matchers ...*labels.Match
 	}
 
 	user := metadata.GetUser(ctx)
+	tracker := querylimit.FromContext(ctx)
 
 	go func() {
 		defer func() {
@@ -142,12 +144,14 @@ func (q *Querier) selectFn(hints *storage.SelectHints, matchers ...*labels.Match
 		}
 	}
 
-	trace.InsertIntIntoSpan("max-routing", q.maxRouting, span)
+	maxRouting := tracker.MaxRouting(q.maxRouting)
+	trace.InsertIntIntoSpan("max-routing", maxRouting, span)
 	trace.InsertStringIntoSpan("reference_name", referenceName, span)
 
 	queryList := q.getQueryList(referenceName)
+	tracker.AddRouting(len(queryList))
 
-	p, _ := ants.NewPoolWithFunc(q.maxRouting, func(i interface{}) {
+	p, _ := ants.NewPoolWithFunc(maxRouting, func(i interface{}) {
 		defer wg.Done()
 		index, ok := i.(int)
 		if ok {
@@ -184,7 +188,7 @@ func (q *Querier) selectFn(hints *storage.SelectHints, matchers ...*labels.Match
 	close(setCh)
 	<-recvDone
 
-	return set
+	return newLimitSeriesSet(set, tracker)
 }
 
 func (q *Querier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
@@ -215,6 +219,48 @@ func (q *Querier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels
 	}
 }
 
+// SelectRaw 按存储依次执行 QueryRaw 并回调，不合并各存储返回的 SeriesSet，调用方可直接迭代输出原始数据
+// 同一个 series 存在于多个存储时会分别回调
+func SelectRaw(
+	ctx context.Context, hints *storage.SelectHints, referenceName string, fn func(set storage.SeriesSet) error,
+) error {
+	var (
+		span oleltrace.Span
+		user = metadata.GetUser(ctx)
+	)
+
+	ctx, span = trace.IntoContext(ctx, trace.TracerName, "prometheus-select-raw")
+	if span != nil {
+		defer span.End()
+	}
+
+	matcher, err := labels.NewMatcher(labels.MatchEqual, labels.MetricName, referenceName)
+	if err != nil {
+		return err
+	}
+
+	tracker := querylimit.FromContext(ctx)
+	queryList := (&Querier{ctx: ctx}).getQueryList(referenceName)
+	tracker.AddRouting(len(queryList))
+	trace.InsertStringIntoSpan("reference_name", referenceName, span)
+
+	for _, query := range queryList {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		metric.TsDBAndTableIDRequestCountInc(
+			ctx, user.SpaceUid, query.qry.TableID, query.instance.GetInstanceType(), "query_raw",
+		)
+
+		set := newLimitSeriesSet(query.instance.QueryRaw(ctx, query.qry, hints, matcher), tracker)
+		if err = fn(set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // LabelValues 返回可能的标签(维度)值。
 // 在查询器的生命周期以外使用这些字符串是不安全的
 func (q *Querier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {